@@ -68,7 +68,7 @@ struct SushitrainApp: App {
 		AppDependencyManager.shared.add(dependency: appState)
 		appState.isLoggingToFile = enableLoggingToFile
 		self.delegate = SushitrainDelegate(appState: appState)
-		client.delegate = self.delegate
+		client.setDelegate(self.delegate, replay: true)
 		client.server?.delegate = self.delegate
 
 		// Start Syncthing node in the background
@@ -87,6 +87,9 @@ class SushitrainDelegate: NSObject {
 	@AppStorage("tapFileToPreview") var tapFileToPreview: Bool = false
 	@AppStorage("cacheThumbnailsToDisk") var cacheThumbnailsToDisk: Bool = true
 	@AppStorage("cacheThumbnailsToFolderID") var cacheThumbnailsToFolderID: String = ""
+	@AppStorage("cacheBlocksToDisk") var cacheBlocksToDisk: Bool = true
+	@AppStorage("blockCacheMemoryMegaBytes") var blockCacheMemoryMegaBytes: Int = 128
+	@AppStorage("blockCacheDiskMegaBytes") var blockCacheDiskMegaBytes: Int = 1024
 	@AppStorage("showThumbnailsInSearchResults") var showThumbnailsInSearchResults: Bool = true
 	@AppStorage("enableSwipeFilesInPreview") var enableSwipeFilesInPreview: Bool = true
 	@AppStorage("automaticallySwitchViewStyle") var automaticallySwitchViewStyle: Bool = true
@@ -364,6 +367,32 @@ struct SyncState {
 			DispatchQueue.main.async {
 				Log.info("Network path change: \(path)")
 			}
+
+			// Reconnect right away instead of waiting for the reconnect interval
+			if path.status == .satisfied, let client = client {
+				let networkType: String
+				if path.usesInterfaceType(.cellular) {
+					networkType = SushitrainNetworkTypeCellular
+				}
+				else if path.usesInterfaceType(.wifi) {
+					networkType = SushitrainNetworkTypeWiFi
+				}
+				else if path.usesInterfaceType(.wiredEthernet) {
+					networkType = SushitrainNetworkTypeWired
+				}
+				else {
+					networkType = SushitrainNetworkTypeOther
+				}
+
+				Task {
+					try? await goTask {
+						// Also sets whether we are on cellular
+						try client.setNetworkType(networkType)
+						try client.networkChanged()
+					}
+				}
+			}
+
 			if let measurement = client?.measurements {
 				Task {
 					try? await goTask {
@@ -424,6 +453,20 @@ struct SyncState {
 		self.client.server?.maxMbitsPerSecondsStreaming = Int64(self.userSettings.streamingLimitMbitsPerSec)
 		Log.info("Apply settings: streaming limit=\(self.userSettings.streamingLimitMbitsPerSec) mbits/s")
 
+		SushitrainSetBlockCacheLimits(self.userSettings.blockCacheMemoryMegaBytes, self.userSettings.blockCacheDiskMegaBytes)
+		let blockCacheDirectory =
+			self.userSettings.cacheBlocksToDisk
+			? URL.cachesDirectory.appendingPathComponent("blocks", isDirectory: true).path(percentEncoded: false) : ""
+		do {
+			try SushitrainSetBlockCacheDirectory(blockCacheDirectory)
+			Log.info(
+				"Apply settings: block cache memory=\(self.userSettings.blockCacheMemoryMegaBytes) MiB disk=\(self.userSettings.blockCacheDiskMegaBytes) MiB dir=\(blockCacheDirectory)"
+			)
+		}
+		catch {
+			Log.warn("Could not set block cache directory: \(error.localizedDescription)")
+		}
+
 		do {
 			if self.userSettings.ignoreExtraneousDefaultFiles {
 				let json = try JSONEncoder().encode(Self.defaultIgnoredExtraneousFiles)
@@ -926,6 +969,20 @@ extension SushitrainDelegate: SushitrainClientDelegateProtocol {
 			appState.changePublisher.send()
 		}
 	}
+
+	func onFolderErrorsChanged(_ folderID: String?, count: Int) {
+		let appState = self.appState
+		DispatchQueue.main.async {
+			appState.changePublisher.send()
+		}
+	}
+
+	func onConfigChanged(_ diffJSON: String?) {
+		let appState = self.appState
+		DispatchQueue.main.async {
+			appState.changePublisher.send()
+		}
+	}
 }
 
 extension SushitrainDelegate: SushitrainStreamingServerDelegateProtocol {
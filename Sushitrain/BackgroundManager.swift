@@ -17,6 +17,10 @@ import BackgroundTasks
 		// Time before the end of allotted background time to start ending the task to prevent forceful expiration by the OS
 		private static let backgroundTimeReserve: TimeInterval = 5.6
 
+		// Longest a scheduled photo back-up may run. The background time remaining is DBL_MAX while the app is in the
+		// foreground, which Task.sleep cannot handle, so the budget is capped at this.
+		private static let maxScheduledBackupBudget: TimeInterval = 30 * 60
+
 		private var currentBackgroundTask: BGTask? = nil
 		private var expireTimer: Timer? = nil
 		private var isEndingBackgroundTask = false
@@ -149,6 +153,12 @@ import BackgroundTasks
 				Log.info("Start photo backup task")
 				photoBackupTask = self.appState.photoBackup.backup(appState: self.appState, fullExport: false, isInBackground: true)
 			}
+			else {
+				// Run a scheduled back-up if one is due, within the time we have left
+				let budget = min(
+					UIApplication.shared.backgroundTimeRemaining - Self.backgroundTimeReserve, Self.maxScheduledBackupBudget)
+				photoBackupTask = self.appState.photoBackup.runIfDue(appState: self.appState, budgetSeconds: budget)
+			}
 
 			// Start background sync on long and short sync task (if enabled) and continued task
 			if appState.userSettings.longBackgroundSyncEnabled || appState.userSettings.shortBackgroundSyncEnabled
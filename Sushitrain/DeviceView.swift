@@ -210,7 +210,7 @@ struct DeviceView: View {
 					Button(
 						"Unlink device", systemImage: "trash", role: .destructive,
 						action: {
-							try? device.remove()
+							try? device.remove(true)
 							dismiss()
 						}
 					)
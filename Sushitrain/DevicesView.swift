@@ -290,7 +290,7 @@ struct LatencyView: View {
 							}
 						}.onDelete(perform: { indexSet in
 							let p = peers
-							for idx in indexSet { try? p[idx].remove() }
+							for idx in indexSet { try? p[idx].remove(true) }
 						})
 					}
 				}
@@ -675,7 +675,7 @@ struct LatencyView: View {
 		private func unlinkSelectedDevices() {
 			for peer in self.peers {
 				if self.selectedPeers.contains(peer.id) {
-					try? peer.remove()
+					try? peer.remove(true)
 				}
 			}
 
@@ -166,7 +166,9 @@ private struct FileViewerContentView: View {
 				else if file.isWebPreviewable {
 					let url = file.localNativeFileURL ?? URL(string: self.file.onDemandURL())!
 					ZStack {
-						WebView(url: url, trustFingerprints: [], isLoading: self.$loading, error: self.$error)
+						WebView(
+							url: url, trustFingerprints: StreamingServerTrust.fingerprints(server: appState.client.server),
+							isLoading: self.$loading, error: self.$error)
 							.id(url)
 							#if os(iOS)
 								.backgroundStyle(.black)
@@ -198,6 +200,7 @@ private struct FileMediaPlayer: View {
 	@Binding var visible: Bool
 
 	@State private var player: AVPlayer?
+	@State private var serverTrust: StreamingServerTrust? = nil
 	#if os(iOS)
 		@State private var session = AVAudioSession.sharedInstance()
 	#endif
@@ -280,6 +283,9 @@ private struct FileMediaPlayer: View {
 		do {
 			let url = file.localNativeFileURL ?? URL(string: self.file.onDemandURL())!
 			let avAsset = AVURLAsset(url: url)
+			let serverTrust = StreamingServerTrust(server: appState.client.server)
+			avAsset.resourceLoader.setDelegate(serverTrust, queue: .main)
+			self.serverTrust = serverTrust
 			if try await avAsset.load(.isPlayable) {
 				let player = AVPlayer(playerItem: AVPlayerItem(asset: avAsset))
 				// TODO: External playback requires us to use http://devicename.local:xxx/file/.. URLs rather than http://localhost.
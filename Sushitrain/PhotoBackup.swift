@@ -99,6 +99,238 @@ enum PhotoBackupFolderStructure: String, Codable {
 	}
 }
 
+// Routes the assets in an album to a folder other than the main back-up destination. The file name template may contain
+// directories and the placeholders {yyyy}, {MM}, {dd}, {HH}, {mm}, {ss} (the date the photo was taken, as recorded in
+// its EXIF data), {filename} (the original file name), {name} (without extension) and {ext} (without dot).
+struct PhotoBackupMapping: Codable, Equatable, Hashable {
+	var albumID: String
+	var folderID: String
+	var subpath: String = ""
+	var template: String = ""
+}
+
+// An album and the place in a folder its assets are saved to
+private struct PhotoBackupDestination {
+	let albumID: String
+	let folderID: String
+	let subDirectoryPath: String
+	let template: String?
+}
+
+// Records which files were saved for an asset. All files that make up an asset (e.g. a live photo and its video) are
+// recorded under a single entry, and only once all of them were saved.
+struct PhotoBackupLedgerEntry: Codable, Equatable {
+	var localIdentifier: String
+	var folderID: String
+	var paths: [String]
+	var savedAt: Date
+}
+
+// The outcome of checking the ledger of a folder against the folder's global index (see PhotoBackup.reconcile)
+struct PhotoBackupReconciliation {
+	let folderID: String
+	let checkedCount: Int
+	let missing: [PhotoBackupLedgerEntry]  // Assets in the ledger of which files are missing or deleted in the folder
+}
+
+final class PhotoBackupLedger: @unchecked Sendable {
+	static let shared = PhotoBackupLedger()
+
+	private struct Contents: Codable {
+		var entries: [String: [String: PhotoBackupLedgerEntry]] = [:]  // Folder ID => local identifier => entry
+		var reupload: [String: Set<String>] = [:]  // Folder ID => local identifiers to save again even when deleted
+	}
+
+	// Number of recorded assets after which the ledger is saved, even though the back-up has not finished yet
+	private static let recordsPerSave = 50
+
+	private var lock = NSLock()
+	private var cachedContents: Contents? = nil
+	private var unsavedRecords = 0
+
+	private var fileURL: URL {
+		return URL.applicationSupportDirectory.appending(path: "photo-backup-ledger.json", directoryHint: .notDirectory)
+	}
+
+	// Must be called with the lock held
+	private var contents: Contents {
+		get {
+			if let c = self.cachedContents {
+				return c
+			}
+			if let json = try? Data(contentsOf: self.fileURL) {
+				return (try? JSONDecoder().decode(Contents.self, from: json)) ?? Contents()
+			}
+			return Contents()
+		}
+		set {
+			self.cachedContents = newValue
+			self.save()
+		}
+	}
+
+	// Must be called with the lock held
+	private func save() {
+		guard let contents = self.cachedContents else { return }
+		do {
+			try FileManager.default.createDirectory(
+				at: self.fileURL.deletingLastPathComponent(), withIntermediateDirectories: true)
+			let json = try JSONEncoder().encode(contents)
+			try json.write(to: self.fileURL, options: .atomic)
+		}
+		catch {
+			Log.warn("Could not save photo back-up ledger: \(error.localizedDescription)")
+		}
+		self.unsavedRecords = 0
+	}
+
+	func entriesFor(folderID: String) -> [PhotoBackupLedgerEntry] {
+		return self.lock.withLock {
+			return Array((self.contents.entries[folderID] ?? [:]).values)
+		}
+	}
+
+	var folderIDs: [String] {
+		return self.lock.withLock {
+			return Array(self.contents.entries.keys)
+		}
+	}
+
+	// Records an asset that was saved. To avoid rewriting the whole ledger for each asset, it is only saved every so
+	// often; call flush when the back-up is done.
+	func record(_ entry: PhotoBackupLedgerEntry) {
+		self.lock.withLock {
+			var c = self.contents
+			c.entries[entry.folderID, default: [:]][entry.localIdentifier] = entry
+			c.reupload[entry.folderID]?.remove(entry.localIdentifier)
+			self.cachedContents = c
+			self.unsavedRecords += 1
+			if self.unsavedRecords >= Self.recordsPerSave {
+				self.save()
+			}
+		}
+	}
+
+	// Saves assets recorded since the ledger was last saved
+	func flush() {
+		self.lock.withLock {
+			if self.unsavedRecords > 0 {
+				self.save()
+			}
+		}
+	}
+
+	// Removes the entries, and makes the next back-up save the assets again even though they were deleted from the folder
+	func clear(folderID: String, localIdentifiers: Set<String>) {
+		self.lock.withLock {
+			var c = self.contents
+			c.entries[folderID] = c.entries[folderID]?.filter { !localIdentifiers.contains($0.key) }
+			c.reupload[folderID, default: []].formUnion(localIdentifiers)
+			self.contents = c
+		}
+	}
+
+	func reuploadsFor(folderID: String) -> Set<String> {
+		return self.lock.withLock {
+			return self.contents.reupload[folderID] ?? []
+		}
+	}
+
+	var hasReuploads: Bool {
+		return self.lock.withLock {
+			return self.contents.reupload.values.contains { !$0.isEmpty }
+		}
+	}
+
+	// Returns the entries of all folders as JSON, e.g. for exporting them
+	func exportJSON() throws -> Data {
+		let entries = self.lock.withLock { self.contents.entries }
+		let encoder = JSONEncoder()
+		encoder.outputFormatting = [.prettyPrinted, .sortedKeys]
+		encoder.dateEncodingStrategy = .iso8601
+		return try encoder.encode(entries)
+	}
+}
+
+// The files that make up a single asset: a photo and the video of a live photo, a RAW photo and its JPEG counterpart,
+// or a lone photo or video. The files are first written to a staging directory and only moved into the folder once all
+// of them were written, so that an asset is never saved partially. XMP sidecars are not saved: Photos merges their
+// metadata into the asset on import and does not make them available as resources.
+private struct PhotoBackupUnit {
+	struct Part {
+		let stagedURL: URL
+		let fileURL: URL
+		let path: EntryPath
+		let resource: PHAssetResource?  // Written to the staged URL by writeResources, unless already written
+	}
+
+	let asset: PHAsset
+	let stagingURL: URL
+	var parts: [Part] = []
+
+	init(asset: PHAsset) {
+		self.asset = asset
+		self.stagingURL = FileManager.default.temporaryDirectory.appending(
+			path: "PhotoBackup/\(UUID().uuidString)", directoryHint: .isDirectory)
+	}
+
+	var hasResourcesToWrite: Bool {
+		return self.parts.contains { $0.resource != nil }
+	}
+
+	// Adds a file to the unit and returns the URL it should be written to
+	@discardableResult mutating func add(fileURL: URL, path: EntryPath, resource: PHAssetResource? = nil) throws -> URL {
+		try FileManager.default.createDirectory(at: self.stagingURL, withIntermediateDirectories: true)
+		let stagedURL = self.stagingURL.appending(path: "\(self.parts.count)", directoryHint: .notDirectory)
+		self.parts.append(Part(stagedURL: stagedURL, fileURL: fileURL, path: path, resource: resource))
+		return stagedURL
+	}
+
+	func writeResources() async throws {
+		for part in self.parts {
+			guard let resource = part.resource else { continue }
+			let options = PHAssetResourceRequestOptions()
+			options.isNetworkAccessAllowed = false
+			try await PHAssetResourceManager.default().writeData(for: resource, toFile: part.stagedURL, options: options)
+		}
+	}
+
+	// Moves all files into place. When one of them cannot be moved, the files moved before it are removed again.
+	func commit() throws {
+		defer {
+			try? FileManager.default.removeItem(at: self.stagingURL)
+		}
+
+		var moved: [URL] = []
+		do {
+			for part in self.parts {
+				try FileManager.default.createDirectory(
+					at: part.fileURL.deletingLastPathComponent(), withIntermediateDirectories: true)
+				if FileManager.default.fileExists(atPath: part.fileURL.path(percentEncoded: false)) {
+					_ = try FileManager.default.replaceItemAt(part.fileURL, withItemAt: part.stagedURL)
+				}
+				else {
+					try FileManager.default.moveItem(at: part.stagedURL, to: part.fileURL)
+					moved.append(part.fileURL)
+				}
+
+				// Set file creation and modified date to photo creation date. The modified date is what is synced
+				if let cd = self.asset.creationDate {
+					try FileManager.default.setAttributes(
+						[FileAttributeKey.creationDate: cd, FileAttributeKey.modificationDate: cd],
+						ofItemAtPath: part.fileURL.path(percentEncoded: false))
+				}
+			}
+		}
+		catch {
+			for url in moved {
+				try? FileManager.default.removeItem(at: url)
+			}
+			throw error
+		}
+	}
+}
+
 enum PhotoSyncProgress {
 	case notStarted
 	case starting
@@ -184,6 +416,14 @@ enum PhotoSyncProgress {
 	@AppStorage("photoBackupTimeZone") var timeZone: PhotoBackupTimeZone = .current
 	@AppStorage("photoBackupLastSuccessfulChangeToken") var lastSuccessfullChangeTokenData: Data = Data()
 
+	// Additional albums that are saved to other folders (see addMapping)
+	@AppStorage("photoBackupMappings") var mappings: [PhotoBackupMapping] = []
+
+	// Scheduled back-ups (see runIfDue). An interval of zero disables the schedule.
+	@AppStorage("photoBackupScheduleIntervalHours") var scheduleIntervalHours: Int = 0
+	@AppStorage("photoBackupScheduleRequiresCharging") var scheduleRequiresCharging: Bool = false
+	@AppStorage("photoBackupScheduleRequiresWifi") var scheduleRequiresWifi: Bool = false
+
 	@Published private(set) var isSynchronizing = false
 	@Published private(set) var progress: PhotoSyncProgress = .notStarted
 	@Published private(set) var photoBackupTask: Task<(), Error>? = nil
@@ -206,6 +446,76 @@ enum PhotoSyncProgress {
 		self.photoBackupTask = nil
 	}
 
+	func setSchedule(intervalHours: Int, requiresCharging: Bool, requiresWifi: Bool) {
+		self.scheduleIntervalHours = max(0, intervalHours)
+		self.scheduleRequiresCharging = requiresCharging
+		self.scheduleRequiresWifi = requiresWifi
+	}
+
+	// Saves the assets in the album to the folder as well, in the subdirectory and following the file name template (the
+	// folder structure of the main back-up is used when the template is empty). Replaces an existing mapping for the
+	// album and folder.
+	func addMapping(albumID: String, folderID: String, subpath: String, template: String) {
+		var mappings = self.mappings.filter { !($0.albumID == albumID && $0.folderID == folderID) }
+		mappings.append(PhotoBackupMapping(albumID: albumID, folderID: folderID, subpath: subpath, template: template))
+		self.mappings = mappings
+	}
+
+	func removeMapping(albumID: String, folderID: String) {
+		self.mappings = self.mappings.filter { !($0.albumID == albumID && $0.folderID == folderID) }
+	}
+
+	// Whether a scheduled back-up should run now: the interval has passed since the last completed back-up, and the
+	// device is charging and/or on Wi-Fi when the schedule requires it
+	@MainActor func isDue(appState: AppState) -> Bool {
+		if !self.isReady || self.scheduleIntervalHours <= 0 { return false }
+
+		if self.lastCompletedDate > 0.0 {
+			let lastDate = Date(timeIntervalSinceReferenceDate: self.lastCompletedDate)
+			if Date.now.timeIntervalSince(lastDate) < Double(self.scheduleIntervalHours) * 3600.0 {
+				return false
+			}
+		}
+
+		if self.scheduleRequiresWifi && appState.client.isOnCellular() {
+			return false
+		}
+
+		#if os(iOS)
+			if self.scheduleRequiresCharging {
+				UIDevice.current.isBatteryMonitoringEnabled = true
+				let batteryState = UIDevice.current.batteryState
+				if batteryState != .charging && batteryState != .full {
+					return false
+				}
+			}
+		#endif
+
+		return true
+	}
+
+	// Starts a back-up when one is due according to the schedule, and cancels it when it has not finished within the
+	// budget. The last completed date is only updated when the back-up finishes, so an interrupted back-up is retried the
+	// next time the app gets to run.
+	@discardableResult
+	@MainActor func runIfDue(appState: AppState, budgetSeconds: TimeInterval) -> Task<(), Error>? {
+		if budgetSeconds <= 0 || !self.isDue(appState: appState) { return nil }
+
+		Log.info("Scheduled photo back-up is due, starting with a budget of \(budgetSeconds) seconds")
+		guard let task = self.backup(appState: appState, fullExport: false, isInBackground: true) else {
+			return nil
+		}
+
+		Task { @MainActor in
+			try? await Task.sleep(for: .seconds(budgetSeconds))
+			if self.photoBackupTask == task {
+				Log.info("Scheduled photo back-up ran out of its time budget, cancelling")
+				self.cancel()
+			}
+		}
+		return task
+	}
+
 	@discardableResult
 	@MainActor func backup(appState: AppState, fullExport: Bool, isInBackground: Bool) -> Task<(), Error>? {
 		if !self.isReady { return nil }
@@ -228,26 +538,6 @@ enum PhotoSyncProgress {
 				}
 			}
 
-			// Determine destination folder and check if we can use it
-			guard let folder = appState.client.folder(withID: selectedFolderID) else {
-				DispatchQueue.main.async {
-					self.progress = .error(String(localized: "Cannot find selected folder with ID '\(selectedFolderID)'"))
-				}
-				return
-			}
-
-			if !folder.exists() {
-				DispatchQueue.main.async { self.progress = .error(String(localized: "Selected folder does not exist")) }
-				return
-			}
-
-			if !folder.isSuitablePhotoBackupDestination {
-				DispatchQueue.main.async {
-					self.progress = .error(String(localized: "The selected folder cannot be used to save photos to"))
-				}
-				return
-			}
-
 			// Let iOS know we are about to do some background stuff
 			#if os(iOS)
 				let bgIdentifier = await UIApplication.shared.beginBackgroundTask(
@@ -263,6 +553,10 @@ enum PhotoSyncProgress {
 				Log.info("Background time remaining: \(await UIApplication.shared.backgroundTimeRemaining))")
 			#endif
 
+			defer {
+				PhotoBackupLedger.shared.flush()
+			}
+
 			// Check to see if anything changed at all
 			var onlyTheseLocalIdentifiers: Set<String>? = nil
 			let changeToken = PHPhotoLibrary.shared().currentChangeToken
@@ -272,7 +566,7 @@ enum PhotoSyncProgress {
 				if !fullExport {
 					do {
 						let ids = try self.insertedOrUpdatedLocalIdentifiers(since: lastSuccessfulChangeToken, to: changeToken)
-						if ids.isEmpty {
+						if ids.isEmpty && !PhotoBackupLedger.shared.hasReuploads {
 							// FIXME: we are skipping purge here
 							Log.info("Nothing changed and not a full export, finishing early!")
 							DispatchQueue.main.async {
@@ -294,60 +588,118 @@ enum PhotoSyncProgress {
 				}
 			}
 
-			// Pause the folder while backing up so we can change selection state
-			let folderWasPaused = folder.isPaused()
-			try folder.setPaused(true)
-			defer {
-				try? folder.setPaused(folderWasPaused)
+			// Save to the main destination first, then to the folders of the album mappings
+			var destinations = [
+				PhotoBackupDestination(
+					albumID: selectedAlbumID, folderID: selectedFolderID, subDirectoryPath: await self.subDirectoryPath,
+					template: nil)
+			]
+			for mapping in await self.mappings {
+				destinations.append(
+					PhotoBackupDestination(
+						albumID: mapping.albumID, folderID: mapping.folderID, subDirectoryPath: mapping.subpath,
+						template: mapping.template.isEmpty ? nil : mapping.template))
 			}
 
-			// Get local path for destination folder
-			var err: NSError? = nil
-			let folderPath = folder.localNativePath(&err)
-			if let err = err {
-				DispatchQueue.main.async { self.progress = .error(err.localizedDescription) }
-				return
+			for destination in destinations {
+				if Task.isCancelled { return }
+				let succeeded = try await self.backupToDestination(
+					appState: appState,
+					destination: destination,
+					fullExport: fullExport,
+					categories: categories,
+					isInBackground: isInBackground,
+					onlyTheseLocalIdentifiers: onlyTheseLocalIdentifiers
+				)
+				if !succeeded { return }
 			}
 
-			// Ensure the subdirectory exists
-			let subDirectoryPath = EntryPath(await self.subDirectoryPath, isDirectory: true)
-			if subDirectoryPath.pathInFolder != "" {
-				let subDirectoryPathURL = URL(fileURLWithPath: folderPath).appendingPathComponent(subDirectoryPath.pathInFolder)
-				try FileManager.default.createDirectory(at: subDirectoryPathURL, withIntermediateDirectories: true)
+			// Save change token
+			DispatchQueue.main.async {
+				self.lastSuccessfulChangeToken = changeToken
 			}
+		}
+		return self.photoBackupTask
+	}
 
-			let folderURL = URL(fileURLWithPath: folderPath)
-			let fetchResult: PHFetchResult<PHAssetCollection>
-			if selectedAlbumID == PhotoBackup.allPhotosAlbumIdentifier {
-				fetchResult = PHAssetCollection.fetchAssetCollections(
-					with: .smartAlbum, subtype: .smartAlbumUserLibrary, options: nil)
-			}
-			else {
-				fetchResult = PHAssetCollection.fetchAssetCollections(withLocalIdentifiers: [selectedAlbumID], options: nil)
-			}
-			guard let album = fetchResult.firstObject else {
-				DispatchQueue.main.async { self.progress = .error(String(localized: "Could not find selected album")) }
-				return
+	// Saves the assets in the album of the destination to its folder. Returns false when an error was reported.
+	private nonisolated func backupToDestination(
+		appState: AppState,
+		destination: PhotoBackupDestination,
+		fullExport: Bool,
+		categories: Set<PhotoSyncCategories>,
+		isInBackground: Bool,
+		onlyTheseLocalIdentifiers: Set<String>?
+	) async throws -> Bool {
+		// Determine destination folder and check if we can use it
+		guard let folder = appState.client.folder(withID: destination.folderID) else {
+			DispatchQueue.main.async {
+				self.progress = .error(String(localized: "Cannot find selected folder with ID '\(destination.folderID)'"))
 			}
+			return false
+		}
 
-			try await self.backupAlbum(
-				appState: appState,
-				album: album,
-				folder: folder,
-				folderURL: folderURL,
-				subDirectoryPath: subDirectoryPath,
-				fullExport: fullExport,
-				categories: categories,
-				isInBackground: isInBackground,
-				onlyTheseLocalIdentifiers: onlyTheseLocalIdentifiers
-			)
+		if !folder.exists() {
+			DispatchQueue.main.async { self.progress = .error(String(localized: "Selected folder does not exist")) }
+			return false
+		}
 
-			// Save change token
+		if !folder.isSuitablePhotoBackupDestination {
 			DispatchQueue.main.async {
-				self.lastSuccessfulChangeToken = changeToken
+				self.progress = .error(String(localized: "The selected folder cannot be used to save photos to"))
 			}
+			return false
 		}
-		return self.photoBackupTask
+
+		// Pause the folder while backing up so we can change selection state
+		let folderWasPaused = folder.isPaused()
+		try folder.setPaused(true)
+		defer {
+			try? folder.setPaused(folderWasPaused)
+		}
+
+		// Get local path for destination folder
+		var err: NSError? = nil
+		let folderPath = folder.localNativePath(&err)
+		if let err = err {
+			DispatchQueue.main.async { self.progress = .error(err.localizedDescription) }
+			return false
+		}
+
+		// Ensure the subdirectory exists
+		let subDirectoryPath = EntryPath(destination.subDirectoryPath, isDirectory: true)
+		if subDirectoryPath.pathInFolder != "" {
+			let subDirectoryPathURL = URL(fileURLWithPath: folderPath).appendingPathComponent(subDirectoryPath.pathInFolder)
+			try FileManager.default.createDirectory(at: subDirectoryPathURL, withIntermediateDirectories: true)
+		}
+
+		let folderURL = URL(fileURLWithPath: folderPath)
+		let fetchResult: PHFetchResult<PHAssetCollection>
+		if destination.albumID == PhotoBackup.allPhotosAlbumIdentifier {
+			fetchResult = PHAssetCollection.fetchAssetCollections(
+				with: .smartAlbum, subtype: .smartAlbumUserLibrary, options: nil)
+		}
+		else {
+			fetchResult = PHAssetCollection.fetchAssetCollections(withLocalIdentifiers: [destination.albumID], options: nil)
+		}
+		guard let album = fetchResult.firstObject else {
+			DispatchQueue.main.async { self.progress = .error(String(localized: "Could not find selected album")) }
+			return false
+		}
+
+		try await self.backupAlbum(
+			appState: appState,
+			album: album,
+			folder: folder,
+			folderURL: folderURL,
+			subDirectoryPath: subDirectoryPath,
+			fullExport: fullExport,
+			categories: categories,
+			isInBackground: isInBackground,
+			onlyTheseLocalIdentifiers: onlyTheseLocalIdentifiers,
+			template: destination.template
+		)
+		return true
 	}
 
 	private nonisolated func insertedOrUpdatedLocalIdentifiers(since: PHPersistentChangeToken, to: PHPersistentChangeToken)
@@ -394,6 +746,34 @@ enum PhotoSyncProgress {
 		self.lastSuccessfulChangeToken = nil
 	}
 
+	// Checks the assets recorded in the ledger against the global index of the folders they were saved to, and reports
+	// those of which any file is missing or deleted (e.g. because it was removed on another device). When `clearMissing`
+	// is set, their ledger entries are cleared so the next back-up saves them again.
+	func reconcile(appState: AppState, clearMissing: Bool) -> [PhotoBackupReconciliation] {
+		var results: [PhotoBackupReconciliation] = []
+		for folderID in PhotoBackupLedger.shared.folderIDs.sorted() {
+			guard let folder = appState.client.folder(withID: folderID), folder.exists() else {
+				Log.warn("Not reconciling photo back-up ledger for folder \(folderID), it does not exist")
+				continue
+			}
+
+			let entries = PhotoBackupLedger.shared.entriesFor(folderID: folderID)
+			let missing = entries.filter { entry in
+				entry.paths.contains { path in
+					guard let file = try? folder.getFileInformation(path) else { return true }
+					return file.isDeleted()
+				}
+			}
+			Log.info("Reconciled photo back-up ledger for \(folderID): \(missing.count) of \(entries.count) assets missing")
+
+			if clearMissing && !missing.isEmpty {
+				PhotoBackupLedger.shared.clear(folderID: folderID, localIdentifiers: Set(missing.map { $0.localIdentifier }))
+			}
+			results.append(PhotoBackupReconciliation(folderID: folderID, checkedCount: entries.count, missing: missing))
+		}
+		return results
+	}
+
 	private nonisolated func backupAlbum(
 		appState: AppState,
 		album: PHAssetCollection,
@@ -403,14 +783,16 @@ enum PhotoSyncProgress {
 		fullExport: Bool,
 		categories: Set<PhotoSyncCategories>,
 		isInBackground: Bool,
-		onlyTheseLocalIdentifiers: Set<String>?
+		onlyTheseLocalIdentifiers: Set<String>?,
+		template: String?
 	) async throws {
 		// Fetch assets to export
 		var cancellingError: Error? = nil
 		var options: PHFetchOptions? = nil
+		let reuploads = PhotoBackupLedger.shared.reuploadsFor(folderID: folder.folderID)
 		if let ids = onlyTheseLocalIdentifiers {
 			options = PHFetchOptions()
-			options!.predicate = NSPredicate(format: "localIdentifier IN %@", Array(ids))
+			options!.predicate = NSPredicate(format: "localIdentifier IN %@", Array(ids.union(reuploads)))
 		}
 		let assets = PHAsset.fetchAssets(in: album, options: options)
 
@@ -420,7 +802,7 @@ enum PhotoSyncProgress {
 
 		// Bookkeeping
 		var videosToExport: [(PHAsset, URL, EntryPath)] = []
-		var livePhotosToExport: [(PHAsset, URL, EntryPath)] = []
+		var unitsToComplete: [PhotoBackupUnit] = []  // Assets waiting for their live photo video or sidecar files
 		var selectPaths: [EntryPath] = []
 		var originalsToPurge: [PHAsset] = []
 		var assetsSavedSuccessfully: [PHAsset] = []
@@ -431,6 +813,18 @@ enum PhotoSyncProgress {
 		let purgeEnabled = await self.purgeEnabled
 		let maxAgeInterval = TimeInterval(Double(await self.maxAgeDays) * 86400.0)
 		let timeZone = await self.timeZone
+		let folderID = folder.folderID
+
+		// Moves the files of an asset into place and records them in the ledger
+		func commit(_ unit: PhotoBackupUnit) throws {
+			try unit.commit()
+			selectPaths.append(contentsOf: unit.parts.map { $0.path })
+			assetsSavedSuccessfully.append(unit.asset)
+			PhotoBackupLedger.shared.record(
+				PhotoBackupLedgerEntry(
+					localIdentifier: unit.asset.localIdentifier, folderID: folderID,
+					paths: unit.parts.map { $0.path.pathInFolder }, savedAt: Date.now))
+		}
 
 		// Enumerate assets in this album and export them (or queue them for export)
 		assets.enumerateObjects { asset, index, stop in
@@ -453,9 +847,10 @@ enum PhotoSyncProgress {
 
 				// Determine target directory path
 				let assetDirectoryPath = asset.directoryPathInFolder(
-					structure: structure, subdirectoryPath: subDirectoryPath, timeZone: timeZone)
+					structure: structure, subdirectoryPath: subDirectoryPath, timeZone: timeZone, template: template)
 				let dirInFolder = folderURL.appending(path: assetDirectoryPath.pathInFolder, directoryHint: .isDirectory)
-				let inFolderPath = asset.pathInFolder(structure: structure, subdirectoryPath: subDirectoryPath, timeZone: timeZone)
+				let inFolderPath = asset.pathInFolder(
+					structure: structure, subdirectoryPath: subDirectoryPath, timeZone: timeZone, template: template)
 				Log.info("- \(inFolderPath) \(dirInFolder) \(subDirectoryPath)")
 
 				// Check if this photo was saved or deleted before
@@ -479,8 +874,9 @@ enum PhotoSyncProgress {
 							}
 						}
 
-						// If the photo was saved then deleted, do not try to save again (unless we are in full export)
-						if !fullExport {
+						// If the photo was saved then deleted, do not try to save again (unless we are in full export, or the
+						// ledger entry for the photo was cleared by reconciliation)
+						if !fullExport && !reuploads.contains(asset.localIdentifier) {
 							if entry.isDeleted() {
 								Log.info("Entry at \(inFolderPath) was deleted, not saving again")
 							}
@@ -497,7 +893,9 @@ enum PhotoSyncProgress {
 
 				// Save asset if it doesn't exist already locally
 				let fileURL = folderURL.appending(path: inFolderPath.pathInFolder, directoryHint: .notDirectory)
-				if !FileManager.default.fileExists(atPath: fileURL.path) || fullExport {
+				var unit = PhotoBackupUnit(asset: asset)
+				let isReupload = reuploads.contains(asset.localIdentifier)
+				if !FileManager.default.fileExists(atPath: fileURL.path) || fullExport || isReupload {
 					// If a video: queue video export session
 					if asset.mediaType == .video {
 						if categories.contains(.video) {
@@ -519,17 +917,17 @@ enum PhotoSyncProgress {
 							PHImageManager.default().requestImageDataAndOrientation(for: asset, options: options) { data, _, _, info in
 								if let data = data {
 									do {
-										try data.write(to: fileURL)
-										selectPaths.append(inFolderPath)
-
-										// Set file creation and modified date to photo creation date. The modified date is what is synced
-										if let cd = asset.creationDate {
-											try FileManager.default.setAttributes(
-												[FileAttributeKey.creationDate: cd, FileAttributeKey.modificationDate: cd],
-												ofItemAtPath: fileURL.path(percentEncoded: false))
+										try data.write(to: try unit.add(fileURL: fileURL, path: inFolderPath))
+
+										// Save the other half of a RAW+JPEG pair next to the photo
+										for resource in PHAssetResource.assetResources(for: asset) where resource.type == .alternatePhoto {
+											let name = resource.originalFilename.replacingOccurrences(of: "/", with: "_")
+											let alternatePath = assetDirectoryPath.appending(name, isDirectory: false)
+											if alternatePath.pathInFolder == inFolderPath.pathInFolder { continue }
+											try unit.add(
+												fileURL: folderURL.appending(path: alternatePath.pathInFolder, directoryHint: .notDirectory),
+												path: alternatePath, resource: resource)
 										}
-
-										assetsSavedSuccessfully.append(asset)
 									}
 									catch {
 										Log.warn("Image data request failed: \(error.localizedDescription) ")
@@ -549,23 +947,39 @@ enum PhotoSyncProgress {
 					}
 				}
 
-				// If the image is a live photo, queue the live photo for saving as well
+				// If the image is a live photo, save the video of the live photo as part of the same unit
 				if asset.mediaType == .image && asset.mediaSubtypes.contains(.photoLive) && categories.contains(.livePhoto) {
 					let liveInFolderPath = asset.livePhotoPathInFolder(
-						structure: structure, subdirectoryPath: subDirectoryPath, timeZone: timeZone)
-					let liveDirectoryURL = folderURL.appending(
-						path: asset.livePhotoDirectoryPathInFolder(
-							structure: structure, subdirectoryPath: subDirectoryPath, timeZone: timeZone
-						)
-						.pathInFolder,
-						directoryHint: .isDirectory)
-					try FileManager.default.createDirectory(at: liveDirectoryURL, withIntermediateDirectories: true)
+						structure: structure, subdirectoryPath: subDirectoryPath, timeZone: timeZone, template: template)
 					let liveFileURL = folderURL.appending(path: liveInFolderPath.pathInFolder, directoryHint: .notDirectory)
 					Log.info("Found live photo \(asset.originalFilename) \(liveInFolderPath)")
 
-					if !FileManager.default.fileExists(atPath: liveFileURL.path) {
-						livePhotosToExport.append((asset, liveFileURL, liveInFolderPath))
+					// Prefer the video of the current (edited) version of the live photo
+					let resources = PHAssetResource.assetResources(for: asset)
+					let videoResource =
+						resources.first(where: { $0.type == .fullSizePairedVideo })
+						?? resources.first(where: { $0.type == .pairedVideo })
+
+					// When the photo could not be saved, do not save its video by itself either
+					let isSavingPhoto = !unit.parts.isEmpty
+					let hasPhoto =
+						isSavingPhoto || !categories.contains(.photo) || FileManager.default.fileExists(atPath: fileURL.path)
+					if let videoResource = videoResource {
+						if hasPhoto && (isSavingPhoto || !FileManager.default.fileExists(atPath: liveFileURL.path)) {
+							try unit.add(fileURL: liveFileURL, path: liveInFolderPath, resource: videoResource)
+						}
 					}
+					else {
+						Log.warn("Could not find paired video resource for \(asset.originalFilename) \(resources)")
+					}
+				}
+
+				// Save the files right away, unless some of them still need to be written
+				if unit.hasResourcesToWrite {
+					unitsToComplete.append(unit)
+				}
+				else if !unit.parts.isEmpty {
+					try commit(unit)
 				}
 			}
 			catch {
@@ -598,6 +1012,9 @@ enum PhotoSyncProgress {
 
 		// Report error
 		if let ce = cancellingError {
+			for unit in unitsToComplete {
+				try? FileManager.default.removeItem(at: unit.stagingURL)
+			}
 			DispatchQueue.main.async { self.progress = .error(ce.localizedDescription) }
 			return
 		}
@@ -617,7 +1034,17 @@ enum PhotoSyncProgress {
 
 				DispatchQueue.main.async { self.progress = .exportingVideos(index: idx, total: videoCount, current: nil) }
 
-				_ = await withCheckedContinuation { resolve in
+				var unit = PhotoBackupUnit(asset: asset)
+				let stagedURL: URL
+				do {
+					stagedURL = try unit.add(fileURL: fileURL, path: selectPath)
+				}
+				catch {
+					Log.warn("Could not prepare export of video \(asset.originalFilename): \(error.localizedDescription)")
+					continue
+				}
+
+				let exported = await withCheckedContinuation { resolve in
 					Log.info("Exporting video \(asset.originalFilename)")
 					let options = PHVideoRequestOptions()
 					options.deliveryMode = .highQualityFormat
@@ -626,13 +1053,13 @@ enum PhotoSyncProgress {
 						forVideo: asset, options: options, exportPreset: AVAssetExportPresetPassthrough
 					) { exportSession, info in
 						if let es = exportSession {
-							es.outputURL = fileURL
+							es.outputURL = stagedURL
 							es.outputFileType = .mov
 							es.shouldOptimizeForNetworkUse = false
 
 							es.exportAsynchronously {
 								Log.info("Done exporting video \(asset.originalFilename)")
-								resolve.resume(returning: true)
+								resolve.resume(returning: es.status == .completed)
 							}
 						}
 						else {
@@ -642,89 +1069,44 @@ enum PhotoSyncProgress {
 					}
 				}
 
-				if let cd = asset.creationDate {
-					do {
-						try FileManager.default.setAttributes(
-							[FileAttributeKey.creationDate: cd, FileAttributeKey.modificationDate: cd],
-							ofItemAtPath: fileURL.path(percentEncoded: false))
-					}
-					catch {
-						Log.warn("Could not set creation time of file: \(fileURL) \(error.localizedDescription)")
+				do {
+					if !exported {
+						throw CocoaError(.fileWriteUnknown)
 					}
+					try commit(unit)
+				}
+				catch {
+					try? FileManager.default.removeItem(at: unit.stagingURL)
+					Log.warn("Could not save video: \(fileURL) \(error.localizedDescription)")
 				}
-
-				selectPaths.append(selectPath)
-				assetsSavedSuccessfully.append(asset)
 			}
 		}
 
-		// Export live photos
-		if categories.contains(.livePhoto) {
-			Log.info("Exporting live photos")
-			#if os(iOS)
-				Log.info("Background time remaining: \(await UIApplication.shared.backgroundTimeRemaining))")
-			#endif
-
-			let liveCount = livePhotosToExport.count
-			DispatchQueue.main.async { self.progress = .exportingLivePhotos(index: 0, total: liveCount, current: nil) }
-			for (idx, (asset, destURL, selectPath)) in livePhotosToExport.enumerated() {
-				if Task.isCancelled { break }
-				Log.info("Exporting live photo \(asset.originalFilename) \(selectPath)")
-
-				do {
-					try await withCheckedThrowingContinuation { resolve in
-						// Export live photo
-						let options = PHLivePhotoRequestOptions()
-						options.deliveryMode = .highQualityFormat
-						var found = false
-						PHImageManager.default().requestLivePhoto(
-							for: asset, targetSize: CGSize(width: 1920, height: 1080), contentMode: PHImageContentMode.default,
-							options: options
-						) { livePhoto, info in
-							if found {
-								// The callback can be called twice
-								return
-							}
-							found = true
-
-							guard let livePhoto = livePhoto else {
-								Log.warn("Did not receive live photo for \(asset.originalFilename)")
-								resolve.resume()
-								return
-							}
-							let assetResources = PHAssetResource.assetResources(for: livePhoto)
-							guard let videoResource = assetResources.first(where: { $0.type == .pairedVideo }) else {
-								Log.warn("Could not find paired video resource for \(asset.originalFilename) \(assetResources)")
-								resolve.resume()
-								return
-							}
-
-							PHAssetResourceManager.default().writeData(for: videoResource, toFile: destURL, options: nil) { error in
-								if let error = error {
-									resolve.resume(throwing: error)
-								}
-								else {
-									resolve.resume()
-								}
-							}
-						}
-					}
-
-					selectPaths.append(selectPath)
-					assetsSavedSuccessfully.append(asset)
+		// Write live photo videos and sidecar files, then save them together with the photos they belong to
+		Log.info("Completing \(unitsToComplete.count) live photos and photos with sidecar files")
+		#if os(iOS)
+			Log.info("Background time remaining: \(await UIApplication.shared.backgroundTimeRemaining))")
+		#endif
 
-					if let cd = asset.creationDate {
-						try FileManager.default.setAttributes(
-							[FileAttributeKey.creationDate: cd, FileAttributeKey.modificationDate: cd],
-							ofItemAtPath: destURL.path(percentEncoded: false))
-					}
-				}
-				catch {
-					Log.warn("Failed to save \(destURL): \(error.localizedDescription)")
-				}
+		let unitCount = unitsToComplete.count
+		DispatchQueue.main.async { self.progress = .exportingLivePhotos(index: 0, total: unitCount, current: nil) }
+		for (idx, unit) in unitsToComplete.enumerated() {
+			if Task.isCancelled {
+				try? FileManager.default.removeItem(at: unit.stagingURL)
+				continue
+			}
+			Log.info("Completing \(unit.asset.originalFilename) \(unit.parts.map { $0.path.pathInFolder })")
 
-				DispatchQueue.main.async { self.progress = .exportingLivePhotos(index: idx, total: liveCount, current: nil) }
+			do {
+				try await unit.writeResources()
+				try commit(unit)
 			}
+			catch {
+				try? FileManager.default.removeItem(at: unit.stagingURL)
+				Log.warn("Failed to save \(unit.asset.originalFilename): \(error.localizedDescription)")
+			}
+
+			DispatchQueue.main.async { self.progress = .exportingLivePhotos(index: idx, total: unitCount, current: nil) }
 		}
 
 		// Select paths
@@ -819,15 +1201,57 @@ extension PHAsset {
 	}
 
 	fileprivate func directoryPathInFolder(
-		structure: PhotoBackupFolderStructure, subdirectoryPath: EntryPath, timeZone: PhotoBackupTimeZone
+		structure: PhotoBackupFolderStructure, subdirectoryPath: EntryPath, timeZone: PhotoBackupTimeZone,
+		template: String? = nil
 	) -> EntryPath {
 		var path = subdirectoryPath
-		for c in self.subdirectoriesInFolder(structure: structure, timeZone: timeZone) {
+		var subdirectories = self.subdirectoriesInFolder(structure: structure, timeZone: timeZone)
+		if let template = template {
+			subdirectories = Array(self.templatedComponents(template: template, timeZone: timeZone).dropLast())
+		}
+		for c in subdirectories {
 			path = path.appending(c, isDirectory: true)
 		}
 		return path
 	}
 
+	// Renders a file name template of a PhotoBackupMapping into path components, the last one being the file name
+	fileprivate func templatedComponents(template: String, timeZone: PhotoBackupTimeZone) -> [String] {
+		let fileName = self.originalFilename
+		let ext = (fileName as NSString).pathExtension
+		var replacements = [
+			"{filename}": fileName,
+			"{name}": (fileName as NSString).deletingPathExtension,
+			"{ext}": ext,
+		]
+
+		// Without a date taken, the placeholders for it render as empty strings (and disappear as directories)
+		let dateFormatter = self.dateFormatter(timeZone: timeZone)
+		for format in ["yyyy", "MM", "dd", "HH", "mm", "ss"] {
+			if let creationDate = self.creationDate {
+				dateFormatter.dateFormat = format
+				replacements["{\(format)}"] = dateFormatter.string(from: creationDate)
+			}
+			else {
+				replacements["{\(format)}"] = ""
+			}
+		}
+
+		var rendered = template
+		for (placeholder, value) in replacements {
+			rendered = rendered.replacingOccurrences(of: placeholder, with: value.replacingOccurrences(of: "/", with: "_"))
+		}
+
+		var components = rendered.split(separator: "/").map { String($0) }.filter { $0 != "." && $0 != ".." }
+		if rendered.hasSuffix("/") || components.isEmpty {
+			components.append(fileName)
+		}
+		else if (components.last! as NSString).pathExtension.isEmpty && !ext.isEmpty {
+			components[components.count - 1] += ".\(ext)"
+		}
+		return components
+	}
+
 	func dateFormatter(timeZone: PhotoBackupTimeZone) -> DateFormatter {
 		let df = DateFormatter()
 		switch timeZone {
@@ -880,11 +1304,17 @@ extension PHAsset {
 	}
 
 	fileprivate func livePhotoDirectoryPathInFolder(
-		structure: PhotoBackupFolderStructure, subdirectoryPath: EntryPath, timeZone: PhotoBackupTimeZone
+		structure: PhotoBackupFolderStructure, subdirectoryPath: EntryPath, timeZone: PhotoBackupTimeZone,
+		template: String? = nil
 	)
 		-> EntryPath
 	{
-		var path = self.directoryPathInFolder(structure: structure, subdirectoryPath: subdirectoryPath, timeZone: timeZone)
+		var path = self.directoryPathInFolder(
+			structure: structure, subdirectoryPath: subdirectoryPath, timeZone: timeZone, template: template)
+		if template != nil {
+			// The video of a live photo is saved next to the photo
+			return path
+		}
 		switch structure {
 		case .byDateAndType, .byType, .byDateComponentAndType, .byYearAndType, .byYearMonthAndType, .byYearDashMonthAndType:
 			path = path.appending("Live", isDirectory: true)
@@ -895,11 +1325,12 @@ extension PHAsset {
 	}
 
 	fileprivate func livePhotoPathInFolder(
-		structure: PhotoBackupFolderStructure, subdirectoryPath: EntryPath, timeZone: PhotoBackupTimeZone
+		structure: PhotoBackupFolderStructure, subdirectoryPath: EntryPath, timeZone: PhotoBackupTimeZone,
+		template: String? = nil
 	) -> EntryPath {
-		let fileName = self.fileNameInFolder(structure: structure) + ".MOV"
+		let fileName = self.fileNameInFolder(structure: structure, timeZone: timeZone, template: template) + ".MOV"
 		return self.livePhotoDirectoryPathInFolder(
-			structure: structure, subdirectoryPath: subdirectoryPath, timeZone: timeZone
+			structure: structure, subdirectoryPath: subdirectoryPath, timeZone: timeZone, template: template
 		)
 		.appending(fileName, isDirectory: false)
 	}
@@ -921,11 +1352,23 @@ extension PHAsset {
 		}
 	}
 
+	fileprivate func fileNameInFolder(
+		structure: PhotoBackupFolderStructure, timeZone: PhotoBackupTimeZone, template: String?
+	) -> String {
+		if let template = template {
+			return self.templatedComponents(template: template, timeZone: timeZone).last!
+		}
+		return self.fileNameInFolder(structure: structure)
+	}
+
 	fileprivate func pathInFolder(
-		structure: PhotoBackupFolderStructure, subdirectoryPath: EntryPath, timeZone: PhotoBackupTimeZone
+		structure: PhotoBackupFolderStructure, subdirectoryPath: EntryPath, timeZone: PhotoBackupTimeZone,
+		template: String? = nil
 	) -> EntryPath {
-		return self.directoryPathInFolder(structure: structure, subdirectoryPath: subdirectoryPath, timeZone: timeZone)
-			.appending(self.fileNameInFolder(structure: structure), isDirectory: false)
+		return self.directoryPathInFolder(
+			structure: structure, subdirectoryPath: subdirectoryPath, timeZone: timeZone, template: template
+		)
+		.appending(self.fileNameInFolder(structure: structure, timeZone: timeZone, template: template), isDirectory: false)
 	}
 }
 
@@ -1071,6 +1071,7 @@ private struct AsyncAddressesView: View {
 	let addressType: AddressType
 
 	@State private var addresses: [String] = []
+	@State private var error: Error? = nil
 
 	var body: some View {
 		AddressesView(
@@ -1081,10 +1082,20 @@ private struct AsyncAddressesView: View {
 			await self.update()
 		}
 		.onChange(of: self.addresses) { _, _ in
-			self.write()
+			Task {
+				await self.write()
+			}
 		}
 		.onDisappear {
-			self.write()
+			Task {
+				await self.write()
+			}
+		}
+		.alert(isPresented: Binding.isNotNil($error)) {
+			Alert(
+				title: Text("These addresses cannot be used"),
+				message: Text(self.error?.localizedDescription ?? ""),
+				dismissButton: .default(Text("OK")))
 		}
 		#if os(iOS)
 			.navigationBarTitleDisplayMode(.inline)
@@ -1109,23 +1120,30 @@ private struct AsyncAddressesView: View {
 		}.value
 	}
 
-	private func write() {
+	private func write() async {
 		let addresses = self.addresses
 		let appState = self.appState
-		Task.detached {
-			switch addressType {
-			case .discovery:
-				try appState.client.setDiscoveryAddresses(
-					SushitrainListOfStrings.from(addresses))
-			case .listening:
-				try appState.client.setListenAddresses(SushitrainListOfStrings.from(addresses))
-			case .device:
-				// not supported
-				abort()
-			case .stun:
-				try appState.client.setStunAddresses(
-					SushitrainListOfStrings.from(addresses))
-			}
+		let addressType = self.addressType
+		do {
+			try await Task.detached {
+				switch addressType {
+				case .discovery:
+					try appState.client.setDiscoveryAddresses(
+						SushitrainListOfStrings.from(addresses))
+				case .listening:
+					try appState.client.setListenAddresses(SushitrainListOfStrings.from(addresses))
+				case .device:
+					// not supported
+					abort()
+				case .stun:
+					try appState.client.setStunAddresses(
+						SushitrainListOfStrings.from(addresses))
+				}
+			}.value
+		}
+		catch {
+			Log.warn("Could not save \(addressType) addresses: \(error.localizedDescription)")
+			self.error = error
 		}
 	}
 }
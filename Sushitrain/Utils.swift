@@ -8,6 +8,7 @@ import SwiftUI
 import SushitrainCore
 import VisionKit
 import WebKit
+import AVFoundation
 import CoreTransferable
 import SwiftUI
 import Combine
@@ -183,7 +184,7 @@ extension SushitrainFolder {
 	@MainActor
 	func removeFolderAndSettings() throws {
 		FolderSettingsManager.shared.removeSettingsFor(folderID: self.folderID)
-		try self.remove()
+		try self.remove(true)
 	}
 
 	@MainActor
@@ -1017,6 +1018,51 @@ extension SecCertificate {
 	}
 }
 
+/// Trusts the self-signed certificate of the streaming server when it only accepts HTTPS connections. The certificate
+/// is pinned using the hash in the `#sha256=` fragment of the server's URL. Use `fingerprints` for a `WebView`, or set
+/// an instance as delegate of an `AVURLAsset`'s resource loader (which does not retain it).
+final class StreamingServerTrust: NSObject, AVAssetResourceLoaderDelegate {
+	let fingerprints: [Data]
+
+	init(server: SushitrainStreamingServer?) {
+		self.fingerprints = Self.fingerprints(server: server)
+	}
+
+	static func fingerprints(server: SushitrainStreamingServer?) -> [Data] {
+		guard let server = server, let url = URL(string: server.urlWithCertificateHash()),
+			let fragment = url.fragment, fragment.hasPrefix("sha256=")
+		else {
+			return []
+		}
+
+		let hex = Array(fragment.dropFirst("sha256=".count))
+		var bytes: [UInt8] = []
+		for index in stride(from: 0, to: hex.count - 1, by: 2) {
+			guard let byte = UInt8(String(hex[index...index + 1]), radix: 16) else {
+				return []
+			}
+			bytes.append(byte)
+		}
+		return bytes.isEmpty ? [] : [Data(bytes)]
+	}
+
+	func resourceLoader(
+		_ resourceLoader: AVAssetResourceLoader,
+		shouldWaitForResponseTo authenticationChallenge: URLAuthenticationChallenge
+	) -> Bool {
+		guard authenticationChallenge.protectionSpace.authenticationMethod == NSURLAuthenticationMethodServerTrust,
+			let serverTrust = authenticationChallenge.protectionSpace.serverTrust,
+			let certChain = SecTrustCopyCertificateChain(serverTrust) as? [SecCertificate], certChain.count == 1,
+			self.fingerprints.contains(certChain[0].sha256)
+		else {
+			return false
+		}
+
+		authenticationChallenge.sender?.use(URLCredential(trust: serverTrust), for: authenticationChallenge)
+		return true
+	}
+}
+
 extension ComparisonResult {
 	var flipped: ComparisonResult {
 		switch self {
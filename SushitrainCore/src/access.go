@@ -0,0 +1,67 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+type FolderAccessDelegate interface {
+	// Called when the path of a folder can no longer be read (e.g. because access to a security-scoped location was
+	// revoked). The folder has been paused; call Folder.Reactivate once access is restored.
+	OnFolderInaccessible(folderID string)
+}
+
+// Returns whether the folder's local path can currently be read. Folders that do not use the local file system (or a
+// location accessed through security-scoped URLs) are always considered accessible.
+func (fld *Folder) IsAccessible() bool {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return false
+	}
+	if fc.FilesystemType != config.FilesystemTypeBasic && !isScopedFilesystemType(fc.FilesystemType) {
+		return true
+	}
+
+	ffs := fc.Filesystem()
+	if _, err := ffs.Lstat("."); err != nil {
+		return false
+	}
+	if _, err := ffs.DirNames("."); err != nil {
+		return false
+	}
+	return true
+}
+
+// Resumes a folder that was paused because its path became inaccessible
+func (fld *Folder) Reactivate() error {
+	if !fld.IsAccessible() {
+		return errors.New("folder path is still not accessible")
+	}
+	return fld.SetPaused(false)
+}
+
+// Called when a folder enters the error state. When this is caused by its path having become inaccessible, the folder
+// is paused, so Syncthing does not keep trying (and failing) to scan it.
+func (clt *Client) checkFolderAccess(folderID string) {
+	fld := clt.FolderWithID(folderID)
+	if fld == nil || fld.IsPaused() || fld.IsAccessible() {
+		return
+	}
+
+	slog.Warn("folder path is not accessible, pausing folder", "folderID", folderID)
+	if err := fld.SetPaused(true); err != nil {
+		slog.Warn("could not pause inaccessible folder", "folderID", folderID, "cause", err)
+		return
+	}
+
+	if clt.FolderAccessDelegate != nil {
+		clt.FolderAccessDelegate.OnFolderInaccessible(folderID)
+	}
+}
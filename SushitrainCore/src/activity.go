@@ -0,0 +1,158 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	activityFileName      = "activity.json"
+	maxActivityRecords    = 1000
+	activityFlushInterval = 10 * time.Second
+
+	ActivityActionAdded   = "added"
+	ActivityActionChanged = "changed"
+	ActivityActionDeleted = "deleted"
+)
+
+type activityRecord struct {
+	FolderID string    `json:"folderID"`
+	Path     string    `json:"path"`
+	Action   string    `json:"action"`
+	ShortID  string    `json:"shortID"`
+	Time     time.Time `json:"time"`
+}
+
+type Changes struct {
+	data []*Change
+}
+
+func (cs *Changes) Count() int {
+	return len(cs.data)
+}
+
+func (cs *Changes) ItemAt(index int) *Change {
+	if index < 0 || index >= len(cs.data) {
+		return nil
+	}
+	return cs.data[index]
+}
+
+func activityKey(folderID string, path string) string {
+	return folderID + "/" + path
+}
+
+// Remembers whether an item that is about to be pulled already exists locally, so that we can later tell additions
+// from changes. Events are handled some time after they happened, by which time the puller may already have written
+// the file, so this looks at our index (which is only updated in batches after items finish) rather than the disk.
+func (clt *Client) handleItemStarted(data map[string]string) {
+	sdb := clt.sdb
+	if sdb == nil {
+		return
+	}
+	local, ok, err := sdb.GetDeviceFile(data["folder"], protocol.LocalDeviceID, data["item"])
+	existed := err == nil && ok && !local.IsDeleted() && !local.IsInvalid()
+
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+	clt.itemsStarted[activityKey(data["folder"], data["item"])] = existed
+}
+
+// Forgets the items of the folder that were started but never finished (Syncthing does not send ItemFinished for
+// items that are abandoned, e.g. when the pull is interrupted)
+func (clt *Client) forgetItemsStarted(folderID string) {
+	prefix := activityKey(folderID, "")
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+	for key := range clt.itemsStarted {
+		if strings.HasPrefix(key, prefix) {
+			delete(clt.itemsStarted, key)
+		}
+	}
+}
+
+func (clt *Client) handleItemFinished(evt events.Event) {
+	data, ok := evt.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	folderID, _ := data["folder"].(string)
+	item, _ := data["item"].(string)
+	action, _ := data["action"].(string)
+
+	key := activityKey(folderID, item)
+	clt.mutex.Lock()
+	existed := clt.itemsStarted[key]
+	delete(clt.itemsStarted, key)
+	clt.mutex.Unlock()
+
+	// Failed items will be retried later
+	if errorMessage, _ := data["error"].(*string); errorMessage != nil || clt.app == nil || clt.app.Internals == nil {
+		return
+	}
+
+	record := activityRecord{
+		FolderID: folderID,
+		Path:     item,
+		Time:     evt.Time,
+	}
+
+	switch {
+	case action == "delete":
+		record.Action = ActivityActionDeleted
+	case existed:
+		record.Action = ActivityActionChanged
+	default:
+		record.Action = ActivityActionAdded
+	}
+
+	if info, ok, err := clt.app.Internals.GlobalFileInfo(folderID, item); err == nil && ok {
+		record.ShortID = info.ModifiedBy.String()
+	}
+
+	clt.activity.modifyLater(func(records *[]activityRecord) {
+		*records = append(*records, record)
+		if len(*records) > maxActivityRecords {
+			*records = (*records)[len(*records)-maxActivityRecords:]
+		}
+	})
+}
+
+func (clt *Client) recentActivity(folderID string, limit int) *Changes {
+	changes := make([]*Change, 0)
+	clt.activity.read(func(records *[]activityRecord) {
+		for i := len(*records) - 1; i >= 0 && (limit <= 0 || len(changes) < limit); i-- {
+			rec := (*records)[i]
+			if folderID != "" && rec.FolderID != folderID {
+				continue
+			}
+			changes = append(changes, &Change{
+				FolderID: rec.FolderID,
+				Path:     rec.Path,
+				Action:   rec.Action,
+				ShortID:  rec.ShortID,
+				Time:     &Date{time: rec.Time},
+			})
+		}
+	})
+	return &Changes{data: changes}
+}
+
+// Returns the items most recently received from other devices, newest first. Pass a limit of zero to return all
+// items remembered.
+func (clt *Client) RecentActivity(limit int) *Changes {
+	return clt.recentActivity("", limit)
+}
+
+// Returns the items in this folder most recently received from other devices, newest first
+func (fld *Folder) RecentActivity(limit int) *Changes {
+	return fld.client.recentActivity(fld.FolderID, limit)
+}
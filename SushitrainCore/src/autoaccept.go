@@ -0,0 +1,210 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const autoAcceptFileName = "autoaccept.json"
+
+// Determines which folder offers are accepted without asking the user
+type autoAcceptPolicy struct {
+	// Devices whose folder offers are accepted (full device IDs)
+	Devices []string `json:"devices"`
+
+	// Where to place accepted folders. May contain {folderID} and {label}. Relative paths are relative to the default
+	// folder location. When empty, the default location for the folder ID is used.
+	PathTemplate string `json:"pathTemplate,omitempty"`
+
+	// Whether accepted folders are selective (only explicitly selected files are synchronized)
+	Selective bool `json:"selective"`
+
+	// When non-zero, non-selective folders larger than this are kept selective
+	MaxSizeBytes int64 `json:"maxSizeBytes,omitempty"`
+}
+
+type autoAcceptState struct {
+	Policy autoAcceptPolicy `json:"policy"`
+
+	// Folders that were accepted as selective until their size is known, so we can check it against the size limit
+	AwaitingSizeCheck []string `json:"awaitingSizeCheck,omitempty"`
+}
+
+// Sets the policy for automatically accepting folder offers, as a JSON object with the keys `devices` (list of device
+// IDs), `pathTemplate` (may contain {folderID} and {label}), `selective` and `maxSizeBytes`. Pass an empty value to
+// stop accepting offers automatically.
+func (clt *Client) SetAutoAcceptPolicy(policyJSON []byte) error {
+	var policy autoAcceptPolicy
+	if len(policyJSON) > 0 {
+		if err := json.Unmarshal(policyJSON, &policy); err != nil {
+			return err
+		}
+	}
+
+	for _, deviceID := range policy.Devices {
+		if _, err := protocol.DeviceIDFromString(deviceID); err != nil {
+			return errors.New("invalid device ID: " + deviceID)
+		}
+	}
+	if policy.MaxSizeBytes < 0 {
+		return errors.New("size limit cannot be negative")
+	}
+
+	return clt.autoAccept.modify(func(state *autoAcceptState) {
+		state.Policy = policy
+	})
+}
+
+// Returns the current policy for automatically accepting folder offers as JSON (see SetAutoAcceptPolicy)
+func (clt *Client) AutoAcceptPolicy() ([]byte, error) {
+	var policy autoAcceptPolicy
+	clt.autoAccept.read(func(state *autoAcceptState) {
+		policy = state.Policy
+	})
+	return json.Marshal(policy)
+}
+
+// Returns the path for a folder accepted under the policy. The folder ID and label are chosen by the remote, so they
+// are only substituted when they cannot be used to escape the directory the template points at.
+func (policy *autoAcceptPolicy) folderPath(filesPath string, folderID string, label string) (string, error) {
+	dirName, err := folderDirectoryName(folderID)
+	if err != nil {
+		return "", err
+	}
+	if policy.PathTemplate == "" {
+		return path.Join(filesPath, dirName), nil
+	}
+
+	label = strings.NewReplacer("/", "_", "\\", "_").Replace(label)
+	if label == "" || label == "." || label == ".." {
+		label = dirName
+	}
+
+	p := strings.NewReplacer("{folderID}", dirName, "{label}", label).Replace(policy.PathTemplate)
+	if !path.IsAbs(p) {
+		p = path.Join(filesPath, p)
+	}
+	return p, nil
+}
+
+// Accepts a folder offer if the policy allows it. Returns whether the offer is (being) accepted. Offers for folders that
+// already exist locally are left to the user, as are offers for a folder that is already being accepted (Syncthing
+// emits an event for each device that offers the folder, and for every cluster config it sends).
+func (clt *Client) autoAcceptFolderOffer(deviceID string, folderID string, label string) bool {
+	var policy autoAcceptPolicy
+	clt.autoAccept.read(func(state *autoAcceptState) {
+		policy = state.Policy
+	})
+	if !slices.Contains(policy.Devices, deviceID) {
+		return false
+	}
+	if _, exists := clt.config.Folder(folderID); exists {
+		return false
+	}
+
+	clt.mutex.Lock()
+	if clt.autoAccepting[folderID] {
+		clt.mutex.Unlock()
+		return true
+	}
+	clt.autoAccepting[folderID] = true
+	clt.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			clt.mutex.Lock()
+			delete(clt.autoAccepting, folderID)
+			clt.mutex.Unlock()
+		}()
+
+		if err := clt.acceptFolderOffer(&policy, deviceID, folderID, label); err != nil {
+			slog.Warn("could not automatically accept folder offer", "folderID", folderID, "deviceID", deviceID, "cause", err)
+			clt.recordRejection(RejectionKindFolder, deviceID, "", "", folderID, label)
+		}
+	}()
+	return true
+}
+
+func (clt *Client) acceptFolderOffer(policy *autoAcceptPolicy, deviceID string, folderID string, label string) error {
+	// When there is a size limit, start out selective until we know the size
+	checkSize := !policy.Selective && policy.MaxSizeBytes > 0
+	folderPath, err := policy.folderPath(clt.filesPath, folderID, label)
+	if err != nil {
+		return err
+	}
+	if label == "" {
+		label = folderID
+	}
+
+	slog.Info("automatically accepting folder offer", "folderID", folderID, "deviceID", deviceID, "path", folderPath)
+	if err := clt.CreateFolder(folderID, label, folderPath, policy.Selective || checkSize); err != nil {
+		return err
+	}
+
+	if checkSize {
+		err := clt.autoAccept.modify(func(state *autoAcceptState) {
+			state.AwaitingSizeCheck = append(state.AwaitingSizeCheck, folderID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return clt.FolderWithID(folderID).ShareWithDevice(deviceID, true, "")
+}
+
+// Called when a folder becomes idle. Folders that were accepted under a size limit become non-selective once we know
+// they fit. The size is only known once the offering device has sent (the first part of) its index.
+func (clt *Client) checkAutoAcceptedFolderSize(folderID string) {
+	var awaiting bool
+	var maxSize int64
+	clt.autoAccept.read(func(state *autoAcceptState) {
+		awaiting = slices.Contains(state.AwaitingSizeCheck, folderID)
+		maxSize = state.Policy.MaxSizeBytes
+	})
+	if !awaiting {
+		return
+	}
+
+	fld := clt.FolderWithID(folderID)
+	if fld == nil {
+		return
+	}
+	stats, err := fld.Statistics()
+	if err != nil {
+		slog.Warn("could not determine size of automatically accepted folder", "folderID", folderID, "cause", err)
+		return
+	}
+	if stats.Global.Files == 0 && stats.Global.Directories == 0 {
+		// No index received yet
+		return
+	}
+
+	if maxSize == 0 || stats.Global.Bytes <= maxSize {
+		slog.Info("automatically accepted folder is within size limit, synchronizing all files", "folderID", folderID, "size", stats.Global.Bytes)
+		if err := fld.SetSelective(false); err != nil {
+			slog.Warn("could not make automatically accepted folder non-selective", "folderID", folderID, "cause", err)
+			return
+		}
+	} else {
+		slog.Info("automatically accepted folder exceeds size limit, keeping it selective", "folderID", folderID, "size", stats.Global.Bytes, "limit", maxSize)
+	}
+
+	err = clt.autoAccept.modify(func(state *autoAcceptState) {
+		state.AwaitingSizeCheck = slices.DeleteFunc(state.AwaitingSizeCheck, func(id string) bool { return id == folderID })
+	})
+	if err != nil {
+		slog.Warn("could not save auto-accept state", "cause", err)
+	}
+}
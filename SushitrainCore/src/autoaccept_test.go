@@ -0,0 +1,40 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import "testing"
+
+func TestAutoAcceptFolderPath(t *testing.T) {
+	policy := autoAcceptPolicy{PathTemplate: "Shared/{label}-{folderID}"}
+	for _, test := range []struct {
+		folderID string
+		label    string
+		expected string
+	}{
+		{"abcd-1234", "Photos", "/files/Shared/Photos-abcd-1234"},
+		{"abcd-1234", "", "/files/Shared/abcd-1234-abcd-1234"},
+		{"abcd-1234", "../../etc", "/files/Shared/.._.._etc-abcd-1234"},
+		{"abcd-1234", "..", "/files/Shared/abcd-1234-abcd-1234"},
+	} {
+		got, err := policy.folderPath("/files", test.folderID, test.label)
+		if err != nil {
+			t.Errorf("unexpected error for %q/%q: %v", test.folderID, test.label, err)
+		} else if got != test.expected {
+			t.Errorf("expected %q for %q/%q, got %q", test.expected, test.folderID, test.label, got)
+		}
+	}
+
+	for _, folderID := range []string{"", ".", "..", "../x", "a/b", `a\b`} {
+		if _, err := policy.folderPath("/files", folderID, "label"); err == nil {
+			t.Errorf("expected folder ID %q to be rejected", folderID)
+		}
+	}
+
+	defaultPolicy := autoAcceptPolicy{}
+	if got, _ := defaultPolicy.folderPath("/files", "abcd-1234", "Photos"); got != "/files/abcd-1234" {
+		t.Errorf("expected default location, got %q", got)
+	}
+}
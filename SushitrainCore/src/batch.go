@@ -0,0 +1,164 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Each configuration change is saved and sent to connected devices. A batch collects changes so they can be applied
+// at once. Only changes made through the folders and devices obtained from the batch (see FolderWithID and PeerWithID)
+// are collected; other changes (e.g. those made by the client itself in the background) are applied right away as
+// usual.
+type ConfigurationBatch struct {
+	client  *Client
+	mutex   sync.Mutex
+	working config.Configuration // Configuration with all changes in the batch applied
+	blocks  []config.ModifyFunction
+	done    bool // Set once the batch is committed or cancelled
+}
+
+var errBatchDone = errors.New("the configuration batch was already committed or cancelled")
+
+// Starts collecting configuration changes made through the setters of the folders and devices obtained from the
+// returned batch (e.g. Folder.SetPaused, Peer.SetName), until the batch is committed. Settings read back through these
+// folders and devices reflect the changes, but other settings and Syncthing itself do not see them until the batch is
+// committed.
+func (clt *Client) BeginConfigurationBatch() (*ConfigurationBatch, error) {
+	if clt.config == nil {
+		return nil, ErrStillLoading
+	}
+	if clt.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	return &ConfigurationBatch{client: clt, working: clt.config.RawCopy()}, nil
+}
+
+// Returns the folder with the given ID, of which changes are collected in the batch, or nil when it does not exist
+func (batch *ConfigurationBatch) FolderWithID(id string) *Folder {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+	if _, _, ok := batch.working.Folder(id); !ok {
+		return nil
+	}
+	return &Folder{client: batch.client, FolderID: id, batch: batch}
+}
+
+// Returns the device with the given ID, of which changes are collected in the batch, or nil when the ID is invalid
+func (batch *ConfigurationBatch) PeerWithID(deviceID string) *Peer {
+	peer := batch.client.PeerWithID(deviceID)
+	if peer != nil {
+		peer.batch = batch
+	}
+	return peer
+}
+
+// Applies the change to the working configuration and remembers it, so it can be applied when committing
+func (batch *ConfigurationBatch) change(block config.ModifyFunction) error {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+	if batch.done {
+		return errBatchDone
+	}
+	block(&batch.working)
+	batch.blocks = append(batch.blocks, block)
+	return nil
+}
+
+func (batch *ConfigurationBatch) folderConfiguration(id string) *config.FolderConfiguration {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+	fc, _, ok := batch.working.Folder(id)
+	if !ok {
+		return nil
+	}
+	return &fc
+}
+
+func (batch *ConfigurationBatch) deviceConfiguration(id protocol.DeviceID) *config.DeviceConfiguration {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+	dc, _, ok := batch.working.Device(id)
+	if !ok {
+		return nil
+	}
+	return &dc
+}
+
+// Applies all changes collected in the batch to the current configuration in a single change, which is saved once.
+// Changes made outside of the batch in the meantime are kept.
+func (batch *ConfigurationBatch) Commit() error {
+	batch.mutex.Lock()
+	if batch.done {
+		batch.mutex.Unlock()
+		return errBatchDone
+	}
+	batch.done = true
+	blocks := batch.blocks
+	batch.mutex.Unlock()
+
+	clt := batch.client
+	if clt.options.ReadOnly {
+		return ErrReadOnly
+	}
+	slog.Info("committing configuration batch", "changes", len(blocks))
+
+	clt.configMutex.Lock()
+	defer clt.configMutex.Unlock()
+	waiter, err := clt.config.Modify(func(cfg *config.Configuration) {
+		for _, block := range blocks {
+			block(cfg)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	waiter.Wait()
+	return clt.saveConfiguration()
+}
+
+// Discards all changes collected in the batch
+func (batch *ConfigurationBatch) Cancel() {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+	batch.done = true
+	batch.blocks = nil
+}
+
+func (clt *Client) setAllFoldersPaused(paused bool) error {
+	return clt.changeConfiguration(func(cfg *config.Configuration) {
+		for i := range cfg.Folders {
+			cfg.Folders[i].Paused = paused
+		}
+	})
+}
+
+func (clt *Client) PauseAllFolders() error {
+	return clt.setAllFoldersPaused(true)
+}
+
+func (clt *Client) ResumeAllFolders() error {
+	return clt.setAllFoldersPaused(false)
+}
+
+// Starts a rescan of all folders that are not paused
+func (clt *Client) RescanAllFolders() error {
+	if clt.app == nil || clt.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	go func() {
+		for folderID, err := range clt.app.Internals.ScanFolders() {
+			slog.Warn("could not rescan folder", "folderID", folderID, "cause", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,406 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	defaultBlockCacheMemoryBytes = 128 * 1024 * 1024
+	defaultBlockCacheDiskBytes   = 1024 * 1024 * 1024
+	maxBlockCacheMemoryEntries   = 4096 // Blocks are at least 128 KiB, so the byte limit is what normally applies
+	blockCacheFileExtension      = ".block"
+
+	readAheadBlocks       = 4 // Number of blocks fetched ahead when a file is read sequentially
+	readAheadMinimumRun   = 2 // Number of consecutive sequential reads after which we start reading ahead
+	readAheadTimeout      = 30 * time.Second
+	maxReadAheadTrackings = 64
+)
+
+// Cache of downloaded blocks (by block hash), kept in memory and optionally on disk. Blocks are stored on disk using
+// their hash as name, and verified when read back.
+type blockCacheStore struct {
+	mutex          sync.Mutex
+	memory         *lru.Cache[string, []byte]
+	memoryBytes    int64
+	memoryLimit    int64
+	diskPath       string // Empty when the disk cache is disabled
+	diskBytes      int64
+	diskLimit      int64
+	memoryHits     atomic.Int64
+	diskHits       atomic.Int64
+	misses         atomic.Int64
+	prefetched     atomic.Int64
+	readAheadMutex sync.Mutex
+	readAheads     *lru.Cache[string, *readAheadState] // Per file being read, see blockCacheStore.noteRead
+}
+
+type readAheadState struct {
+	nextBlock   int // The block that follows the last one read
+	run         int // Number of consecutive sequential reads
+	prefetching bool
+}
+
+// Global cache of downloaded blocks
+var blockCache = newBlockCacheStore()
+
+func newBlockCacheStore() *blockCacheStore {
+	bc := &blockCacheStore{memoryLimit: defaultBlockCacheMemoryBytes, diskLimit: defaultBlockCacheDiskBytes}
+	bc.memory, _ = lru.NewWithEvict(maxBlockCacheMemoryEntries, func(_ string, block []byte) {
+		bc.memoryBytes -= int64(len(block))
+	})
+	bc.readAheads, _ = lru.New[string, *readAheadState](maxReadAheadTrackings)
+	return bc
+}
+
+func blockCacheKey(hash []byte) string {
+	return hex.EncodeToString(hash)
+}
+
+func (bc *blockCacheStore) get(hash []byte) ([]byte, bool) {
+	key := blockCacheKey(hash)
+	bc.mutex.Lock()
+	if block, ok := bc.memory.Get(key); ok {
+		bc.mutex.Unlock()
+		bc.memoryHits.Add(1)
+		return block, true
+	}
+	diskPath := bc.diskPath
+	bc.mutex.Unlock()
+
+	if diskPath != "" {
+		blockPath := filepath.Join(diskPath, key+blockCacheFileExtension)
+		if block, err := os.ReadFile(blockPath); err == nil {
+			if sum := sha256.Sum256(block); bytes.Equal(sum[:], hash) {
+				now := time.Now()
+				os.Chtimes(blockPath, now, now) // Eviction removes the least recently used blocks first
+				bc.diskHits.Add(1)
+				bc.addToMemory(key, block)
+				return block, true
+			}
+			slog.Warn("removing corrupt block from disk cache", "hash", key)
+			bc.removeFromDisk(blockPath)
+		}
+	}
+
+	bc.misses.Add(1)
+	return nil, false
+}
+
+func (bc *blockCacheStore) add(hash []byte, block []byte) {
+	key := blockCacheKey(hash)
+	bc.addToMemory(key, block)
+
+	bc.mutex.Lock()
+	diskPath, diskLimit := bc.diskPath, bc.diskLimit
+	bc.mutex.Unlock()
+	if diskPath == "" || int64(len(block)) > diskLimit {
+		return
+	}
+
+	blockPath := filepath.Join(diskPath, key+blockCacheFileExtension)
+	if _, err := os.Stat(blockPath); err == nil {
+		return
+	}
+	tempPath := blockPath + ".tmp"
+	if err := os.WriteFile(tempPath, block, 0o600); err != nil {
+		slog.Warn("could not write block to disk cache", "hash", key, "cause", err)
+		os.Remove(tempPath)
+		return
+	}
+	if err := os.Rename(tempPath, blockPath); err != nil {
+		os.Remove(tempPath)
+		return
+	}
+	bc.mutex.Lock()
+	bc.diskBytes += int64(len(block))
+	overLimit := bc.diskBytes > bc.diskLimit
+	bc.mutex.Unlock()
+	if overLimit {
+		bc.evictFromDisk()
+	}
+}
+
+func (bc *blockCacheStore) addToMemory(key string, block []byte) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	if int64(len(block)) > bc.memoryLimit || bc.memory.Contains(key) {
+		return
+	}
+	bc.memory.Add(key, block)
+	bc.memoryBytes += int64(len(block))
+	for bc.memoryBytes > bc.memoryLimit {
+		if _, _, ok := bc.memory.RemoveOldest(); !ok {
+			break
+		}
+	}
+}
+
+func (bc *blockCacheStore) removeFromDisk(blockPath string) {
+	info, err := os.Stat(blockPath)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(blockPath); err == nil {
+		bc.mutex.Lock()
+		bc.diskBytes -= info.Size()
+		bc.mutex.Unlock()
+	}
+}
+
+type cachedBlockFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func listCachedBlockFiles(diskPath string) []cachedBlockFile {
+	entries, err := os.ReadDir(diskPath)
+	if err != nil {
+		return nil
+	}
+	files := make([]cachedBlockFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != blockCacheFileExtension {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cachedBlockFile{
+			path:    filepath.Join(diskPath, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return files
+}
+
+// Removes the least recently used blocks from disk until the cache is at 90% of its limit
+func (bc *blockCacheStore) evictFromDisk() {
+	bc.mutex.Lock()
+	diskPath, target := bc.diskPath, bc.diskLimit*9/10
+	bc.mutex.Unlock()
+	if diskPath == "" {
+		return
+	}
+
+	files := listCachedBlockFiles(diskPath)
+	slices.SortFunc(files, func(a, b cachedBlockFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+	total := int64(0)
+	for _, file := range files {
+		total += file.size
+	}
+
+	removed := 0
+	for _, file := range files {
+		if total <= target {
+			break
+		}
+		if err := os.Remove(file.path); err == nil {
+			total -= file.size
+			removed++
+		}
+	}
+
+	bc.mutex.Lock()
+	bc.diskBytes = total
+	bc.mutex.Unlock()
+	slog.Info("evicted blocks from disk cache", "removed", removed, "remainingBytes", total)
+}
+
+// Records that blocks [firstBlock, lastBlock) of the file are being read. When the file is read sequentially, the
+// blocks that follow are fetched in the background, so that e.g. video playback does not stall at each block boundary.
+func (bc *blockCacheStore) noteRead(mp *miniPuller, folderID string, info protocol.FileInfo, firstBlock int, lastBlock int) {
+	key := folderID + "/" + info.Name + "/" + blockCacheKey(info.BlocksHash)
+
+	bc.readAheadMutex.Lock()
+	state, ok := bc.readAheads.Get(key)
+	if !ok {
+		state = &readAheadState{}
+		bc.readAheads.Add(key, state)
+	}
+	if ok && firstBlock >= state.nextBlock-1 && firstBlock <= state.nextBlock {
+		state.run++
+	} else {
+		state.run = 0
+	}
+	state.nextBlock = lastBlock
+	start := lastBlock
+	end := min(lastBlock+readAheadBlocks, len(info.Blocks))
+	shouldPrefetch := state.run >= readAheadMinimumRun && !state.prefetching && start < end
+	if shouldPrefetch {
+		state.prefetching = true
+	}
+	bc.readAheadMutex.Unlock()
+
+	if !shouldPrefetch {
+		return
+	}
+
+	go func() {
+		defer func() {
+			bc.readAheadMutex.Lock()
+			state.prefetching = false
+			bc.readAheadMutex.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), readAheadTimeout)
+		defer cancel()
+		for blockIndex := start; blockIndex < end; blockIndex++ {
+			if bc.contains(info.Blocks[blockIndex].Hash) {
+				continue
+			}
+			if _, err := mp.downloadBlock(ctx, folderID, blockIndex, info, 1); err != nil {
+				slog.Debug("read ahead failed", "index", blockIndex, "cause", err)
+				return
+			}
+			bc.prefetched.Add(1)
+		}
+	}()
+}
+
+// Returns whether the block is in memory or on disk, without counting as a hit or miss
+func (bc *blockCacheStore) contains(hash []byte) bool {
+	key := blockCacheKey(hash)
+	bc.mutex.Lock()
+	inMemory, diskPath := bc.memory.Contains(key), bc.diskPath
+	bc.mutex.Unlock()
+	if inMemory {
+		return true
+	}
+	if diskPath == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(diskPath, key+blockCacheFileExtension))
+	return err == nil
+}
+
+// Removes all blocks from memory (blocks cached on disk are kept, see ClearBlockCacheOnDisk)
+func ClearBlockCache() {
+	blockCache.mutex.Lock()
+	defer blockCache.mutex.Unlock()
+	slog.Info("Purging blocks cache", "entries", blockCache.memory.Len())
+	blockCache.memory.Purge()
+	blockCache.memoryBytes = 0
+}
+
+// Removes all blocks cached on disk
+func ClearBlockCacheOnDisk() error {
+	blockCache.mutex.Lock()
+	diskPath := blockCache.diskPath
+	blockCache.mutex.Unlock()
+	if diskPath == "" {
+		return nil
+	}
+
+	for _, file := range listCachedBlockFiles(diskPath) {
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	blockCache.mutex.Lock()
+	blockCache.diskBytes = 0
+	blockCache.mutex.Unlock()
+	return nil
+}
+
+// Sets how much memory the block cache may use, and how much disk space (by default 128 MiB and 1 GiB). Blocks are only
+// cached on disk when a directory was set using SetBlockCacheDirectory; a disk limit of zero also disables the disk
+// cache.
+func SetBlockCacheLimits(memoryMegaBytes int, diskMegaBytes int) {
+	blockCache.mutex.Lock()
+	blockCache.memoryLimit = int64(max(0, memoryMegaBytes)) * 1024 * 1024
+	blockCache.diskLimit = int64(max(0, diskMegaBytes)) * 1024 * 1024
+	for blockCache.memoryBytes > blockCache.memoryLimit {
+		if _, _, ok := blockCache.memory.RemoveOldest(); !ok {
+			break
+		}
+	}
+	overLimit := blockCache.diskBytes > blockCache.diskLimit
+	blockCache.mutex.Unlock()
+
+	if overLimit {
+		blockCache.evictFromDisk()
+	}
+}
+
+// Sets the directory in which blocks are cached on disk (e.g. the app's caches directory), or disables the disk cache
+// when empty. The directory is created when it does not exist. Blocks already in the directory are used.
+func SetBlockCacheDirectory(path string) error {
+	diskBytes := int64(0)
+	if path != "" {
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			return err
+		}
+		for _, file := range listCachedBlockFiles(path) {
+			diskBytes += file.size
+		}
+	}
+
+	blockCache.mutex.Lock()
+	blockCache.diskPath = path
+	blockCache.diskBytes = diskBytes
+	overLimit := path != "" && diskBytes > blockCache.diskLimit
+	blockCache.mutex.Unlock()
+
+	if overLimit {
+		blockCache.evictFromDisk()
+	}
+	return nil
+}
+
+type BlockCacheStatistics struct {
+	MemoryBytes  int64
+	MemoryBlocks int
+	DiskBytes    int64
+	MemoryHits   int64
+	DiskHits     int64
+	Misses       int64
+	Prefetched   int64 // Blocks fetched ahead of time because a file was being read sequentially
+}
+
+// Returns the fraction of block reads that were served from the cache (0 when nothing was read yet)
+func (stats *BlockCacheStatistics) HitRate() float64 {
+	total := stats.MemoryHits + stats.DiskHits + stats.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(stats.MemoryHits+stats.DiskHits) / float64(total)
+}
+
+// Returns the size of the block cache and how effective it has been since the app started
+func GetBlockCacheStatistics() *BlockCacheStatistics {
+	blockCache.mutex.Lock()
+	defer blockCache.mutex.Unlock()
+	return &BlockCacheStatistics{
+		MemoryBytes:  blockCache.memoryBytes,
+		MemoryBlocks: blockCache.memory.Len(),
+		DiskBytes:    blockCache.diskBytes,
+		MemoryHits:   blockCache.memoryHits.Load(),
+		DiskHits:     blockCache.diskHits.Load(),
+		Misses:       blockCache.misses.Load(),
+		Prefetched:   blockCache.prefetched.Load(),
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+// Crossing the gomobile bridge is expensive for each object. For large listings, the functions in this file return all
+// results as a single JSON document instead.
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+type entryJSON struct {
+	FolderID    string `json:"folder"`
+	Path        string `json:"path"`
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	IsDirectory bool   `json:"isDirectory"`
+	IsSymlink   bool   `json:"isSymlink"`
+	IsDeleted   bool   `json:"isDeleted"`
+	ModifiedAt  int64  `json:"modifiedAt"` // Milliseconds since the epoch
+}
+
+func newEntryJSON(folderID string, name string, size int64, fileType protocol.FileInfoType, deleted bool, modified time.Time) entryJSON {
+	return entryJSON{
+		FolderID:    folderID,
+		Path:        name,
+		Name:        path.Base(name),
+		Size:        size,
+		IsDirectory: fileType == protocol.FileInfoTypeDirectory,
+		IsSymlink:   fileType == protocol.FileInfoTypeSymlink,
+		IsDeleted:   deleted,
+		ModifiedAt:  modified.UnixMilli(),
+	}
+}
+
+// Returns the direct children of the directory at `prefix` as a JSON array. When `directories` is set, only
+// subdirectories are returned.
+func (fld *Folder) ListEntriesJSON(prefix string, directories bool) ([]byte, error) {
+	entries, err := fld.listEntries(prefix, directories, false)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	result := make([]entryJSON, 0, len(entries))
+	for _, entry := range entries {
+		fileType := protocol.FileInfoTypeFile
+		switch entry.Type {
+		case protocol.FileInfoTypeDirectory.String():
+			fileType = protocol.FileInfoTypeDirectory
+		case protocol.FileInfoTypeSymlink.String():
+			fileType = protocol.FileInfoTypeSymlink
+		}
+		result = append(result, newEntryJSON(fld.FolderID, path.Join(prefix, entry.Name), entry.Size, fileType, false, entry.ModTime))
+	}
+	return json.Marshal(result)
+}
+
+// Like Client.Search, but returns all results at once as a JSON array
+func (clt *Client) SearchJSON(text string, maxResults int, folderID string, prefix string) ([]byte, error) {
+	if clt.app == nil || clt.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+
+	text = strings.ToLower(text)
+	result := make([]entryJSON, 0)
+
+	for _, folder := range clt.config.FolderList() {
+		if folderID != "" && folder.ID != folderID {
+			continue
+		}
+
+		matches, err := clt.searchFolder(folder.ID, text, prefix, func() bool { return false })
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if maxResults > 0 && len(result) >= maxResults {
+				break
+			}
+			result = append(result, newEntryJSON(folder.ID, match.name, match.size, match.fileType, false, match.modTime))
+		}
+	}
+	return json.Marshal(result)
+}
+
+// Returns the files this device still needs for this folder as a JSON array (see also FilesNeeded)
+func (fld *Folder) NeedsJSON() ([]byte, error) {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+
+	result := make([]entryJSON, 0)
+	page := 1
+	perPage := 512
+	for {
+		progress, queued, rest, err := fld.client.app.Internals.NeedFolderFiles(fld.FolderID, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+
+		batch := append(append(progress, queued...), rest...)
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, fi := range batch {
+			result = append(result, newEntryJSON(fld.FolderID, fi.FileName(), fi.FileSize(), fi.Type, fi.IsDeleted(), fi.ModTime()))
+		}
+		page += 1
+	}
+	return json.Marshal(result)
+}
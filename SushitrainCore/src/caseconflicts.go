@@ -0,0 +1,256 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const caseConflictsFileName = "caseconflicts.json"
+
+// Two paths in the global index that differ only in case, and therefore cannot both exist on a case-insensitive
+// filesystem (such as the one used on iOS)
+type CaseConflict struct {
+	Path                  string
+	ModifiedBy            string // Short device ID of the device that last changed Path
+	OtherPath             string
+	OtherModifiedBy       string
+	IsLocallyPresent      bool // Whether Path exists locally
+	OtherIsLocallyPresent bool // Whether OtherPath exists locally
+}
+
+type CaseConflicts struct {
+	items []*CaseConflict
+}
+
+func (cs *CaseConflicts) Count() int {
+	return len(cs.items)
+}
+
+func (cs *CaseConflicts) ItemAt(index int) *CaseConflict {
+	return cs.items[index]
+}
+
+// Returns pairs of paths in the global index that differ only in case. Syncthing will not create the second path of
+// such a pair on a case-insensitive filesystem, and reports an error for it instead.
+func (fld *Folder) CaseConflicts() (*CaseConflicts, error) {
+	conflicts, err := fld.caseConflicts()
+	if err != nil {
+		return nil, err
+	}
+	return &CaseConflicts{items: conflicts}, nil
+}
+
+func (fld *Folder) caseConflicts() ([]*CaseConflict, error) {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return nil, ErrFolderNotFound
+	}
+
+	internals := fld.client.app.Internals
+	byFoldedName := map[string][]string{}
+	for f, err := range zipError(internals.AllGlobalFiles(fld.FolderID)) {
+		if err != nil {
+			return nil, err
+		}
+		if f.Deleted {
+			continue
+		}
+		folded := fs.UnicodeLowercaseNormalized(f.Name)
+		byFoldedName[folded] = append(byFoldedName[folded], f.Name)
+	}
+
+	ffs := fc.Filesystem()
+	isLocallyPresent := func(name string) bool {
+		// On a case-insensitive filesystem, Lstat succeeds for either path of the pair, so look for the exact name
+		names, err := ffs.DirNames(osutil.NativeFilename(path.Dir(name)))
+		return err == nil && slices.Contains(names, path.Base(name))
+	}
+	modifiedBy := func(name string) string {
+		info, ok, err := internals.GlobalFileInfo(fld.FolderID, name)
+		if err != nil || !ok {
+			return ""
+		}
+		return info.ModifiedBy.String()
+	}
+
+	conflicts := make([]*CaseConflict, 0)
+	for _, names := range byFoldedName {
+		if len(names) < 2 {
+			continue
+		}
+		slices.Sort(names)
+
+		// Report each path against the one that is present locally, or the first one when none is
+		first := names[0]
+		for _, name := range names {
+			if isLocallyPresent(name) {
+				first = name
+				break
+			}
+		}
+
+		for _, name := range names {
+			if name == first {
+				continue
+			}
+			conflicts = append(conflicts, &CaseConflict{
+				Path:                  first,
+				ModifiedBy:            modifiedBy(first),
+				OtherPath:             name,
+				OtherModifiedBy:       modifiedBy(name),
+				IsLocallyPresent:      isLocallyPresent(first),
+				OtherIsLocallyPresent: isLocallyPresent(name),
+			})
+		}
+	}
+
+	slices.SortFunc(conflicts, func(a, b *CaseConflict) int {
+		return strings.Compare(a.OtherPath, b.OtherPath)
+	})
+	return conflicts, nil
+}
+
+// Returns whether case conflicts in this folder are resolved automatically (see SetRenameCaseConflicts)
+func (fld *Folder) RenameCaseConflicts() bool {
+	enabled := false
+	fld.client.caseConflictFolders.read(func(folders *[]string) {
+		enabled = slices.Contains(*folders, fld.FolderID)
+	})
+	return enabled
+}
+
+// Sets whether case conflicts are resolved automatically when the folder becomes idle. The path of each pair that could
+// not be created locally is renamed on all devices, by adding a suffix (e.g. "Photo (case conflict ABCDEFG).jpg").
+// Only folders that send changes can be resolved this way.
+func (fld *Folder) SetRenameCaseConflicts(enabled bool) error {
+	if enabled {
+		fc := fld.folderConfiguration()
+		if fc == nil {
+			return ErrFolderNotFound
+		}
+		if fc.Type != config.FolderTypeSendReceive {
+			return errors.New("case conflicts can only be renamed in send-receive folders")
+		}
+	}
+
+	err := fld.client.caseConflictFolders.modify(func(folders *[]string) {
+		*folders = slices.DeleteFunc(*folders, func(id string) bool { return id == fld.FolderID })
+		if enabled {
+			*folders = append(*folders, fld.FolderID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if enabled {
+		go fld.client.renameCaseConflicts(fld.FolderID)
+	}
+	return nil
+}
+
+func caseConflictPath(name string, modifiedBy string) string {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if modifiedBy == "" {
+		return fmt.Sprintf("%s (case conflict)%s", stem, ext)
+	}
+	return fmt.Sprintf("%s (case conflict %s)%s", stem, modifiedBy, ext)
+}
+
+// Called when a folder becomes idle, renames paths that could not be created locally because a path differing only in
+// case exists on disk. Only the paths that could not be pulled (see Folder.Errors) are considered, so that the global
+// index does not need to be scanned each time, and pairs of which neither path exists locally are left alone.
+func (clt *Client) renameCaseConflicts(folderID string) {
+	fld := clt.FolderWithID(folderID)
+	if fld == nil || !fld.RenameCaseConflicts() {
+		return
+	}
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return
+	}
+
+	ffs := fc.Filesystem()
+	for _, fileError := range clt.folderErrors.get(folderID) {
+		name := fileError.Path
+		existing, ok := locallyPresentCaseVariant(ffs, name)
+		if !ok {
+			continue
+		}
+		entry, err := fld.GetFileInformation(name)
+		if err != nil || entry == nil {
+			continue
+		}
+		if entry.IsDirectory() {
+			// Moving directories requires them to be available locally, which is exactly what is not possible
+			slog.Warn("cannot rename directory with case conflict", "folderID", folderID, "path", name)
+			continue
+		}
+
+		newPath := caseConflictPath(name, entry.info.ModifiedBy.String())
+		slog.Info("renaming path with case conflict", "folderID", folderID, "path", name, "existing", existing,
+			"newPath", newPath)
+		if err := fld.renameCaseConflictingFile(entry, newPath); err != nil {
+			slog.Warn("could not rename path with case conflict", "folderID", folderID, "path", name, "cause", err)
+		}
+	}
+}
+
+// Returns the name of the entry on disk that differs from the given path only in case (in its last component), if any
+func locallyPresentCaseVariant(ffs fs.Filesystem, name string) (string, bool) {
+	names, err := ffs.DirNames(osutil.NativeFilename(path.Dir(name)))
+	if err != nil {
+		return "", false
+	}
+	base := path.Base(name)
+	folded := fs.UnicodeLowercaseNormalized(base)
+	for _, candidate := range names {
+		if candidate != base && fs.UnicodeLowercaseNormalized(candidate) == folded {
+			return path.Join(path.Dir(name), candidate), true
+		}
+	}
+	return "", false
+}
+
+// Renames a file that cannot be created locally because a file differing only in case exists. Entry.Rename cannot be
+// used, as local file operations on the path would affect that other file.
+func (fld *Folder) renameCaseConflictingFile(entry *Entry, newPath string) error {
+	canonical, err := fld.canonicalWritablePath(newPath)
+	if err != nil {
+		return err
+	}
+	if info, ok, err := fld.client.app.Internals.GlobalFileInfo(fld.FolderID, canonical); err != nil {
+		return err
+	} else if ok && !info.IsDeleted() {
+		return errors.New("destination already exists")
+	}
+
+	reader := &entryReader{
+		entry:      entry,
+		puller:     newMiniPuller(fld.client.Measurements, fld.client.app.Internals),
+		remoteOnly: true,
+	}
+	if err := fld.writeFileFrom(canonical, io.NewSectionReader(reader, 0, entry.info.Size)); err != nil {
+		return err
+	}
+	return fld.deleteFromIndex([]protocol.FileInfo{entry.info})
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+const cellularPolicyFileName = "cellular.json"
+
+type cellularPolicy struct {
+	// Folders that should not sync while on cellular
+	Disallowed []string `json:"disallowed"`
+
+	// Folders that we paused because of this policy (and that we should therefore resume when WiFi is back)
+	PausedByPolicy []string `json:"pausedByPolicy"`
+}
+
+// Returns whether the folder should be paused, depending on whether the device is on cellular
+func (policy *cellularPolicy) pausesFolder(onCellular bool, folderID string) bool {
+	return onCellular && slices.Contains(policy.Disallowed, folderID)
+}
+
+// Returns whether this folder keeps syncing while the device is using a cellular connection (the default)
+func (fld *Folder) SyncOnCellular() bool {
+	allowed := true
+	fld.client.cellular.read(func(policy *cellularPolicy) {
+		allowed = !slices.Contains(policy.Disallowed, fld.FolderID)
+	})
+	return allowed
+}
+
+// Sets whether this folder may sync while the device is using a cellular connection. Folders that may not are paused
+// while on cellular, and resumed when the device is on another network again (see Client.SetOnCellular).
+func (fld *Folder) SetSyncOnCellular(allowed bool) error {
+	err := fld.client.cellular.modify(func(policy *cellularPolicy) {
+		policy.Disallowed = slices.DeleteFunc(policy.Disallowed, func(id string) bool { return id == fld.FolderID })
+		if !allowed {
+			policy.Disallowed = append(policy.Disallowed, fld.FolderID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return fld.client.applyCellularPolicy()
+}
+
+// Should be called by the app whenever the network path changes, to indicate whether the device is using a cellular
+// connection
+func (clt *Client) SetOnCellular(onCellular bool) error {
+	clt.mutex.Lock()
+	changed := clt.onCellular != onCellular
+	clt.onCellular = onCellular
+	clt.mutex.Unlock()
+
+	if !changed {
+		return nil
+	}
+	slog.Info("cellular state changed", "onCellular", onCellular)
+	return clt.applyCellularPolicy()
+}
+
+func (clt *Client) IsOnCellular() bool {
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+	return clt.onCellular
+}
+
+// Pauses folders that may not sync on cellular when on cellular, and resumes the folders we paused otherwise
+func (clt *Client) applyCellularPolicy() error {
+	if clt.config == nil {
+		return ErrStillLoading
+	}
+
+	onCellular := clt.IsOnCellular()
+	var pause, resume []string
+
+	err := clt.cellular.modify(func(policy *cellularPolicy) {
+		shouldBePaused := func(folderID string) bool {
+			return policy.pausesFolder(onCellular, folderID)
+		}
+
+		folders := clt.config.Folders()
+		for _, folderID := range policy.PausedByPolicy {
+			if !shouldBePaused(folderID) {
+				resume = append(resume, folderID)
+			}
+		}
+		for _, folderID := range policy.Disallowed {
+			fc, ok := folders[folderID]
+			if ok && shouldBePaused(folderID) && !fc.Paused && !slices.Contains(policy.PausedByPolicy, folderID) {
+				pause = append(pause, folderID)
+			}
+		}
+
+		policy.PausedByPolicy = slices.DeleteFunc(policy.PausedByPolicy, func(id string) bool {
+			return slices.Contains(resume, id)
+		})
+		policy.PausedByPolicy = append(policy.PausedByPolicy, pause...)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Folders that should not sync at the current power level stay paused, from now on because of the power policy
+	resume, err = clt.keepPausedForPower(resume)
+	if err != nil {
+		return err
+	}
+	if len(pause) == 0 && len(resume) == 0 {
+		return nil
+	}
+
+	slog.Info("applying cellular policy", "pause", pause, "resume", resume)
+	return clt.changeConfiguration(func(cfg *config.Configuration) {
+		for i := range cfg.Folders {
+			if slices.Contains(pause, cfg.Folders[i].ID) {
+				cfg.Folders[i].Paused = true
+			} else if slices.Contains(resume, cfg.Folders[i].ID) {
+				cfg.Folders[i].Paused = false
+			}
+		}
+	})
+}
+
+// Takes over folders that another policy is about to resume, when they may not sync on the current network. Returns
+// the folders that can be resumed.
+func (clt *Client) keepPausedForCellular(folderIDs []string) ([]string, error) {
+	if len(folderIDs) == 0 {
+		return folderIDs, nil
+	}
+
+	onCellular := clt.IsOnCellular()
+	resumable := make([]string, 0, len(folderIDs))
+	err := clt.cellular.modify(func(policy *cellularPolicy) {
+		for _, folderID := range folderIDs {
+			if policy.pausesFolder(onCellular, folderID) {
+				if !slices.Contains(policy.PausedByPolicy, folderID) {
+					policy.PausedByPolicy = append(policy.PausedByPolicy, folderID)
+				}
+			} else {
+				resumable = append(resumable, folderID)
+			}
+		}
+	})
+	return resumable, err
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"crypto/sha256"
+	"log/slog"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+type CertificateDelegate interface {
+	// Called when a known device connects but presents a certificate with a name other than the one we expect. The
+	// connection is refused by Syncthing. The UI may ask the user to confirm the certificate name (see
+	// Peer.SetCertificateName) after which the next connection attempt will succeed.
+	OnUnexpectedCertificate(deviceID string, address string, cause string)
+}
+
+// Returns the SHA-256 hash of our own device certificate (DER encoded)
+func (clt *Client) CertificateFingerprintSHA256() []byte {
+	if clt.cert == nil || len(clt.cert.Certificate) == 0 {
+		return nil
+	}
+	fingerprint := sha256.Sum256(clt.cert.Certificate[0])
+	return fingerprint[:]
+}
+
+// The name we expect on the certificate presented by this peer. An empty string means the Syncthing default is expected.
+func (peer *Peer) ExpectedCertificateName() string {
+	dc := peer.deviceConfiguration()
+	if dc == nil {
+		return ""
+	}
+	return dc.CertName
+}
+
+func (peer *Peer) SetCertificateName(name string) error {
+	return peer.changeDeviceConfiguration(func(dc *config.DeviceConfiguration) {
+		dc.CertName = name
+	})
+}
+
+func (clt *Client) handleBadCertificateLogRecord(r slog.Record) {
+	shortDeviceID, address, cause := connectionLogAttrs(r)
+
+	if clt.config == nil || clt.CertificateDelegate == nil {
+		return
+	}
+
+	peer := clt.PeerWithShortID(shortDeviceID)
+	if peer == nil {
+		slog.Info("unexpected certificate from unknown device", "shortDeviceID", shortDeviceID)
+		return
+	}
+
+	go clt.CertificateDelegate.OnUnexpectedCertificate(peer.DeviceID(), address, cause)
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// A change to the local index of a folder (see Folder.ChangesSince)
+type IndexChange struct {
+	Path     string
+	Action   string // One of ActivityActionAdded, ActivityActionChanged or ActivityActionDeleted
+	Sequence int64
+	Entry    *Entry
+}
+
+type IndexChanges struct {
+	items    []*IndexChange
+	Sequence int64 // Pass this to ChangesSince to obtain the next changes
+	HasMore  bool  // There are more changes than the limit allowed to return
+}
+
+func (ic *IndexChanges) Count() int {
+	return len(ic.items)
+}
+
+func (ic *IndexChanges) ItemAt(index int) *IndexChange {
+	if index < 0 || index >= len(ic.items) {
+		return nil
+	}
+	return ic.items[index]
+}
+
+// Returns the sequence number of the most recent change to the local index of the folder
+func (fld *Folder) CurrentSequence() (int64, error) {
+	if fld.client.sdb == nil {
+		return 0, ErrStillLoading
+	}
+	return fld.client.sdb.GetDeviceSequence(fld.FolderID, protocol.LocalDeviceID)
+}
+
+// Returns at most `limit` changes made to the local index of the folder after `sequence`, oldest first. Start with
+// zero (or CurrentSequence) and pass the returned sequence on the next call to only obtain newer changes. Each entry
+// appears once, with its latest sequence number. Changes on other devices appear once they have been pulled. The index
+// does not record whether an entry existed at an earlier sequence, so entries are only reported as added when starting
+// from zero; after that, new entries are reported as changed.
+func (fld *Folder) ChangesSince(sequence int64, limit int) (*IndexChanges, error) {
+	sdb := fld.client.sdb
+	if sdb == nil {
+		return nil, ErrStillLoading
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit must be positive")
+	}
+	if fld.folderConfiguration() == nil {
+		return nil, ErrFolderNotFound
+	}
+
+	changes := &IndexChanges{items: make([]*IndexChange, 0), Sequence: sequence}
+
+	// Ask for one more than the limit to find out whether there are more changes
+	for info, err := range zipError(sdb.AllLocalFilesBySequence(fld.FolderID, protocol.LocalDeviceID, sequence+1, limit+1)) {
+		if err != nil {
+			return nil, err
+		}
+		if len(changes.items) == limit {
+			changes.HasMore = true
+			break
+		}
+
+		action := ActivityActionChanged
+		if info.IsDeleted() {
+			action = ActivityActionDeleted
+		} else if sequence == 0 {
+			action = ActivityActionAdded
+		}
+
+		changes.items = append(changes.items, &IndexChange{
+			Path:     info.FileName(),
+			Action:   action,
+			Sequence: info.Sequence,
+			Entry:    &Entry{info: info, Folder: fld},
+		})
+		changes.Sequence = info.Sequence
+	}
+	return changes, nil
+}
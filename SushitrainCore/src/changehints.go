@@ -0,0 +1,205 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+)
+
+const (
+	changeHintCheckInterval = 10 * time.Second
+	changeHintRetention     = 15 * time.Minute // How long a hinted directory keeps being checked
+)
+
+type changeHint struct {
+	hintedAt  time.Time
+	signature uint64   // Of the directory listing when last checked; zero when not checked yet
+	scanPaths []string // What to rescan when the listing changed: the directory itself, or the hinted files at the root
+}
+
+// Directories the app told us about (see Folder.HintChanged), per folder
+type changeHints struct {
+	mutex       sync.Mutex
+	directories map[string]map[string]*changeHint // Folder ID => directory => hint
+}
+
+func newChangeHints() *changeHints {
+	return &changeHints{directories: map[string]map[string]*changeHint{}}
+}
+
+// Tells the client that the app (or a file provider extension) changed something at the path in the folder. File system
+// events are not delivered reliably for all locations on iOS, so the directory containing the path is checked every
+// few seconds for a while, and rescanned (only that directory) when its listing changed. The first check always
+// rescans it. This avoids both missed changes and full rescans of the folder. For files at the root of the folder, only
+// the hinted files are rescanned.
+func (fld *Folder) HintChanged(path string) error {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ErrFolderNotFound
+	}
+
+	canonical, err := fs.Canonicalize(path)
+	if err != nil {
+		return err
+	}
+
+	// Keep an eye on the directory containing a file, so that renames and deletions are noticed too
+	directory := canonical
+	if info, err := fc.Filesystem().Lstat(osutil.NativeFilename(canonical)); err != nil || !info.IsDir() {
+		directory = filepath.ToSlash(filepath.Dir(canonical))
+		if directory == "." {
+			directory = "" // The root of the folder
+		}
+	}
+
+	hints := fld.client.changeHints
+	hints.mutex.Lock()
+	defer hints.mutex.Unlock()
+	folderHints, ok := hints.directories[fld.FolderID]
+	if !ok {
+		folderHints = map[string]*changeHint{}
+		hints.directories[fld.FolderID] = folderHints
+	}
+
+	scanPaths := []string{directory}
+	if directory == "" {
+		// Rescanning the root would rescan the whole folder
+		scanPaths = []string{canonical}
+		if previous, ok := folderHints[directory]; ok {
+			scanPaths = previous.scanPaths
+			if !slices.Contains(scanPaths, canonical) {
+				scanPaths = append(slices.Clip(scanPaths), canonical)
+			}
+		}
+	}
+	folderHints[directory] = &changeHint{hintedAt: time.Now(), scanPaths: scanPaths}
+	return nil
+}
+
+func (clt *Client) serveChangeHints(ctx context.Context) {
+	ticker := time.NewTicker(changeHintCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			clt.checkChangeHints()
+		}
+	}
+}
+
+type changeHintCheck struct {
+	folderID  string
+	ffs       fs.Filesystem
+	directory string
+	hint      *changeHint
+	signature uint64 // Of the listing when last checked
+}
+
+func (clt *Client) checkChangeHints() {
+	if clt.app == nil || clt.app.Internals == nil || clt.config == nil {
+		return
+	}
+
+	// Listing directories may be slow, so the lock is not held while doing so
+	var checks []changeHintCheck
+	hints := clt.changeHints
+	hints.mutex.Lock()
+	now := time.Now()
+	for folderID, folderHints := range hints.directories {
+		fc, ok := clt.config.Folder(folderID)
+		if !ok {
+			delete(hints.directories, folderID)
+			continue
+		}
+		if fc.Paused {
+			continue
+		}
+
+		ffs := fc.Filesystem()
+		for directory, hint := range folderHints {
+			if now.Sub(hint.hintedAt) > changeHintRetention {
+				delete(folderHints, directory)
+				continue
+			}
+			checks = append(checks, changeHintCheck{folderID, ffs, directory, hint, hint.signature})
+		}
+		if len(folderHints) == 0 {
+			delete(hints.directories, folderID)
+		}
+	}
+	hints.mutex.Unlock()
+
+	signatures := make([]uint64, len(checks))
+	for i, check := range checks {
+		signatures[i] = directorySignature(check.ffs, check.directory)
+	}
+
+	changed := map[string][]string{}
+	hints.mutex.Lock()
+	for i, check := range checks {
+		if signatures[i] == check.signature {
+			continue
+		}
+		// The hint may have been replaced in the meantime, in which case it will be checked (and rescanned) next time
+		if hints.directories[check.folderID][check.directory] == check.hint {
+			check.hint.signature = signatures[i]
+		}
+		changed[check.folderID] = append(changed[check.folderID], check.hint.scanPaths...)
+	}
+	hints.mutex.Unlock()
+
+	for folderID, paths := range changed {
+		slices.Sort(paths)
+		paths = slices.Compact(paths)
+		slog.Info("rescanning hinted directories", "folderID", folderID, "paths", paths)
+		go func() {
+			if err := clt.app.Internals.ScanFolderSubdirs(folderID, paths); err != nil {
+				slog.Warn("could not rescan hinted directories", "folderID", folderID, "cause", err)
+			}
+		}()
+	}
+}
+
+// Hashes the names, sizes, types and modification times of the entries in a directory. Returns 1 for directories that
+// cannot be read, so that their disappearance is noticed too.
+func directorySignature(ffs fs.Filesystem, directory string) uint64 {
+	nativeDirectory := osutil.NativeFilename(directory)
+	names, err := ffs.DirNames(nativeDirectory)
+	if err != nil {
+		return 1
+	}
+	slices.Sort(names)
+
+	hash := fnv.New64a()
+	var buffer [8]byte
+	for _, name := range names {
+		hash.Write([]byte(name))
+		info, err := ffs.Lstat(filepath.Join(nativeDirectory, name))
+		if err != nil {
+			continue
+		}
+		binary.LittleEndian.PutUint64(buffer[:], uint64(info.Size()))
+		hash.Write(buffer[:])
+		binary.LittleEndian.PutUint64(buffer[:], uint64(info.ModTime().UnixNano()))
+		hash.Write(buffer[:])
+		if info.IsDir() {
+			hash.Write([]byte{1})
+		}
+	}
+	return max(hash.Sum64(), 2)
+}
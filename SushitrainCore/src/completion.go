@@ -0,0 +1,103 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"sync"
+	"time"
+)
+
+// Minimum time between two completion callbacks for the same folder and device
+const completionNotifyInterval = time.Second
+
+type CompletionDelegate interface {
+	// Called when the completion percentage (0-100) of a folder on a device changes
+	OnFolderCompletionChanged(folderID string, deviceID string, percentage float64)
+}
+
+type completionRecord struct {
+	delivered    float64
+	pending      float64
+	hasPending   bool
+	lastNotified time.Time
+	timer        *time.Timer
+}
+
+// Delivers FolderCompletion events to the CompletionDelegate, at most once per interval for each folder and device.
+// The last value received within an interval is delivered when it ends.
+type completionNotifier struct {
+	mutex   sync.Mutex
+	records map[string]*completionRecord // folderID/deviceID
+}
+
+func newCompletionNotifier() *completionNotifier {
+	return &completionNotifier{
+		records: map[string]*completionRecord{},
+	}
+}
+
+func (clt *Client) handleFolderCompletion(data map[string]any) {
+	folderID, _ := data["folder"].(string)
+	deviceID, _ := data["device"].(string)
+	percentage, ok := data["completion"].(float64)
+	if folderID == "" || deviceID == "" || !ok {
+		return
+	}
+
+	cn := clt.completionNotifier
+	cn.mutex.Lock()
+	defer cn.mutex.Unlock()
+
+	key := folderID + "/" + deviceID
+	rec, ok := cn.records[key]
+	if !ok {
+		rec = &completionRecord{delivered: -1}
+		cn.records[key] = rec
+	}
+
+	if percentage == rec.delivered {
+		rec.hasPending = false
+		return
+	}
+	rec.pending = percentage
+	rec.hasPending = true
+
+	if rec.timer != nil {
+		// A delivery is already scheduled and will pick up the new value
+		return
+	}
+	wait := completionNotifyInterval - time.Since(rec.lastNotified)
+	if wait < 0 {
+		wait = 0
+	}
+	rec.timer = time.AfterFunc(wait, func() {
+		clt.deliverFolderCompletion(folderID, deviceID, rec)
+	})
+}
+
+func (clt *Client) deliverFolderCompletion(folderID string, deviceID string, rec *completionRecord) {
+	cn := clt.completionNotifier
+	cn.mutex.Lock()
+	rec.timer = nil
+	if !rec.hasPending {
+		cn.mutex.Unlock()
+		return
+	}
+	percentage := rec.pending
+	rec.delivered = percentage
+	rec.hasPending = false
+	rec.lastNotified = time.Now()
+	cn.mutex.Unlock()
+
+	clt.mutex.Lock()
+	delegate := clt.CompletionDelegate
+	ignore := clt.IgnoreEvents
+	clt.mutex.Unlock()
+
+	if delegate != nil && !ignore {
+		delegate.OnFolderCompletionChanged(folderID, deviceID, percentage)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const configFileCheckInterval = 30 * time.Second
+
+// Where a configuration change came from (see ClientDelegate.OnConfigChanged)
+const (
+	ConfigChangeSourceApp      = "app"      // Saved by this app
+	ConfigChangeSourceExternal = "external" // The configuration file was changed by something else
+)
+
+// The difference between two configurations, delivered to the delegate as JSON
+type configDiff struct {
+	Source          string   `json:"source"`
+	FoldersAdded    []string `json:"foldersAdded"`
+	FoldersRemoved  []string `json:"foldersRemoved"`
+	FoldersModified []string `json:"foldersModified"`
+	DevicesAdded    []string `json:"devicesAdded"`
+	DevicesRemoved  []string `json:"devicesRemoved"`
+	DevicesModified []string `json:"devicesModified"`
+	OptionsChanged  []string `json:"optionsChanged"` // Names of the changed options, as in config.xml
+	GUIChanged      bool     `json:"guiChanged"`
+	DefaultsChanged bool     `json:"defaultsChanged"`
+}
+
+func (diff *configDiff) isEmpty() bool {
+	return len(diff.FoldersAdded) == 0 && len(diff.FoldersRemoved) == 0 && len(diff.FoldersModified) == 0 &&
+		len(diff.DevicesAdded) == 0 && len(diff.DevicesRemoved) == 0 && len(diff.DevicesModified) == 0 &&
+		len(diff.OptionsChanged) == 0 && !diff.GUIChanged && !diff.DefaultsChanged
+}
+
+// Returns the keys that were added to, removed from or changed between two maps
+func diffMaps[K comparable, V any](from map[K]V, to map[K]V, key func(K) string) (added, removed, modified []string) {
+	added, removed, modified = []string{}, []string{}, []string{}
+	for k, toValue := range to {
+		if fromValue, ok := from[k]; !ok {
+			added = append(added, key(k))
+		} else if !reflect.DeepEqual(fromValue, toValue) {
+			modified = append(modified, key(k))
+		}
+	}
+	for k := range from {
+		if _, ok := to[k]; !ok {
+			removed = append(removed, key(k))
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	slices.Sort(modified)
+	return
+}
+
+func computeConfigDiff(from config.Configuration, to config.Configuration) configDiff {
+	diff := configDiff{OptionsChanged: []string{}}
+	diff.FoldersAdded, diff.FoldersRemoved, diff.FoldersModified = diffMaps(from.FolderMap(), to.FolderMap(),
+		func(id string) string { return id })
+	diff.DevicesAdded, diff.DevicesRemoved, diff.DevicesModified = diffMaps(from.DeviceMap(), to.DeviceMap(),
+		func(id protocol.DeviceID) string { return id.String() })
+
+	fromOptions, toOptions := reflect.ValueOf(from.Options), reflect.ValueOf(to.Options)
+	for i := range fromOptions.NumField() {
+		if !reflect.DeepEqual(fromOptions.Field(i).Interface(), toOptions.Field(i).Interface()) {
+			field := fromOptions.Type().Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+			diff.OptionsChanged = append(diff.OptionsChanged, cmp.Or(name, field.Name))
+		}
+	}
+
+	diff.GUIChanged = !reflect.DeepEqual(from.GUI, to.GUI)
+	diff.DefaultsChanged = !reflect.DeepEqual(from.Defaults, to.Defaults)
+	return diff
+}
+
+// Remembers the configuration as last saved, to compare new configurations with
+type configDiffTracker struct {
+	mutex       sync.Mutex
+	previous    *config.Configuration
+	fileModTime time.Time // Of the configuration file when we last saved or checked it
+}
+
+func newConfigDiffTracker() *configDiffTracker {
+	return &configDiffTracker{}
+}
+
+func (clt *Client) configFileModTime() time.Time {
+	if clt.configFile == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(clt.configFile)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Starts tracking changes from the currently loaded configuration
+func (clt *Client) resetConfigDiffs() {
+	if clt.config == nil {
+		return
+	}
+	current := clt.config.RawCopy()
+	tracker := clt.configDiffs
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.previous = &current
+	tracker.fileModTime = clt.configFileModTime()
+}
+
+// Handles the ConfigSaved event, which Syncthing sends after it saved a changed configuration
+func (clt *Client) handleConfigSaved(saved config.Configuration) {
+	tracker := clt.configDiffs
+	tracker.mutex.Lock()
+	previous := tracker.previous
+	tracker.previous = &saved
+	tracker.fileModTime = clt.configFileModTime()
+	tracker.mutex.Unlock()
+
+	if previous != nil {
+		clt.deliverConfigDiff(computeConfigDiff(*previous, saved), ConfigChangeSourceApp)
+	}
+}
+
+func (clt *Client) serveConfigFileWatch(ctx context.Context) {
+	ticker := time.NewTicker(configFileCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			clt.checkConfigFile()
+		}
+	}
+}
+
+// Reports changes made to the configuration file by something other than this app (e.g. the user editing it on macOS).
+// The changes are not applied: they take effect when the app is restarted, unless the app saves its configuration first.
+func (clt *Client) checkConfigFile() {
+	if clt.config == nil {
+		return
+	}
+
+	tracker := clt.configDiffs
+	modTime := clt.configFileModTime()
+	tracker.mutex.Lock()
+	changed := !modTime.IsZero() && !modTime.Equal(tracker.fileModTime)
+	tracker.fileModTime = modTime
+	tracker.mutex.Unlock()
+	if !changed {
+		return
+	}
+
+	file, err := os.Open(clt.configFile)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	external, _, err := config.ReadXML(file, clt.deviceID())
+	if err != nil {
+		slog.Warn("could not read externally changed configuration file", "cause", err)
+		return
+	}
+	// Compare with the configuration in use rather than the one last saved, so our own saves are never reported here.
+	// The encryption passwords in the keychain are never written to the file, so they are not compared.
+	current := clt.withoutKeychainPasswords(clt.config.RawCopy())
+	clt.deliverConfigDiff(computeConfigDiff(current, external), ConfigChangeSourceExternal)
+}
+
+func (clt *Client) deliverConfigDiff(diff configDiff, source string) {
+	if diff.isEmpty() {
+		return
+	}
+	diff.Source = source
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		slog.Warn("could not encode configuration diff", "cause", err)
+		return
+	}
+	slog.Info("configuration changed", "source", source, "diff", string(diffJSON))
+	clt.notifyDelegate(func(delegate ClientDelegate) {
+		delegate.OnConfigChanged(string(diffJSON))
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	ConnectionProfileDefault       = "default"
+	ConnectionProfileRadioFriendly = "radio-friendly"
+	ConnectionProfileCustom        = "custom"
+
+	connectionProfileFileName = "connectionprofile.json"
+)
+
+type connectionProfileState struct {
+	// The profile last applied with SetConnectionProfile, applied again when the client is loaded
+	Profile string `json:"profile"`
+}
+
+type connectionProfile struct {
+	reconnectIntervalS      int
+	relayReconnectIntervalM int
+	progressUpdateIntervalS int // -1 disables sending download progress to peers
+}
+
+var connectionProfiles = map[string]connectionProfile{
+	// Reconnects to relays and reports download progress more often than Syncthing does by default, which is worth the
+	// cost on mobile networks
+	ConnectionProfileDefault: {
+		reconnectIntervalS:      60,
+		relayReconnectIntervalM: 1,
+		progressUpdateIntervalS: 1,
+	},
+
+	// Wakes up the radio less often: reconnection attempts are spread out further, and download progress (which would
+	// otherwise be sent to peers every second while pulling) is not sent at all
+	ConnectionProfileRadioFriendly: {
+		reconnectIntervalS:      300,
+		relayReconnectIntervalM: 10,
+		progressUpdateIntervalS: -1,
+	},
+}
+
+func (profile connectionProfile) matchesOptions(opts config.OptionsConfiguration) bool {
+	return opts.ReconnectIntervalS == profile.reconnectIntervalS &&
+		opts.RelayReconnectIntervalM == profile.relayReconnectIntervalM &&
+		opts.ProgressUpdateIntervalS == profile.progressUpdateIntervalS
+}
+
+// Sets the options that are forced each time the client is loaded. The reconnect interval is left alone, as Syncthing's
+// default already matches the default profile.
+func (profile connectionProfile) applyLoadOptions(opts *config.OptionsConfiguration) {
+	opts.RelayReconnectIntervalM = profile.relayReconnectIntervalM
+	opts.ProgressUpdateIntervalS = profile.progressUpdateIntervalS
+}
+
+// Returns the connection profile that was last applied with SetConnectionProfile, or the default profile
+func (clt *Client) storedConnectionProfile() connectionProfile {
+	name := ConnectionProfileDefault
+	clt.connectionProfile.read(func(state *connectionProfileState) {
+		if _, ok := connectionProfiles[state.Profile]; ok {
+			name = state.Profile
+		}
+	})
+	return connectionProfiles[name]
+}
+
+// Returns the name of the connection profile matching the current settings, or ConnectionProfileCustom when the
+// settings were changed otherwise (e.g. using SetReconnectIntervalS)
+func (clt *Client) ConnectionProfile() string {
+	if clt.config == nil {
+		return ConnectionProfileCustom
+	}
+
+	opts := clt.config.Options()
+	for name, profile := range connectionProfiles {
+		if profile.matchesOptions(opts) {
+			return name
+		}
+	}
+	return ConnectionProfileCustom
+}
+
+// Applies one of the connection profiles (ConnectionProfileDefault or ConnectionProfileRadioFriendly). The
+// radio-friendly profile keeps the cellular radio idle for longer, at the cost of reconnecting to peers that came back
+// later and peers not seeing our download progress. The profile is remembered and applied again when the client is
+// loaded.
+func (clt *Client) SetConnectionProfile(profileName string) error {
+	profile, ok := connectionProfiles[profileName]
+	if !ok {
+		return errors.New("unknown connection profile")
+	}
+
+	slog.Info("set connection profile", "profile", profileName)
+	err := clt.changeConfiguration(func(cfg *config.Configuration) {
+		cfg.Options.ReconnectIntervalS = profile.reconnectIntervalS
+		cfg.Options.RelayReconnectIntervalM = profile.relayReconnectIntervalM
+		cfg.Options.ProgressUpdateIntervalS = profile.progressUpdateIntervalS
+	})
+	if err != nil {
+		return err
+	}
+	return clt.connectionProfile.modify(func(state *connectionProfileState) {
+		state.Profile = profileName
+	})
+}
+
+// Returns the interval (in seconds) at which keep-alive pings are sent on otherwise idle connections. This interval is
+// fixed by Syncthing (peers close connections they did not hear from for five minutes), so it is the same for all
+// connection profiles.
+func (clt *Client) KeepAliveIntervalSeconds() int {
+	return int(protocol.PingSendInterval.Seconds())
+}
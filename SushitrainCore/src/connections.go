@@ -0,0 +1,138 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+const connectionHistoryFileName = "connections.json"
+
+// What we remember about connections with a device across launches
+type connectionRecord struct {
+	LastSeen    time.Time `json:"lastSeen"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastErrorAt time.Time `json:"lastErrorAt"`
+
+	// As announced by the device in its Hello message when it last connected
+	RemoteName    string `json:"remoteName,omitempty"`
+	ClientName    string `json:"clientName,omitempty"`
+	ClientVersion string `json:"clientVersion,omitempty"`
+	LastAddress   string `json:"lastAddress,omitempty"` // Remote address of the connection (host:port)
+
+	// Traffic with the device since TrafficSince (see Peer.TotalBytesIn)
+	BytesIn      int64     `json:"bytesIn,omitempty"`
+	BytesOut     int64     `json:"bytesOut,omitempty"`
+	TrafficSince time.Time `json:"trafficSince"`
+}
+
+// Extracts the device, address and error attributes that Syncthing attaches to connection-related log messages
+func connectionLogAttrs(r slog.Record) (shortDeviceID string, address string, cause string) {
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case "device":
+			shortDeviceID = a.Value.String()
+		case "address":
+			address = a.Value.String()
+		case "error":
+			cause = a.Value.String()
+		}
+		return true
+	})
+	return
+}
+
+// Errors with which Syncthing closes connections on purpose (e.g. when the device is paused), which do not indicate a
+// connection problem
+var expectedDisconnectErrors = []string{
+	"device is paused",
+	"Syncthing is being stopped",
+	"replacing connection",
+}
+
+// Records a lost connection using the data from the DeviceDisconnected event, including the error the connection was
+// closed with
+func (clt *Client) recordDisconnected(data map[string]string) {
+	clt.updateConnectionRecord(data["id"], func(rec *connectionRecord) {
+		now := time.Now()
+		rec.LastSeen = now
+		if cause := data["error"]; cause != "" && !slices.Contains(expectedDisconnectErrors, cause) {
+			rec.LastError = cause
+			rec.LastErrorAt = now
+		}
+	})
+}
+
+// Records a new connection using the data from the DeviceConnected event
+func (clt *Client) recordConnected(data map[string]string) {
+	clt.updateConnectionRecord(data["id"], func(rec *connectionRecord) {
+		rec.LastSeen = time.Now()
+		rec.RemoteName = data["deviceName"]
+		rec.ClientName = data["clientName"]
+		rec.ClientVersion = data["clientVersion"]
+		if data["addr"] != "" {
+			rec.LastAddress = data["addr"]
+		}
+	})
+}
+
+func (clt *Client) updateConnectionRecord(deviceID string, update func(rec *connectionRecord)) {
+	err := clt.connections.modify(func(records *map[string]*connectionRecord) {
+		rec, ok := (*records)[deviceID]
+		if !ok {
+			rec = &connectionRecord{}
+			(*records)[deviceID] = rec
+		}
+		update(rec)
+	})
+	if err != nil {
+		slog.Warn("could not save connection history", "cause", err)
+	}
+}
+
+func (peer *Peer) connectionRecord() connectionRecord {
+	var rec connectionRecord
+	peer.client.connections.read(func(records *map[string]*connectionRecord) {
+		if r, ok := (*records)[peer.deviceID.String()]; ok {
+			rec = *r
+		}
+	})
+	return rec
+}
+
+// Returns the error with which the most recent connection to this device that ended unexpectedly was closed, or an
+// empty string if none is known.
+//
+// Errors from failed attempts to connect (e.g. an address that cannot be reached) are not included. Syncthing keeps
+// these per address in the status of its connection service (ConnectionStatus), which it only exposes through its REST
+// API. That API is disabled in this app, so these errors are not available to it.
+func (peer *Peer) LastConnectionError() string {
+	return peer.connectionRecord().LastError
+}
+
+// Returns when the error returned by LastConnectionError occurred, or nil if there is none
+func (peer *Peer) LastConnectionErrorDate() *Date {
+	rec := peer.connectionRecord()
+	if rec.LastError == "" {
+		return nil
+	}
+	return &Date{time: rec.LastErrorAt}
+}
+
+// Returns the name the device gave itself when it last connected. Hello messages do not carry a hardware model, but
+// devices usually announce their host name or model name (e.g. "iPhone").
+func (peer *Peer) DeviceModel() string {
+	return peer.connectionRecord().RemoteName
+}
+
+// Returns the name and version of the software the device ran when it last connected (e.g. "syncthing v1.30.0")
+func (peer *Peer) ClientVersion() string {
+	rec := peer.connectionRecord()
+	return strings.TrimSpace(rec.ClientName + " " + rec.ClientVersion)
+}
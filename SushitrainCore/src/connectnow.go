@@ -0,0 +1,110 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+const (
+	defaultSyncthingPort = 22000
+	wakeDatagram         = "sushitrain-wake"
+)
+
+// Attempts to connect to the device right away, instead of waiting until the next attempt scheduled by Syncthing (which
+// backs off to several minutes for devices that could not be reached). When `wake` is set, a UDP datagram is first sent
+// to the addresses on the local network the device was last known at. This is not a Wake-on-LAN magic packet (which
+// requires the hardware address of the device, which we do not know), but it does wake devices that wake up on unicast
+// traffic, such as many NAS devices and Macs.
+func (peer *Peer) ConnectNow(wake bool) error {
+	clt := peer.client
+	if clt.app == nil || clt.app.Internals == nil {
+		return ErrStillLoading
+	}
+	if peer.IsSelf() {
+		return errors.New("cannot connect to ourselves")
+	}
+	dc := peer.deviceConfiguration()
+	if dc == nil {
+		return ErrDeviceNotFound
+	}
+	if dc.Paused {
+		return errors.New("device is paused")
+	}
+	if peer.IsConnected() {
+		return nil
+	}
+
+	if wake {
+		peer.sendWakeDatagrams()
+	}
+
+	// Syncthing dials a device right away (forgetting about earlier failures) when it is resumed, which is the only way
+	// to reset its backoff from the outside. The device is not connected, so pausing it briefly does not interrupt
+	// anything.
+	slog.Info("connecting to device now", "deviceID", peer.deviceID.String(), "wake", wake)
+	if err := peer.SetPaused(true); err != nil {
+		return err
+	}
+	return peer.SetPaused(false)
+}
+
+// Returns the addresses (host:port) on the local network at which the device was last seen, was discovered, or that
+// are configured for it
+func (peer *Peer) localNetworkAddresses() []string {
+	candidates := make([]string, 0)
+	if last := peer.connectionRecord().LastAddress; last != "" {
+		candidates = append(candidates, last)
+	}
+	if entry, ok := peer.client.localDiscovery.all()[peer.deviceID]; ok {
+		candidates = append(candidates, entry.addresses...)
+	}
+	if dc := peer.deviceConfiguration(); dc != nil {
+		candidates = append(candidates, dc.Addresses...)
+	}
+
+	addresses := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		hostPort := candidate
+		if u, err := url.Parse(candidate); err == nil && u.Host != "" {
+			hostPort = u.Host
+		}
+		host, port, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			host, port = hostPort, strconv.Itoa(defaultSyncthingPort)
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !(ip.IsPrivate() || ip.IsLinkLocalUnicast()) {
+			continue
+		}
+		address := net.JoinHostPort(ip.String(), port)
+		if !slices.Contains(addresses, address) {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+func (peer *Peer) sendWakeDatagrams() {
+	for _, address := range peer.localNetworkAddresses() {
+		conn, err := net.Dial("udp", address)
+		if err != nil {
+			slog.Warn("could not send wake datagram", "address", address, "cause", err)
+			continue
+		}
+		if _, err := conn.Write([]byte(wakeDatagram)); err != nil {
+			slog.Warn("could not send wake datagram", "address", address, "cause", err)
+		} else {
+			slog.Info("sent wake datagram", "deviceID", peer.deviceID.String(), "address", address)
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/syncthing"
+)
+
+// Returned by Client.Start when the database could not be opened. The client then remains in a degraded mode in which
+// the configuration and identity can be accessed, and the database can be repaired (see RepairDatabase) or reset (see
+// ResetDatabase).
+var ErrDatabaseUnavailable = errors.New("the database could not be opened")
+
+const (
+	databaseJournalBackupSuffix = ".journal-backup"
+	databaseResetBackupSuffix   = ".reset-backup"
+)
+
+// Returns whether the client is in degraded mode because the database could not be opened
+func (clt *Client) IsDatabaseUnavailable() bool {
+	return clt.databaseError != nil
+}
+
+// Returns the internals of the running Syncthing instance, or nil while it is loading or when the database could not be
+// opened (in which case the instance is stopped and clt.app is cleared, see restart)
+func (clt *Client) internals() *syncthing.Internals {
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+	if clt.app == nil {
+		return nil
+	}
+	return clt.app.Internals
+}
+
+// Returns why the database could not be opened, or an empty string when it was opened
+func (clt *Client) DatabaseError() string {
+	if clt.databaseError == nil {
+		return ""
+	}
+	return clt.databaseError.Error()
+}
+
+// Attempts to open the database again after it could not be opened. When that fails, the journal files of the database
+// are moved aside (losing the most recent changes, which will be scanned or received again) before trying once more.
+// After a successful repair, the client can be started.
+func (clt *Client) RepairDatabase() error {
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+
+	if clt.databaseError == nil {
+		return errors.New("the database is not in need of repair")
+	}
+	if err := clt.reopenDatabase(); err == nil {
+		return nil
+	}
+
+	dbPath := locations.Get(locations.Database)
+	backupPath := dbPath + databaseJournalBackupSuffix
+	entries, err := os.ReadDir(dbPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(backupPath, 0o700); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, "-wal") && !strings.HasSuffix(name, "-shm") {
+			continue
+		}
+		slog.Warn("moving database journal aside", "name", name)
+		if err := os.Rename(filepath.Join(dbPath, name), filepath.Join(backupPath, name)); err != nil {
+			return err
+		}
+	}
+	return clt.reopenDatabase()
+}
+
+// Moves the database aside and starts with an empty one, after which the client can be started. The index is rebuilt
+// by scanning the folders and receiving the indexes of peers again, which can take a while. Selections and settings
+// are not affected. The previous database is kept until the next reset.
+func (clt *Client) ResetDatabase() error {
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+
+	if clt.databaseError == nil {
+		return errors.New("the database can only be reset when it could not be opened")
+	}
+
+	dbPath := locations.Get(locations.Database)
+	backupPath := dbPath + databaseResetBackupSuffix
+	if err := os.RemoveAll(backupPath); err != nil {
+		return err
+	}
+	slog.Warn("resetting database", "path", dbPath, "backupPath", backupPath)
+	if err := os.Rename(dbPath, backupPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return clt.reopenDatabase()
+}
+
+func (clt *Client) reopenDatabase() error {
+	app, err := clt.newApp(false)
+	if err != nil {
+		clt.databaseError = err
+		return err
+	}
+	clt.app = app
+	clt.databaseError = nil
+
+	// When the database became unavailable while restarting (see RestartInPlace), the client was started already
+	if clt.Server != nil {
+		return app.Start()
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	dataUsageFileName     = "datausage.json"
+	dataUsageSaveInterval = time.Minute
+	dataUsageRetention    = 400 // days
+	dataUsageDayFormat    = "2006-01-02"
+
+	// Bytes written to folder files per folder and source ("network" for data received from peers)
+	metricNameFolderProcessedBytes = "syncthing_model_folder_processed_bytes_total"
+)
+
+// Network types the app can declare with SetNetworkType
+const (
+	NetworkTypeWiFi     = "wifi"
+	NetworkTypeCellular = "cellular"
+	NetworkTypeWired    = "wired"
+	NetworkTypeOther    = "other"
+)
+
+type dataUsageCounts struct {
+	BytesIn       int64            `json:"bytesIn"`
+	BytesOut      int64            `json:"bytesOut"`
+	FolderBytesIn map[string]int64 `json:"folderBytesIn,omitempty"`
+}
+
+type dataUsageState struct {
+	CountingSince time.Time                              `json:"countingSince"`
+	Days          map[string]map[string]*dataUsageCounts `json:"days"` // Day => network type => counts
+}
+
+// Keeps track of which part of Syncthing's (per-process) counters was already attributed to a network type
+type dataUsageTracker struct {
+	mutex         sync.Mutex
+	networkType   string
+	savedIn       int64
+	savedOut      int64
+	savedFolderIn map[string]int64
+}
+
+func newDataUsageTracker() *dataUsageTracker {
+	return &dataUsageTracker{
+		networkType:   NetworkTypeOther,
+		savedFolderIn: map[string]int64{},
+	}
+}
+
+// Reads the cumulative number of bytes received from the network for each folder from Syncthing's metrics
+func folderNetworkTotals() map[string]int64 {
+	totals := map[string]int64{}
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		slog.Warn("could not gather folder metrics", "cause", err)
+		return totals
+	}
+
+	for _, family := range families {
+		if family.GetName() != metricNameFolderProcessedBytes {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			folderID, source := "", ""
+			for _, label := range metric.GetLabel() {
+				switch label.GetName() {
+				case "folder":
+					folderID = label.GetValue()
+				case "source":
+					source = label.GetValue()
+				}
+			}
+			if source == "network" {
+				totals[folderID] = int64(metric.GetCounter().GetValue())
+			}
+		}
+	}
+	return totals
+}
+
+// Should be called by the app whenever the network path changes, with the type of network the device now uses
+// ("wifi", "cellular", "wired" or "other"). Traffic is attributed to the declared network type (see DataUsage). This
+// also calls SetOnCellular.
+func (clt *Client) SetNetworkType(networkType string) error {
+	switch networkType {
+	case NetworkTypeWiFi, NetworkTypeCellular, NetworkTypeWired, NetworkTypeOther:
+	default:
+		return errors.New("unknown network type")
+	}
+
+	// Attribute what was transferred so far to the previous network type
+	clt.saveDataUsage()
+
+	tracker := clt.dataUsageTracker
+	tracker.mutex.Lock()
+	tracker.networkType = networkType
+	tracker.mutex.Unlock()
+
+	err := clt.SetOnCellular(networkType == NetworkTypeCellular)
+	if errors.Is(err, ErrStillLoading) {
+		return nil
+	}
+	return err
+}
+
+func (clt *Client) NetworkType() string {
+	tracker := clt.dataUsageTracker
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	return tracker.networkType
+}
+
+func (clt *Client) serveDataUsage(ctx context.Context) {
+	ticker := time.NewTicker(dataUsageSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			clt.saveDataUsage()
+		}
+	}
+}
+
+// Adds the traffic since the last save to the counts of today for the current network type
+func (clt *Client) saveDataUsage() {
+	in, out := protocol.TotalInOut()
+	folderIn := folderNetworkTotals()
+
+	tracker := clt.dataUsageTracker
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	deltaIn, deltaOut := max(0, in-tracker.savedIn), max(0, out-tracker.savedOut)
+	deltaFolderIn := map[string]int64{}
+	for folderID, total := range folderIn {
+		if delta := total - tracker.savedFolderIn[folderID]; delta > 0 {
+			deltaFolderIn[folderID] = delta
+		}
+	}
+	if deltaIn == 0 && deltaOut == 0 && len(deltaFolderIn) == 0 {
+		return
+	}
+
+	now := time.Now()
+	err := clt.dataUsage.modify(func(state *dataUsageState) {
+		if state.CountingSince.IsZero() {
+			state.CountingSince = now
+		}
+		if state.Days == nil {
+			state.Days = map[string]map[string]*dataUsageCounts{}
+		}
+		day := now.Format(dataUsageDayFormat)
+		if state.Days[day] == nil {
+			state.Days[day] = map[string]*dataUsageCounts{}
+		}
+		counts, ok := state.Days[day][tracker.networkType]
+		if !ok {
+			counts = &dataUsageCounts{}
+			state.Days[day][tracker.networkType] = counts
+		}
+		counts.BytesIn += deltaIn
+		counts.BytesOut += deltaOut
+		if len(deltaFolderIn) > 0 && counts.FolderBytesIn == nil {
+			counts.FolderBytesIn = map[string]int64{}
+		}
+		for folderID, delta := range deltaFolderIn {
+			counts.FolderBytesIn[folderID] += delta
+		}
+
+		oldest := now.AddDate(0, 0, -dataUsageRetention).Format(dataUsageDayFormat)
+		maps.DeleteFunc(state.Days, func(day string, _ map[string]*dataUsageCounts) bool {
+			return day < oldest
+		})
+	})
+	if err != nil {
+		slog.Warn("could not save data usage", "cause", err)
+		return
+	}
+
+	tracker.savedIn, tracker.savedOut = max(tracker.savedIn, in), max(tracker.savedOut, out)
+	for folderID, total := range folderIn {
+		tracker.savedFolderIn[folderID] = max(tracker.savedFolderIn[folderID], total)
+	}
+}
+
+// Data transferred per network type over a period (see Client.DataUsage)
+type DataUsage struct {
+	since  time.Time
+	counts map[string]*dataUsageCounts // Network type => counts
+}
+
+// Returns since when data is counted; this is the start of the period, or later when counting started (or was reset)
+// after that
+func (du *DataUsage) Since() *Date {
+	return &Date{time: du.since}
+}
+
+// Returns the network types for which data was transferred during the period
+func (du *DataUsage) NetworkTypes() *ListOfStrings {
+	return List(slices.Sorted(maps.Keys(du.counts)))
+}
+
+// Returns the number of bytes received from all devices over networks of the given type (including protocol overhead)
+func (du *DataUsage) BytesIn(networkType string) int64 {
+	if counts, ok := du.counts[networkType]; ok {
+		return counts.BytesIn
+	}
+	return 0
+}
+
+// Returns the number of bytes sent to all devices over networks of the given type (including protocol overhead)
+func (du *DataUsage) BytesOut(networkType string) int64 {
+	if counts, ok := du.counts[networkType]; ok {
+		return counts.BytesOut
+	}
+	return 0
+}
+
+// Returns the IDs of folders for which file data was received during the period
+func (du *DataUsage) FolderIDs() *ListOfStrings {
+	folderIDs := map[string]bool{}
+	for _, counts := range du.counts {
+		for folderID := range counts.FolderBytesIn {
+			folderIDs[folderID] = true
+		}
+	}
+	return List(slices.Sorted(maps.Keys(folderIDs)))
+}
+
+// Returns the number of bytes of file data received for the folder over networks of the given type. Only data that
+// was actually downloaded is counted (not blocks copied from local files), and data sent is not attributed to folders.
+func (du *DataUsage) FolderBytesIn(networkType string, folderID string) int64 {
+	if counts, ok := du.counts[networkType]; ok {
+		return counts.FolderBytesIn[folderID]
+	}
+	return 0
+}
+
+// Returns the data transferred per network type (see SetNetworkType) and folder during the last `periodDays` days
+// (including today). Counts are kept for a little over a year.
+func (clt *Client) DataUsage(periodDays int) (*DataUsage, error) {
+	if periodDays < 1 {
+		return nil, errors.New("period must be at least one day")
+	}
+	clt.saveDataUsage()
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()-(periodDays-1), 0, 0, 0, 0, now.Location())
+	firstDay := start.Format(dataUsageDayFormat)
+	usage := &DataUsage{since: start, counts: map[string]*dataUsageCounts{}}
+
+	clt.dataUsage.read(func(state *dataUsageState) {
+		if state.CountingSince.After(usage.since) {
+			usage.since = state.CountingSince
+		}
+		for day, perType := range state.Days {
+			if day < firstDay {
+				continue
+			}
+			for networkType, counts := range perType {
+				total, ok := usage.counts[networkType]
+				if !ok {
+					total = &dataUsageCounts{FolderBytesIn: map[string]int64{}}
+					usage.counts[networkType] = total
+				}
+				total.BytesIn += counts.BytesIn
+				total.BytesOut += counts.BytesOut
+				for folderID, bytes := range counts.FolderBytesIn {
+					total.FolderBytesIn[folderID] += bytes
+				}
+			}
+		}
+	})
+	return usage, nil
+}
+
+// Forgets all data usage counted so far, e.g. at the start of a new billing period
+func (clt *Client) ResetDataUsage() error {
+	clt.saveDataUsage()
+	return clt.dataUsage.modify(func(state *dataUsageState) {
+		state.CountingSince = time.Now()
+		state.Days = map[string]map[string]*dataUsageCounts{}
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+
+	"github.com/syncthing/syncthing/lib/build"
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+const configDefaultsFileName = "defaults.json"
+
+type configDefaultsState struct {
+	// Set once the user changed the folder defaults, after which the app no longer imposes its own on launch
+	CustomFolderDefaults bool `json:"customFolderDefaults"`
+}
+
+// The defaults for new folders the app uses unless the user changed them
+func applyAppFolderDefaults(fc *config.FolderConfiguration) {
+	fc.IgnorePerms = true              // iOS doesn't expose permissions to users
+	fc.RescanIntervalS = 3600          // Force default rescan interval
+	fc.FSWatcherEnabled = !build.IsIOS // Enable watching by default but not on iOS
+}
+
+func (clt *Client) hasCustomFolderDefaults() bool {
+	custom := false
+	clt.configDefaults.read(func(state *configDefaultsState) {
+		custom = state.CustomFolderDefaults
+	})
+	return custom
+}
+
+// Returns the settings new folders (including those accepted from offers) are created with, as JSON in the format of
+// Syncthing's folder configuration (e.g. `versioning`, `ignorePerms`, `rescanIntervalS`, `fsWatcherEnabled`)
+func (clt *Client) DefaultFolderSettings() ([]byte, error) {
+	if clt.config == nil {
+		return nil, ErrStillLoading
+	}
+	return json.Marshal(clt.config.DefaultFolder())
+}
+
+// Changes the settings new folders are created with. Only the keys present in the JSON object are changed (see
+// DefaultFolderSettings for the format). Pass an empty value to go back to the defaults of the app.
+func (clt *Client) SetDefaultFolderSettings(settingsJSON []byte) error {
+	if clt.config == nil {
+		return ErrStillLoading
+	}
+
+	var defaults config.FolderConfiguration
+	if len(settingsJSON) == 0 {
+		defaults = config.New(clt.deviceID()).Defaults.Folder
+		applyAppFolderDefaults(&defaults)
+	} else {
+		var err error
+		defaults, err = mergeSettings(clt.config.DefaultFolder(), settingsJSON)
+		if err != nil {
+			return err
+		}
+		if err := validateFolderDefaults(&defaults); err != nil {
+			return err
+		}
+	}
+
+	err := clt.changeConfiguration(func(cfg *config.Configuration) {
+		cfg.Defaults.Folder = defaults
+	})
+	if err != nil {
+		return err
+	}
+	return clt.configDefaults.modify(func(state *configDefaultsState) {
+		state.CustomFolderDefaults = len(settingsJSON) > 0
+	})
+}
+
+// Returns the settings new devices are added with, as JSON in the format of Syncthing's device configuration (e.g.
+// `autoAcceptFolders`, `compression`, `introducer`)
+func (clt *Client) DefaultDeviceSettings() ([]byte, error) {
+	if clt.config == nil {
+		return nil, ErrStillLoading
+	}
+	return json.Marshal(clt.config.DefaultDevice())
+}
+
+// Changes the settings new devices are added with. Only the keys present in the JSON object are changed (see
+// DefaultDeviceSettings for the format).
+func (clt *Client) SetDefaultDeviceSettings(settingsJSON []byte) error {
+	if clt.config == nil {
+		return ErrStillLoading
+	}
+
+	defaults, err := mergeSettings(clt.config.DefaultDevice(), settingsJSON)
+	if err != nil {
+		return err
+	}
+	return clt.changeConfiguration(func(cfg *config.Configuration) {
+		cfg.Defaults.Device = defaults
+	})
+}
+
+// Applies the keys in `changesJSON` to the JSON representation of `current`. Syncthing's configuration types reset
+// fields to their defaults when unmarshalling, so the changes are merged with the complete current value first.
+func mergeSettings[T any](current T, changesJSON []byte) (T, error) {
+	var merged T
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return merged, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(currentJSON, &fields); err != nil {
+		return merged, err
+	}
+
+	changes := map[string]json.RawMessage{}
+	if err := json.Unmarshal(changesJSON, &changes); err != nil {
+		return merged, err
+	}
+	for key := range changes {
+		if _, ok := fields[key]; !ok {
+			return merged, errors.New("unknown setting: " + key)
+		}
+	}
+	maps.Copy(fields, changes)
+
+	mergedJSON, err := json.Marshal(fields)
+	if err != nil {
+		return merged, err
+	}
+	err = json.Unmarshal(mergedJSON, &merged)
+	return merged, err
+}
+
+func validateFolderDefaults(fc *config.FolderConfiguration) error {
+	switch fc.Versioning.Type {
+	case "", "simple", "trashcan", "staggered", "external":
+	default:
+		return errors.New("unknown versioning type: " + fc.Versioning.Type)
+	}
+	if fc.RescanIntervalS < 0 {
+		return errors.New("rescan interval cannot be negative")
+	}
+	return nil
+}
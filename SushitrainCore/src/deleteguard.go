@@ -0,0 +1,298 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const deleteGuardFileName = "deleteguard.json"
+
+type DeleteGuardDelegate interface {
+	// Called when a folder was paused because peers want to delete more of it than its delete guard allows. Call
+	// Folder.ApprovePendingDeletes or Folder.RevertPendingDeletes to continue.
+	OnDeletesHeld(folderID string, deletes int, total int)
+}
+
+type deleteGuardRecord struct {
+	ThresholdPercent int  `json:"thresholdPercent"`
+	IsHolding        bool `json:"isHolding"` // The folder was paused by the guard
+	PendingDeletes   int  `json:"pendingDeletes"`
+	IsApproved       bool `json:"isApproved"` // Deletes were approved, the guard is inactive until the folder is idle again
+}
+
+// Returns the percentage of files in the folder that may be deleted by a single update from peers before the folder is
+// paused (see SetDeleteGuard), or zero when the guard is disabled
+func (fld *Folder) DeleteGuard() int {
+	threshold := 0
+	fld.client.deleteGuards.read(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[fld.FolderID]; ok {
+			threshold = rec.ThresholdPercent
+		}
+	})
+	return threshold
+}
+
+// Protects against a peer deleting (a large part of) the folder. When changes received from peers would delete more than
+// `thresholdPercent` percent of the files in the folder, the folder is paused and DeleteGuardDelegate is notified. Pass
+// zero to disable the guard. The check runs in the background when index updates come in and when a pull starts.
+// Syncthing does not wait for it before pulling, so some deletes may already be carried out before the folder is
+// paused; the guard limits the damage of a large deletion rather than preventing every delete.
+func (fld *Folder) SetDeleteGuard(thresholdPercent int) error {
+	if thresholdPercent < 0 || thresholdPercent > 100 {
+		return errors.New("threshold must be between 0 and 100 percent")
+	}
+	if fld.folderConfiguration() == nil {
+		return ErrFolderNotFound
+	}
+
+	err := fld.client.deleteGuards.modify(func(guards *map[string]*deleteGuardRecord) {
+		if thresholdPercent == 0 {
+			delete(*guards, fld.FolderID)
+		} else if rec, ok := (*guards)[fld.FolderID]; ok {
+			rec.ThresholdPercent = thresholdPercent
+		} else {
+			(*guards)[fld.FolderID] = &deleteGuardRecord{ThresholdPercent: thresholdPercent}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if thresholdPercent > 0 {
+		fld.client.scheduleDeleteGuardCheck(fld.FolderID)
+	}
+	return nil
+}
+
+// Returns the number of deletes held back by the delete guard, or zero when the guard did not pause the folder
+func (fld *Folder) PendingDeletes() int {
+	pending := 0
+	fld.client.deleteGuards.read(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[fld.FolderID]; ok && rec.IsHolding {
+			pending = rec.PendingDeletes
+		}
+	})
+	return pending
+}
+
+// Resumes a folder paused by the delete guard, allowing the deletes to happen
+func (fld *Folder) ApprovePendingDeletes() error {
+	if !fld.isHeldByDeleteGuard() {
+		return errors.New("folder has no pending deletes")
+	}
+
+	err := fld.client.deleteGuards.modify(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[fld.FolderID]; ok {
+			rec.IsHolding = false
+			rec.PendingDeletes = 0
+			rec.IsApproved = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return fld.SetPaused(false)
+}
+
+// Resumes a folder paused by the delete guard after undoing the deletes: our copies of the files are announced as newer
+// than the deletions, so the files are restored on the other devices instead.
+func (fld *Folder) RevertPendingDeletes() error {
+	client := fld.client
+	if client.app == nil || client.app.Internals == nil || client.sdb == nil {
+		return ErrStillLoading
+	}
+	if !fld.isHeldByDeleteGuard() {
+		return errors.New("folder has no pending deletes")
+	}
+
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ErrFolderNotFound
+	}
+	if fc.Type != config.FolderTypeSendReceive {
+		return errors.New("deletes can only be reverted in send-receive folders")
+	}
+
+	// The folder is paused, so look for deletes in the global index rather than asking the (stopped) folder what it needs
+	deleted := make([]string, 0)
+	for f, err := range zipError(client.app.Internals.AllGlobalFiles(fld.FolderID)) {
+		if err != nil {
+			return err
+		}
+		if f.Deleted {
+			deleted = append(deleted, f.Name)
+		}
+	}
+
+	shortID := client.deviceID().Short()
+	restored := make([]protocol.FileInfo, 0)
+	for _, name := range deleted {
+		global, ok, err := client.app.Internals.GlobalFileInfo(fld.FolderID, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		local, ok, err := client.sdb.GetDeviceFile(fld.FolderID, protocol.LocalDeviceID, name)
+		if err != nil {
+			return err
+		}
+		if !ok || local.IsDeleted() {
+			continue
+		}
+		local.Version = local.Version.Merge(global.Version).Update(shortID)
+		restored = append(restored, local)
+	}
+
+	if len(restored) > 0 {
+		// The global and needed flags were computed for the old version, and are computed again by the database
+		if err := fld.updateLocalIndex(restored, protocol.FlagLocalGlobal|protocol.FlagLocalNeeded); err != nil {
+			return err
+		}
+		slog.Info("reverted deletes held by delete guard", "folderID", fld.FolderID, "count", len(restored))
+	}
+
+	err := client.deleteGuards.modify(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[fld.FolderID]; ok {
+			rec.IsHolding = false
+			rec.PendingDeletes = 0
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return fld.SetPaused(false)
+}
+
+func (fld *Folder) isHeldByDeleteGuard() bool {
+	holding := false
+	fld.client.deleteGuards.read(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[fld.FolderID]; ok {
+			holding = rec.IsHolding
+		}
+	})
+	return holding
+}
+
+// Runs checkDeleteGuard for the folder in the background. When a check is already running for the folder, it is run
+// once more after that instead, as index updates tend to arrive in quick succession.
+func (clt *Client) scheduleDeleteGuardCheck(folderID string) {
+	clt.mutex.Lock()
+	if _, running := clt.deleteGuardChecks[folderID]; running {
+		clt.deleteGuardChecks[folderID] = true
+		clt.mutex.Unlock()
+		return
+	}
+	clt.deleteGuardChecks[folderID] = false
+	clt.mutex.Unlock()
+
+	go func() {
+		for {
+			clt.checkDeleteGuard(folderID)
+
+			clt.mutex.Lock()
+			if !clt.deleteGuardChecks[folderID] {
+				delete(clt.deleteGuardChecks, folderID)
+				clt.mutex.Unlock()
+				return
+			}
+			clt.deleteGuardChecks[folderID] = false
+			clt.mutex.Unlock()
+		}
+	}()
+}
+
+// Pauses the folder when peers would delete too much of it. Called through scheduleDeleteGuardCheck when peers sent
+// index updates for the folder and when a pull starts.
+func (clt *Client) checkDeleteGuard(folderID string) {
+	if clt.app == nil || clt.app.Internals == nil {
+		return
+	}
+
+	var guard deleteGuardRecord
+	clt.deleteGuards.read(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[folderID]; ok {
+			guard = *rec
+		}
+	})
+	if guard.ThresholdPercent == 0 || guard.IsHolding || guard.IsApproved {
+		return
+	}
+
+	fld := clt.FolderWithID(folderID)
+	if fld == nil || fld.IsPaused() {
+		return
+	}
+
+	need, err := clt.app.Internals.NeedSize(folderID, protocol.LocalDeviceID)
+	if err != nil || need.Deleted == 0 {
+		return
+	}
+	local, err := clt.app.Internals.LocalSize(folderID)
+	if err != nil {
+		return
+	}
+	total := local.Files + local.Directories + local.Symlinks
+	if total == 0 || need.Deleted*100 <= total*guard.ThresholdPercent {
+		return
+	}
+
+	slog.Warn("pausing folder because peers want to delete too many files", "folderID", folderID, "deletes", need.Deleted,
+		"total", total, "thresholdPercent", guard.ThresholdPercent)
+	if err := fld.SetPaused(true); err != nil {
+		slog.Warn("could not pause folder for delete guard", "folderID", folderID, "cause", err)
+		return
+	}
+
+	err = clt.deleteGuards.modify(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[folderID]; ok {
+			rec.IsHolding = true
+			rec.PendingDeletes = need.Deleted
+		}
+	})
+	if err != nil {
+		slog.Warn("could not save delete guard state", "folderID", folderID, "cause", err)
+	}
+
+	if clt.DeleteGuardDelegate != nil {
+		clt.DeleteGuardDelegate.OnDeletesHeld(folderID, need.Deleted, total)
+	}
+}
+
+// Called when a folder becomes idle. Once the approved deletes have been carried out, the guard is active again.
+func (clt *Client) resetDeleteGuardApproval(folderID string) {
+	if clt.app == nil || clt.app.Internals == nil {
+		return
+	}
+
+	approved := false
+	clt.deleteGuards.read(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[folderID]; ok {
+			approved = rec.IsApproved
+		}
+	})
+	if !approved {
+		return
+	}
+	if need, err := clt.app.Internals.NeedSize(folderID, protocol.LocalDeviceID); err != nil || need.Deleted > 0 {
+		return
+	}
+
+	err := clt.deleteGuards.modify(func(guards *map[string]*deleteGuardRecord) {
+		if rec, ok := (*guards)[folderID]; ok {
+			rec.IsApproved = false
+		}
+	})
+	if err != nil {
+		slog.Warn("could not save delete guard state", "folderID", folderID, "cause", err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/fs"
@@ -207,6 +208,35 @@ func (entry *Entry) FetchLocal(start int64, length int64) ([]byte, error) {
 	}
 }
 
+// Opens the local copy of the file when it is the version this entry describes (i.e. the one in the local index, with
+// the size it should have), so that it can be read instead of fetching the file from peers
+func (entry *Entry) openLocalVersion() (fs.File, error) {
+	sdb := entry.Folder.client.sdb
+	fc := entry.Folder.folderConfiguration()
+	if sdb == nil || fc == nil {
+		return nil, errors.New("file not available")
+	}
+
+	local, ok, err := sdb.GetDeviceFile(entry.Folder.FolderID, protocol.LocalDeviceID, entry.info.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || local.IsDeleted() || local.IsInvalid() || !local.Version.Equal(entry.info.Version) {
+		return nil, errors.New("local copy is not the requested version")
+	}
+
+	ffs := fc.Filesystem()
+	file, err := ffs.Open(osutil.NativeFilename(entry.info.Name))
+	if err != nil {
+		return nil, err
+	}
+	if stat, err := file.Stat(); err != nil || stat.Size() != entry.info.Size {
+		file.Close()
+		return nil, errors.New("local copy was changed")
+	}
+	return file, nil
+}
+
 func (entry *Entry) IsLocallyPresent() bool {
 	fc := entry.Folder.folderConfiguration()
 	if fc == nil {
@@ -247,6 +277,9 @@ func (entry *Entry) IsExplicitlySelected() bool {
 }
 
 func (entry *Entry) SetExplicitlySelected(selected bool) error {
+	if selected && entry.IsSymlink() && entry.Folder.SymlinkPolicy() == SymlinkPolicySkip {
+		return errors.New("symlinks are skipped in this folder")
+	}
 	paths := map[string]bool{}
 	paths[entry.info.Name] = selected
 	return entry.Folder.setExplicitlySelected(paths)
@@ -399,22 +432,65 @@ func (entry *Entry) availabilityPerDevice() (map[protocol.DeviceID]int, int, err
 	return deviceStatus, len(info.Blocks), nil
 }
 
+// Detailed progress of a single download
+type DownloadProgress struct {
+	BytesDone      int64
+	BytesTotal     int64
+	BlocksDone     int
+	BlocksTotal    int
+	BytesPerSecond float64 // Average since the download started
+	ETASeconds     float64 // Negative when unknown
+}
+
+type DownloadProgressDelegate interface {
+	OnDownloadProgress(progress *DownloadProgress)
+}
+
 type progressWriter struct {
-	delegate DownloadDelegate
-	out      io.Writer
-	written  int
-	total    int
+	delegate    DownloadDelegate
+	progress    DownloadProgressDelegate
+	out         io.Writer
+	written     int
+	total       int
+	blocksDone  int
+	blocksTotal int
+	started     time.Time
 }
 
+// Called by miniPuller.downloadInto for each block
 func (pw *progressWriter) Write(buf []byte) (n int, err error) {
 	n, err = pw.out.Write(buf)
 	pw.written += n
+	pw.blocksDone += 1
 	pw.delegate.OnProgress(float64(pw.written) / float64(pw.total))
+
+	if pw.progress != nil {
+		progress := &DownloadProgress{
+			BytesDone:   int64(pw.written),
+			BytesTotal:  int64(pw.total),
+			BlocksDone:  pw.blocksDone,
+			BlocksTotal: pw.blocksTotal,
+			ETASeconds:  -1,
+		}
+		if elapsed := time.Since(pw.started).Seconds(); elapsed > 0 {
+			progress.BytesPerSecond = float64(pw.written) / elapsed
+		}
+		if progress.BytesPerSecond > 0 {
+			progress.ETASeconds = float64(pw.total-pw.written) / progress.BytesPerSecond
+		}
+		pw.progress.OnDownloadProgress(progress)
+	}
 	return
 }
 
 /** Download this file to the specific location (should be outside the synced folder!) **/
 func (entry *Entry) Download(toPath string, delegate DownloadDelegate) {
+	entry.DownloadWithProgress(toPath, delegate, nil)
+}
+
+// Like Download, but additionally reports detailed progress (bytes and blocks done, transfer rate and ETA) for this
+// download to `progress`
+func (entry *Entry) DownloadWithProgress(toPath string, delegate DownloadDelegate, progress DownloadProgressDelegate) {
 	go func() {
 		context := context.WithoutCancel(context.Background())
 		m := entry.Folder.client.app.Internals
@@ -446,10 +522,13 @@ func (entry *Entry) Download(toPath string, delegate DownloadDelegate) {
 		delegate.OnProgress(0.0)
 		mp := newMiniPuller(entry.Folder.client.Measurements, m)
 		pw := progressWriter{
-			out:      outFile,
-			delegate: delegate,
-			total:    int(info.Size),
-			written:  0,
+			out:         outFile,
+			delegate:    delegate,
+			progress:    progress,
+			total:       int(info.Size),
+			written:     0,
+			blocksTotal: len(info.Blocks),
+			started:     time.Now(),
 		}
 		err = mp.downloadInto(context, &pw, folderID, info)
 		if err != nil {
@@ -469,10 +548,23 @@ func (entry *Entry) OnDemandURL() string {
 	return server.urlFor(entry.Folder.FolderID, entry.info.FileName())
 }
 
+// Like OnDemandURL, but usable by other devices on the local network. Empty when LAN access to the streaming server is
+// not allowed (see StreamingServer.SetAllowLAN).
+func (entry *Entry) LANOnDemandURL() string {
+	server := entry.Folder.client.Server
+	if server == nil {
+		return ""
+	}
+
+	return server.lanURLFor(entry.Folder.FolderID, entry.info.FileName())
+}
+
 func (entry *Entry) Extension() string {
 	return filepath.Ext(entry.info.FileName())
 }
 
+// Returns the MIME type based on the extension. This is cheap enough to call while listing files; use SniffMIMEType to
+// also inspect the contents of files with an unknown extension.
 func (entry *Entry) MIMEType() string {
 	ext := filepath.Ext(entry.info.FileName())
 	return MIMETypeForExtension(ext)
@@ -0,0 +1,50 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import "log/slog"
+
+// The maximum number of delegate calls kept while no delegate is set. The oldest calls are dropped first.
+const maxReplayedDelegateCalls = 256
+
+// Calls the delegate, or keeps the call for replay when no delegate was set yet (see SetDelegate). Nothing is delivered
+// or kept while IgnoreEvents is set.
+func (clt *Client) notifyDelegate(call func(delegate ClientDelegate)) {
+	clt.mutex.Lock()
+	if clt.IgnoreEvents {
+		clt.mutex.Unlock()
+		return
+	}
+	delegate := clt.Delegate
+	if delegate == nil {
+		clt.replayBuffer = append(clt.replayBuffer, call)
+		if len(clt.replayBuffer) > maxReplayedDelegateCalls {
+			clt.replayBuffer = clt.replayBuffer[len(clt.replayBuffer)-maxReplayedDelegateCalls:]
+		}
+		clt.mutex.Unlock()
+		return
+	}
+	clt.mutex.Unlock()
+	call(delegate)
+}
+
+// Sets the delegate. Events that occurred while no delegate was set (e.g. folder offers or device connections right
+// after start-up) are delivered to the new delegate, in order, when `replay` is set, and discarded otherwise.
+func (clt *Client) SetDelegate(delegate ClientDelegate, replay bool) {
+	clt.mutex.Lock()
+	clt.Delegate = delegate
+	missed := clt.replayBuffer
+	clt.replayBuffer = nil
+	clt.mutex.Unlock()
+
+	if !replay || delegate == nil || len(missed) == 0 {
+		return
+	}
+	slog.Info("replaying events to delegate", "count", len(missed))
+	for _, call := range missed {
+		call(delegate)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const exclusionsFileName = "exclusions.json"
+
+// Lines delimiting the ignore patterns we maintain for excluded extensions and large files. The block is kept at the
+// start of the ignore file (patterns are matched in order), also in selective folders.
+const (
+	exclusionsStartLine = "// Exclusions maintained by Synctrain"
+	exclusionsEndLine   = "// End of exclusions"
+)
+
+type exclusionRecord struct {
+	Extensions            []string `json:"extensions"` // Lower case, without leading dot
+	MaxAutoDownloadSizeMB int      `json:"maxAutoDownloadSizeMB"`
+}
+
+func (fld *Folder) exclusion() exclusionRecord {
+	var rec exclusionRecord
+	fld.client.exclusions.read(func(exclusions *map[string]*exclusionRecord) {
+		if r, ok := (*exclusions)[fld.FolderID]; ok {
+			rec = *r
+		}
+	})
+	return rec
+}
+
+func (fld *Folder) modifyExclusion(modify func(rec *exclusionRecord)) error {
+	err := fld.client.exclusions.modify(func(exclusions *map[string]*exclusionRecord) {
+		rec, ok := (*exclusions)[fld.FolderID]
+		if !ok {
+			rec = &exclusionRecord{}
+		}
+		modify(rec)
+		if len(rec.Extensions) == 0 && rec.MaxAutoDownloadSizeMB == 0 {
+			delete(*exclusions, fld.FolderID)
+		} else {
+			(*exclusions)[fld.FolderID] = rec
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return fld.updateExclusions()
+}
+
+// Returns the extensions (without leading dot) of files that are never synchronized in this folder
+func (fld *Folder) ExcludedExtensions() *ListOfStrings {
+	return List(fld.exclusion().Extensions)
+}
+
+// Sets the extensions (e.g. "mov" or ".mov", case insensitive) of files that are never synchronized in this folder. In
+// selective folders, files with these extensions cannot be selected, and are skipped inside selected directories.
+func (fld *Folder) SetExcludedExtensions(extensions *ListOfStrings) error {
+	normalized := make([]string, 0, len(extensions.data))
+	for _, ext := range extensions.data {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext == "" {
+			continue
+		}
+		if strings.ContainsAny(ext, "/\\"+strings.Join(specialChars, "")) {
+			return errors.New("invalid extension: " + ext)
+		}
+		normalized = append(normalized, ext)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	return fld.modifyExclusion(func(rec *exclusionRecord) {
+		rec.Extensions = normalized
+	})
+}
+
+// Returns the size in megabytes above which files are not synchronized automatically, or zero when there is no limit
+func (fld *Folder) MaxAutoDownloadSizeMB() int {
+	return fld.exclusion().MaxAutoDownloadSizeMB
+}
+
+// Sets the size in megabytes above which files are not synchronized automatically (zero to remove the limit). Large
+// files can still be selected explicitly in selective folders. The limit is applied when the folder becomes idle and
+// before it starts pulling, so a large file that arrives while the folder is syncing may still be downloaded.
+func (fld *Folder) SetMaxAutoDownloadSizeMB(sizeMB int) error {
+	if sizeMB < 0 {
+		return errors.New("size cannot be negative")
+	}
+	return fld.modifyExclusion(func(rec *exclusionRecord) {
+		rec.MaxAutoDownloadSizeMB = sizeMB
+	})
+}
+
+// Returns whether files at this path are excluded because of their extension
+func (fld *Folder) isExcludedExtension(filePath string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(filePath), "."))
+	return ext != "" && slices.Contains(fld.exclusion().Extensions, ext)
+}
+
+// Brings the ignore patterns for exclusions in line with the settings and the files currently in the index
+func (fld *Folder) updateExclusions() error {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	lines, _, err := fld.client.app.Internals.Ignores(fld.FolderID)
+	if err != nil {
+		return err
+	}
+	newLines := withoutLineBlock(lines, exclusionsStartLine, exclusionsEndLine)
+	selection := NewSelection(newLines)
+
+	rec := fld.exclusion()
+	block := []string{exclusionsStartLine}
+	for _, ext := range rec.Extensions {
+		block = append(block, "(?i)*."+ext)
+	}
+
+	if rec.MaxAutoDownloadSizeMB > 0 {
+		maxSize := int64(rec.MaxAutoDownloadSizeMB) * 1024 * 1024
+		for f, err := range zipError(fld.client.app.Internals.AllGlobalFiles(fld.FolderID)) {
+			if err != nil {
+				return err
+			}
+			if f.Deleted || f.Type != protocol.FileInfoTypeFile || f.Size <= maxSize || fld.isExcludedExtension(f.Name) {
+				continue
+			}
+			// Large files that were selected explicitly are downloaded anyway
+			if selection.isSelectiveIgnore() && selection.IsPathExplicitlySelected(f.Name) {
+				continue
+			}
+			block = append(block, strings.TrimPrefix(ignoreLineForSelectingPath(f.Name), "!"))
+		}
+	}
+
+	if len(block) > 1 {
+		block = append(block, exclusionsEndLine)
+		newLines = append(block, newLines...)
+	}
+
+	if slices.Equal(lines, newLines) {
+		return nil
+	}
+	slog.Info("updating ignore patterns for exclusions", "folderID", fld.FolderID)
+	fld.cachedIgnore.matcher = nil
+	return fld.client.app.Internals.SetIgnores(fld.FolderID, newLines)
+}
+
+// Called when a folder becomes idle or is about to pull, to pick up large files that appeared in the meantime
+func (clt *Client) checkExclusions(folderID string) {
+	fld := clt.FolderWithID(folderID)
+	if fld == nil || fld.MaxAutoDownloadSizeMB() == 0 {
+		return
+	}
+	if err := fld.updateExclusions(); err != nil {
+		slog.Warn("could not update ignore patterns for exclusions", "folderID", folderID, "cause", err)
+	}
+}
+
+// Returns the lines without the block delimited by the start and end lines
+func withoutLineBlock(lines []string, startLine string, endLine string) []string {
+	result := make([]string, 0, len(lines))
+	inBlock := false
+	for _, line := range lines {
+		switch {
+		case line == startLine:
+			inBlock = true
+		case line == endLine:
+			inBlock = false
+		case !inBlock:
+			result = append(result, line)
+		}
+	}
+	return result
+}
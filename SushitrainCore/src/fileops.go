@@ -0,0 +1,367 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The parts of Syncthing's database we use directly (the database type itself is internal to Syncthing)
+type localIndexWriter interface {
+	Update(folder string, device protocol.DeviceID, fs []protocol.FileInfo) error
+	GetDeviceSequence(folder string, device protocol.DeviceID) (int64, error)
+	GetDeviceFile(folder string, device protocol.DeviceID, file string) (protocol.FileInfo, bool, error)
+	AllLocalFilesBySequence(folder string, device protocol.DeviceID, startSeq int64, limit int) (iter.Seq[protocol.FileInfo], func() error)
+	DropFolder(folder string) error
+}
+
+// Checks that a path supplied by the app points inside the folder and returns it in canonical form
+func (fld *Folder) canonicalWritablePath(filePath string) (string, error) {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return "", ErrFolderNotFound
+	}
+	if fc.Type == config.FolderTypeReceiveEncrypted {
+		return "", errors.New("cannot write to a receive-encrypted folder")
+	}
+
+	canonical, err := fs.Canonicalize(filePath)
+	if err != nil {
+		return "", err
+	}
+	if canonical == "." || fs.IsInternal(canonical) || fs.IsTemporary(canonical) {
+		return "", errors.New("invalid path")
+	}
+	return canonical, nil
+}
+
+// In a selective folder, selects the path so the file we are about to create is not ignored
+func (fld *Folder) selectPathForWriting(canonical string) error {
+	ignores, err := fld.loadIgnores()
+	if err != nil {
+		return err
+	}
+	if !NewSelection(ignores.Lines()).isSelectiveIgnore() || !ignores.Match(canonical).IsIgnored() {
+		return nil
+	}
+	return fld.SetLocalFileExplicitlySelected(canonical, true)
+}
+
+// Deselects a path that no longer exists, so it does not linger in the selection
+func (fld *Folder) deselectRemovedPath(canonical string) {
+	ignores, err := fld.loadIgnores()
+	if err != nil {
+		return
+	}
+	selection := NewSelection(ignores.Lines())
+	if !selection.isSelectiveIgnore() || !selection.IsPathExplicitlySelected(canonical) {
+		return
+	}
+	if err := fld.SetLocalFileExplicitlySelected(canonical, false); err != nil {
+		slog.Warn("could not deselect removed path", "folderID", fld.FolderID, "path", canonical, "cause", err)
+	}
+}
+
+// Writes a file into the folder, replacing any existing file at the path and creating parent directories as needed.
+// In selective folders, the file is selected. The file is then scanned so it is sent to other devices.
+func (fld *Folder) WriteFile(filePath string, data []byte) error {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	canonical, err := fld.canonicalWritablePath(filePath)
+	if err != nil {
+		return err
+	}
+	return fld.writeFileFrom(canonical, bytes.NewReader(data))
+}
+
+func (fld *Folder) writeFileFrom(canonical string, reader io.Reader) error {
+	if err := fld.writeFileContents(canonical, reader); err != nil {
+		return err
+	}
+	return fld.client.app.Internals.ScanFolderSubdirs(fld.FolderID, []string{canonical})
+}
+
+// Writes a file without scanning it, for callers that write several files and scan them at once
+func (fld *Folder) writeFileContents(canonical string, reader io.Reader) error {
+	if err := fld.selectPathForWriting(canonical); err != nil {
+		return err
+	}
+
+	ffs := fld.folderConfiguration().Filesystem()
+	nativePath := osutil.NativeFilename(canonical)
+	if dir := path.Dir(canonical); dir != "." {
+		if err := ffs.MkdirAll(osutil.NativeFilename(dir), 0o755); err != nil {
+			return err
+		}
+	}
+
+	// Write to a temporary file first, so the scanner never picks up a partially written file
+	tempPath := fs.TempName(nativePath)
+	file, err := ffs.Create(tempPath)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = ffs.Rename(tempPath, nativePath)
+	}
+	if err != nil {
+		ffs.Remove(tempPath)
+		return err
+	}
+
+	slog.Info("wrote file", "folderID", fld.FolderID, "path", canonical, "size", written)
+	return nil
+}
+
+// Creates a directory (and its parents) in the folder. In selective folders, the directory is selected. The directory is
+// then scanned so it is sent to other devices.
+func (fld *Folder) Mkdir(dirPath string) error {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	canonical, err := fld.canonicalWritablePath(dirPath)
+	if err != nil {
+		return err
+	}
+	if err := fld.selectPathForWriting(canonical); err != nil {
+		return err
+	}
+
+	ffs := fld.folderConfiguration().Filesystem()
+	if err := ffs.MkdirAll(osutil.NativeFilename(canonical), 0o755); err != nil {
+		return err
+	}
+
+	slog.Info("created directory", "folderID", fld.FolderID, "path", canonical)
+	return fld.client.app.Internals.ScanFolderSubdirs(fld.FolderID, []string{canonical})
+}
+
+// Records the deletion of files we do not have locally in our index, so that they are deleted on other devices. Syncthing
+// only records deletions it observes while scanning, so we write the records to the database ourselves.
+func (fld *Folder) deleteFromIndex(infos []protocol.FileInfo) error {
+	if fld.client.sdb == nil {
+		return ErrStillLoading
+	}
+
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ErrFolderNotFound
+	}
+	if fc.Type != config.FolderTypeSendReceive && fc.Type != config.FolderTypeSendOnly {
+		return errors.New("files not available locally can only be deleted in folders that send changes")
+	}
+
+	shortID := fld.client.deviceID().Short()
+	for i := range infos {
+		infos[i].SetDeleted(shortID)
+	}
+	if err := fld.updateLocalIndex(infos, protocol.LocalAllFlags); err != nil {
+		return err
+	}
+	slog.Info("recorded deletion of files not available locally", "folderID", fld.FolderID, "count", len(infos))
+	return nil
+}
+
+// Writes changed file infos to our own index, and announces them to peers. The local flags in `clearFlags` are removed
+// from each file info.
+func (fld *Folder) updateLocalIndex(infos []protocol.FileInfo, clearFlags protocol.FlagLocal) error {
+	sdb := fld.client.sdb
+	names := make([]string, 0, len(infos))
+	for i := range infos {
+		infos[i].LocalFlags &^= clearFlags
+		infos[i].Sequence = 0
+		names = append(names, infos[i].Name)
+	}
+	if err := sdb.Update(fld.FolderID, protocol.LocalDeviceID, infos); err != nil {
+		return err
+	}
+
+	// Wake up the index senders
+	seq, err := sdb.GetDeviceSequence(fld.FolderID, protocol.LocalDeviceID)
+	if err != nil {
+		return err
+	}
+	fld.client.evLogger.Log(events.LocalIndexUpdated, map[string]any{
+		"folder":    fld.FolderID,
+		"items":     len(infos),
+		"filenames": names,
+		"sequence":  seq,
+		"version":   seq,
+	})
+	return nil
+}
+
+// Deletes a file or directory that is not available locally from all other devices, by recording its deletion (and
+// that of everything inside it) in our index. This allows removing files from a selective folder without first
+// fetching them.
+func (entry *Entry) DeleteGlobally() error {
+	fld := entry.Folder
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+	if entry.info.IsDeleted() {
+		return nil
+	}
+	if entry.IsLocallyPresent() {
+		return errors.New("file is available locally, use Delete instead")
+	}
+	if fld.isPinned(entry.info.Name) {
+		return errPinned
+	}
+
+	infos := []protocol.FileInfo{}
+	if entry.IsDirectory() {
+		internals := fld.client.app.Internals
+		prefix := entry.info.Name + "/"
+
+		// Collect the paths first, so we don't keep the database busy while looking up each file
+		children := make([]string, 0)
+		for f, err := range zipError(internals.AllGlobalFiles(fld.FolderID)) {
+			if err != nil {
+				return err
+			}
+			if !f.Deleted && strings.HasPrefix(f.Name, prefix) {
+				children = append(children, f.Name)
+			}
+		}
+
+		for _, child := range children {
+			info, ok, err := internals.GlobalFileInfo(fld.FolderID, child)
+			if err != nil {
+				return err
+			}
+			if ok {
+				infos = append(infos, info)
+			}
+		}
+
+		// Children before their parents
+		slices.SortFunc(infos, func(a, b protocol.FileInfo) int {
+			return strings.Count(b.Name, "/") - strings.Count(a.Name, "/")
+		})
+	}
+	infos = append(infos, entry.info)
+	return fld.deleteFromIndex(infos)
+}
+
+// Deletes the file or directory on this and all other devices. Files that are not available locally are deleted using
+// DeleteGlobally.
+func (entry *Entry) Delete() error {
+	fld := entry.Folder
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+	if fld.isPinned(entry.info.Name) {
+		return errPinned
+	}
+
+	if !entry.IsLocallyPresent() {
+		return entry.DeleteGlobally()
+	}
+
+	ffs := fld.folderConfiguration().Filesystem()
+	if err := ffs.RemoveAll(osutil.NativeFilename(entry.info.Name)); err != nil {
+		return err
+	}
+
+	// Scan before deselecting, ignored paths are not scanned so the deletion would go unnoticed
+	if err := fld.client.app.Internals.ScanFolderSubdirs(fld.FolderID, []string{entry.info.Name}); err != nil {
+		return err
+	}
+	fld.deselectRemovedPath(entry.info.Name)
+	return nil
+}
+
+// Moves the file or directory to another path in the same folder
+func (entry *Entry) Rename(newPath string) error {
+	return entry.MoveToFolder(entry.Folder.FolderID, newPath)
+}
+
+// Moves the file to a path in another (or the same) folder. Files not available locally are fetched from other devices.
+// Directories can only be moved within a folder, and must be available locally.
+func (entry *Entry) MoveToFolder(destFolderID string, destPath string) error {
+	fld := entry.Folder
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	dest := fld.client.FolderWithID(destFolderID)
+	if dest == nil {
+		return errors.New("destination folder does not exist")
+	}
+	canonical, err := dest.canonicalWritablePath(destPath)
+	if err != nil {
+		return err
+	}
+	if dest.FolderID == fld.FolderID && canonical == entry.info.Name {
+		return nil
+	}
+	if fld.isPinned(entry.info.Name) {
+		return errPinned
+	}
+
+	destFS := dest.folderConfiguration().Filesystem()
+	if _, err := destFS.Lstat(osutil.NativeFilename(canonical)); err == nil {
+		return errors.New("destination already exists")
+	}
+	if info, ok, err := fld.client.app.Internals.GlobalFileInfo(dest.FolderID, canonical); err != nil {
+		return err
+	} else if ok && !info.IsDeleted() {
+		return errors.New("destination already exists")
+	}
+
+	// Within a folder, local files and directories can simply be renamed
+	if dest.FolderID == fld.FolderID && entry.IsLocallyPresent() {
+		if err := fld.selectPathForWriting(canonical); err != nil {
+			return err
+		}
+		if dir := path.Dir(canonical); dir != "." {
+			if err := destFS.MkdirAll(osutil.NativeFilename(dir), 0o755); err != nil {
+				return err
+			}
+		}
+		if err := destFS.Rename(osutil.NativeFilename(entry.info.Name), osutil.NativeFilename(canonical)); err != nil {
+			return err
+		}
+
+		slog.Info("renamed", "folderID", fld.FolderID, "from", entry.info.Name, "to", canonical)
+		if err := fld.client.app.Internals.ScanFolderSubdirs(fld.FolderID, []string{entry.info.Name, canonical}); err != nil {
+			return err
+		}
+		fld.deselectRemovedPath(entry.info.Name)
+		return nil
+	}
+
+	if entry.IsDirectory() {
+		return errors.New("directories can only be moved within a folder when available locally")
+	}
+
+	reader := io.NewSectionReader(newEntryReader(entry), 0, entry.info.Size)
+	if err := dest.writeFileFrom(canonical, reader); err != nil {
+		return err
+	}
+	return entry.Delete()
+}
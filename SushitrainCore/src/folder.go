@@ -38,11 +38,37 @@ type Folder struct {
 	client       *Client
 	FolderID     string
 	cachedIgnore CachedIgnore
+	batch        *ConfigurationBatch // When set, configuration changes are collected in the batch
+}
+
+// Changes the configuration of the folder in place, as it is when the change is applied. Only the fields changed by
+// `block` are affected, so changes made in the meantime (e.g. by Syncthing) are kept. When the folder was obtained
+// from a configuration batch, the change is collected in the batch.
+func (fld *Folder) changeFolderConfiguration(block func(fc *config.FolderConfiguration)) error {
+	change := func(cfg *config.Configuration) {
+		if _, index, ok := cfg.Folder(fld.FolderID); ok {
+			block(&cfg.Folders[index])
+		}
+	}
+	if fld.batch != nil {
+		return fld.batch.change(change)
+	}
+	return fld.client.changeConfiguration(change)
+}
+
+// Returns the folder outside of any configuration batch, for changes that need to take effect right away
+func (fld *Folder) unbatched() *Folder {
+	if fld.batch == nil {
+		return fld
+	}
+	return &Folder{client: fld.client, FolderID: fld.FolderID}
 }
 
 func (fld *Folder) folderConfiguration() *config.FolderConfiguration {
-	folders := fld.client.config.Folders()
-	folderInfo, ok := folders[fld.FolderID]
+	if fld.batch != nil {
+		return fld.batch.folderConfiguration(fld.FolderID)
+	}
+	folderInfo, ok := fld.client.config.Folders()[fld.FolderID]
 	if !ok {
 		return nil
 	}
@@ -75,13 +101,8 @@ func (fld *Folder) RescanIntervalSeconds() int {
 }
 
 func (fld *Folder) SetRescanInterval(seconds int) error {
-	return fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		config := fld.folderConfiguration()
-		if config == nil {
-			return
-		}
-		config.RescanIntervalS = seconds
-		cfg.SetFolder(*config)
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.RescanIntervalS = seconds
 	})
 }
 
@@ -95,20 +116,105 @@ func (fld *Folder) WatcherDelaySeconds() int {
 }
 
 func (fld *Folder) SetWatcherDelaySeconds(seconds int) error {
-	return fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		config := fld.folderConfiguration()
-		if config == nil {
-			return
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.FSWatcherDelayS = float64(seconds)
+	})
+}
+
+// Returns the order in which files are pulled (random, alphabetic, smallestFirst, largestFirst, oldestFirst or
+// newestFirst)
+func (fld *Folder) PullOrder() string {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ""
+	}
+	return fc.Order.String()
+}
+
+func (fld *Folder) SetPullOrder(order string) error {
+	var pullOrder config.PullOrder
+	if err := pullOrder.UnmarshalText([]byte(order)); err != nil {
+		return err
+	}
+	// UnmarshalText falls back to random for unknown values
+	if pullOrder.String() != order {
+		return errors.New("unknown pull order")
+	}
+
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.Order = pullOrder
+	})
+}
+
+// Returns how file contents are copied when Syncthing reuses blocks of existing files (e.g. for renames, versioning and
+// files that changed only partially): "standard" (plain byte copies), "auto" (the fastest method the platform supports)
+// or one of Syncthing's platform-specific methods ("ioctl", "copy_file_range", "sendfile", "duplicate_extents").
+func (fld *Folder) CopyRangeMethod() string {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ""
+	}
+	if fc.CopyRangeMethod == config.CopyRangeMethodAllWithFallback {
+		return "auto"
+	}
+	return fc.CopyRangeMethod.String()
+}
+
+// Sets how file contents are copied (see CopyRangeMethod). With "auto", each accelerated method is tried in turn,
+// falling back to plain copies when none is supported by the file system. Note that Syncthing only implements
+// accelerated methods for Linux and Windows (it has no support for APFS clonefile), so on iOS and macOS "auto" currently
+// makes plain copies, just like "standard". It is still the sensible choice, as it picks up such support once Syncthing
+// gains it.
+func (fld *Folder) SetCopyRangeMethod(method string) error {
+	var copyRangeMethod config.CopyRangeMethod
+	if method == "auto" {
+		copyRangeMethod = config.CopyRangeMethodAllWithFallback
+	} else {
+		// UnmarshalText falls back to standard for unknown values
+		_ = copyRangeMethod.UnmarshalText([]byte(method))
+		if copyRangeMethod.String() != method {
+			return errors.New("unknown copy range method")
 		}
-		config.FSWatcherDelayS = float64(seconds)
-		cfg.SetFolder(*config)
+	}
+
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.CopyRangeMethod = copyRangeMethod
+	})
+}
+
+// Returns the order in which the blocks of a file are pulled (standard, random or inOrder). Syncthing picks block
+// sizes by file size and does not allow tuning them per folder, so this is the setting to use to adapt pulling to flash
+// storage instead.
+func (fld *Folder) BlockPullOrder() string {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ""
+	}
+	return fc.BlockPullOrder.String()
+}
+
+// Sets the order in which blocks are pulled. "inOrder" writes files sequentially, which suits flash storage and lets
+// partially downloaded media be previewed; "standard" spreads requests over peers for faster transfers.
+func (fld *Folder) SetBlockPullOrder(order string) error {
+	var blockPullOrder config.BlockPullOrder
+	if err := blockPullOrder.UnmarshalText([]byte(order)); err != nil {
+		return err
+	}
+	if blockPullOrder.String() != order {
+		return errors.New("unknown block pull order")
+	}
+
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.BlockPullOrder = blockPullOrder
 	})
 }
 
 func (fld *Folder) Unlink() error {
+	// The folder is removed right away, also when obtained from a configuration batch
+	fld = fld.unbatched()
 	fc := fld.folderConfiguration()
 	if fc == nil {
-		return errors.New("folder does not exist")
+		return ErrFolderNotFound
 	}
 	err := fld.client.changeConfiguration(func(cfg *config.Configuration) {
 		folders := make([]config.FolderConfiguration, 0)
@@ -130,23 +236,52 @@ func (fld *Folder) Unlink() error {
 func (fld *Folder) filesystem() (fs.Filesystem, error) {
 	fc := fld.folderConfiguration()
 	if fc == nil {
-		return nil, errors.New("folder does not exist")
+		return nil, ErrFolderNotFound
 	}
 	return fc.Filesystem(), nil
 }
 
-func (fld *Folder) Remove() error {
-	ffs, err := fld.filesystem()
-	if err != nil {
-		return err
+// Stops sharing the folder with all peers and removes it from the configuration. Syncthing then also removes the index
+// for the folder from the database. When `deleteLocalFiles` is set, the local copy of the folder is removed as well.
+func (fld *Folder) Remove(deleteLocalFiles bool) error {
+	// Local files are removed right away, so the folder has to be removed from the configuration as well
+	fld = fld.unbatched()
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ErrFolderNotFound
 	}
+	ffs := fc.Filesystem()
 
-	err = fld.Unlink()
+	// Unshare first, so peers are told (through a cluster config update) that we are not sharing the folder anymore
+	if len(fc.Devices) > 0 {
+		err := fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+			fc.Devices = Filter(fc.Devices, func(fdc config.FolderDeviceConfiguration) bool {
+				return fdc.DeviceID == fld.client.deviceID()
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := fld.Unlink()
 	if err != nil {
 		return err
 	}
+	fld.cachedIgnore.matcher = nil
+
+	if !deleteLocalFiles {
+		return nil
+	}
+
+	// Only delete files on the regular filesystem; custom filesystems are not ours to delete from
+	if fc.FilesystemType != config.FilesystemTypeBasic && fc.FilesystemType != "" {
+		slog.Warn("not deleting local files of folder with custom filesystem", "folderID", fld.FolderID, "fsType", fc.FilesystemType)
+		return nil
+	}
 
 	// Remove local copy
+	slog.Info("removing local copy of folder", "folderID", fld.FolderID, "path", fc.Path)
 	return ffs.RemoveAll("")
 }
 
@@ -164,13 +299,8 @@ func (fld *Folder) IsPaused() bool {
 }
 
 func (fld *Folder) SetPaused(paused bool) error {
-	return fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		config := fld.folderConfiguration()
-		if config == nil {
-			return
-		}
-		config.Paused = paused
-		cfg.SetFolder(*config)
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.Paused = paused
 	})
 }
 
@@ -184,13 +314,8 @@ func (fld *Folder) IsWatcherEnabled() bool {
 }
 
 func (fld *Folder) SetWatcherEnabled(enabled bool) error {
-	return fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		config := fld.folderConfiguration()
-		if config == nil {
-			return
-		}
-		config.FSWatcherEnabled = enabled
-		cfg.SetFolder(*config)
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.FSWatcherEnabled = enabled
 	})
 }
 
@@ -205,13 +330,8 @@ func (fld *Folder) MaxConflicts() int {
 }
 
 func (fld *Folder) SetMaxConflicts(mx int) error {
-	return fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		config := fld.folderConfiguration()
-		if config == nil {
-			return
-		}
-		config.MaxConflicts = mx
-		cfg.SetFolder(*config)
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.MaxConflicts = mx
 	})
 }
 
@@ -227,6 +347,41 @@ func (fld *Folder) State() (string, error) {
 	return state, err
 }
 
+// Returns the reason the folder is in the error state, or an empty string when it is not
+func (fld *Folder) StateError() string {
+	if _, err := fld.State(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// Returns when the folder last changed state, or nil when unknown
+func (fld *Folder) StateChangedAt() *Date {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return nil
+	}
+
+	_, changed, _ := fld.client.app.Internals.FolderState(fld.FolderID)
+	if changed.IsZero() {
+		return nil
+	}
+	return &Date{time: changed}
+}
+
+// Returns whether the folder's root directory exists (it may be missing when e.g. removed outside of the app)
+func (fld *Folder) ExistsOnDisk() bool {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return false
+	}
+
+	stat, err := fc.Filesystem().Lstat(".")
+	if err != nil {
+		return false
+	}
+	return stat.IsDir()
+}
+
 func (fld *Folder) GetFileInformation(path string) (*Entry, error) {
 	if fld.client.app == nil {
 		return nil, nil
@@ -271,7 +426,7 @@ func (fld *Folder) listEntries(prefix string, directories bool, recurse bool) ([
 		levels = -1
 	}
 
-	return fld.client.app.Internals.GlobalTree(fld.FolderID, prefix, levels, directories)
+	return fld.client.globalTree(fld.FolderID, prefix, levels, directories)
 }
 
 func (fld *Folder) List(prefix string, directories bool, recurse bool) (*ListOfStrings, error) {
@@ -290,11 +445,12 @@ func (fld *Folder) ShareWithDevice(deviceID string, toggle bool, encryptionPassw
 		return err
 	}
 
-	err = fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		fc := fld.folderConfiguration()
-		if fc == nil {
-			return
-		}
+	// Store the password first, so that it is removed from the configuration file when the configuration is saved
+	if toggle {
+		fld.client.storeFolderEncryptionPassword(fld.FolderID, devID.String(), encryptionPassword)
+	}
+
+	err = fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
 
 		devices := make([]config.FolderDeviceConfiguration, 0)
 		for _, fc := range fc.Devices {
@@ -310,10 +466,15 @@ func (fld *Folder) ShareWithDevice(deviceID string, toggle bool, encryptionPassw
 				EncryptionPassword: encryptionPassword,
 			})
 		}
-
-		cfg.SetFolder(*fc)
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	if !toggle {
+		fld.client.storeFolderEncryptionPassword(fld.FolderID, devID.String(), "")
+	}
+	return nil
 }
 
 func (fld *Folder) sharedWith() ([]protocol.DeviceID, error) {
@@ -358,6 +519,10 @@ func (fld *Folder) EncryptionPasswordFor(peer string) string {
 		return ""
 	}
 
+	if password := fld.client.keychainFolderEncryptionPassword(fld.FolderID, did.String()); password != "" {
+		return password
+	}
+
 	fc := fld.folderConfiguration()
 	if fc == nil {
 		return ""
@@ -399,12 +564,8 @@ func (fld *Folder) Label() string {
 }
 
 func (fld *Folder) SetLabel(label string) error {
-	return fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		config := fld.folderConfiguration()
-		if config != nil {
-			config.Label = label
-			cfg.SetFolder(*config)
-		}
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.Label = label
 	})
 }
 
@@ -413,6 +574,8 @@ var (
 )
 
 func (fld *Folder) whilePaused(block func() error) error {
+	// Pausing has to take effect right away
+	fld = fld.unbatched()
 	pausedBefore := fld.IsPaused()
 	if !pausedBefore {
 		err := fld.SetPaused(true)
@@ -430,14 +593,25 @@ func (fld *Folder) SetSelective(selective bool) error {
 		return errNoClient
 	}
 
+	if fld.IsPlaceholder() {
+		if err := fld.SetPlaceholder(false); err != nil {
+			return err
+		}
+	}
+
 	return fld.whilePaused(func() error {
+		var err error
 		if selective {
 			fld.cachedIgnore.matcher = nil // Purge our cache
-			return fld.client.app.Internals.SetIgnores(fld.FolderID, []string{"*"})
+			err = fld.client.app.Internals.SetIgnores(fld.FolderID, []string{"*"})
 		} else {
 			fld.cachedIgnore.matcher = nil // Purge our cache
-			return fld.client.app.Internals.SetIgnores(fld.FolderID, []string{})
+			err = fld.client.app.Internals.SetIgnores(fld.FolderID, []string{})
 		}
+		if err != nil {
+			return err
+		}
+		return fld.updateExclusions()
 	})
 }
 
@@ -447,6 +621,9 @@ func (fld *Folder) ClearSelection() error {
 	if err != nil {
 		return err
 	}
+	if err := fld.updateExclusions(); err != nil {
+		return err
+	}
 
 	return fld.CleanSelection()
 }
@@ -454,7 +631,7 @@ func (fld *Folder) ClearSelection() error {
 func (fld *Folder) SelectedPaths(onlyExisting bool) (*ListOfStrings, error) {
 	fc := fld.folderConfiguration()
 	if fc == nil {
-		return nil, errors.New("folder does not exist")
+		return nil, ErrFolderNotFound
 	}
 
 	if fld.client.app == nil || fld.client.app.Internals == nil {
@@ -554,14 +731,53 @@ func (fld *Folder) Path() string {
 	return fc.Path
 }
 
+// Changes the path of the folder. Fails with ErrFolderPathInUse, ErrFolderPathNested or ErrFolderPathInConfigFolder
+// when the path cannot be used.
 func (fld *Folder) SetPath(path string) error {
-	return fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		fc := fld.folderConfiguration()
-		if fc == nil {
-			return
+	if fc := fld.folderConfiguration(); fc != nil && fc.FilesystemType == config.FilesystemTypeBasic {
+		if err := fld.client.checkFolderPathAvailable(path, fld.FolderID); err != nil {
+			return err
 		}
+	}
+
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
 		fc.Path = path
-		cfg.SetFolder(*fc)
+	})
+}
+
+// Returns the name of the file or directory that must exist in the root of the folder for Syncthing to synchronize it
+// (".stfolder" by default)
+func (fld *Folder) MarkerName() string {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ""
+	}
+	return fc.MarkerName
+}
+
+// Sets the name of the marker that must exist in the root of the folder (see MarkerName); an empty name restores the
+// default. Syncthing only creates the default marker, so a custom marker (e.g. a file that is only present when an
+// external drive is attached) must already exist.
+func (fld *Folder) SetMarkerName(name string) error {
+	if name == "" {
+		name = config.DefaultMarkerName
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return errors.New("invalid marker name")
+	}
+
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ErrFolderNotFound
+	}
+	if name != config.DefaultMarkerName {
+		if _, err := fc.Filesystem().Lstat(name); err != nil {
+			return errors.New("the marker does not exist in the folder")
+		}
+	}
+
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.MarkerName = name
 	})
 }
 
@@ -574,22 +790,24 @@ func (fld *Folder) FilesystemType() string {
 }
 
 func (fld *Folder) SetFolderType(folderType string) error {
-	return fld.client.changeConfiguration(func(cfg *config.Configuration) {
-		fc := fld.folderConfiguration()
-		if fc == nil {
-			return
-		}
+	var newType config.FolderType
+	switch folderType {
+	case FolderTypeReceiveOnly:
+		newType = config.FolderTypeReceiveOnly
+	case FolderTypeSendReceive:
+		newType = config.FolderTypeSendReceive
+	default:
+		// Don't change
+		return nil
+	}
 
-		switch folderType {
-		case FolderTypeReceiveOnly:
-			fc.Type = config.FolderTypeReceiveOnly
-		case FolderTypeSendReceive:
-			fc.Type = config.FolderTypeSendReceive
-		default:
-			// Don't change
-			return
-		}
-		cfg.SetFolder(*fc)
+	// Placeholder folders stay receive-only; they get the new type when they stop being a placeholder
+	if fld.IsPlaceholder() {
+		return fld.setPlaceholderPreviousType(newType)
+	}
+
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		fc.Type = newType
 	})
 }
 
@@ -614,7 +832,7 @@ func (fld *Folder) IsSelective() bool {
 func (fld *Folder) LocalNativePath() (string, error) {
 	fc := fld.folderConfiguration()
 	if fc == nil {
-		return "", errors.New("folder does not exist")
+		return "", ErrFolderNotFound
 	}
 
 	// This is a bit of a hack, according to similar code in model.warnAboutOverwritingProtectedFiles :-)
@@ -628,7 +846,7 @@ func (fld *Folder) LocalNativePath() (string, error) {
 func (fld *Folder) loadIgnores() (*ignore.Matcher, error) {
 	cfg := fld.folderConfiguration()
 	if cfg == nil {
-		return nil, errors.New("folder does not exist")
+		return nil, ErrFolderNotFound
 	}
 
 	ffs := cfg.Filesystem()
@@ -671,7 +889,7 @@ func (fld *Folder) extraneousFiles(stopAtOne bool) (*ListOfStrings, error) {
 	cfg := fld.folderConfiguration()
 
 	if cfg == nil {
-		return nil, errors.New("folder does not exist")
+		return nil, ErrFolderNotFound
 	}
 
 	ignores, err := fld.loadIgnores()
@@ -744,7 +962,7 @@ func (fld *Folder) CleanSelection() error {
 
 		cfg := fld.folderConfiguration()
 		if cfg == nil {
-			return errors.New("folder does not exist")
+			return ErrFolderNotFound
 		}
 
 		ignores, err := fld.loadIgnores()
@@ -754,7 +972,7 @@ func (fld *Folder) CleanSelection() error {
 
 		fc := fld.folderConfiguration()
 		if fc == nil {
-			return errors.New("folder does not exist")
+			return ErrFolderNotFound
 		}
 		ffs := fc.Filesystem()
 		return ffs.Walk("", func(path string, info fs.FileInfo, err error) error {
@@ -853,17 +1071,36 @@ func (fld *Folder) RemoveSuperfluousSubdirectories() error {
 	}
 
 	ffs := fld.folderConfiguration().Filesystem()
-	return fld.removeRedundantChildren(ffs, "", true)
+	_, err := fld.removeRedundantChildren(ffs, "", true)
+	return err
 }
 
-func (fld *Folder) removeRedundantChildren(ffs fs.Filesystem, path string, directoriesAndAlwaysIgnoredOnly bool) error {
+// Remove local copies of unselected files below `prefix` (or in the whole folder when empty) that are available in full
+// on at least one other device. Selected files are left alone. As the removed files are ignored, the index is not
+// affected. Returns the number of bytes reclaimed.
+func (fld *Folder) EvictLocalCopies(prefix string) (int64, error) {
+	if !fld.IsSelective() {
+		return 0, errors.New("folder is not selective")
+	}
+
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return 0, ErrFolderNotFound
+	}
+
+	return fld.removeRedundantChildren(fc.Filesystem(), strings.Trim(prefix, "/"), false)
+}
+
+// Returns the total size of the files that were removed
+func (fld *Folder) removeRedundantChildren(ffs fs.Filesystem, path string, directoriesAndAlwaysIgnoredOnly bool) (int64, error) {
 	ignores, err := fld.loadIgnores()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	slog.Info("remove redundant children", "path", path)
 	toDelete := make([]string, 0)
+	sizes := make(map[string]int64)
 
 	err = ffs.Walk(path, func(childPath string, info fs.FileInfo, err error) error {
 		if err != nil {
@@ -872,8 +1109,8 @@ func (fld *Folder) removeRedundantChildren(ffs fs.Filesystem, path string, direc
 
 		slog.Info("remove redundant children", "childPath", path)
 
-		// Leave internal files alone
-		if fs.IsInternal(childPath) || childPath == ignoreFileName {
+		// Leave internal and pinned files alone
+		if fs.IsInternal(childPath) || childPath == ignoreFileName || fld.isPinned(filepath.ToSlash(childPath)) {
 			slog.Info("remove redundant children skip, is internal", "childPath", childPath)
 			return nil
 		}
@@ -883,6 +1120,9 @@ func (fld *Folder) removeRedundantChildren(ffs fs.Filesystem, path string, direc
 		if fld.client.isExtraneousIgnored(filepath.Base(childPath)) {
 			slog.Info("ignoring always ignored extraneous file", "childPath", childPath, "base", filepath.Base(childPath))
 			toDelete = append(toDelete, childPath)
+			if !info.IsDir() {
+				sizes[childPath] = info.Size()
+			}
 			return nil
 		}
 
@@ -911,6 +1151,9 @@ func (fld *Folder) removeRedundantChildren(ffs fs.Filesystem, path string, direc
 
 				if lst.Count() > 0 {
 					toDelete = append(toDelete, childPath)
+					if !info.IsDir() {
+						sizes[childPath] = info.Size()
+					}
 				} else {
 					// File is not available elsewhere, skip it
 				}
@@ -920,7 +1163,7 @@ func (fld *Folder) removeRedundantChildren(ffs fs.Filesystem, path string, direc
 	})
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Scan complete, sort the list of paths to be deleted from long to short so we can delete them in child-first orer
@@ -929,12 +1172,15 @@ func (fld *Folder) removeRedundantChildren(ffs fs.Filesystem, path string, direc
 
 	slog.Info("delete", "toDelete", toDelete)
 
+	var reclaimed int64 = 0
 	for _, delPath := range toDelete {
 		// Swallow delete errors. Parent directories may have been removed before we get to them
-		_ = ffs.Remove(delPath)
+		if ffs.Remove(delPath) == nil {
+			reclaimed += sizes[delPath]
+		}
 		deleteEmptyParentDirectories(ffs, delPath)
 	}
-	return nil
+	return reclaimed, nil
 }
 
 // If `path` points to a file, remove it. If `path` points to a subdirectory, delete children that we are reasonably sure
@@ -949,7 +1195,7 @@ func (fld *Folder) deleteLocalFileAndRedundantChildren(path string) error {
 
 	// Try to recursively remove children that are ignored and available on other peers
 	if stat.IsDir() {
-		err = fld.removeRedundantChildren(ffs, path, false)
+		_, err = fld.removeRedundantChildren(ffs, path, false)
 		if err != nil {
 			return err
 		}
@@ -965,6 +1211,10 @@ func (fld *Folder) deleteLocalFileAndRedundantChildren(path string) error {
 }
 
 func (fld *Folder) deleteAndDeselectLocalFile(path string) error {
+	if fld.isPinned(path) {
+		return errPinned
+	}
+
 	err := fld.deleteLocalFileAndRedundantChildren(path)
 	if err != nil {
 		return err
@@ -1067,6 +1317,22 @@ func (fld *Folder) setExplicitlySelected(paths map[string]bool) error {
 		return nil
 	}
 
+	if fld.IsPlaceholder() {
+		return ErrPlaceholderFolder
+	}
+
+	// Pinned files cannot be deselected, files with excluded extensions cannot be selected
+	for path, selected := range paths {
+		if !selected && fld.isPinned(path) {
+			return errPinned
+		}
+		if selected && fld.isExcludedExtension(path) {
+			if entry, err := fld.GetFileInformation(path); err == nil && entry != nil && !entry.IsDirectory() {
+				return errors.New("files with this extension are excluded in this folder")
+			}
+		}
+	}
+
 	// If we are in the special 'low disk space' mode, allow only deselections
 	if lowDiskSpace {
 		for _, selected := range paths {
@@ -1126,6 +1392,11 @@ func (fld *Folder) setExplicitlySelected(paths map[string]bool) error {
 			}
 		}
 	}
+
+	// Explicitly selected large files are no longer excluded
+	if fld.MaxAutoDownloadSizeMB() > 0 {
+		return fld.updateExclusions()
+	}
 	return nil
 }
 
@@ -1148,18 +1419,17 @@ func (fld *Folder) Statistics() (*FolderStats, error) {
 		return nil, ErrStillLoading
 	}
 
-	internals := fld.client.app.Internals
-	globalSize, err := internals.GlobalSize(fld.FolderID)
+	globalSize, err := fld.client.globalSize(fld.FolderID)
 	if err != nil {
 		return nil, err
 	}
 
-	localSize, err := internals.LocalSize(fld.FolderID)
+	localSize, err := fld.client.localSize(fld.FolderID)
 	if err != nil {
 		return nil, err
 	}
 
-	needSize, err := internals.NeedSize(fld.FolderID, protocol.LocalDeviceID)
+	needSize, err := fld.client.localNeedSize(fld.FolderID)
 	if err != nil {
 		return nil, err
 	}
@@ -1171,6 +1441,56 @@ func (fld *Folder) Statistics() (*FolderStats, error) {
 	}, nil
 }
 
+// Returns the number of files, directories and bytes under `prefix` (or the whole folder when empty) according to the
+// global index. Deleted entries are not counted.
+func (fld *Folder) SizeOfSubtree(prefix string) (*FolderCounts, error) {
+	return fld.sizeOfPaths([]string{prefix})
+}
+
+func (fld *Folder) sizeOfPaths(paths []string) (*FolderCounts, error) {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+
+	prefixes := make([]string, 0, len(paths))
+	for _, path := range paths {
+		path = strings.Trim(path, "/")
+		if path == "" {
+			// The whole folder is included, other paths do not matter anymore
+			prefixes = []string{""}
+			break
+		}
+		prefixes = append(prefixes, path)
+	}
+
+	counts := FolderCounts{}
+	for f, err := range zipError(fld.client.app.Internals.AllGlobalFiles(fld.FolderID)) {
+		if err != nil {
+			return nil, err
+		}
+
+		if f.Deleted {
+			continue
+		}
+
+		// Each file is counted only once, even when it is included by multiple (overlapping) paths
+		for _, prefix := range prefixes {
+			if prefix == "" || f.Name == prefix || strings.HasPrefix(f.Name, prefix+"/") {
+				switch f.Type {
+				case protocol.FileInfoTypeDirectory:
+					counts.Directories += 1
+				default:
+					counts.Files += 1
+					counts.Bytes += f.Size
+				}
+				break
+			}
+		}
+	}
+
+	return &counts, nil
+}
+
 type Completion struct {
 	CompletionPct float64
 	GlobalBytes   int64
@@ -1181,6 +1501,8 @@ type Completion struct {
 	Sequence      int64
 }
 
+// Returns how far the device with the given ID is in synchronizing this folder, based on the latest index and
+// completion information received from it.
 func (fld *Folder) CompletionForDevice(deviceID string) (*Completion, error) {
 	if fld.client.app == nil || fld.client.app.Internals == nil {
 		return nil, ErrStillLoading
@@ -1268,6 +1590,17 @@ func (fld *Folder) FilesNeededBy(peer string) (*ListOfStrings, error) {
 	return List(files), nil
 }
 
+// Returns a URL on the streaming server that serves a zip archive of the subdirectory at `prefix` (or the whole folder
+// when empty). Files do not need to be present locally.
+func (fld *Folder) ZipStreamURL(prefix string) string {
+	server := fld.client.Server
+	if server == nil {
+		return ""
+	}
+
+	return server.zipURLFor(fld.FolderID, prefix)
+}
+
 func (fld *Folder) IsDiskSpaceSufficient() bool {
 	if minFree := fld.folderConfiguration().MinDiskFree; minFree.Value > 0 {
 		fs := fld.folderConfiguration().Filesystem()
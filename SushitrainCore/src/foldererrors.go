@@ -0,0 +1,155 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/syncthing/syncthing/lib/model"
+)
+
+// Kinds of errors that can occur while pulling a file (see FolderError)
+const (
+	FolderErrorKindPermission  = "permission"  // The file or its directory cannot be written
+	FolderErrorKindPathMissing = "pathMissing" // The file, its directory or the folder itself does not exist
+	FolderErrorKindConflict    = "conflict"    // Something else is in the way (e.g. a directory where a file should be)
+	FolderErrorKindUnavailable = "unavailable" // No connected device has the needed version of the file
+	FolderErrorKindOther       = "other"
+)
+
+type FolderError struct {
+	Path  string
+	Error string
+	Kind  string
+}
+
+type FolderErrors struct {
+	data []*FolderError
+}
+
+func (fe *FolderErrors) Count() int {
+	return len(fe.data)
+}
+
+func (fe *FolderErrors) ItemAt(index int) *FolderError {
+	if index < 0 || index >= len(fe.data) {
+		return nil
+	}
+	return fe.data[index]
+}
+
+// Syncthing only reports the errors of a pull when there are any (in the FolderErrors event), so we keep them until a
+// pull finishes without reporting errors
+type folderErrorTracker struct {
+	mutex   sync.Mutex
+	errors  map[string][]model.FileError // Folder ID => errors
+	pulling map[string]bool              // Folder ID => whether a pull is in progress that did not report errors
+}
+
+func newFolderErrorTracker() *folderErrorTracker {
+	return &folderErrorTracker{errors: map[string][]model.FileError{}, pulling: map[string]bool{}}
+}
+
+// Records the errors of a folder, and returns whether they changed
+func (fet *folderErrorTracker) set(folderID string, errors []model.FileError) bool {
+	fet.mutex.Lock()
+	defer fet.mutex.Unlock()
+	delete(fet.pulling, folderID)
+	changed := !slices.Equal(fet.errors[folderID], errors)
+	if len(errors) == 0 {
+		delete(fet.errors, folderID)
+	} else {
+		fet.errors[folderID] = slices.Clone(errors)
+	}
+	return changed
+}
+
+func (fet *folderErrorTracker) pullStarted(folderID string) {
+	fet.mutex.Lock()
+	defer fet.mutex.Unlock()
+	fet.pulling[folderID] = true
+}
+
+// Returns whether a pull was in progress that finished without reporting errors
+func (fet *folderErrorTracker) pullFinished(folderID string) bool {
+	fet.mutex.Lock()
+	defer fet.mutex.Unlock()
+	pulling := fet.pulling[folderID]
+	delete(fet.pulling, folderID)
+	return pulling
+}
+
+func (fet *folderErrorTracker) get(folderID string) []model.FileError {
+	fet.mutex.Lock()
+	defer fet.mutex.Unlock()
+	return fet.errors[folderID]
+}
+
+// Handles the FolderErrors event, which is sent after a pull in which errors occurred
+func (clt *Client) handleFolderErrors(data map[string]interface{}) {
+	folderID, _ := data["folder"].(string)
+	errors, _ := data["errors"].([]model.FileError)
+	clt.updateFolderErrors(folderID, errors)
+}
+
+// Handles a change of the state of a folder. A pull starts in the sync-preparing state and ends in the idle state. The
+// FolderErrors event is sent before the folder becomes idle, so when it was not sent, the pull succeeded.
+func (clt *Client) handleFolderStateForErrors(folderID string, state string) {
+	switch state {
+	case model.FolderSyncPreparing.String():
+		clt.folderErrors.pullStarted(folderID)
+	case model.FolderIdle.String():
+		if clt.folderErrors.pullFinished(folderID) {
+			clt.updateFolderErrors(folderID, nil)
+		}
+	}
+}
+
+func (clt *Client) updateFolderErrors(folderID string, errors []model.FileError) {
+	if !clt.folderErrors.set(folderID, errors) {
+		return
+	}
+	count := len(errors)
+	clt.notifyDelegate(func(delegate ClientDelegate) {
+		delegate.OnFolderErrorsChanged(folderID, count)
+	})
+}
+
+// Classifies the error messages Syncthing reports for files that could not be pulled
+func folderErrorKind(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "permission denied"), strings.Contains(lower, "operation not permitted"),
+		strings.Contains(lower, "read-only file system"):
+		return FolderErrorKindPermission
+	case strings.Contains(lower, "no such file or directory"), strings.Contains(lower, "folder path missing"),
+		strings.Contains(lower, "parent directory"), strings.Contains(lower, "does not exist"):
+		return FolderErrorKindPathMissing
+	case strings.Contains(lower, "no connected device"), strings.Contains(lower, "peers who had this file went away"):
+		return FolderErrorKindUnavailable
+	case strings.Contains(lower, "is a directory"), strings.Contains(lower, "not a directory"),
+		strings.Contains(lower, "directory not empty"), strings.Contains(lower, "file exists"),
+		strings.Contains(lower, "contains changed files"), strings.Contains(lower, "modified but not rescanned"),
+		strings.Contains(lower, "case conflict"):
+		return FolderErrorKindConflict
+	default:
+		return FolderErrorKindOther
+	}
+}
+
+// Returns the files that could not be synchronized during the last pull, and why
+func (fld *Folder) Errors() *FolderErrors {
+	fileErrors := fld.client.folderErrors.get(fld.FolderID)
+	return &FolderErrors{data: Map(fileErrors, func(fe model.FileError) *FolderError {
+		return &FolderError{
+			Path:  fe.Path,
+			Error: fe.Err,
+			Kind:  folderErrorKind(fe.Err),
+		}
+	})}
+}
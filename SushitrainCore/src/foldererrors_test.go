@@ -0,0 +1,67 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/model"
+)
+
+func TestFolderErrorTrackerReportsChangedContents(t *testing.T) {
+	fet := newFolderErrorTracker()
+	first := []model.FileError{{Path: "a", Err: "permission denied"}}
+	second := []model.FileError{{Path: "b", Err: "permission denied"}}
+
+	if !fet.set("folder", first) {
+		t.Error("expected first errors to be a change")
+	}
+	if fet.set("folder", first) {
+		t.Error("expected the same errors not to be a change")
+	}
+	if !fet.set("folder", second) {
+		t.Error("expected different errors with the same count to be a change")
+	}
+	if !fet.set("folder", nil) || len(fet.get("folder")) != 0 {
+		t.Error("expected errors to be cleared")
+	}
+}
+
+func TestFolderErrorTrackerPulls(t *testing.T) {
+	fet := newFolderErrorTracker()
+	if fet.pullFinished("folder") {
+		t.Error("expected no pull to be in progress")
+	}
+
+	// A pull that did not report errors
+	fet.pullStarted("folder")
+	if !fet.pullFinished("folder") {
+		t.Error("expected a pull without errors to have finished")
+	}
+
+	// A pull that reported errors (Syncthing sends these before the folder becomes idle)
+	fet.pullStarted("folder")
+	fet.set("folder", []model.FileError{{Path: "a", Err: "permission denied"}})
+	if fet.pullFinished("folder") {
+		t.Error("expected a pull with errors not to clear them")
+	}
+	if len(fet.get("folder")) != 1 {
+		t.Error("expected errors to be kept")
+	}
+}
+
+func TestFolderErrorKind(t *testing.T) {
+	for message, kind := range map[string]string{
+		"open /a/b: permission denied":                              FolderErrorKindPermission,
+		"no connected device has the required version of this file": FolderErrorKindUnavailable,
+		"parent directory is missing":                               FolderErrorKindPathMissing,
+		"something unexpected":                                      FolderErrorKindOther,
+	} {
+		if got := folderErrorKind(message); got != kind {
+			t.Errorf("expected %q to be of kind %q, got %q", message, kind, got)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Drops everything the database knows about this folder (our own index as well as those of peers). The folder is
+// paused while doing so; when it resumes, the local files are scanned again and peers send their complete index anew.
+// Files themselves are not touched. This helps when the index of a folder has become inconsistent.
+func (fld *Folder) ResetIndex() error {
+	client := fld.client
+	if client.app == nil || client.app.Internals == nil || client.sdb == nil {
+		return ErrStillLoading
+	}
+	if !fld.Exists() {
+		return ErrFolderNotFound
+	}
+
+	return fld.whilePaused(func() error {
+		slog.Warn("resetting folder index", "folderID", fld.FolderID)
+		if err := client.sdb.DropFolder(fld.FolderID); err != nil {
+			return err
+		}
+		client.queryCache.invalidate(fld.FolderID)
+		return nil
+	})
+}
+
+// Scans the folder and then announces the local state as the newest version of every file that differs from the
+// global state, so that peers adopt our files (and delete files we do not have). This is what Syncthing calls
+// 'override changes' for send-only folders, here also allowed for send-receive folders to break out of loops in which
+// a folder never becomes in sync.
+func (fld *Folder) ForceRescanAndOverride() error {
+	client := fld.client
+	if client.app == nil || client.app.Internals == nil || client.sdb == nil {
+		return ErrStillLoading
+	}
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ErrFolderNotFound
+	}
+	if fc.Type != config.FolderTypeSendReceive && fc.Type != config.FolderTypeSendOnly {
+		return errors.New("only folders that send changes can override")
+	}
+
+	if err := client.app.Internals.ScanFolderSubdirs(fld.FolderID, nil); err != nil {
+		return err
+	}
+
+	// Pause so the folder does not start pulling the files we are about to override
+	return fld.whilePaused(func() error {
+		shortID := client.deviceID().Short()
+		overridden := make([]protocol.FileInfo, 0)
+		for page := 1; ; page++ {
+			progress, queued, rest, err := client.app.Internals.NeedFolderFiles(fld.FolderID, page, 512)
+			if err != nil {
+				return err
+			}
+			batch := append(append(progress, queued...), rest...)
+			if len(batch) == 0 {
+				break
+			}
+
+			for _, global := range batch {
+				local, ok, err := client.sdb.GetDeviceFile(fld.FolderID, protocol.LocalDeviceID, global.Name)
+				if err != nil {
+					return err
+				}
+				if ok && local.IsInvalid() {
+					continue
+				}
+				if !ok {
+					// We never had the file, so it should go away on peers too
+					global.SetDeleted(shortID)
+					overridden = append(overridden, global)
+					continue
+				}
+				local.Version = local.Version.Merge(global.Version).Update(shortID)
+				overridden = append(overridden, local)
+			}
+		}
+
+		if len(overridden) == 0 {
+			return nil
+		}
+		if err := fld.updateLocalIndex(overridden, protocol.LocalAllFlags); err != nil {
+			return err
+		}
+		slog.Info("overrode global state with local state", "folderID", fld.FolderID, "count", len(overridden))
+		return nil
+	})
+}
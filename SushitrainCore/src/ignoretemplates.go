@@ -0,0 +1,76 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"slices"
+)
+
+// Sets of ignore patterns for commonly unwanted files. The (?d) prefix allows Syncthing to delete these files when they
+// are in the way of deleting a directory.
+var ignoreTemplates = map[string][]string{
+	"macos-junk": {
+		"(?d).DS_Store",
+		"(?d)._*",
+		"(?d).Spotlight-V100",
+		"(?d).Trashes",
+		"(?d).fseventsd",
+		"(?d).TemporaryItems",
+		"(?d).AppleDouble",
+	},
+	"windows-junk": {
+		"(?d)Thumbs.db",
+		"(?d)ehthumbs.db",
+		"(?d)desktop.ini",
+		"(?d)$RECYCLE.BIN",
+		"(?d)System Volume Information",
+	},
+	"node-modules": {
+		"(?d)node_modules",
+	},
+	"photo-sidecars": {
+		"(?d)(?i)*.aae",
+		"(?d)(?i)*.xmp",
+		"(?d)(?i)*.thm",
+	},
+}
+
+// Returns the names of the templates that can be used with Folder.ApplyIgnoreTemplate
+func (clt *Client) AvailableIgnoreTemplates() *ListOfStrings {
+	names := KeysOf(ignoreTemplates)
+	slices.Sort(names)
+	return List(names)
+}
+
+// Adds the patterns from the named template to the folder's ignore patterns. Patterns that are already present are not
+// added again. The patterns are placed before existing patterns so that they take precedence.
+func (fld *Folder) ApplyIgnoreTemplate(name string) error {
+	template, ok := ignoreTemplates[name]
+	if !ok {
+		return errors.New("unknown ignore template")
+	}
+
+	if fld.IsSelective() {
+		// Everything not explicitly selected is already ignored, and adding patterns would break the selection
+		return errors.New("ignore templates cannot be applied to selective folders")
+	}
+
+	ignores, err := fld.loadIgnores()
+	if err != nil {
+		return err
+	}
+
+	existing := ignores.Lines()
+	added := Filter(template, func(line string) bool {
+		return !slices.Contains(existing, line)
+	})
+	if len(added) == 0 {
+		return nil
+	}
+
+	return fld.SetIgnoreLines(List(append(added, existing...)))
+}
@@ -0,0 +1,263 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// What to do when a file being imported already exists in the folder (see Client.ImportFiles)
+const (
+	ImportCollisionRename    = "rename"    // Import under a new name, e.g. "photo (1).jpg"
+	ImportCollisionOverwrite = "overwrite" // Replace the existing file
+	ImportCollisionSkip      = "skip"      // Leave the existing file alone and do not import
+)
+
+// What happened to a single file being imported
+const (
+	ImportStatusImported    = "imported"
+	ImportStatusRenamed     = "renamed"
+	ImportStatusOverwritten = "overwritten"
+	ImportStatusSkipped     = "skipped"
+	ImportStatusFailed      = "failed"
+)
+
+// The maximum number of alternative names tried for a file when renaming on collision
+const maxImportRenameAttempts = 1000
+
+type ImportResult struct {
+	SourcePath string
+	Path       string // Path of the file in the folder, or the path it would have had when skipped or failed
+	Status     string
+	Error      string
+}
+
+type ImportResults struct {
+	data []*ImportResult
+}
+
+func (ir *ImportResults) Count() int {
+	return len(ir.data)
+}
+
+func (ir *ImportResults) ItemAt(index int) *ImportResult {
+	if index < 0 || index >= len(ir.data) {
+		return nil
+	}
+	return ir.data[index]
+}
+
+// Returns the number of results with the given status (one of the ImportStatus... constants)
+func (ir *ImportResults) CountWithStatus(status string) int {
+	count := 0
+	for _, result := range ir.data {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Copies files handed to the app (e.g. from the share sheet) into the subdirectory of a folder. When a file with the same
+// name already exists in the folder (locally or on other devices), the collision policy (one of the ImportCollision...
+// constants) determines what happens. In selective folders, the imported files are selected. All imported files are
+// scanned at once afterwards. Failures for individual files are reported in the results rather than as an error. When
+// the imported files could not be scanned, the results are returned together with the error.
+func (clt *Client) ImportFiles(folderID string, subpath string, sourcePaths *ListOfStrings, collisionPolicy string) (*ImportResults, error) {
+	if clt.app == nil || clt.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+	switch collisionPolicy {
+	case ImportCollisionRename, ImportCollisionOverwrite, ImportCollisionSkip:
+	default:
+		return nil, errors.New("invalid collision policy")
+	}
+	if sourcePaths == nil {
+		return &ImportResults{data: []*ImportResult{}}, nil
+	}
+
+	fld := clt.FolderWithID(folderID)
+	if fld == nil {
+		return nil, ErrFolderNotFound
+	}
+	directory := ""
+	if strings.Trim(subpath, "/") != "" {
+		canonical, err := fld.canonicalWritablePath(subpath)
+		if err != nil {
+			return nil, err
+		}
+		directory = canonical
+	}
+
+	results := make([]*ImportResult, 0, len(sourcePaths.data))
+	written := make([]string, 0, len(sourcePaths.data))
+	for _, sourcePath := range sourcePaths.data {
+		result := fld.importFile(sourcePath, directory, collisionPolicy)
+		if result.Status == ImportStatusFailed {
+			slog.Warn("could not import file", "folderID", folderID, "source", sourcePath, "cause", result.Error)
+		} else if result.Status != ImportStatusSkipped {
+			written = append(written, result.Path)
+		}
+		results = append(results, result)
+	}
+
+	if len(written) > 0 {
+		slog.Info("imported files", "folderID", folderID, "count", len(written))
+		if err := clt.app.Internals.ScanFolderSubdirs(folderID, written); err != nil {
+			return &ImportResults{data: results}, err
+		}
+	}
+	return &ImportResults{data: results}, nil
+}
+
+func (fld *Folder) importFile(sourcePath string, directory string, collisionPolicy string) *ImportResult {
+	result := &ImportResult{SourcePath: sourcePath, Path: path.Join(directory, filepath.Base(sourcePath))}
+	fail := func(err error) *ImportResult {
+		result.Status = ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	canonical, err := fld.canonicalWritablePath(result.Path)
+	if err != nil {
+		return fail(err)
+	}
+	result.Path = canonical
+
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return fail(err)
+	}
+	defer source.Close()
+	if info, err := source.Stat(); err != nil {
+		return fail(err)
+	} else if !info.Mode().IsRegular() {
+		return fail(errors.New("only regular files can be imported"))
+	}
+
+	result.Status = ImportStatusImported
+	adopt := false
+	existing, exists, err := fld.existingPath(canonical)
+	if err != nil {
+		return fail(err)
+	}
+	if exists {
+		switch collisionPolicy {
+		case ImportCollisionSkip:
+			result.Status = ImportStatusSkipped
+			return result
+		case ImportCollisionOverwrite:
+			// Replace the existing file under its own name, which may differ in case
+			result.Path = existing
+			info, err := fld.folderConfiguration().Filesystem().Lstat(osutil.NativeFilename(existing))
+			if err == nil && !info.IsRegular() {
+				return fail(errors.New("a directory exists at the path"))
+			}
+			adopt = err != nil
+			result.Status = ImportStatusOverwritten
+		case ImportCollisionRename:
+			alternative, err := fld.availableImportPath(canonical)
+			if err != nil {
+				return fail(err)
+			}
+			result.Path = alternative
+			result.Status = ImportStatusRenamed
+		}
+	}
+
+	if err := fld.writeFileContents(result.Path, source); err != nil {
+		return fail(err)
+	}
+
+	// Only adopt the global version once the file is there, as otherwise our index would list a file that is missing
+	// locally, which the next scan would announce to other devices as deleted
+	if adopt {
+		if err := fld.adoptGlobalVersion(result.Path); err != nil {
+			return fail(err)
+		}
+	}
+	return result
+}
+
+// Returns the path of the file that exists at the path, either locally or on other devices. On a case-insensitive
+// filesystem this may be a file whose name differs only in case, as both cannot exist next to each other.
+func (fld *Folder) existingPath(canonical string) (string, bool, error) {
+	fc := fld.folderConfiguration()
+	if _, err := fc.Filesystem().Lstat(osutil.NativeFilename(canonical)); err == nil {
+		return canonical, true, nil
+	}
+	info, ok, err := fld.client.app.Internals.GlobalFileInfo(fld.FolderID, canonical)
+	if err != nil {
+		return "", false, err
+	}
+	if ok && !info.IsDeleted() {
+		return canonical, true, nil
+	}
+	if fc.CaseSensitiveFS {
+		return "", false, nil
+	}
+
+	directory := path.Dir(canonical)
+	if directory == "." {
+		directory = ""
+	}
+	siblings, err := fld.client.app.Internals.GlobalTree(fld.FolderID, directory, 0, false)
+	if err != nil {
+		return "", false, err
+	}
+	folded := fs.UnicodeLowercaseNormalized(path.Base(canonical))
+	for _, sibling := range siblings {
+		if fs.UnicodeLowercaseNormalized(sibling.Name) == folded {
+			return path.Join(directory, sibling.Name), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Records the global version of a file that was not available locally in our index, as if we had it, so that the file
+// just written at the path replaces it on other devices. Otherwise the scanner would consider the new file unaware of
+// the global version, which causes a conflict copy.
+func (fld *Folder) adoptGlobalVersion(canonical string) error {
+	global, ok, err := fld.client.app.Internals.GlobalFileInfo(fld.FolderID, canonical)
+	if err != nil || !ok || global.IsDeleted() {
+		return err
+	}
+	local, hasLocal, err := fld.client.sdb.GetDeviceFile(fld.FolderID, protocol.LocalDeviceID, canonical)
+	if err != nil {
+		return err
+	}
+	if hasLocal && !local.IsInvalid() && local.Version.Equal(global.Version) {
+		return nil
+	}
+	return fld.updateLocalIndex([]protocol.FileInfo{global}, protocol.LocalAllFlags)
+}
+
+// Finds a name for the file that is not in use yet, by appending a number (e.g. "photo (1).jpg")
+func (fld *Folder) availableImportPath(canonical string) (string, error) {
+	extension := path.Ext(canonical)
+	base := strings.TrimSuffix(canonical, extension)
+	for attempt := 1; attempt <= maxImportRenameAttempts; attempt++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, attempt, extension)
+		_, exists, err := fld.existingPath(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("could not find an unused name for the file")
+}
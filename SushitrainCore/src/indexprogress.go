@@ -0,0 +1,140 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// A peer sends its index in batches in quick succession. When no batch arrived for this long, the exchange has either
+// finished or stalled.
+const indexExchangeQuietPeriod = 10 * time.Second
+
+// Progress of receiving the index (list of files) of a folder from a peer, which may take a long time for large folders
+// after pairing
+type IndexExchangeProgress struct {
+	// Highest sequence number of the peer's index we have received
+	ReceivedSequence int64
+
+	// Number of file records received from the peer since it last connected
+	ReceivedItems int64
+
+	// Whether index updates are currently coming in
+	IsReceiving bool
+
+	// Whether index updates came in since the peer connected, but none arrived for a while. Syncthing does not expose
+	// the size of the peer's index, so this means the peer either sent all of it, or stopped sending halfway.
+	IsStalled bool
+
+	LastReceivedAt *Date
+
+	// Fraction of the index received. The total size of the peer's index is not exposed by Syncthing, so this is -1
+	// once updates came in since the peer connected (see IsReceiving and IsStalled). It is 1 when the peer connected
+	// without sending anything we did not have yet, and 0 when we never received anything from the peer.
+	Fraction float64
+}
+
+type indexExchangeRecord struct {
+	sequence       int64
+	items          int64
+	lastReceivedAt time.Time
+}
+
+// Keeps track of incoming index updates per device and folder, based on RemoteIndexUpdated events
+type indexExchangeTracker struct {
+	mutex   sync.Mutex
+	records map[string]*indexExchangeRecord // deviceID/folderID
+}
+
+func newIndexExchangeTracker() *indexExchangeTracker {
+	return &indexExchangeTracker{
+		records: map[string]*indexExchangeRecord{},
+	}
+}
+
+func indexExchangeKey(deviceID string, folderID string) string {
+	return deviceID + "/" + folderID
+}
+
+func (it *indexExchangeTracker) received(deviceID string, folderID string, items int64, sequence int64) {
+	it.mutex.Lock()
+	defer it.mutex.Unlock()
+
+	key := indexExchangeKey(deviceID, folderID)
+	rec, ok := it.records[key]
+	if !ok {
+		rec = &indexExchangeRecord{}
+		it.records[key] = rec
+	}
+	rec.items += items
+	rec.sequence = max(rec.sequence, sequence)
+	rec.lastReceivedAt = time.Now()
+}
+
+// Forgets the items counted for a device, called when it (re)connects and starts sending its index again
+func (it *indexExchangeTracker) reset(deviceID string) {
+	it.mutex.Lock()
+	defer it.mutex.Unlock()
+
+	prefix := deviceID + "/"
+	for key := range it.records {
+		if strings.HasPrefix(key, prefix) {
+			delete(it.records, key)
+		}
+	}
+}
+
+func (it *indexExchangeTracker) handleRemoteIndexUpdated(data map[string]any) {
+	deviceID, _ := data["device"].(string)
+	folderID, _ := data["folder"].(string)
+	items, _ := data["items"].(int)
+	sequence, _ := data["sequence"].(int64)
+	if deviceID == "" || folderID == "" {
+		return
+	}
+	it.received(deviceID, folderID, int64(items), sequence)
+}
+
+// Returns the progress of receiving the index of the folder from this peer
+func (peer *Peer) IndexCompletionForFolder(folderID string) (*IndexExchangeProgress, error) {
+	sdb := peer.client.sdb
+	if sdb == nil {
+		return nil, ErrStillLoading
+	}
+	if peer.client.FolderWithID(folderID) == nil {
+		return nil, ErrFolderNotFound
+	}
+
+	sequence, err := sdb.GetDeviceSequence(folderID, peer.deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &IndexExchangeProgress{
+		ReceivedSequence: sequence,
+		Fraction:         0.0,
+	}
+
+	tracker := peer.client.indexExchange
+	tracker.mutex.Lock()
+	rec, ok := tracker.records[indexExchangeKey(peer.deviceID.String(), folderID)]
+	if ok {
+		progress.ReceivedItems = rec.items
+		progress.LastReceivedAt = &Date{time: rec.lastReceivedAt}
+		progress.IsReceiving = time.Since(rec.lastReceivedAt) < indexExchangeQuietPeriod
+		progress.IsStalled = !progress.IsReceiving
+	}
+	tracker.mutex.Unlock()
+
+	if ok {
+		progress.Fraction = -1.0
+	} else if sequence > 0 {
+		progress.Fraction = 1.0
+	}
+	return progress, nil
+}
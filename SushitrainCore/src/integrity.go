@@ -0,0 +1,112 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Returns the SHA-256 hashes of the blocks of the file (base64-encoded, in order) as recorded in the index
+func (entry *Entry) BlockHashesBase64() *ListOfStrings {
+	return List(Map(entry.info.Blocks, func(block protocol.BlockInfo) string {
+		return base64.StdEncoding.EncodeToString(block.Hash)
+	}))
+}
+
+// Returns the SHA-256 hash (hex-encoded) of the contents of the file. The contents are read block by block from the
+// local copy when available and from peers otherwise, so this can take a while for large files. Each block is checked
+// against the index on the way.
+func (entry *Entry) SHA256OfFile() (string, error) {
+	client := entry.Folder.client
+	if client.app == nil || client.app.Internals == nil {
+		return "", ErrStillLoading
+	}
+	if entry.IsDirectory() || entry.IsSymlink() {
+		return "", errors.New("entry is not a file")
+	}
+	if err := entry.checkHashable(); err != nil {
+		return "", err
+	}
+
+	reader := newEntryReader(entry)
+	reader.ctx = client.ctx
+	hasher := sha256.New()
+	buffer := make([]byte, entry.info.BlockSize())
+	for _, block := range entry.info.Blocks {
+		data := buffer[:block.Size]
+		if _, err := reader.ReadAt(data, block.Offset); err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		blockHash := sha256.Sum256(data)
+		if !bytes.Equal(blockHash[:], block.Hash) {
+			return "", errors.New("file contents do not match the index")
+		}
+		hasher.Write(data)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Returns whether the local copy of the file has exactly the contents recorded in the index (by rehashing it). Returns
+// false when there is no local copy.
+func (entry *Entry) InfoHashMatchesLocal() (bool, error) {
+	if entry.IsDirectory() || entry.IsSymlink() {
+		return false, errors.New("entry is not a file")
+	}
+	if err := entry.checkHashable(); err != nil {
+		return false, err
+	}
+
+	fc := entry.Folder.folderConfiguration()
+	if fc == nil {
+		return false, errors.New("invalid folder")
+	}
+	ffs := fc.Filesystem()
+	nativePath := osutil.NativeFilename(entry.info.Name)
+	stat, err := ffs.Lstat(nativePath)
+	if err != nil || !stat.IsRegular() {
+		return false, nil
+	}
+	if stat.Size() != entry.info.Size {
+		return false, nil
+	}
+
+	file, err := ffs.Open(nativePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, entry.info.BlockSize())
+	for _, block := range entry.info.Blocks {
+		n, err := file.ReadAt(buffer[:block.Size], block.Offset)
+		if err != nil && !(errors.Is(err, io.EOF) && n == block.Size) {
+			return false, err
+		}
+		hash := sha256.Sum256(buffer[:block.Size])
+		if !bytes.Equal(hash[:], block.Hash) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Block hashes in receive-encrypted folders are of the encrypted data, which we cannot check
+func (entry *Entry) checkHashable() error {
+	fc := entry.Folder.folderConfiguration()
+	if fc != nil && fc.Type == config.FolderTypeReceiveEncrypted {
+		return errors.New("files in receive-encrypted folders cannot be hashed")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Invite links have the following forms:
+//
+//	syncthing://device/<device ID>?name=<name>&address=<address>&address=...
+//	syncthing://folder/<folder ID>?label=<label>&device=<device ID>&name=<name>&address=<address>&address=...
+//
+// Addresses may also be given as a single comma-separated `addresses` parameter. A bare device ID is accepted as well.
+const inviteURLScheme = "syncthing"
+
+// The contents of a device or folder invite link
+type InviteURL struct {
+	DeviceID    string
+	DeviceName  string
+	FolderID    string // Empty for device invites
+	FolderLabel string
+	addresses   []string
+}
+
+func (invite *InviteURL) IsFolderInvite() bool {
+	return invite.FolderID != ""
+}
+
+// Addresses at which the device can be reached (e.g. "tcp://192.168.1.2:22000")
+func (invite *InviteURL) Addresses() *ListOfStrings {
+	return List(invite.addresses)
+}
+
+// Returns the invite as a syncthing:// URL
+func (invite *InviteURL) URL() string {
+	params := url.Values{}
+	u := url.URL{Scheme: inviteURLScheme}
+	if invite.IsFolderInvite() {
+		u.Host = "folder"
+		u.Path = "/" + invite.FolderID
+		if invite.FolderLabel != "" {
+			params.Set("label", invite.FolderLabel)
+		}
+		params.Set("device", invite.DeviceID)
+	} else {
+		u.Host = "device"
+		u.Path = "/" + invite.DeviceID
+	}
+
+	if invite.DeviceName != "" {
+		params.Set("name", invite.DeviceName)
+	}
+	for _, address := range invite.addresses {
+		params.Add("address", address)
+	}
+	u.RawQuery = params.Encode()
+	return u.String()
+}
+
+// Parses a device or folder invite link (or a bare device ID)
+func (clt *Client) ParseSyncthingURL(inviteURL string) (*InviteURL, error) {
+	inviteURL = strings.TrimSpace(inviteURL)
+
+	if devID, err := protocol.DeviceIDFromString(inviteURL); err == nil {
+		return &InviteURL{DeviceID: devID.String()}, nil
+	}
+
+	u, err := url.Parse(inviteURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != inviteURLScheme {
+		return nil, errors.New("not a syncthing:// URL")
+	}
+
+	params := u.Query()
+	invite := &InviteURL{
+		DeviceName: params.Get("name"),
+	}
+
+	invite.addresses = append(invite.addresses, params["address"]...)
+	for _, list := range params["addresses"] {
+		for _, address := range strings.Split(list, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				invite.addresses = append(invite.addresses, address)
+			}
+		}
+	}
+
+	id := strings.Trim(u.Path, "/")
+	deviceID := ""
+	switch u.Host {
+	case "device":
+		deviceID = id
+	case "folder":
+		if id == "" {
+			return nil, errors.New("folder invite does not contain a folder ID")
+		}
+		invite.FolderID = id
+		invite.FolderLabel = params.Get("label")
+		deviceID = params.Get("device")
+	default:
+		return nil, errors.New("unsupported syncthing:// URL")
+	}
+
+	devID, err := protocol.DeviceIDFromString(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	invite.DeviceID = devID.String()
+	return invite, nil
+}
+
+// Returns an invite link for this device
+func (clt *Client) DeviceInviteURL() (string, error) {
+	invite, err := clt.ownInvite()
+	if err != nil {
+		return "", err
+	}
+	return invite.URL(), nil
+}
+
+// Returns an invite link for this folder, which other devices can use to add this device and the folder
+func (fld *Folder) InviteURL() (string, error) {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return "", ErrFolderNotFound
+	}
+
+	invite, err := fld.client.ownInvite()
+	if err != nil {
+		return "", err
+	}
+	invite.FolderID = fc.ID
+	invite.FolderLabel = fc.Label
+	return invite.URL(), nil
+}
+
+// Returns an invite link for this peer, e.g. for introducing it to another device manually
+func (peer *Peer) InviteURL() string {
+	invite := &InviteURL{DeviceID: peer.deviceID.String()}
+	if dc := peer.deviceConfiguration(); dc != nil {
+		invite.DeviceName = dc.Name
+		invite.addresses = staticAddresses(dc.Addresses)
+	}
+	return invite.URL()
+}
+
+func (clt *Client) ownInvite() (*InviteURL, error) {
+	if clt.config == nil {
+		return nil, ErrStillLoading
+	}
+
+	selfConfig, ok := clt.config.Devices()[clt.deviceID()]
+	if !ok {
+		return nil, errors.New("cannot find myself")
+	}
+
+	return &InviteURL{
+		DeviceID:   clt.DeviceID(),
+		DeviceName: selfConfig.Name,
+		addresses:  staticAddresses(selfConfig.Addresses),
+	}, nil
+}
+
+// The 'dynamic' address only means something to the device that has it configured
+func staticAddresses(addresses []string) []string {
+	return slices.DeleteFunc(slices.Clone(addresses), func(address string) bool {
+		return address == "dynamic"
+	})
+}
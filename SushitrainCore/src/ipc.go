@@ -0,0 +1,344 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+// The app and its extensions run in separate processes. Instead of starting a second Syncthing instance, an extension
+// can ask the running app for information through a unix socket in the shared (app group) container. The protocol is
+// plain HTTP with JSON responses.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	ipcRequestTimeout = 10 * time.Second
+
+	// Directory next to the socket that files are downloaded into for other processes (see IPCClient.Download)
+	ipcDownloadsDirName = "Downloads"
+)
+
+type ipcFolderJSON struct {
+	ID       string `json:"id"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	IsPaused bool   `json:"isPaused"`
+}
+
+// Starts answering requests from other processes on a unix socket at `socketPath`. Note that the path of a unix socket
+// may not be longer than 104 bytes on Darwin. Files are only downloaded for other processes into the directory returned
+// by IPCClient.DownloadsDirectory, next to the socket.
+func (clt *Client) StartIPCServer(socketPath string) error {
+	if clt.app == nil || clt.app.Internals == nil {
+		return ErrStillLoading
+	}
+	clt.StopIPCServer()
+
+	// A socket file left behind by a process that exited without cleaning up prevents listening
+	if _, err := os.Stat(socketPath); err == nil {
+		if conn, err := net.DialTimeout("unix", socketPath, time.Second); err == nil {
+			conn.Close()
+			return ErrAlreadyRunning
+		}
+		os.Remove(socketPath)
+	}
+
+	downloadsDir := ipcDownloadsDirectory(socketPath)
+	if err := os.MkdirAll(downloadsDir, 0o700); err != nil {
+		return err
+	}
+	downloadsDir, err := filepath.EvalSymlinks(downloadsDir)
+	if err != nil {
+		return err
+	}
+
+	// Create the socket accessible to our own user only, so no other process can connect before its mode is changed
+	previousUmask := syscall.Umask(0o177)
+	listener, err := net.Listen("unix", socketPath)
+	syscall.Umask(previousUmask)
+	if err != nil {
+		return err
+	}
+
+	clt.mutex.Lock()
+	clt.ipcListener = listener
+	clt.mutex.Unlock()
+
+	go http.Serve(listener, clt.ipcHandler(downloadsDir))
+	slog.Info("IPC service listening", "path", socketPath)
+	return nil
+}
+
+// Stops answering requests from other processes and removes the socket
+func (clt *Client) StopIPCServer() {
+	clt.mutex.Lock()
+	listener := clt.ipcListener
+	clt.ipcListener = nil
+	clt.mutex.Unlock()
+
+	if listener != nil {
+		// Closing a unix listener also removes the socket file
+		listener.Close()
+	}
+}
+
+func (clt *Client) ipcHandler(downloadsDir string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /folders", func(w http.ResponseWriter, r *http.Request) {
+		result := make([]ipcFolderJSON, 0)
+		for _, fc := range clt.config.FolderList() {
+			result = append(result, ipcFolderJSON{
+				ID:       fc.ID,
+				Label:    fc.Label,
+				Type:     fc.Type.String(),
+				IsPaused: fc.Paused,
+			})
+		}
+		writeIPCJSON(w, result)
+	})
+
+	// The handlers below need the Syncthing instance, which is not available while the database cannot be opened
+	mux.HandleFunc("GET /entries", clt.ipcWhileRunning(func(w http.ResponseWriter, r *http.Request) {
+		fld := clt.FolderWithID(r.URL.Query().Get("folder"))
+		if fld == nil {
+			http.Error(w, "folder does not exist", http.StatusNotFound)
+			return
+		}
+		js, err := fld.ListEntriesJSON(r.URL.Query().Get("prefix"), r.URL.Query().Get("directories") == "1")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(js)
+	}))
+
+	mux.HandleFunc("GET /entry", clt.ipcWhileRunning(func(w http.ResponseWriter, r *http.Request) {
+		entry, err := clt.ipcEntry(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		info := entry.info
+		writeIPCJSON(w, newEntryJSON(entry.Folder.FolderID, info.Name, info.Size, info.Type, info.Deleted, info.ModTime()))
+	}))
+
+	// Downloads the file to the path in the `to` parameter, which must be in the downloads directory. The response is
+	// sent when the download has finished.
+	mux.HandleFunc("POST /download", clt.ipcWhileRunning(func(w http.ResponseWriter, r *http.Request) {
+		entry, err := clt.ipcEntry(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		toPath := r.URL.Query().Get("to")
+		if toPath == "" {
+			http.Error(w, "no destination path given", http.StatusBadRequest)
+			return
+		}
+
+		toPath, err = ipcDownloadPath(downloadsDir, toPath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if err := clt.ipcDownload(r.Context(), entry, toPath); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	return mux
+}
+
+// Wraps a handler so that it is answered with an error while Syncthing is not running
+func (clt *Client) ipcWhileRunning(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clt.internals() == nil {
+			http.Error(w, ErrStillLoading.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (clt *Client) ipcEntry(r *http.Request) (*Entry, error) {
+	fld := clt.FolderWithID(r.URL.Query().Get("folder"))
+	if fld == nil {
+		return nil, ErrFolderNotFound
+	}
+	entry, err := fld.GetFileInformation(r.URL.Query().Get("path"))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, errors.New("entry does not exist")
+	}
+	return entry, nil
+}
+
+func ipcDownloadsDirectory(socketPath string) string {
+	return filepath.Join(filepath.Dir(socketPath), ipcDownloadsDirName)
+}
+
+// Checks that the path is inside the downloads directory (`downloadsDir`, with symbolic links resolved), also after
+// resolving symbolic links in its parent directories. Returns the resolved path.
+func ipcDownloadPath(downloadsDir string, toPath string) (string, error) {
+	toPath = filepath.Clean(toPath)
+	if !filepath.IsAbs(toPath) {
+		return "", errors.New("destination path must be absolute")
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(toPath))
+	if err != nil {
+		return "", err
+	}
+	if parent != downloadsDir && !strings.HasPrefix(parent, downloadsDir+string(filepath.Separator)) {
+		return "", errors.New("destination path is not in the downloads directory")
+	}
+	return filepath.Join(parent, filepath.Base(toPath)), nil
+}
+
+func (clt *Client) ipcDownload(ctx context.Context, entry *Entry, toPath string) error {
+	if entry.IsDirectory() {
+		return errors.New("cannot download a directory")
+	}
+	m := clt.internals()
+	if m == nil {
+		return ErrStillLoading
+	}
+
+	// Never follow a symbolic link at the destination itself out of the downloads directory
+	outFile, err := os.OpenFile(toPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, 0o600)
+	if err != nil {
+		return err
+	}
+
+	mp := newMiniPuller(clt.Measurements, m)
+	err = mp.downloadInto(ctx, outFile, entry.Folder.FolderID, entry.info)
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(toPath)
+	}
+	return err
+}
+
+func writeIPCJSON(w http.ResponseWriter, value any) {
+	js, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// Talks to a client running in another process (see Client.StartIPCServer)
+type IPCClient struct {
+	http       *http.Client
+	socketPath string
+}
+
+func NewIPCClient(socketPath string) *IPCClient {
+	return &IPCClient{
+		socketPath: socketPath,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+func (ipc *IPCClient) request(ctx context.Context, method string, endpoint string, params url.Values) ([]byte, error) {
+	u := url.URL{Scheme: "http", Host: "sushitrain", Path: endpoint, RawQuery: params.Encode()}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := ipc.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s failed (%d): %s", method, endpoint, res.StatusCode, body)
+	}
+	return body, nil
+}
+
+func (ipc *IPCClient) get(endpoint string, params url.Values) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ipcRequestTimeout)
+	defer cancel()
+	return ipc.request(ctx, http.MethodGet, endpoint, params)
+}
+
+// Returns whether the other process is running and answering requests
+func (ipc *IPCClient) IsAvailable() bool {
+	_, err := ipc.get("/ping", nil)
+	return err == nil
+}
+
+// Returns the folders as a JSON array of objects with the keys `id`, `label`, `type` and `isPaused`
+func (ipc *IPCClient) FoldersJSON() ([]byte, error) {
+	return ipc.get("/folders", nil)
+}
+
+// Like Folder.ListEntriesJSON
+func (ipc *IPCClient) ListEntriesJSON(folderID string, prefix string, directories bool) ([]byte, error) {
+	params := url.Values{"folder": {folderID}, "prefix": {prefix}}
+	if directories {
+		params.Set("directories", "1")
+	}
+	return ipc.get("/entries", params)
+}
+
+// Returns a single entry as JSON object, in the same format as ListEntriesJSON
+func (ipc *IPCClient) EntryJSON(folderID string, path string) ([]byte, error) {
+	return ipc.get("/entry", url.Values{"folder": {folderID}, "path": {path}})
+}
+
+// Returns the directory the other process downloads files into (see Download)
+func (ipc *IPCClient) DownloadsDirectory() string {
+	return ipcDownloadsDirectory(ipc.socketPath)
+}
+
+// Has the other process download the file to `toPath`, and waits until it is done. The path must be in the directory
+// returned by DownloadsDirectory.
+func (ipc *IPCClient) Download(folderID string, path string, toPath string) error {
+	params := url.Values{"folder": {folderID}, "path": {path}, "to": {toPath}}
+	_, err := ipc.request(context.Background(), http.MethodPost, "/download", params)
+	return err
+}
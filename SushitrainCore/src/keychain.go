@@ -0,0 +1,149 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/osutil"
+)
+
+// Allows the app to keep folder encryption passwords in secure storage (i.e. the iOS keychain). Syncthing needs the
+// passwords in its configuration to be able to share a folder encrypted, so they are kept in the configuration in
+// memory, but left out when it is written to config.xml (see keychainConfigWrapper). They are restored from the
+// keychain when the client is loaded, so the delegate must be set before calling Client.Load.
+type KeychainDelegate interface {
+	// Returns the encryption password for the folder as shared with the device, or an empty string if none is stored
+	FolderEncryptionPassword(folderID string, deviceID string) string
+
+	// Stores the encryption password for the folder as shared with the device. An empty password removes it.
+	StoreFolderEncryptionPassword(folderID string, deviceID string, password string) error
+
+	// Returns the secret used to sign requests to the webhook, or an empty string if none is stored
+	WebhookSecret(webhookID string) string
+
+	// Stores the secret used to sign requests to the webhook. An empty secret removes it.
+	StoreWebhookSecret(webhookID string, secret string) error
+}
+
+func (clt *Client) storeFolderEncryptionPassword(folderID string, deviceID string, password string) {
+	if clt.KeychainDelegate == nil {
+		return
+	}
+
+	if err := clt.KeychainDelegate.StoreFolderEncryptionPassword(folderID, deviceID, password); err != nil {
+		slog.Warn("could not store folder encryption password in keychain", "folderID", folderID, "deviceID", deviceID, "cause", err)
+	}
+}
+
+func (clt *Client) keychainFolderEncryptionPassword(folderID string, deviceID string) string {
+	if clt.KeychainDelegate == nil {
+		return ""
+	}
+	return clt.KeychainDelegate.FolderEncryptionPassword(folderID, deviceID)
+}
+
+// Fills in the encryption passwords that were removed from the configuration file from the keychain. Passwords that are
+// in the configuration file but not in the keychain (e.g. from before the keychain was used) are moved to the keychain.
+func (clt *Client) restoreFolderEncryptionPasswords() error {
+	if clt.KeychainDelegate == nil {
+		return nil
+	}
+
+	cfg := clt.config.RawCopy()
+	restored := false
+	moved := false
+	for i, fc := range cfg.Folders {
+		for j, dc := range fc.Devices {
+			stored := clt.keychainFolderEncryptionPassword(fc.ID, dc.DeviceID.String())
+			if dc.EncryptionPassword == "" && stored != "" {
+				cfg.Folders[i].Devices[j].EncryptionPassword = stored
+				restored = true
+			} else if dc.EncryptionPassword != "" && stored == "" {
+				clt.storeFolderEncryptionPassword(fc.ID, dc.DeviceID.String(), dc.EncryptionPassword)
+				moved = true
+			}
+		}
+	}
+
+	if restored {
+		// Only in memory; the configuration is not saved
+		waiter, err := clt.config.Modify(func(conf *config.Configuration) {
+			conf.Folders = cfg.Folders
+		})
+		if err != nil {
+			return err
+		}
+		waiter.Wait()
+	}
+	if moved {
+		// Saving leaves out the passwords that are now in the keychain
+		return clt.saveConfiguration()
+	}
+	return nil
+}
+
+// Returns a copy of the configuration without the encryption passwords that are stored in the keychain, as it is
+// written to the configuration file
+func (clt *Client) withoutKeychainPasswords(cfg config.Configuration) config.Configuration {
+	cfg = cfg.Copy()
+	if clt.KeychainDelegate == nil {
+		return cfg
+	}
+	for i, fc := range cfg.Folders {
+		for j, dc := range fc.Devices {
+			if dc.EncryptionPassword == "" {
+				continue
+			}
+			if clt.keychainFolderEncryptionPassword(fc.ID, dc.DeviceID.String()) == dc.EncryptionPassword {
+				cfg.Folders[i].Devices[j].EncryptionPassword = ""
+			}
+		}
+	}
+	return cfg
+}
+
+// Writes the configuration file without the encryption passwords that are stored in the keychain. Syncthing saves the
+// configuration through this wrapper too, so the passwords are never written to the file.
+type keychainConfigWrapper struct {
+	config.Wrapper
+	client *Client
+	logger events.Logger
+	mutex  sync.Mutex
+}
+
+func (clt *Client) newKeychainConfigWrapper(wrapper config.Wrapper, logger events.Logger) config.Wrapper {
+	if wrapper.ConfigPath() == "" {
+		return wrapper // Never saves
+	}
+	return &keychainConfigWrapper{Wrapper: wrapper, client: clt, logger: logger}
+}
+
+// Does what the wrapped configuration does when saving, except for writing the passwords
+func (w *keychainConfigWrapper) Save() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	cfg := w.RawCopy()
+	out, err := osutil.CreateAtomic(w.ConfigPath())
+	if err != nil {
+		return err
+	}
+	written := w.client.withoutKeychainPasswords(cfg)
+	if err := written.WriteXML(osutil.LineEndingsWriter(out)); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	w.logger.Log(events.ConfigSaved, cfg)
+	return nil
+}
@@ -0,0 +1,197 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status of one of the configured listen addresses
+type ListenerStatus struct {
+	// The listen address as configured (with 'default' expanded)
+	Address string
+
+	// Whether the listener is accepting connections
+	Active bool
+
+	// Addresses through which the listener can be reached (LAN and WAN), when active
+	ResolvedAddresses *ListOfStrings
+
+	// Most recent error when starting the listener (e.g. because the port is in use), if any
+	Error     string
+	ErrorDate *Date
+}
+
+type ListenerStatuses struct {
+	data []*ListenerStatus
+}
+
+func (ls *ListenerStatuses) Count() int {
+	return len(ls.data)
+}
+
+func (ls *ListenerStatuses) ItemAt(index int) *ListenerStatus {
+	if index < 0 || index >= len(ls.data) {
+		return nil
+	}
+	return ls.data[index]
+}
+
+type listenerError struct {
+	message string
+	time    time.Time
+}
+
+// Syncthing does not expose the status of its listeners, but logs when they fail to start. Most of these messages do
+// not say which listener failed, only which kind of listener (TCP, QUIC or relay), so errors are kept per kind as
+// well as per address.
+type listenerTracker struct {
+	mutex          sync.Mutex
+	errorsByURI    map[string]listenerError
+	errorsByFamily map[string]listenerError
+}
+
+func newListenerTracker() *listenerTracker {
+	return &listenerTracker{
+		errorsByURI:    map[string]listenerError{},
+		errorsByFamily: map[string]listenerError{},
+	}
+}
+
+// Returns the kind of listener for a listen address scheme, as used in Syncthing's log messages
+func listenerFamily(scheme string) string {
+	switch {
+	case strings.HasPrefix(scheme, "tcp"):
+		return "TCP"
+	case strings.HasPrefix(scheme, "quic"):
+		return "QUIC"
+	case scheme == "relay" || strings.HasPrefix(scheme, dynamicRelayPrefix):
+		return "relay"
+	}
+	return ""
+}
+
+func (lt *listenerTracker) handleLogRecord(r slog.Record) {
+	attrs := map[string]slog.Value{}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value
+		return true
+	})
+	failure := listenerError{time: r.Time}
+	if cause, ok := attrs["error"]; ok {
+		failure.message = cause.String()
+	} else {
+		failure.message = r.Message
+	}
+
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	switch r.Message {
+	case "Failed to listen (TCP)":
+		lt.errorsByFamily["TCP"] = failure
+	case "Failed to listen (QUIC)":
+		lt.errorsByFamily["QUIC"] = failure
+	case "Failed to listen (relay)":
+		lt.errorsByFamily["relay"] = failure
+	case "Failed to get listener", "Skipping malformed listener URL", "Skipping malformed listener URL (not canonical)":
+		// E.g. QUIC not being available in this build
+		if uri, ok := attrs["uri"]; ok {
+			lt.errorsByURI[uri.String()] = failure
+		}
+	}
+}
+
+// Called when a listener reports its addresses; a listener with addresses evidently started successfully
+func (lt *listenerTracker) handleAddressesChanged(uri *url.URL, hasAddresses bool) {
+	if !hasAddresses {
+		return
+	}
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+	delete(lt.errorsByURI, uri.String())
+	delete(lt.errorsByFamily, listenerFamily(uri.Scheme))
+}
+
+// Returns the status of each of the configured listen addresses
+func (clt *Client) ListenerStatus() (*ListenerStatuses, error) {
+	if clt.app == nil || clt.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+
+	clt.mutex.Lock()
+	resolved := make(map[string][]string, len(clt.ResolvedListenAddresses))
+	for uri, addrs := range clt.ResolvedListenAddresses {
+		resolved[uri] = slices.Clone(addrs)
+	}
+	clt.mutex.Unlock()
+
+	lt := clt.listeners
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	statuses := make([]*ListenerStatus, 0)
+	for _, address := range clt.config.Options().ListenAddresses() {
+		addrs := resolved[address]
+		status := &ListenerStatus{
+			Address:           address,
+			Active:            len(addrs) > 0,
+			ResolvedAddresses: List(addrs),
+		}
+
+		failure, failed := lt.errorsByURI[address]
+		if !failed && !status.Active {
+			if uri, err := url.Parse(address); err == nil {
+				failure, failed = lt.errorsByFamily[listenerFamily(uri.Scheme)]
+			}
+		}
+		if failed {
+			status.Error = failure.message
+			status.ErrorDate = &Date{time: failure.time}
+		}
+		statuses = append(statuses, status)
+	}
+	return &ListenerStatuses{data: statuses}, nil
+}
+
+// Checks that each address is either 'default' or a listen address Syncthing understands (e.g. tcp://0.0.0.0:22000,
+// quic://:22000, relay://relay.example.com:22067 or dynamic+https://relays.syncthing.net/endpoint)
+func validateListenAddresses(addrs []string) error {
+	if len(addrs) == 0 {
+		return errors.New("at least one listen address is required (use SetListening to stop listening)")
+	}
+
+	for _, addr := range addrs {
+		if addr == "default" {
+			continue
+		}
+		uri, err := url.Parse(addr)
+		if err != nil {
+			return errors.New("invalid listen address: " + addr)
+		}
+
+		switch uri.Scheme {
+		case "tcp", "tcp4", "tcp6", "quic", "quic4", "quic6":
+			if _, port, err := net.SplitHostPort(uri.Host); err != nil || port == "" {
+				return errors.New("listen address must include a port: " + addr)
+			}
+		case "relay", dynamicRelayPrefix + "http", dynamicRelayPrefix + "https":
+			if uri.Host == "" {
+				return errors.New("listen address must include a host: " + addr)
+			}
+		default:
+			return errors.New("unsupported listen address: " + addr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// A device that announced itself on the local network (see Client.LocalDiscoveryResults)
+type DiscoveredDevice struct {
+	DeviceID     string
+	Addresses    *ListOfStrings
+	IsKnown      bool  // Whether the device is in our configuration
+	DiscoveredAt *Date // When Syncthing last reported the device as (re)discovered
+}
+
+type DiscoveredDevices struct {
+	data []*DiscoveredDevice
+}
+
+func (dd *DiscoveredDevices) Count() int {
+	return len(dd.data)
+}
+
+func (dd *DiscoveredDevices) ItemAt(index int) *DiscoveredDevice {
+	if index < 0 || index >= len(dd.data) {
+		return nil
+	}
+	return dd.data[index]
+}
+
+type localDiscoveryEntry struct {
+	addresses    []string
+	discoveredAt time.Time
+}
+
+// Devices reported by the DeviceDiscovered event, which Syncthing only emits for local discovery. Syncthing does not
+// report a device again while it keeps announcing itself (only when it restarted or was silent for a while), and does not
+// make its discovery cache available, so there is no way to tell a device went away. Devices are therefore remembered
+// until the client stops.
+type localDiscoveryTracker struct {
+	mutex   sync.Mutex
+	devices map[protocol.DeviceID]localDiscoveryEntry
+}
+
+func newLocalDiscoveryTracker() *localDiscoveryTracker {
+	return &localDiscoveryTracker{devices: map[protocol.DeviceID]localDiscoveryEntry{}}
+}
+
+func (ldt *localDiscoveryTracker) record(deviceID protocol.DeviceID, addresses []string) {
+	ldt.mutex.Lock()
+	defer ldt.mutex.Unlock()
+	ldt.devices[deviceID] = localDiscoveryEntry{addresses: slices.Clone(addresses), discoveredAt: time.Now()}
+}
+
+func (ldt *localDiscoveryTracker) all() map[protocol.DeviceID]localDiscoveryEntry {
+	ldt.mutex.Lock()
+	defer ldt.mutex.Unlock()
+	return maps.Clone(ldt.devices)
+}
+
+// Returns the devices that announced themselves on the local network since the client started, including devices that
+// are not in our configuration (yet), ordered by device ID. Syncthing does not tell us about each announcement, only
+// about devices that are new, restarted or were silent for a while, so a device remains listed after it went away. The
+// instance IDs in the announcements are not made available by Syncthing and can therefore not be listed. The list is
+// empty when local discovery is disabled.
+func (clt *Client) LocalDiscoveryResults() (*DiscoveredDevices, error) {
+	if clt.config == nil {
+		return nil, ErrStillLoading
+	}
+	if !clt.config.Options().LocalAnnEnabled {
+		return &DiscoveredDevices{data: []*DiscoveredDevice{}}, nil
+	}
+
+	self := clt.deviceID()
+	devices := clt.localDiscovery.all()
+	result := make([]*DiscoveredDevice, 0, len(devices))
+	for deviceID, entry := range devices {
+		if deviceID == self {
+			continue
+		}
+		_, isKnown := clt.config.Device(deviceID)
+		result = append(result, &DiscoveredDevice{
+			DeviceID:     deviceID.String(),
+			Addresses:    List(entry.addresses),
+			IsKnown:      isKnown,
+			DiscoveredAt: &Date{time: entry.discoveredAt},
+		})
+	}
+	slices.SortFunc(result, func(a, b *DiscoveredDevice) int {
+		return strings.Compare(a.DeviceID, b.DeviceID)
+	})
+	return &DiscoveredDevices{data: result}, nil
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Number of temporary files for which the downloaded fraction is remembered
+const maxTempFileFractions = 256
+
+const (
+	LocalStateNotPresent          = "notPresent"
+	LocalStatePartiallyDownloaded = "partiallyDownloaded"
+	LocalStatePresentAndUpToDate  = "presentAndUpToDate"
+	LocalStatePresentButOutdated  = "presentButOutdated"
+	LocalStateLocallyModified     = "locallyModified"
+)
+
+type LocalFileState struct {
+	State string // One of the LocalState... constants
+
+	// For partially downloaded files, the fraction of the blocks of the new version that are in the temporary file
+	DownloadedFraction float64
+}
+
+// Returns the state of the local copy of this entry compared to the global version, based on the local index, the file
+// on disk and (for files that are being downloaded) the temporary file the puller writes to.
+func (entry *Entry) LocalState() (*LocalFileState, error) {
+	fld := entry.Folder
+	sdb := fld.client.sdb
+	if sdb == nil {
+		return nil, ErrStillLoading
+	}
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return nil, ErrFolderNotFound
+	}
+	ffs := fc.Filesystem()
+	global := entry.info
+
+	local, ok, err := sdb.GetDeviceFile(fld.FolderID, protocol.LocalDeviceID, global.Name)
+	if err != nil {
+		return nil, err
+	}
+	hasLocal := ok && !local.IsDeleted() && !local.IsIgnored()
+
+	nativePath := osutil.NativeFilename(global.Name)
+	stat, err := ffs.Lstat(nativePath)
+	onDisk := err == nil
+
+	// Changes that were scanned but not (yet) accepted, or that were not scanned yet
+	if hasLocal && onDisk {
+		if local.IsReceiveOnlyChanged() || local.Version.Concurrent(global.Version) {
+			return &LocalFileState{State: LocalStateLocallyModified}, nil
+		}
+		if local.Type == protocol.FileInfoTypeFile && stat.IsRegular() {
+			modTimeDifference := stat.ModTime().Sub(local.ModTime()).Abs()
+			if stat.Size() != local.Size || modTimeDifference > fc.ModTimeWindow() {
+				return &LocalFileState{State: LocalStateLocallyModified}, nil
+			}
+		}
+	} else if onDisk != hasLocal && !global.IsDeleted() {
+		// Created or removed locally, but not scanned yet
+		return &LocalFileState{State: LocalStateLocallyModified}, nil
+	}
+
+	upToDate := hasLocal && onDisk && local.Version.Equal(global.Version)
+	if !upToDate && global.Type == protocol.FileInfoTypeFile && !global.IsDeleted() {
+		if fraction, ok := entry.downloadedFraction(ffs, nativePath); ok {
+			return &LocalFileState{State: LocalStatePartiallyDownloaded, DownloadedFraction: fraction}, nil
+		}
+	}
+
+	switch {
+	case upToDate:
+		return &LocalFileState{State: LocalStatePresentAndUpToDate}, nil
+	case onDisk:
+		return &LocalFileState{State: LocalStatePresentButOutdated}, nil
+	default:
+		return &LocalFileState{State: LocalStateNotPresent}, nil
+	}
+}
+
+// Returns the fraction of the file that was downloaded when a download is in progress or was interrupted. The progress
+// reported by the puller is used while downloading; otherwise the blocks in the temporary file are checked.
+func (entry *Entry) downloadedFraction(ffs fs.Filesystem, nativePath string) (float64, bool) {
+	global := entry.info
+	client := entry.Folder.client
+
+	client.mutex.Lock()
+	progress, ok := client.downloadProgress[entry.Folder.FolderID][global.Name]
+	client.mutex.Unlock()
+	if ok && progress.BytesTotal > 0 {
+		return float64(progress.BytesDone) / float64(progress.BytesTotal), true
+	}
+
+	tempPath := fs.TempName(nativePath)
+	stat, err := ffs.Stat(tempPath)
+	if err != nil {
+		return 0, false
+	}
+	key := tempFileFractionKey{folderID: entry.Folder.FolderID, path: tempPath, version: global.Version.String()}
+	if fraction, ok := client.tempFileFractions.get(key, stat.Size(), stat.ModTime()); ok {
+		return fraction, true
+	}
+
+	file, err := ffs.Open(tempPath)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+	if len(global.Blocks) == 0 {
+		return 0, true
+	}
+
+	present := 0
+	buffer := make([]byte, global.BlockSize())
+	for _, block := range global.Blocks {
+		n, err := file.ReadAt(buffer[:block.Size], block.Offset)
+		if err != nil && !(errors.Is(err, io.EOF) && n == block.Size) {
+			continue
+		}
+		hash := sha256.Sum256(buffer[:block.Size])
+		if bytes.Equal(hash[:], block.Hash) {
+			present += 1
+		}
+	}
+	fraction := float64(present) / float64(len(global.Blocks))
+	client.tempFileFractions.set(key, stat.Size(), stat.ModTime(), fraction)
+	return fraction, true
+}
+
+type tempFileFractionKey struct {
+	folderID string
+	path     string
+	version  string // The version of the file that is being downloaded, as the blocks differ between versions
+}
+
+type tempFileFraction struct {
+	size     int64
+	modTime  time.Time
+	fraction float64
+}
+
+// Remembers the fraction of the blocks found in temporary files, so that these do not need to be hashed again until
+// they change (which is told by their size and modification time)
+type tempFileFractionCache struct {
+	mutex   sync.Mutex
+	entries map[tempFileFractionKey]tempFileFraction
+}
+
+func newTempFileFractionCache() *tempFileFractionCache {
+	return &tempFileFractionCache{entries: map[tempFileFractionKey]tempFileFraction{}}
+}
+
+func (tc *tempFileFractionCache) get(key tempFileFractionKey, size int64, modTime time.Time) (float64, bool) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	entry, ok := tc.entries[key]
+	if !ok || entry.size != size || !entry.modTime.Equal(modTime) {
+		return 0, false
+	}
+	return entry.fraction, true
+}
+
+func (tc *tempFileFractionCache) set(key tempFileFractionKey, size int64, modTime time.Time, fraction float64) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	if _, ok := tc.entries[key]; !ok && len(tc.entries) >= maxTempFileFractions {
+		clear(tc.entries)
+	}
+	tc.entries[key] = tempFileFraction{size: size, modTime: modTime, fraction: fraction}
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/syncthing/syncthing/lib/locations"
+)
+
+const instanceLockFileName = "sushitrain.lock"
+
+// Returned by Client.Load when another process (e.g. the app while loading from an extension) is already using the
+// configuration directory, and the client was not created with the `attachIfRunning` option
+var ErrAlreadyRunning = errors.New("another instance is already running using this configuration")
+
+// Takes an exclusive lock on the configuration directory, so that two processes never use the same database. Returns
+// ErrAlreadyRunning when another process holds the lock. The lock is released when the process exits.
+func (clt *Client) acquireInstanceLock() error {
+	file, err := lockDirectory(locations.GetBaseDir(locations.ConfigBaseDir))
+	if err != nil {
+		return err
+	}
+	clt.instanceLock = file
+	return nil
+}
+
+func lockDirectory(dir string) (*os.File, error) {
+	file, err := os.OpenFile(path.Join(dir, instanceLockFileName), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrAlreadyRunning
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+func unlockDirectory(file *os.File) {
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	file.Close()
+}
+
+func (clt *Client) releaseInstanceLock() {
+	if clt.instanceLock == nil {
+		return
+	}
+	unlockDirectory(clt.instanceLock)
+	clt.instanceLock = nil
+}
+
+// Takes the instance lock, or when another instance holds it and the options allow it, switches this client to a
+// reduced mode: the configuration can be read but not changed, the state kept next to it is not written, a temporary
+// database is used and no connections are made, so that the other instance is not disturbed.
+func (clt *Client) acquireInstanceLockOrAttach() error {
+	err := clt.acquireInstanceLock()
+	if !errors.Is(err, ErrAlreadyRunning) || !clt.options.AttachIfRunning {
+		return err
+	}
+
+	slog.Warn("another instance is running, continuing in reduced read-only mode")
+	clt.options.ReadOnly = true
+	clt.options.InMemoryDatabase = true
+	clt.options.SkipInitialScan = true
+	clt.options.DisableDiscovery = true
+	clt.options.ListenAddresses = []string{NoListenAddress}
+	clt.options.attached = true
+	clt.stores.readOnly.Store(true)
+	return nil
+}
+
+// Returns whether this client runs in reduced read-only mode because another instance was already running (see the
+// `attachIfRunning` option of NewClientWithOptions)
+func (clt *Client) IsAttachedToRunningInstance() bool {
+	return clt.options.attached
+}
@@ -105,16 +105,24 @@ func (lt *logTail) write(to io.Writer, redact bool) error {
 	return nil
 }
 
+// Receives log records of at least observedLogLevel, regardless of the configured minimum log level. Used to react to
+// conditions that Syncthing only reports through its log.
+type logObserver func(r slog.Record)
+
+// Some state (e.g. NAT detection results) is only logged at the info level
+const observedLogLevel = slog.LevelInfo
+
 type logHandler struct {
 	logger   *log.Logger
 	minLevel slog.Level
 	tail     *logTail
+	observer logObserver
 }
 
 var _ slog.Handler = (*logHandler)(nil)
 
 func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return level >= h.minLevel
+	return level >= h.minLevel || (h.observer != nil && level >= observedLogLevel)
 }
 
 func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
@@ -132,6 +140,14 @@ func (h *logHandler) WithGroup(name string) slog.Handler {
 }
 
 func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.observer != nil && r.Level >= observedLogLevel {
+		h.observer(r)
+	}
+
+	if r.Level < h.minLevel {
+		return nil
+	}
+
 	var sb strings.Builder
 	r.Attrs(func(a slog.Attr) bool {
 		sb.WriteString(a.Key)
@@ -0,0 +1,260 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	materializedDirName             = "materialized"
+	defaultMaterializedCacheMaxSize = 1024 * 1024 * 1024 // 1 GiB
+	materializedEvictionInterval    = time.Minute
+)
+
+type materializedItem struct {
+	path    string
+	size    int64
+	expires time.Time
+}
+
+// Keeps temporary local copies of files outside of the synchronized folders, e.g. for previewing a file or opening it in
+// another app without selecting it for synchronization.
+type materializationCache struct {
+	mutex       sync.Mutex
+	dir         string // Set by Client.Start
+	temporary   bool   // Whether dir is removed when the client stops
+	maxBytes    int64
+	items       map[string]*materializedItem
+	downloading map[string]int // Number of downloads in progress into the directory of each item
+}
+
+func newMaterializationCache() *materializationCache {
+	return &materializationCache{
+		maxBytes:    defaultMaterializedCacheMaxSize,
+		items:       map[string]*materializedItem{},
+		downloading: map[string]int{},
+	}
+}
+
+// Sets the directory materialized files are kept in. Clients that share their configuration directory with another
+// process (i.e. read-only clients) get a temporary directory of their own.
+func (clt *Client) setMaterializationDirectory() error {
+	mc := clt.materialized
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if clt.options.ReadOnly {
+		dir, err := os.MkdirTemp("", "sushitrain-"+materializedDirName+"-")
+		if err != nil {
+			return err
+		}
+		mc.dir = dir
+		mc.temporary = true
+		return nil
+	}
+	mc.dir = filepath.Join(clt.options.ConfigPath, materializedDirName)
+	return nil
+}
+
+// Periodically removes expired items. Items from a previous run are not tracked, so these are removed on start.
+func (mc *materializationCache) serve(ctx context.Context) {
+	if err := os.RemoveAll(mc.dir); err != nil {
+		slog.Warn("could not clear materialized files", "path", mc.dir, "cause", err)
+	}
+
+	ticker := time.NewTicker(materializedEvictionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if mc.temporary {
+				os.RemoveAll(mc.dir)
+			}
+			return
+		case <-ticker.C:
+			mc.evict()
+		}
+	}
+}
+
+// Removes expired items, then removes the items that expire soonest until the cache fits its size limit
+func (mc *materializationCache) evict() {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	mc.evictLocked(0)
+}
+
+// Like evict, but makes room for an item of `reserve` bytes that is about to be added
+func (mc *materializationCache) evictLocked(reserve int64) {
+	now := time.Now()
+	total := reserve
+	remaining := make([]string, 0, len(mc.items))
+	for key, item := range mc.items {
+		if item.expires.Before(now) {
+			mc.removeLocked(key)
+		} else {
+			total += item.size
+			remaining = append(remaining, key)
+		}
+	}
+
+	slices.SortFunc(remaining, func(a, b string) int {
+		return mc.items[a].expires.Compare(mc.items[b].expires)
+	})
+	for _, key := range remaining {
+		if total <= mc.maxBytes {
+			break
+		}
+		total -= mc.items[key].size
+		mc.removeLocked(key)
+	}
+}
+
+func (mc *materializationCache) removeLocked(key string) {
+	item, ok := mc.items[key]
+	if !ok {
+		return
+	}
+	delete(mc.items, key)
+
+	// The directory of the item also holds the partial files of downloads of the same version still in progress
+	remove := os.RemoveAll
+	target := filepath.Dir(item.path)
+	if mc.downloading[key] > 0 {
+		remove = os.Remove
+		target = item.path
+	}
+	if err := remove(target); err != nil {
+		slog.Warn("could not remove materialized file", "path", item.path, "cause", err)
+	}
+}
+
+type materializeDelegate struct {
+	DownloadDelegate
+	onFinished func(path string)
+	onError    func()
+}
+
+func (md *materializeDelegate) OnFinished(path string) {
+	md.onFinished(path)
+}
+
+func (md *materializeDelegate) OnError(error string) {
+	md.onError()
+	md.DownloadDelegate.OnError(error)
+}
+
+// Sets the maximum total size of the files kept by Entry.MaterializeTemporarily
+func (clt *Client) SetMaterializationCacheLimit(maxBytes int64) {
+	clt.materialized.mutex.Lock()
+	clt.materialized.maxBytes = maxBytes
+	clt.materialized.mutex.Unlock()
+	clt.materialized.evict()
+}
+
+// Downloads this file to a temporary location outside of the synchronized folder, where it is kept for at least
+// `ttlSeconds` (unless the cache size limit is reached). The file is not selected for synchronization. The path to
+// the local copy is passed to the delegate's OnFinished. When a copy of the same version of the file is already
+// present, it is reused.
+func (entry *Entry) MaterializeTemporarily(ttlSeconds int, delegate DownloadDelegate) {
+	entry.MaterializeTemporarilyWithProgress(ttlSeconds, delegate, nil)
+}
+
+// Like MaterializeTemporarily, but reports detailed progress to `progress` when the file needs to be downloaded
+func (entry *Entry) MaterializeTemporarilyWithProgress(ttlSeconds int, delegate DownloadDelegate, progress DownloadProgressDelegate) {
+	mc := entry.Folder.client.materialized
+	key := entry.materializationKey()
+	expires := time.Now().Add(time.Duration(ttlSeconds) * time.Second)
+
+	mc.mutex.Lock()
+	if mc.dir == "" {
+		mc.mutex.Unlock()
+		delegate.OnError(ErrStillLoading.Error())
+		return
+	}
+	if item, ok := mc.items[key]; ok {
+		if expires.After(item.expires) {
+			item.expires = expires
+		}
+		mc.mutex.Unlock()
+		delegate.OnFinished(item.path)
+		return
+	}
+	mc.downloading[key]++
+	mc.mutex.Unlock()
+
+	finishDownload := func() {
+		mc.mutex.Lock()
+		if mc.downloading[key]--; mc.downloading[key] <= 0 {
+			delete(mc.downloading, key)
+		}
+		mc.mutex.Unlock()
+	}
+
+	itemDir := filepath.Join(mc.dir, key)
+	if err := os.MkdirAll(itemDir, 0o700); err != nil {
+		finishDownload()
+		delegate.OnError(err.Error())
+		return
+	}
+
+	// The same file may be materialized more than once at the same time, so each download gets its own partial file
+	itemPath := filepath.Join(itemDir, entry.FileName())
+	partial, err := os.CreateTemp(itemDir, entry.FileName()+".*.partial")
+	if err != nil {
+		finishDownload()
+		delegate.OnError(err.Error())
+		return
+	}
+	downloadPath := partial.Name()
+	partial.Close()
+
+	entry.DownloadWithProgress(downloadPath, &materializeDelegate{
+		DownloadDelegate: delegate,
+		onFinished: func(string) {
+			defer finishDownload()
+			if err := os.Rename(downloadPath, itemPath); err != nil {
+				os.Remove(downloadPath)
+				delegate.OnError(err.Error())
+				return
+			}
+
+			// Make room for the new item before adding it, so it is not evicted before the delegate gets to use it
+			mc.mutex.Lock()
+			if item, ok := mc.items[key]; ok {
+				// Another download of the same version finished first
+				if expires.After(item.expires) {
+					item.expires = expires
+				}
+			} else {
+				mc.evictLocked(entry.Size())
+				mc.items[key] = &materializedItem{path: itemPath, size: entry.Size(), expires: expires}
+			}
+			mc.mutex.Unlock()
+			delegate.OnFinished(itemPath)
+		},
+		onError: func() {
+			os.Remove(downloadPath)
+			finishDownload()
+		},
+	}, progress)
+}
+
+// Identifies this version of the file in the materialization cache
+func (entry *Entry) materializationKey() string {
+	id := fmt.Sprintf("%s\x00%s\x00%d\x00%d", entry.Folder.FolderID, entry.info.FileName(), entry.info.FileSize(), entry.info.ModTime().UnixNano())
+	hash := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(hash[:16])
+}
@@ -0,0 +1,385 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Number of bytes http.DetectContentType looks at
+const sniffLength = 512
+
+type MediaMetadata struct {
+	Width           int
+	Height          int
+	TakenAt         *Date // Nil when unknown
+	DurationSeconds float64
+}
+
+// Reads (parts of) an entry, from the local copy when available or from peers otherwise
+type entryReader struct {
+	entry      *Entry
+	puller     *miniPuller
+	remoteOnly bool            // Never read from the local file, e.g. when it may be another file differing only in case
+	ctx        context.Context // Cancels reads from peers; reads are not cancelled when nil
+}
+
+func newEntryReader(entry *Entry) *entryReader {
+	client := entry.Folder.client
+	return &entryReader{
+		entry:  entry,
+		puller: newMiniPuller(client.Measurements, client.app.Internals),
+	}
+}
+
+// ReadAt implements io.ReaderAt.
+func (er *entryReader) ReadAt(p []byte, off int64) (int, error) {
+	size := er.entry.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	want := p
+	if off+int64(len(p)) > size {
+		want = p[:size-off]
+	}
+
+	if er.remoteOnly || !er.readLocal(want, off) {
+		ctx := er.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		n, err := er.puller.downloadRange(ctx, er.entry.Folder.client.app.Internals, er.entry.Folder.FolderID, er.entry.info, want, off, 1)
+		if err != nil {
+			return int(n), err
+		}
+	}
+
+	if len(want) < len(p) {
+		return len(want), io.EOF
+	}
+	return len(p), nil
+}
+
+// Reads the range from the local copy, but only when it is the version of the file this entry describes
+func (er *entryReader) readLocal(want []byte, off int64) bool {
+	file, err := er.entry.openLocalVersion()
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	_, err = file.ReadAt(want, off)
+	return err == nil
+}
+
+// Like MIMEType, but inspects the first bytes of the file (fetching them from peers when the file is not available
+// locally) when the extension is unknown. Should not be called for each file in a listing.
+func (entry *Entry) SniffMIMEType() (string, error) {
+	if tp := MIMETypeForExtension(entry.Extension()); tp != "" {
+		return tp, nil
+	}
+	return entry.sniffMIMEType()
+}
+
+// Determines the MIME type by looking at the first bytes of the file, which may need to be fetched from peers
+func (entry *Entry) sniffMIMEType() (string, error) {
+	if entry.IsDirectory() || entry.Size() == 0 {
+		return "", nil
+	}
+	if entry.Folder.client.app == nil || entry.Folder.client.app.Internals == nil {
+		return "", ErrStillLoading
+	}
+
+	buffer := make([]byte, min(entry.Size(), sniffLength))
+	if _, err := newEntryReader(entry).ReadAt(buffer, 0); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	tp := http.DetectContentType(buffer)
+	if tp == "application/octet-stream" {
+		// This is what DetectContentType returns when it does not know
+		return "", nil
+	}
+	return tp, nil
+}
+
+// Reads image dimensions, the date a photo or video was taken, and video duration, fetching only the parts of the file
+// that are needed. Supports JPEG, PNG and GIF images and MP4/QuickTime videos.
+func (entry *Entry) MediaMetadata() (*MediaMetadata, error) {
+	if entry.Folder.client.app == nil || entry.Folder.client.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+	if entry.IsDirectory() {
+		return nil, errors.New("directories have no media metadata")
+	}
+
+	mimeType, err := entry.SniffMIMEType()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := newEntryReader(entry)
+	meta := &MediaMetadata{}
+
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		cfg, _, err := image.DecodeConfig(bufio.NewReaderSize(io.NewSectionReader(reader, 0, entry.Size()), 64*1024))
+		if err != nil {
+			return nil, err
+		}
+		meta.Width = cfg.Width
+		meta.Height = cfg.Height
+
+		if mimeType == "image/jpeg" {
+			if takenAt, err := jpegDateTaken(reader, entry.Size()); err != nil {
+				slog.Warn("could not read EXIF date", "path", entry.Path(), "cause", err)
+			} else if !takenAt.IsZero() {
+				meta.TakenAt = &Date{time: takenAt}
+			}
+		}
+
+	case strings.HasPrefix(mimeType, "video/"):
+		if err := readMP4Metadata(reader, entry.Size(), meta); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, errors.New("unsupported media type")
+	}
+
+	return meta, nil
+}
+
+const (
+	exifTagDateTime         = 0x0132
+	exifTagExifIFD          = 0x8769
+	exifTagDateTimeOriginal = 0x9003
+	exifDateLayout          = "2006:01:02 15:04:05"
+)
+
+// Walks the JPEG segments up to the start of the image data, looking for the EXIF segment. Returns the zero time when
+// the file has no EXIF date.
+func jpegDateTaken(r io.ReaderAt, size int64) (time.Time, error) {
+	offset := int64(2) // Skip SOI marker
+	header := make([]byte, 4)
+	for offset+4 <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return time.Time{}, err
+		}
+		if header[0] != 0xFF {
+			return time.Time{}, errors.New("invalid JPEG marker")
+		}
+
+		marker := header[1]
+		length := int64(binary.BigEndian.Uint16(header[2:4]))
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan or end of image: no more metadata
+			return time.Time{}, nil
+		}
+
+		if marker == 0xE1 && length > 8 {
+			segment := make([]byte, length-2)
+			if _, err := r.ReadAt(segment, offset+4); err != nil {
+				return time.Time{}, err
+			}
+			if bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+				return exifDateTaken(segment[6:])
+			}
+		}
+		offset += 2 + length
+	}
+	return time.Time{}, nil
+}
+
+// Reads DateTimeOriginal (or DateTime when not available) from EXIF data in TIFF format
+func exifDateTaken(tiff []byte) (time.Time, error) {
+	if len(tiff) < 8 {
+		return time.Time{}, errors.New("EXIF data too short")
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return time.Time{}, errors.New("invalid EXIF byte order")
+	}
+
+	// Returns the offset of the value of a tag in an IFD
+	findTag := func(ifdOffset uint32, tag uint16) (uint32, uint32, bool) {
+		if int(ifdOffset)+2 > len(tiff) {
+			return 0, 0, false
+		}
+		count := int(order.Uint16(tiff[ifdOffset:]))
+		for i := range count {
+			entryOffset := int(ifdOffset) + 2 + i*12
+			if entryOffset+12 > len(tiff) {
+				return 0, 0, false
+			}
+			if order.Uint16(tiff[entryOffset:]) == tag {
+				valueCount := order.Uint32(tiff[entryOffset+4:])
+				if valueCount <= 4 {
+					return uint32(entryOffset + 8), valueCount, true
+				}
+				return order.Uint32(tiff[entryOffset+8:]), valueCount, true
+			}
+		}
+		return 0, 0, false
+	}
+
+	readDate := func(offset uint32, count uint32) (time.Time, error) {
+		if int64(offset)+int64(count) > int64(len(tiff)) {
+			return time.Time{}, errors.New("EXIF date out of bounds")
+		}
+		value := strings.TrimRight(string(tiff[offset:offset+count]), "\x00 ")
+		return time.ParseInLocation(exifDateLayout, value, time.Local)
+	}
+
+	ifd0 := order.Uint32(tiff[4:8])
+	if exifIFDOffset, _, ok := findTag(ifd0, exifTagExifIFD); ok && int(exifIFDOffset)+4 <= len(tiff) {
+		exifIFD := order.Uint32(tiff[exifIFDOffset:])
+		if offset, count, ok := findTag(exifIFD, exifTagDateTimeOriginal); ok {
+			return readDate(offset, count)
+		}
+	}
+	if offset, count, ok := findTag(ifd0, exifTagDateTime); ok {
+		return readDate(offset, count)
+	}
+	return time.Time{}, nil
+}
+
+// Seconds between the MP4 epoch (1904-01-01) and the Unix epoch
+const mp4EpochOffset = 2082844800
+
+// Calls `block` for each box in the range, with the type and the offset and size of its contents
+func walkMP4Boxes(r io.ReaderAt, start int64, end int64, block func(boxType string, offset int64, size int64) (bool, error)) error {
+	header := make([]byte, 16)
+	offset := start
+	for offset+8 <= end {
+		if _, err := r.ReadAt(header[0:8], offset); err != nil {
+			return err
+		}
+
+		boxSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		boxType := string(header[4:8])
+		headerSize := int64(8)
+		switch boxSize {
+		case 0:
+			// Box extends to the end
+			boxSize = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if boxSize < headerSize || offset+boxSize > end {
+			return errors.New("invalid MP4 box size")
+		}
+
+		cont, err := block(boxType, offset+headerSize, boxSize-headerSize)
+		if err != nil || !cont {
+			return err
+		}
+		offset += boxSize
+	}
+	return nil
+}
+
+// Reads the duration and creation time from the movie header, and the dimensions from the first track that has them
+func readMP4Metadata(r io.ReaderAt, size int64, meta *MediaMetadata) error {
+	foundMovie := false
+	err := walkMP4Boxes(r, 0, size, func(boxType string, offset int64, boxSize int64) (bool, error) {
+		if boxType != "moov" {
+			return true, nil
+		}
+		foundMovie = true
+
+		return false, walkMP4Boxes(r, offset, offset+boxSize, func(boxType string, offset int64, boxSize int64) (bool, error) {
+			switch boxType {
+			case "mvhd":
+				return true, readMP4MovieHeader(r, offset, boxSize, meta)
+			case "trak":
+				if meta.Width != 0 {
+					return true, nil
+				}
+				return true, walkMP4Boxes(r, offset, offset+boxSize, func(boxType string, offset int64, boxSize int64) (bool, error) {
+					if boxType != "tkhd" || boxSize < 8 {
+						return true, nil
+					}
+					// Width and height are the last two fields, as 16.16 fixed point numbers
+					dimensions := make([]byte, 8)
+					if _, err := r.ReadAt(dimensions, offset+boxSize-8); err != nil {
+						return false, err
+					}
+					meta.Width = int(binary.BigEndian.Uint32(dimensions[0:4]) >> 16)
+					meta.Height = int(binary.BigEndian.Uint32(dimensions[4:8]) >> 16)
+					return false, nil
+				})
+			}
+			return true, nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if !foundMovie {
+		return errors.New("no movie header found")
+	}
+	return nil
+}
+
+func readMP4MovieHeader(r io.ReaderAt, offset int64, size int64, meta *MediaMetadata) error {
+	// Version 0 headers (the shortest) have 20 bytes up to and including the duration
+	if size < 20 {
+		return errors.New("movie header too short")
+	}
+	buffer := make([]byte, min(size, 32))
+	if _, err := r.ReadAt(buffer, offset); err != nil {
+		return err
+	}
+
+	var created, timescale, duration uint64
+	switch buffer[0] {
+	case 0:
+		created = uint64(binary.BigEndian.Uint32(buffer[4:8]))
+		timescale = uint64(binary.BigEndian.Uint32(buffer[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(buffer[16:20]))
+	case 1:
+		if len(buffer) < 32 {
+			return errors.New("movie header too short")
+		}
+		created = binary.BigEndian.Uint64(buffer[4:12])
+		timescale = uint64(binary.BigEndian.Uint32(buffer[20:24]))
+		duration = binary.BigEndian.Uint64(buffer[24:32])
+	default:
+		return errors.New("unsupported movie header version")
+	}
+
+	if timescale > 0 {
+		meta.DurationSeconds = float64(duration) / float64(timescale)
+	}
+	if created > mp4EpochOffset {
+		meta.TakenAt = &Date{time: time.Unix(int64(created-mp4EpochOffset), 0)}
+	}
+	return nil
+}
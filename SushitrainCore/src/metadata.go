@@ -0,0 +1,146 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+)
+
+const metadataFileName = "metadata.json"
+
+// Arbitrary key/value pairs the app stores for folders and devices (e.g. colors, emoji, sort order). Syncthing's
+// configuration has no room for these, so they are kept next to it, by folder and device ID.
+type metadataState struct {
+	Folders map[string]map[string]string `json:"folders"`
+	Devices map[string]map[string]string `json:"devices"`
+}
+
+var errEmptyMetadataKey = errors.New("metadata key cannot be empty")
+
+func getMetadata(items map[string]map[string]string, id string, key string) string {
+	return items[id][key]
+}
+
+func setMetadata(items *map[string]map[string]string, id string, key string, value string) {
+	if *items == nil {
+		*items = map[string]map[string]string{}
+	}
+	if value == "" {
+		delete((*items)[id], key)
+		if len((*items)[id]) == 0 {
+			delete(*items, id)
+		}
+		return
+	}
+	if (*items)[id] == nil {
+		(*items)[id] = map[string]string{}
+	}
+	(*items)[id][key] = value
+}
+
+func metadataKeys(items map[string]map[string]string, id string) *ListOfStrings {
+	return List(slices.Sorted(maps.Keys(items[id])))
+}
+
+// Returns the value stored for the key using SetMetadata, or an empty string
+func (fld *Folder) Metadata(key string) string {
+	value := ""
+	fld.client.metadata.read(func(state *metadataState) {
+		value = getMetadata(state.Folders, fld.FolderID, key)
+	})
+	return value
+}
+
+// Stores a value for the key with the folder. An empty value removes the key. The value is kept when the folder is
+// removed, so that it applies again when a folder with the same ID is added later.
+func (fld *Folder) SetMetadata(key string, value string) error {
+	if key == "" {
+		return errEmptyMetadataKey
+	}
+	return fld.client.metadata.modify(func(state *metadataState) {
+		setMetadata(&state.Folders, fld.FolderID, key, value)
+	})
+}
+
+// Returns the keys for which a value is stored with the folder, in alphabetical order
+func (fld *Folder) MetadataKeys() *ListOfStrings {
+	var keys *ListOfStrings
+	fld.client.metadata.read(func(state *metadataState) {
+		keys = metadataKeys(state.Folders, fld.FolderID)
+	})
+	return keys
+}
+
+// Returns the value stored for the key using SetMetadata, or an empty string
+func (peer *Peer) Metadata(key string) string {
+	value := ""
+	peer.client.metadata.read(func(state *metadataState) {
+		value = getMetadata(state.Devices, peer.deviceID.String(), key)
+	})
+	return value
+}
+
+// Stores a value for the key with the device. An empty value removes the key. The value is kept when the device is
+// removed, so that it applies again when the device is added later.
+func (peer *Peer) SetMetadata(key string, value string) error {
+	if key == "" {
+		return errEmptyMetadataKey
+	}
+	return peer.client.metadata.modify(func(state *metadataState) {
+		setMetadata(&state.Devices, peer.deviceID.String(), key, value)
+	})
+}
+
+// Returns the keys for which a value is stored with the device, in alphabetical order
+func (peer *Peer) MetadataKeys() *ListOfStrings {
+	var keys *ListOfStrings
+	peer.client.metadata.read(func(state *metadataState) {
+		keys = metadataKeys(state.Devices, peer.deviceID.String())
+	})
+	return keys
+}
+
+// Returns the metadata of all folders and devices as JSON, so the app can keep it somewhere that survives a
+// reinstallation (e.g. iCloud key-value storage) and restore it with ImportMetadataJSON
+func (clt *Client) ExportMetadataJSON() ([]byte, error) {
+	var js []byte
+	var err error
+	clt.metadata.read(func(state *metadataState) {
+		js, err = json.Marshal(state)
+	})
+	return js, err
+}
+
+// Merges metadata exported with ExportMetadataJSON into the current metadata. Values that are already stored for a
+// folder or device are replaced.
+func (clt *Client) ImportMetadataJSON(js []byte) error {
+	var imported metadataState
+	if err := json.Unmarshal(js, &imported); err != nil {
+		return err
+	}
+
+	return clt.metadata.modify(func(state *metadataState) {
+		for folderID, values := range imported.Folders {
+			for key, value := range values {
+				if key == "" {
+					continue
+				}
+				setMetadata(&state.Folders, folderID, key, value)
+			}
+		}
+		for deviceID, values := range imported.Devices {
+			for key, value := range values {
+				if key == "" {
+					continue
+				}
+				setMetadata(&state.Devices, deviceID, key, value)
+			}
+		}
+	})
+}
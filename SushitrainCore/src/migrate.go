@@ -0,0 +1,268 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/syncthing"
+)
+
+// Kinds of data directories MigrateForeignDataDirectory understands
+const (
+	MigrationFlavorSyncthing = "syncthing" // A regular Syncthing configuration directory
+	MigrationFlavorMobius    = "mobius"    // A copy of the data of Möbius Sync
+)
+
+// Places inside a foreign data directory where the Syncthing configuration directory may be found, in order
+var migrationConfigSubdirectories = map[string][]string{
+	MigrationFlavorSyncthing: {"", "syncthing", ".config/syncthing", ".local/state/syncthing",
+		"Library/Application Support/Syncthing"},
+	MigrationFlavorMobius: {"", "Syncthing", "syncthing", "Library/Application Support/Syncthing",
+		"Library/Application Support"},
+}
+
+// Outcome of MigrateForeignDataDirectory
+type MigrationResult struct {
+	DeviceID string
+
+	// Folders whose files were found and moved into place, so they do not need to be synchronized again
+	MovedFolderIDs *ListOfStrings
+
+	// Folders whose files were not found; these will be synchronized again from peers
+	MissingFolderIDs *ListOfStrings
+
+	// Whether the index was taken over as well (when not, it is rebuilt by scanning)
+	IndexMigrated bool
+}
+
+// Takes over the identity, configuration and index of another Syncthing client, so that the user can switch to this
+// app without synchronizing everything again. `dataPath` is the configuration directory of the other client, or a copy
+// of its data in which common locations of the configuration directory are checked. Folder files are moved from their
+// configured location, or from a directory with the same name in `dataPath`, to the default location of this app.
+//
+// This can only be done before the client is loaded, and only when this client has no configuration or identity yet.
+func (clt *Client) MigrateForeignDataDirectory(dataPath string, flavor string) (*MigrationResult, error) {
+	if clt.app != nil || clt.config != nil {
+		return nil, errors.New("migration must happen before the client is loaded")
+	}
+	subdirectories, ok := migrationConfigSubdirectories[flavor]
+	if !ok {
+		return nil, errors.New("unknown data directory flavor")
+	}
+
+	sourceDir := ""
+	for _, subdirectory := range subdirectories {
+		candidate := filepath.Join(dataPath, subdirectory)
+		if isSyncthingConfigDirectory(candidate) {
+			sourceDir = candidate
+			break
+		}
+	}
+	if sourceDir == "" {
+		return nil, errors.New("no Syncthing configuration found in this directory")
+	}
+
+	targetDir := clt.options.ConfigPath
+	for _, name := range []string{ConfigFileName, CertFileName, KeyFileName} {
+		if _, err := os.Stat(filepath.Join(targetDir, name)); err == nil {
+			return nil, errors.New("this app already has a configuration or identity")
+		}
+	}
+	slog.Info("migrating foreign data directory", "source", sourceDir, "flavor", flavor)
+
+	// Identity
+	cert, err := tls.LoadX509KeyPair(filepath.Join(sourceDir, CertFileName), filepath.Join(sourceDir, KeyFileName))
+	if err != nil {
+		return nil, err
+	}
+	deviceID := protocol.NewDeviceID(cert.Certificate[0])
+
+	// Configuration
+	fd, err := os.Open(filepath.Join(sourceDir, ConfigFileName))
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := config.ReadXML(fd, deviceID)
+	fd.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	moved := make([]string, 0)
+	missing := make([]string, 0)
+	for i, fc := range cfg.Folders {
+		if fc.FilesystemType != config.FilesystemTypeBasic {
+			continue
+		}
+		dirName, err := folderDirectoryName(fc.ID)
+		if err != nil {
+			return nil, err
+		}
+		standardPath := path.Join(clt.filesPath, dirName)
+		if relocateFolderFiles(fc.Path, dataPath, standardPath) {
+			moved = append(moved, fc.ID)
+		} else {
+			missing = append(missing, fc.ID)
+		}
+		cfg.Folders[i].Path = standardPath
+	}
+	cfg.GUI.Enabled = false
+
+	// Index. The legacy index is converted when the client is loaded. The index must not contain folders whose files
+	// are missing, as Syncthing refuses to start a folder when its marker is missing while it has files in the index.
+	// These are removed from the index, so they are synchronized again like newly added folders. This is not possible
+	// for the legacy index, which is therefore not taken over when files of any folder are missing.
+	indexMigrated := false
+	for _, location := range []locations.LocationEnum{locations.Database, locations.LegacyDatabase} {
+		name := filepath.Base(locations.Get(location))
+		source := filepath.Join(sourceDir, name)
+		if _, err := os.Stat(source); err != nil {
+			continue
+		}
+		if location == locations.LegacyDatabase && len(missing) > 0 {
+			slog.Warn("not taking over legacy index because files of some folders are missing, it will be rebuilt",
+				"missing", missing)
+			break
+		}
+		if err := copyDirectory(source, filepath.Join(targetDir, name)); err != nil {
+			slog.Warn("could not copy index, it will be rebuilt", "source", source, "cause", err)
+			os.RemoveAll(filepath.Join(targetDir, name))
+			continue
+		}
+		if err := dropFoldersFromIndex(filepath.Join(targetDir, name), missing); err != nil {
+			slog.Warn("could not remove missing folders from index, it will be rebuilt", "cause", err)
+			os.RemoveAll(filepath.Join(targetDir, name))
+			continue
+		}
+		indexMigrated = true
+		break
+	}
+
+	// Write the identity and configuration last, so a failed migration can simply be tried again
+	for _, name := range []string{CertFileName, KeyFileName} {
+		if err := copyFile(filepath.Join(sourceDir, name), filepath.Join(targetDir, name), 0o600); err != nil {
+			return nil, err
+		}
+	}
+	out, err := osutil.CreateAtomic(filepath.Join(targetDir, ConfigFileName))
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.WriteXML(osutil.LineEndingsWriter(out)); err != nil {
+		out.Close()
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("migrated foreign data directory", "deviceID", deviceID, "moved", moved, "missing", missing,
+		"indexMigrated", indexMigrated)
+	return &MigrationResult{
+		DeviceID:         deviceID.String(),
+		MovedFolderIDs:   List(moved),
+		MissingFolderIDs: List(missing),
+		IndexMigrated:    indexMigrated,
+	}, nil
+}
+
+// Removes folders from a (copied) index
+func dropFoldersFromIndex(dbPath string, folderIDs []string) error {
+	if len(folderIDs) == 0 {
+		return nil
+	}
+	sdb, err := syncthing.OpenDatabase(dbPath, dbDeleteRetentionInterval)
+	if err != nil {
+		return err
+	}
+	for _, folderID := range folderIDs {
+		if err := sdb.DropFolder(folderID); err != nil {
+			sdb.Close()
+			return err
+		}
+	}
+	return sdb.Close()
+}
+
+func isSyncthingConfigDirectory(dir string) bool {
+	for _, name := range []string{ConfigFileName, CertFileName, KeyFileName} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// Moves the files of a folder from its original location (or a directory with the same name in the foreign data
+// directory) to the target path. Returns whether the files were found and moved.
+func relocateFolderFiles(originalPath string, dataPath string, targetPath string) bool {
+	if _, err := os.Stat(targetPath); err == nil {
+		return false
+	}
+
+	originalPath, _ = fs.ExpandTilde(originalPath)
+	for _, candidate := range []string{originalPath, filepath.Join(dataPath, filepath.Base(originalPath))} {
+		if info, err := os.Stat(candidate); err != nil || !info.IsDir() {
+			continue
+		}
+		if err := os.Rename(candidate, targetPath); err != nil {
+			slog.Warn("could not move folder files", "from", candidate, "to", targetPath, "cause", err)
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func copyDirectory(source string, target string) error {
+	return filepath.WalkDir(source, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, p)
+		if err != nil {
+			return err
+		}
+		destination := filepath.Join(target, rel)
+		if d.IsDir() {
+			return os.MkdirAll(destination, 0o700)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(p, destination, 0o600)
+	})
+}
+
+func copyFile(source string, target string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+type NATStatus struct {
+	// NAT type as detected through STUN (e.g. 'Full cone NAT'), empty when not (yet) known
+	NATType string
+
+	// External addresses through which we are reachable, as discovered through STUN or opened using UPnP/NAT-PMP
+	ExternalAddresses *ListOfStrings
+
+	// Port mappings currently opened on gateways, in the form 'protocol local -> external (gateway)'
+	PortMappings *ListOfStrings
+
+	// Number of gateways found that support UPnP or NAT-PMP
+	NATServicesFound int
+
+	// Most recent error when opening or renewing a port mapping, if any
+	LastMappingError     string
+	LastMappingErrorDate *Date
+}
+
+// Syncthing does not expose the state of its STUN and NAT services, but logs changes to it. We collect the information
+// from these log messages.
+type natTracker struct {
+	mutex                sync.Mutex
+	natTypes             map[string]string // listener URI -> NAT type
+	externalAddresses    map[string]string // listener URI -> external address
+	portMappings         map[string]string // protocol, local address and gateway -> description
+	natServicesFound     int
+	lastMappingError     string
+	lastMappingErrorTime time.Time
+}
+
+func newNATTracker() *natTracker {
+	return &natTracker{
+		natTypes:          map[string]string{},
+		externalAddresses: map[string]string{},
+		portMappings:      map[string]string{},
+	}
+}
+
+func (nt *natTracker) handleLogRecord(r slog.Record) {
+	attrs := map[string]slog.Value{}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value
+		return true
+	})
+
+	// Both the messages for adding and removing a port mapping contain these attributes
+	mappingKey := attrs["protocol"].String() + " " + attrs["external"].String() + " " + attrs["gateway"].String()
+
+	nt.mutex.Lock()
+	defer nt.mutex.Unlock()
+
+	switch r.Message {
+	case "Detected NAT type":
+		nt.natTypes[attrs["uri"].String()] = attrs["type"].String()
+	case "Resolved external address":
+		nt.externalAddresses[attrs["uri"].String()] = attrs["address"].String()
+	case "Detected NAT services":
+		if count := attrs["count"]; count.Kind() == slog.KindInt64 {
+			nt.natServicesFound = int(count.Int64())
+		}
+	case "New external port opened":
+		nt.portMappings[mappingKey] = fmt.Sprintf("%s %s -> %s (%s)", attrs["protocol"], attrs["local"], attrs["external"], attrs["gateway"])
+	case "Removing external open port":
+		delete(nt.portMappings, mappingKey)
+	case "Failed to acquire open port", "Failed to renew open port":
+		nt.lastMappingError = attrs["mapping"].String() + ": " + attrs["error"].String()
+		nt.lastMappingErrorTime = r.Time
+	}
+}
+
+// Returns what is known about NAT traversal: the NAT type and external addresses found through STUN, and port mappings
+// made through UPnP or NAT-PMP.
+func (clt *Client) NATStatus() *NATStatus {
+	nt := clt.natTracker
+	nt.mutex.Lock()
+	defer nt.mutex.Unlock()
+
+	status := &NATStatus{
+		ExternalAddresses: List(slices.Sorted(maps.Values(nt.externalAddresses))),
+		PortMappings:      List(slices.Sorted(maps.Values(nt.portMappings))),
+		NATServicesFound:  nt.natServicesFound,
+		LastMappingError:  nt.lastMappingError,
+	}
+
+	// There is one NAT type per (QUIC) listener, but in practice they will all be the same
+	for _, natType := range slices.Sorted(maps.Values(nt.natTypes)) {
+		status.NATType = natType
+		break
+	}
+
+	if nt.lastMappingError != "" {
+		status.LastMappingErrorDate = &Date{time: nt.lastMappingErrorTime}
+	}
+	return status
+}
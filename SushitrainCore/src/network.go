@@ -0,0 +1,100 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// How long to wait for the network path to settle before restarting connections. The system often reports several
+// changes in a row (e.g. when WiFi drops out and cellular takes over), which should lead to a single restart.
+const networkChangeDelay = 2 * time.Second
+
+// Should be called by the app when the network path changes (e.g. when switching between WiFi and cellular). Instead
+// of waiting for the reconnect interval, this restarts the listeners and discovery and redials all devices that are
+// not connected. Changes reported in quick succession are coalesced: the restart happens once no change has been
+// reported for a little while.
+func (clt *Client) NetworkChanged() error {
+	if clt.app == nil || clt.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+	if clt.networkChangeTimer != nil {
+		clt.networkChangeTimer.Reset(networkChangeDelay)
+		return nil
+	}
+	clt.networkChangeTimer = time.AfterFunc(networkChangeDelay, func() {
+		clt.mutex.Lock()
+		clt.networkChangeTimer = nil
+		clt.mutex.Unlock()
+		if err := clt.restartConnections(); err != nil {
+			slog.Warn("could not restart connections after network change", "cause", err)
+		}
+	})
+	return nil
+}
+
+// Syncthing does not offer a way to restart connections directly. We apply a configuration in which listeners,
+// discovery and the disconnected devices are switched off, and then restore the original settings. Syncthing will then
+// recreate the listeners and discovery clients, and dial the devices that were 'unpaused' immediately. The
+// configuration lock is held throughout, so that no other change can save the intermediate configuration or be
+// overwritten when the original settings are restored. Only the restored configuration is saved (which is the same as
+// what was saved before).
+func (clt *Client) restartConnections() error {
+	if clt.app == nil || clt.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	clt.configMutex.Lock()
+	defer clt.configMutex.Unlock()
+
+	original := clt.config.RawCopy()
+	redial := make([]protocol.DeviceID, 0)
+	for _, dev := range original.Devices {
+		if !dev.Paused && dev.DeviceID != clt.deviceID() && !clt.app.Internals.IsConnectedTo(dev.DeviceID) {
+			redial = append(redial, dev.DeviceID)
+		}
+	}
+	slog.Info("network changed, restarting connections", "redial", len(redial))
+
+	waiter, err := clt.config.Modify(func(cfg *config.Configuration) {
+		cfg.Options.RawListenAddresses = nil
+		cfg.Options.GlobalAnnEnabled = false
+		cfg.Options.LocalAnnEnabled = false
+		for i := range cfg.Devices {
+			if slices.Contains(redial, cfg.Devices[i].DeviceID) {
+				cfg.Devices[i].Paused = true
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	waiter.Wait()
+
+	waiter, err = clt.config.Modify(func(cfg *config.Configuration) {
+		cfg.Options.RawListenAddresses = original.Options.RawListenAddresses
+		cfg.Options.GlobalAnnEnabled = original.Options.GlobalAnnEnabled
+		cfg.Options.LocalAnnEnabled = original.Options.LocalAnnEnabled
+		for i := range cfg.Devices {
+			if slices.Contains(redial, cfg.Devices[i].DeviceID) {
+				cfg.Devices[i].Paused = false
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	waiter.Wait()
+	return clt.saveConfiguration()
+}
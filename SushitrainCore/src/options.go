@@ -0,0 +1,127 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+// Options for creating a client (see NewClientWithOptions)
+type clientOptions struct {
+	// Directory containing the configuration, identity and database
+	ConfigPath string `json:"configPath"`
+
+	// Directory containing the synchronized folders
+	FilesPath string `json:"filesPath"`
+
+	// Write the log to a file in FilesPath
+	SaveLog bool `json:"saveLog"`
+
+	// Use a temporary database that is removed when the client stops, instead of the database in ConfigPath
+	InMemoryDatabase bool `json:"inMemoryDatabase"`
+
+	// Never write the configuration; all configuration changes fail with ErrReadOnly. Other state kept in ConfigPath is
+	// only changed in memory.
+	ReadOnly bool `json:"readOnly"`
+
+	// Listen addresses to use instead of the configured ones (e.g. ["tcp://:22001"]). Saved with the configuration
+	// unless ReadOnly is set.
+	ListenAddresses []string `json:"listenAddresses,omitempty"`
+
+	// Disable local and global discovery. Saved with the configuration unless ReadOnly is set.
+	DisableDiscovery bool `json:"disableDiscovery"`
+
+	// Build metadata reported to other devices, when different from the defaults
+	Version string `json:"version,omitempty"`
+	Host    string `json:"host,omitempty"`
+	User    string `json:"user,omitempty"`
+
+	// Start with all folders paused, so they are not scanned. Requires ReadOnly, as the folders would otherwise be
+	// paused permanently.
+	SkipInitialScan bool `json:"skipInitialScan"`
+
+	// When another process is already using ConfigPath, continue in a reduced read-only mode instead of failing with
+	// ErrAlreadyRunning
+	AttachIfRunning bool `json:"attachIfRunning"`
+
+	// Set when the client continues in reduced mode because another instance is running
+	attached bool
+
+	// Name of the profile to use (see NewClientForProfile), or empty for the default profile
+	Profile string `json:"profile,omitempty"`
+
+	// Directories containing the named profiles, set by resolveProfile
+	profilesConfigPath string
+	profilesFilesPath  string
+
+	// Set for clients created by tests, which may be loaded in the same process as other test clients
+	testing bool
+}
+
+// Creates a client using options provided as a JSON object with the keys `configPath`, `filesPath`, `saveLog`,
+// `inMemoryDatabase`, `readOnly`, `listenAddresses`, `disableDiscovery`, `version`, `host`, `user`, `skipInitialScan`,
+// `attachIfRunning` and `profile`. This allows for a lighter client, e.g. for use in an app extension.
+func NewClientWithOptions(optionsJSON []byte) (*Client, error) {
+	var options clientOptions
+	if err := json.Unmarshal(optionsJSON, &options); err != nil {
+		return nil, err
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if err := options.resolveProfile(); err != nil {
+		return nil, err
+	}
+	return newClient(options), nil
+}
+
+func (options *clientOptions) validate() error {
+	if options.ConfigPath == "" || options.FilesPath == "" {
+		return errors.New("configPath and filesPath are required")
+	}
+	if options.SkipInitialScan && !options.ReadOnly {
+		return errors.New("skipInitialScan requires readOnly")
+	}
+	return nil
+}
+
+// Applies the overrides in the options to the configuration while it is loaded
+func (options *clientOptions) apply(conf *config.Configuration) {
+	if len(options.ListenAddresses) > 0 {
+		conf.Options.RawListenAddresses = options.ListenAddresses
+	}
+
+	if options.DisableDiscovery {
+		conf.Options.GlobalAnnEnabled = false
+		conf.Options.LocalAnnEnabled = false
+	}
+
+	if options.SkipInitialScan {
+		for i := range conf.Folders {
+			conf.Folders[i].Paused = true
+		}
+	}
+
+	// Keep test clients from reaching out to anything but each other
+	if options.testing {
+		conf.Options.RelaysEnabled = false
+		conf.Options.NATEnabled = false
+		conf.Options.URAccepted = -1
+		conf.Options.URURL = ""
+		conf.Defaults.Folder.FSWatcherEnabled = false
+	}
+
+	// Stay away from the devices the other instance is connected to
+	if options.attached {
+		conf.Options.RelaysEnabled = false
+		for i := range conf.Devices {
+			conf.Devices[i].Paused = true
+		}
+	}
+}
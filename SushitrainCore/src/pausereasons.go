@@ -0,0 +1,76 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"slices"
+)
+
+// Why a folder or device is paused (see Folder.PauseReason and Peer.PauseReason)
+const (
+	PauseReasonNone          = ""
+	PauseReasonUser          = "user"          // Paused by the user (or for a reason we do not know)
+	PauseReasonCellular      = "cellular"      // Not allowed to sync on cellular (see Folder.SetSyncOnCellular)
+	PauseReasonPower         = "power"         // Paused while the device is critically hot or low on battery
+	PauseReasonDeleteGuard   = "deleteGuard"   // Held because peers want to delete too many files
+	PauseReasonInaccessible  = "inaccessible"  // The folder path cannot be read
+	PauseReasonOtherInstance = "otherInstance" // Another instance of the app is connected to the device
+)
+
+// Returns why the folder is paused (one of the PauseReason... constants), or an empty string when it is not paused
+func (fld *Folder) PauseReason() string {
+	if !fld.IsPaused() {
+		return PauseReasonNone
+	}
+	if fld.isHeldByDeleteGuard() {
+		return PauseReasonDeleteGuard
+	}
+
+	pausedByCellular := false
+	fld.client.cellular.read(func(policy *cellularPolicy) {
+		pausedByCellular = slices.Contains(policy.PausedByPolicy, fld.FolderID)
+	})
+	if pausedByCellular {
+		return PauseReasonCellular
+	}
+
+	pausedByPower := false
+	fld.client.power.read(func(policy *powerPolicy) {
+		pausedByPower = slices.Contains(policy.PausedByPolicy, fld.FolderID)
+	})
+	if pausedByPower {
+		return PauseReasonPower
+	}
+
+	if !fld.IsAccessible() {
+		return PauseReasonInaccessible
+	}
+	return PauseReasonUser
+}
+
+// Returns whether the folder will be resumed without the user doing anything once the reason it was paused for no
+// longer applies (i.e. when it was paused for being on cellular or because of the power state). Folders held by the
+// delete guard need approval (see Folder.ApprovePendingDeletes), inaccessible folders need Folder.Reactivate. Resuming
+// such a folder manually is possible, but it will be paused again the next time the condition arises.
+func (fld *Folder) ResumesAutomatically() bool {
+	switch fld.PauseReason() {
+	case PauseReasonCellular, PauseReasonPower:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns why the device is paused (one of the PauseReason... constants), or an empty string when it is not paused
+func (peer *Peer) PauseReason() string {
+	if !peer.IsPaused() {
+		return PauseReasonNone
+	}
+	if peer.client.options.attached {
+		return PauseReasonOtherInstance
+	}
+	return PauseReasonUser
+}
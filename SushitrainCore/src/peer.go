@@ -6,6 +6,7 @@
 package sushitrain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/config"
@@ -15,6 +16,7 @@ import (
 type Peer struct {
 	client   *Client
 	deviceID protocol.DeviceID
+	batch    *ConfigurationBatch // When set, configuration changes are collected in the batch
 }
 
 func (peer *Peer) DeviceID() string {
@@ -46,12 +48,20 @@ func (peer *Peer) LastSeen() *Date {
 	if err != nil {
 		return nil
 	}
-	return &Date{time: stats[peer.deviceID].LastSeen}
+
+	// Use our own connection history when it is more recent than Syncthing's statistics
+	lastSeen := stats[peer.deviceID].LastSeen
+	if rec := peer.connectionRecord(); rec.LastSeen.After(lastSeen) {
+		lastSeen = rec.LastSeen
+	}
+	return &Date{time: lastSeen}
 }
 
 func (peer *Peer) deviceConfiguration() *config.DeviceConfiguration {
-	devs := peer.client.config.Devices()
-	dev, ok := devs[peer.deviceID]
+	if peer.batch != nil {
+		return peer.batch.deviceConfiguration(peer.deviceID)
+	}
+	dev, ok := peer.client.config.Devices()[peer.deviceID]
 	if !ok {
 		return nil
 	}
@@ -69,13 +79,8 @@ func (peer *Peer) Name() string {
 }
 
 func (peer *Peer) SetName(name string) error {
-	return peer.client.changeConfiguration(func(cfg *config.Configuration) {
-		dc, ok := cfg.DeviceMap()[peer.deviceID]
-		if !ok {
-			return
-		}
+	return peer.changeDeviceConfiguration(func(dc *config.DeviceConfiguration) {
 		dc.Name = name
-		cfg.SetDevice(dc)
 	})
 }
 
@@ -95,13 +100,8 @@ func (peer *Peer) IsConnected() bool {
 }
 
 func (peer *Peer) SetPaused(paused bool) error {
-	return peer.client.changeConfiguration(func(cfg *config.Configuration) {
-		dc, ok := cfg.DeviceMap()[peer.deviceID]
-		if !ok {
-			return
-		}
+	return peer.changeDeviceConfiguration(func(dc *config.DeviceConfiguration) {
 		dc.Paused = paused
-		cfg.SetDevice(dc)
 	})
 }
 
@@ -156,22 +156,64 @@ func (peer *Peer) IntroducedBy() *Peer {
 	return peer.client.PeerWithID(did.String())
 }
 
+// Returns which data is compressed when sent to this device: "never", "metadata" (the default) or "always"
+func (peer *Peer) Compression() string {
+	compression := config.CompressionMetadata
+	if dc := peer.deviceConfiguration(); dc != nil {
+		compression = dc.Compression
+	}
+	text, _ := compression.MarshalText()
+	return string(text)
+}
+
+// Sets which data is compressed when sent to this device ("never", "metadata" or "always"). Compression saves
+// bandwidth (e.g. over relays) at the cost of CPU time. The setting is used for new connections, so it takes effect the
+// next time the device connects.
+func (peer *Peer) SetCompression(mode string) error {
+	var compression config.Compression
+	switch mode {
+	case "never":
+		compression = config.CompressionNever
+	case "metadata":
+		compression = config.CompressionMetadata
+	case "always":
+		compression = config.CompressionAlways
+	default:
+		return errors.New("invalid compression mode")
+	}
+
+	return peer.changeDeviceConfiguration(func(dc *config.DeviceConfiguration) {
+		dc.Compression = compression
+	})
+}
+
+// Changes the configuration of the device in place, as it is when the change is applied, so that only the fields
+// changed by `block` are affected. When the device was obtained from a configuration batch, the change is collected in
+// the batch.
 func (peer *Peer) changeDeviceConfiguration(block func(*config.DeviceConfiguration)) error {
-	return peer.client.changeConfiguration(func(cfg *config.Configuration) {
-		dc, ok := cfg.DeviceMap()[peer.deviceID]
-		if !ok {
-			return
+	change := func(cfg *config.Configuration) {
+		if _, index, ok := cfg.Device(peer.deviceID); ok {
+			block(&cfg.Devices[index])
 		}
-		block(&dc)
-		cfg.SetDevice(dc)
-	})
+	}
+	if peer.batch != nil {
+		return peer.batch.change(change)
+	}
+	return peer.client.changeConfiguration(change)
 }
 
 func (peer *Peer) IsSelf() bool {
 	return peer.client.deviceID().Equals(peer.deviceID)
 }
 
-func (peer *Peer) Remove() error {
+// Remove the device from the configuration. When unshareFolders is set, the device is also removed from the device
+// list of every folder it shares. Pending folder offers from the device are discarded by Syncthing once the device
+// no longer exists in the configuration.
+func (peer *Peer) Remove(unshareFolders bool) error {
+	if peer.IsSelf() {
+		return errors.New("cannot remove own device")
+	}
+
 	return peer.client.changeConfiguration(func(cfg *config.Configuration) {
 		devices := make([]config.DeviceConfiguration, 0)
 		for _, dc := range cfg.Devices {
@@ -180,9 +222,30 @@ func (peer *Peer) Remove() error {
 			}
 		}
 		cfg.Devices = devices
+
+		if unshareFolders {
+			for fi, fc := range cfg.Folders {
+				shares := make([]config.FolderDeviceConfiguration, 0, len(fc.Devices))
+				for _, fdc := range fc.Devices {
+					if fdc.DeviceID != peer.deviceID {
+						shares = append(shares, fdc)
+					}
+				}
+				cfg.Folders[fi].Devices = shares
+			}
+		}
 	})
 }
 
+// Returns how far this device is in synchronizing the folder with the given ID, as far as we know
+func (peer *Peer) CompletionForFolder(folderID string) (*Completion, error) {
+	fld := peer.client.FolderWithID(folderID)
+	if fld == nil {
+		return nil, ErrFolderNotFound
+	}
+	return fld.CompletionForDevice(peer.deviceID.String())
+}
+
 func (peer *Peer) SharedFolderIDs() *ListOfStrings {
 	folders := peer.client.config.Folders()
 	sharedWith := make([]string, 0)
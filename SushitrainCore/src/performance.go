@@ -0,0 +1,114 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+const (
+	PerformanceProfileBatterySaver  = "battery-saver"
+	PerformanceProfileBalanced      = "balanced"
+	PerformanceProfileMaxThroughput = "max-throughput"
+	PerformanceProfileCustom        = "custom"
+
+	// Syncthing's default for FolderConfiguration.MaxConcurrentWrites
+	defaultMaxConcurrentWrites = 16
+)
+
+type performanceProfile struct {
+	// Folder settings. Zero means 'use the Syncthing default'.
+	hashers             int
+	copiers             int
+	pullerMaxPendingKiB int
+	maxConcurrentWrites int
+
+	// Device-wide connection limits. Zero means 'no limit'.
+	connectionLimitEnough int
+	connectionLimitMax    int
+}
+
+var performanceProfiles = map[string]performanceProfile{
+	PerformanceProfileBatterySaver: {
+		hashers:               1,
+		copiers:               1,
+		pullerMaxPendingKiB:   8 * 1024,
+		maxConcurrentWrites:   2,
+		connectionLimitEnough: 2,
+		connectionLimitMax:    4,
+	},
+	PerformanceProfileBalanced: {},
+	PerformanceProfileMaxThroughput: {
+		copiers:             4,
+		pullerMaxPendingKiB: 128 * 1024,
+		maxConcurrentWrites: 64,
+	},
+}
+
+func (profile performanceProfile) matchesFolder(fc *config.FolderConfiguration) bool {
+	// Syncthing replaces a zero value for this setting with its default when loading the configuration
+	maxConcurrentWrites := profile.maxConcurrentWrites
+	if maxConcurrentWrites == 0 {
+		maxConcurrentWrites = defaultMaxConcurrentWrites
+	}
+
+	return fc.Hashers == profile.hashers && fc.Copiers == profile.copiers &&
+		fc.PullerMaxPendingKiB == profile.pullerMaxPendingKiB && fc.MaxConcurrentWrites == maxConcurrentWrites
+}
+
+func (profile performanceProfile) applyToFolder(fc *config.FolderConfiguration) {
+	fc.Hashers = profile.hashers
+	fc.Copiers = profile.copiers
+	fc.PullerMaxPendingKiB = profile.pullerMaxPendingKiB
+	fc.MaxConcurrentWrites = profile.maxConcurrentWrites
+}
+
+// Applies one of the performance profiles (PerformanceProfileBatterySaver, PerformanceProfileBalanced or
+// PerformanceProfileMaxThroughput) to the hashing, copying and writing concurrency of this folder.
+func (fld *Folder) SetPerformanceProfile(profileName string) error {
+	profile, ok := performanceProfiles[profileName]
+	if !ok {
+		return errors.New("unknown performance profile")
+	}
+
+	return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+		profile.applyToFolder(fc)
+	})
+}
+
+// Returns the name of the performance profile matching this folder's settings, or PerformanceProfileCustom if the
+// settings were changed otherwise.
+func (fld *Folder) PerformanceProfile() string {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return PerformanceProfileCustom
+	}
+
+	for name, profile := range performanceProfiles {
+		if profile.matchesFolder(fc) {
+			return name
+		}
+	}
+	return PerformanceProfileCustom
+}
+
+// Applies the performance profile to all folders as well as to the device-wide connection limits
+func (clt *Client) SetPerformanceProfile(profileName string) error {
+	profile, ok := performanceProfiles[profileName]
+	if !ok {
+		return errors.New("unknown performance profile")
+	}
+
+	return clt.changeConfiguration(func(cfg *config.Configuration) {
+		for idx := range cfg.Folders {
+			profile.applyToFolder(&cfg.Folders[idx])
+		}
+		cfg.Options.ConnectionLimitEnough = profile.connectionLimitEnough
+		cfg.Options.ConnectionLimitMax = profile.connectionLimitMax
+	})
+}
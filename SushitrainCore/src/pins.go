@@ -0,0 +1,69 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"slices"
+)
+
+const pinsFileName = "pins.json"
+
+var errPinned = errors.New("file is pinned, unpin it first")
+
+// Pinned files are always kept locally and kept up to date with remote changes. Unlike a plain selection, a pin is not
+// undone by deselecting or by freeing up space.
+func (fld *Folder) PinnedPaths() *ListOfStrings {
+	paths := make([]string, 0)
+	fld.client.pins.read(func(pins *map[string][]string) {
+		paths = slices.Clone((*pins)[fld.FolderID])
+	})
+	slices.Sort(paths)
+	return List(paths)
+}
+
+func (fld *Folder) isPinned(path string) bool {
+	pinned := false
+	fld.client.pins.read(func(pins *map[string][]string) {
+		pinned = slices.Contains((*pins)[fld.FolderID], path)
+	})
+	return pinned
+}
+
+func (entry *Entry) IsPinned() bool {
+	return entry.Folder.isPinned(entry.info.Name)
+}
+
+// Pins or unpins this file. Pinning a file in a selective folder also selects it. Unpinning leaves the selection as is.
+func (entry *Entry) SetPinned(pinned bool) error {
+	if entry.IsDirectory() {
+		return errors.New("only files can be pinned")
+	}
+
+	path := entry.info.Name
+	if pinned && entry.Folder.IsSelective() && !entry.IsSelected() {
+		if err := entry.SetExplicitlySelected(true); err != nil {
+			return err
+		}
+	}
+
+	return entry.Folder.client.pins.modify(func(pins *map[string][]string) {
+		folderPins := (*pins)[entry.Folder.FolderID]
+		if pinned {
+			if !slices.Contains(folderPins, path) {
+				folderPins = append(folderPins, path)
+			}
+		} else {
+			folderPins = slices.DeleteFunc(folderPins, func(p string) bool { return p == path })
+		}
+
+		if len(folderPins) == 0 {
+			delete(*pins, entry.Folder.FolderID)
+		} else {
+			(*pins)[entry.Folder.FolderID] = folderPins
+		}
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+const placeholderFoldersFileName = "placeholders.json"
+
+// Returned when trying to select files in a placeholder folder (see Folder.SetPlaceholder)
+var ErrPlaceholderFolder = errors.New("files cannot be selected in a placeholder folder")
+
+type placeholderRecord struct {
+	// The type of the folder before it became a placeholder, restored when it stops being one
+	PreviousType config.FolderType `json:"previousType"`
+}
+
+// Returns whether the folder is a placeholder folder (see SetPlaceholder)
+func (fld *Folder) IsPlaceholder() bool {
+	isPlaceholder := false
+	fld.client.placeholders.read(func(records *map[string]*placeholderRecord) {
+		_, isPlaceholder = (*records)[fld.FolderID]
+	})
+	return isPlaceholder
+}
+
+// Turns the folder into a placeholder folder, or back into a selective folder without any selected files. A placeholder
+// folder exchanges indexes (so that its files can be browsed, searched and downloaded on demand) but never pulls
+// anything, and none of its files can be selected. Unlike a selective folder with nothing selected, it is made
+// receive-only, so that files that happen to exist locally are never sent to other devices and cannot cause
+// conflicts. Placeholder folders are not counted in OverallSyncProgress. Other devices do not count the files of the
+// folder as needed by this device: when Syncthing skips a needed file because it is ignored, it records the file as
+// ignored at that version in our index.
+func (fld *Folder) SetPlaceholder(placeholder bool) error {
+	// Ignores are changed right away, so the folder type has to be as well
+	fld = fld.unbatched()
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return ErrFolderNotFound
+	}
+	if placeholder == fld.IsPlaceholder() {
+		return nil
+	}
+	if placeholder && (fc.Type == config.FolderTypeSendOnly || fc.Type == config.FolderTypeReceiveEncrypted) {
+		return errors.New("send-only and encrypted folders cannot be placeholders")
+	}
+
+	slog.Info("setting placeholder mode", "folderID", fld.FolderID, "placeholder", placeholder)
+	return fld.whilePaused(func() error {
+		fld.cachedIgnore.matcher = nil // Purge our cache
+		if err := fld.client.app.Internals.SetIgnores(fld.FolderID, []string{"*"}); err != nil {
+			return err
+		}
+
+		folderType := config.FolderTypeReceiveOnly
+		err := fld.client.placeholders.modify(func(records *map[string]*placeholderRecord) {
+			if placeholder {
+				(*records)[fld.FolderID] = &placeholderRecord{PreviousType: fc.Type}
+			} else {
+				if record, ok := (*records)[fld.FolderID]; ok {
+					folderType = record.PreviousType
+				}
+				delete(*records, fld.FolderID)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		return fld.changeFolderConfiguration(func(fc *config.FolderConfiguration) {
+			fc.Type = folderType
+		})
+	})
+}
+
+// Remembers the type a placeholder folder should get once it stops being one
+func (fld *Folder) setPlaceholderPreviousType(folderType config.FolderType) error {
+	return fld.client.placeholders.modify(func(records *map[string]*placeholderRecord) {
+		if record, ok := (*records)[fld.FolderID]; ok {
+			record.PreviousType = folderType
+		}
+	})
+}
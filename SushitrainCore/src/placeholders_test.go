@@ -0,0 +1,59 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestPlaceholderFolderIsNotNeededByPeers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts two clients")
+	}
+
+	first, second := startConnectedTestClients(t)
+	shareTestFolder(t, first, second, "shared")
+	if err := second.FolderWithID("shared").SetPlaceholder(true); err != nil {
+		t.Fatal(err)
+	}
+
+	ffs := first.FolderWithID("shared").folderConfiguration().Filesystem()
+	file, err := ffs.Create("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte("hello placeholder")); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err := first.app.Internals.ScanFolderSubdirs("shared", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(testClientConnectTimeout)
+	for {
+		completion, err := first.app.Internals.Completion(second.deviceID(), "shared")
+		if err == nil && completion.GlobalBytes > 0 && completion.NeedBytes == 0 && completion.NeedItems == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("peer still considers files needed by the placeholder folder: %+v (%v)", completion, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// The file was skipped rather than pulled
+	local, ok, err := second.sdb.GetDeviceFile("shared", protocol.LocalDeviceID, "file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || !local.IsIgnored() {
+		t.Errorf("expected the file to be recorded as ignored, got %v", local)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+const powerPolicyFileName = "power.json"
+
+// Thermal states, as reported by the operating system (e.g. ProcessInfo.ThermalState on Apple platforms)
+const (
+	ThermalStateNominal  = "nominal"
+	ThermalStateFair     = "fair"
+	ThermalStateSerious  = "serious"
+	ThermalStateCritical = "critical"
+)
+
+const (
+	// Below these battery levels (when not charging), pulling is throttled or non-priority folders are paused
+	lowBatteryPercent      = 20
+	criticalBatteryPercent = 10
+)
+
+type powerLevel int
+
+const (
+	powerLevelNormal      powerLevel = iota
+	powerLevelConstrained            // Throttle hashing, copying and writing
+	powerLevelCritical               // Also pause non-priority folders (when enabled)
+)
+
+type folderConcurrency struct {
+	Hashers             int `json:"hashers"`
+	Copiers             int `json:"copiers"`
+	PullerMaxPendingKiB int `json:"pullerMaxPendingKiB"`
+	MaxConcurrentWrites int `json:"maxConcurrentWrites"`
+}
+
+type powerPolicy struct {
+	// Folders that keep syncing when the device is critically hot or low on battery
+	PriorityFolders []string `json:"priorityFolders"`
+
+	// Whether to pause non-priority folders when the device is critically hot or low on battery
+	PauseNonPriority bool `json:"pauseNonPriority"`
+
+	// The settings of folders we throttled, so they can be restored afterwards
+	Throttled map[string]folderConcurrency `json:"throttled"`
+
+	// Folders that we paused because of this policy (and that we should therefore resume later)
+	PausedByPolicy []string `json:"pausedByPolicy"`
+}
+
+// Returns whether the folder should be paused at the power level
+func (policy *powerPolicy) pausesFolder(level powerLevel, folderID string) bool {
+	return level == powerLevelCritical && policy.PauseNonPriority && !slices.Contains(policy.PriorityFolders, folderID)
+}
+
+// Should be called by the app whenever the thermal state of the device changes (one of the ThermalState... constants).
+// Pulling is throttled in the serious state; non-priority folders are paused in the critical state when enabled.
+func (clt *Client) SetThermalState(state string) error {
+	if state != ThermalStateNominal && state != ThermalStateFair && state != ThermalStateSerious &&
+		state != ThermalStateCritical {
+		return errors.New("invalid thermal state")
+	}
+
+	clt.mutex.Lock()
+	changed := clt.thermalState != state
+	clt.thermalState = state
+	clt.mutex.Unlock()
+
+	if !changed {
+		return nil
+	}
+	slog.Info("thermal state changed", "state", state)
+	return clt.applyPowerPolicy()
+}
+
+// Should be called by the app whenever the battery level or charging state changes. Pulling is throttled when the
+// battery is low and not charging; non-priority folders are paused when it is critically low, when enabled.
+func (clt *Client) SetBatteryLevel(percent int, charging bool) error {
+	if percent < 0 || percent > 100 {
+		return errors.New("battery level must be between 0 and 100 percent")
+	}
+
+	clt.mutex.Lock()
+	changed := clt.batteryPercent != percent || clt.batteryCharging != charging || !clt.chargingKnown
+	clt.batteryPercent = percent
+	clt.batteryCharging = charging
+	clt.chargingKnown = true
+	clt.mutex.Unlock()
+
+	if !changed {
+		return nil
+	}
+	slog.Info("battery state changed", "percent", percent, "charging", charging)
+	if err := clt.applyPowerPolicy(); err != nil {
+		return err
+	}
+	return clt.applyScanWindows()
+}
+
+func (clt *Client) powerLevel() powerLevel {
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+
+	onBattery := !clt.batteryCharging && clt.batteryPercent >= 0
+	switch {
+	case clt.thermalState == ThermalStateCritical || (onBattery && clt.batteryPercent < criticalBatteryPercent):
+		return powerLevelCritical
+	case clt.thermalState == ThermalStateSerious || (onBattery && clt.batteryPercent < lowBatteryPercent):
+		return powerLevelConstrained
+	default:
+		return powerLevelNormal
+	}
+}
+
+// Returns whether pulling is currently throttled because of the thermal state or battery level
+func (clt *Client) IsThrottledForPower() bool {
+	return clt.powerLevel() >= powerLevelConstrained
+}
+
+// Returns whether non-priority folders are paused when the device is critically hot or low on battery
+func (clt *Client) PausesNonPriorityFolders() bool {
+	pause := false
+	clt.power.read(func(policy *powerPolicy) {
+		pause = policy.PauseNonPriority
+	})
+	return pause
+}
+
+func (clt *Client) SetPausesNonPriorityFolders(pause bool) error {
+	err := clt.power.modify(func(policy *powerPolicy) {
+		policy.PauseNonPriority = pause
+	})
+	if err != nil {
+		return err
+	}
+	return clt.applyPowerPolicy()
+}
+
+// Returns whether this folder keeps syncing when non-priority folders are paused (see SetPausesNonPriorityFolders)
+func (fld *Folder) IsPriority() bool {
+	priority := false
+	fld.client.power.read(func(policy *powerPolicy) {
+		priority = slices.Contains(policy.PriorityFolders, fld.FolderID)
+	})
+	return priority
+}
+
+func (fld *Folder) SetPriority(priority bool) error {
+	err := fld.client.power.modify(func(policy *powerPolicy) {
+		policy.PriorityFolders = slices.DeleteFunc(policy.PriorityFolders, func(id string) bool { return id == fld.FolderID })
+		if priority {
+			policy.PriorityFolders = append(policy.PriorityFolders, fld.FolderID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return fld.client.applyPowerPolicy()
+}
+
+// Throttles or pauses folders according to the current power level, and restores the folders we throttled or paused
+// when the level allows it again
+func (clt *Client) applyPowerPolicy() error {
+	if clt.config == nil {
+		return ErrStillLoading
+	}
+
+	level := clt.powerLevel()
+	throttleProfile := performanceProfiles[PerformanceProfileBatterySaver]
+	throttle := map[string]bool{}
+	restore := map[string]folderConcurrency{}
+	var pause, resume []string
+
+	err := clt.power.modify(func(policy *powerPolicy) {
+		if policy.Throttled == nil {
+			policy.Throttled = map[string]folderConcurrency{}
+		}
+		shouldBePaused := func(folderID string) bool {
+			return policy.pausesFolder(level, folderID)
+		}
+
+		folders := clt.config.Folders()
+		for folderID, fc := range folders {
+			_, throttled := policy.Throttled[folderID]
+			if level >= powerLevelConstrained && !throttled && !throttleProfile.matchesFolder(&fc) {
+				throttle[folderID] = true
+				policy.Throttled[folderID] = folderConcurrency{
+					Hashers:             fc.Hashers,
+					Copiers:             fc.Copiers,
+					PullerMaxPendingKiB: fc.PullerMaxPendingKiB,
+					MaxConcurrentWrites: fc.MaxConcurrentWrites,
+				}
+			}
+			if shouldBePaused(folderID) && !fc.Paused && !slices.Contains(policy.PausedByPolicy, folderID) {
+				pause = append(pause, folderID)
+			}
+		}
+
+		if level < powerLevelConstrained {
+			for folderID, concurrency := range policy.Throttled {
+				restore[folderID] = concurrency
+			}
+			policy.Throttled = map[string]folderConcurrency{}
+		}
+
+		for _, folderID := range policy.PausedByPolicy {
+			if !shouldBePaused(folderID) {
+				resume = append(resume, folderID)
+			}
+		}
+		policy.PausedByPolicy = slices.DeleteFunc(policy.PausedByPolicy, func(id string) bool {
+			return slices.Contains(resume, id)
+		})
+		policy.PausedByPolicy = append(policy.PausedByPolicy, pause...)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Folders that may not sync on the current network stay paused, from now on because of the cellular policy
+	resume, err = clt.keepPausedForCellular(resume)
+	if err != nil {
+		return err
+	}
+	if len(throttle) == 0 && len(restore) == 0 && len(pause) == 0 && len(resume) == 0 {
+		return nil
+	}
+
+	slog.Info("applying power policy", "level", level, "throttle", len(throttle), "restore", len(restore), "pause", pause,
+		"resume", resume)
+	return clt.changeConfiguration(func(cfg *config.Configuration) {
+		for i := range cfg.Folders {
+			fc := &cfg.Folders[i]
+			if throttle[fc.ID] {
+				throttleProfile.applyToFolder(fc)
+			} else if concurrency, ok := restore[fc.ID]; ok && throttleProfile.matchesFolder(fc) {
+				// Only restore when the settings were not changed in the meantime
+				fc.Hashers = concurrency.Hashers
+				fc.Copiers = concurrency.Copiers
+				fc.PullerMaxPendingKiB = concurrency.PullerMaxPendingKiB
+				fc.MaxConcurrentWrites = concurrency.MaxConcurrentWrites
+			}
+
+			if slices.Contains(pause, fc.ID) {
+				fc.Paused = true
+			} else if slices.Contains(resume, fc.ID) {
+				fc.Paused = false
+			}
+		}
+	})
+}
+
+// Takes over folders that another policy is about to resume, when they should stay paused because of the power level.
+// Returns the folders that can be resumed.
+func (clt *Client) keepPausedForPower(folderIDs []string) ([]string, error) {
+	if len(folderIDs) == 0 {
+		return folderIDs, nil
+	}
+
+	level := clt.powerLevel()
+	resumable := make([]string, 0, len(folderIDs))
+	err := clt.power.modify(func(policy *powerPolicy) {
+		for _, folderID := range folderIDs {
+			if policy.pausesFolder(level, folderID) {
+				if !slices.Contains(policy.PausedByPolicy, folderID) {
+					policy.PausedByPolicy = append(policy.PausedByPolicy, folderID)
+				}
+			} else {
+				resumable = append(resumable, folderID)
+			}
+		}
+	})
+	return resumable, err
+}
@@ -0,0 +1,272 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+// Besides the default profile (which uses the configuration and files directories directly), named profiles each have
+// their own configuration directory (and therefore identity, folders and database) in the 'profiles' subdirectory of
+// the configuration directory, and their own files directory in the 'profiles' subdirectory of the files directory.
+
+import (
+	"cmp"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/syncthing/syncthing/lib/build"
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const profilesDirName = "profiles"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _-]{0,63}$`)
+
+// Syncthing keeps its locations (configuration file, database, etc.) in global state. Only one client per process can
+// be loaded at a time, so the locations of one profile are not used by a client for another.
+var (
+	loadedClientMutex sync.Mutex
+	loadedClient      *Client
+)
+
+// Creates a client for the named profile. The directories for the profile are created when they do not exist yet. The
+// default profile is used when the name is empty.
+func NewClientForProfile(configPath string, filesPath string, name string, saveLog bool) (*Client, error) {
+	options := clientOptions{
+		ConfigPath: configPath,
+		FilesPath:  filesPath,
+		SaveLog:    saveLog,
+		Profile:    name,
+	}
+	if err := options.resolveProfile(); err != nil {
+		return nil, err
+	}
+	return newClient(options), nil
+}
+
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return errors.New("invalid profile name")
+	}
+	return nil
+}
+
+// Points the configuration and files paths at the directories of the profile, remembering the original paths
+func (options *clientOptions) resolveProfile() error {
+	options.profilesConfigPath = path.Join(options.ConfigPath, profilesDirName)
+	options.profilesFilesPath = path.Join(options.FilesPath, profilesDirName)
+	if options.Profile == "" {
+		return nil
+	}
+	if err := validateProfileName(options.Profile); err != nil {
+		return err
+	}
+
+	options.ConfigPath = path.Join(options.profilesConfigPath, options.Profile)
+	options.FilesPath = path.Join(options.profilesFilesPath, options.Profile)
+	for _, dir := range []string{options.ConfigPath, options.FilesPath} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the configuration directory of a profile ("" for the default profile)
+func (clt *Client) profileConfigPath(name string) (string, error) {
+	if name == "" {
+		return path.Dir(clt.options.profilesConfigPath), nil
+	}
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+	return path.Join(clt.options.profilesConfigPath, name), nil
+}
+
+// Returns the files directory of a profile ("" for the default profile)
+func (clt *Client) profileFilesPath(name string) (string, error) {
+	if name == "" {
+		return path.Dir(clt.options.profilesFilesPath), nil
+	}
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+	return path.Join(clt.options.profilesFilesPath, name), nil
+}
+
+// Returns the name of the profile this client uses, or an empty string for the default profile
+func (clt *Client) Profile() string {
+	return clt.options.Profile
+}
+
+// Returns the names of the profiles that exist besides the default profile
+func (clt *Client) ListProfiles() (*ListOfStrings, error) {
+	entries, err := os.ReadDir(clt.options.profilesConfigPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return List([]string{}), nil
+		}
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() && validateProfileName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+	return List(names), nil
+}
+
+// Creates a new profile with the configuration (folders, devices and settings) of an existing profile ("" for the
+// default profile). The new profile gets its own identity, so other devices will see it as a new device. Folders get
+// their own directory in the files directory of the new profile, so the profiles never share a folder root.
+func (clt *Client) CloneProfile(from string, to string) error {
+	if to == "" {
+		return errors.New("cannot clone into the default profile")
+	}
+	sourceDir, err := clt.profileConfigPath(from)
+	if err != nil {
+		return err
+	}
+	targetDir, err := clt.profileConfigPath(to)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(targetDir); err == nil {
+		return errors.New("profile already exists")
+	}
+	sourceFilesDir, err := clt.profileFilesPath(from)
+	if err != nil {
+		return err
+	}
+	targetFilesDir, err := clt.profileFilesPath(to)
+	if err != nil {
+		return err
+	}
+
+	// The configuration lists the source device itself, which should not become a peer of the new profile
+	sourceCert, err := tls.LoadX509KeyPair(path.Join(sourceDir, CertFileName), path.Join(sourceDir, KeyFileName))
+	if err != nil {
+		return err
+	}
+	sourceID := protocol.NewDeviceID(sourceCert.Certificate[0])
+
+	fd, err := os.Open(path.Join(sourceDir, ConfigFileName))
+	if err != nil {
+		return err
+	}
+	cfg, _, err := config.ReadXML(fd, sourceID)
+	fd.Close()
+	if err != nil {
+		return err
+	}
+
+	cfg.Devices = slices.DeleteFunc(cfg.Devices, func(dc config.DeviceConfiguration) bool {
+		return dc.DeviceID == sourceID
+	})
+	for i := range cfg.Folders {
+		fc := &cfg.Folders[i]
+		fc.Devices = slices.DeleteFunc(fc.Devices, func(fdc config.FolderDeviceConfiguration) bool {
+			return fdc.DeviceID == sourceID
+		})
+
+		if fc.FilesystemType != config.FilesystemTypeBasic {
+			continue
+		}
+		if rel, err := filepath.Rel(sourceFilesDir, fc.Path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			fc.Path = path.Join(targetFilesDir, rel)
+		} else {
+			// Folders outside of the files directory cannot be moved along, so they start out empty in the new profile
+			dirName, err := folderDirectoryName(fc.ID)
+			if err != nil {
+				return err
+			}
+			fc.Path = path.Join(targetFilesDir, dirName)
+		}
+	}
+
+	if err := os.MkdirAll(targetDir, 0o700); err != nil {
+		return err
+	}
+	out, err := osutil.CreateAtomic(path.Join(targetDir, ConfigFileName))
+	if err != nil {
+		return err
+	}
+	if err := cfg.WriteXML(osutil.LineEndingsWriter(out)); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Deletes the configuration directory (including identity and database) of a profile. The files directory of the
+// profile is left alone. A profile that is in use cannot be deleted.
+func (clt *Client) DeleteProfile(name string) error {
+	if name == "" {
+		return errors.New("cannot delete the default profile")
+	}
+	if name == clt.options.Profile {
+		return errors.New("cannot delete the profile that is in use")
+	}
+	dir, err := clt.profileConfigPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+
+	// Make sure no other process (e.g. an extension) is using the profile
+	lock, err := lockDirectory(dir)
+	if err != nil {
+		return err
+	}
+	defer unlockDirectory(lock)
+	return os.RemoveAll(dir)
+}
+
+// Makes Syncthing's locations point to the directories of this client, failing when a client for another profile is
+// loaded in this process
+func (clt *Client) claimLocations() error {
+	loadedClientMutex.Lock()
+	defer loadedClientMutex.Unlock()
+
+	// Test clients are loaded one after the other, so they can share the locations while loading
+	sharing := loadedClient != nil && loadedClient.options.testing && clt.options.testing
+	if loadedClient != nil && loadedClient != clt && !sharing {
+		return errors.New("another profile is already loaded in this process")
+	}
+	loadedClient = clt
+
+	// Everything that is process-wide is only set up here, so that creating a client for another profile does not
+	// affect the one that is loaded
+	build.Version = cmp.Or(clt.options.Version, defaultBuildVersion)
+	build.Host = cmp.Or(clt.options.Host, defaultBuildHost)
+	build.User = cmp.Or(clt.options.User, defaultBuildUser)
+	slog.SetDefault(slog.New(clt.logHandler))
+	clt.IsUsingCustomConfiguration = applyLocations(clt.options.ConfigPath, clt.filesPath)
+	clt.configFile = locations.Get(locations.ConfigFile)
+	return nil
+}
+
+func (clt *Client) releaseLocations() {
+	loadedClientMutex.Lock()
+	defer loadedClientMutex.Unlock()
+
+	if loadedClient == clt {
+		loadedClient = nil
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"sync"
+
+	"golang.org/x/net/proxy"
+)
+
+// Syncthing dials through golang.org/x/net/proxy, which only looks at the proxy named in the ALL_PROXY environment
+// variable when the app launches, before SetProxy can be called. Syncthing registers a dialer for socks:// proxy URLs,
+// which we replace with one that uses the proxy set with SetProxy (or dials directly when none is set). The proxy can
+// therefore only be changed at runtime when the app is launched with ALL_PROXY set to a socks:// URL.
+const proxyLaunchScheme = "socks"
+
+var errProxyNotAvailable = errors.New("the proxy can only be changed when ALL_PROXY is set to a socks:// URL at launch")
+
+type proxyState struct {
+	mutex     sync.RWMutex
+	available bool     // Whether Syncthing dials through dialCurrentProxy
+	url       *url.URL // Current proxy (always with the socks5 scheme), or nil to connect directly
+}
+
+var currentProxy proxyState
+
+func init() {
+	launchURL := os.Getenv("ALL_PROXY")
+	if launchURL == "" {
+		launchURL = os.Getenv("all_proxy")
+	}
+	if u, err := url.Parse(launchURL); err == nil && u.Scheme == proxyLaunchScheme {
+		currentProxy.available = true
+		currentProxy.url = socks5URL(u)
+	}
+
+	// Runs after the initialization of Syncthing's dialer package, so this replaces its registration
+	proxy.RegisterDialerType(proxyLaunchScheme, dialCurrentProxy)
+}
+
+// Returns a copy of the URL with the scheme of the SOCKS5 dialer built into the proxy package
+func socks5URL(u *url.URL) *url.URL {
+	copied := *u
+	copied.Scheme = "socks5"
+	return &copied
+}
+
+// Called by the proxy package for each connection Syncthing makes
+func dialCurrentProxy(_ *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+	currentProxy.mutex.RLock()
+	u := currentProxy.url
+	currentProxy.mutex.RUnlock()
+	if u == nil {
+		return forward, nil
+	}
+	return proxy.FromURL(u, forward)
+}
+
+func setCurrentProxy(u *url.URL) error {
+	currentProxy.mutex.Lock()
+	defer currentProxy.mutex.Unlock()
+	if !currentProxy.available {
+		return errProxyNotAvailable
+	}
+	currentProxy.url = u
+	return nil
+}
+
+type ProxySettings struct {
+	URL         string
+	Username    string
+	HasPassword bool
+}
+
+// Returns whether the proxy can be changed with SetProxy (see proxyLaunchScheme)
+func (clt *Client) IsProxyConfigurable() bool {
+	currentProxy.mutex.RLock()
+	defer currentProxy.mutex.RUnlock()
+	return currentProxy.available
+}
+
+// Configures the SOCKS5 proxy used for outgoing connections to other devices. The URL should be of the form
+// socks5://host:port. Pass an empty URL to connect directly. New connections use the proxy right away; existing
+// connections are not affected. The setting is not persisted and should be applied again on each launch.
+func (clt *Client) SetProxy(proxyURL string, username string, password string) error {
+	if proxyURL == "" {
+		return setCurrentProxy(nil)
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks" {
+		return errors.New("only SOCKS5 proxies are supported")
+	}
+	if u.Host == "" {
+		return errors.New("proxy URL does not contain a host")
+	}
+
+	if username != "" {
+		if password != "" {
+			u.User = url.UserPassword(username, password)
+		} else {
+			u.User = url.User(username)
+		}
+	} else {
+		u.User = nil
+	}
+
+	return setCurrentProxy(socks5URL(u))
+}
+
+// Returns the currently configured proxy, or nil when connecting directly
+func (clt *Client) ProxySettings() *ProxySettings {
+	currentProxy.mutex.RLock()
+	current := currentProxy.url
+	currentProxy.mutex.RUnlock()
+	if current == nil {
+		return nil
+	}
+
+	u := *current
+	settings := &ProxySettings{}
+	if u.User != nil {
+		settings.Username = u.User.Username()
+		_, settings.HasPassword = u.User.Password()
+		u.User = nil
+	}
+	settings.URL = u.String()
+	return settings
+}
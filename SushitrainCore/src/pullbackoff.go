@@ -0,0 +1,144 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// The state of pulling a folder after it failed (see Folder.PullStatus)
+type PullStatus struct {
+	ConsecutiveFailures int
+	LastFailureAt       *Date  // Nil when pulling did not fail since the last successful pull
+	NextRetryAt         *Date  // Nil when no retry is scheduled
+	LastError           string // The folder error, or the first error of a file that could not be pulled
+}
+
+// Whether Syncthing is waiting to retry pulling the folder
+func (ps *PullStatus) IsWaitingForRetry() bool {
+	return ps.NextRetryAt != nil && ps.NextRetryAt.time.After(time.Now())
+}
+
+// How long to wait for Syncthing to log that a pull failed, after the folder became idle
+const pullFailureLogDelay = 5 * time.Second
+
+type pullFailure struct {
+	consecutive int
+	lastAt      time.Time
+	retryAt     time.Time
+}
+
+// Syncthing backs off from pulling a folder after each failed pull (up to an hour), but only logs this
+type pullBackoffTracker struct {
+	mutex    sync.Mutex
+	failures map[string]*pullFailure // Folder ID => failure
+}
+
+func newPullBackoffTracker() *pullBackoffTracker {
+	return &pullBackoffTracker{failures: map[string]*pullFailure{}}
+}
+
+// Handles the "Folder failed to sync, will be retried" log message
+func (pbt *pullBackoffTracker) handleLogRecord(r slog.Record) {
+	folderID := ""
+	var wait time.Duration
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case "folder":
+			for _, groupAttr := range a.Value.Group() {
+				if groupAttr.Key == "id" {
+					folderID = groupAttr.Value.String()
+				}
+			}
+		case "wait":
+			wait, _ = time.ParseDuration(a.Value.String())
+		}
+		return true
+	})
+	if folderID == "" {
+		return
+	}
+
+	pbt.mutex.Lock()
+	defer pbt.mutex.Unlock()
+	failure, ok := pbt.failures[folderID]
+	if !ok {
+		failure = &pullFailure{}
+		pbt.failures[folderID] = failure
+	}
+	failure.consecutive++
+	failure.lastAt = r.Time
+	failure.retryAt = r.Time.Add(wait)
+}
+
+// Called when a pull of the folder finished (the folder went from syncing to idle at the given time). Syncthing logs
+// that the pull failed right after the folder becomes idle, so the backoff is reset when no failure is logged shortly
+// after.
+func (pbt *pullBackoffTracker) pullFinished(folderID string, at time.Time) {
+	time.AfterFunc(pullFailureLogDelay, func() {
+		pbt.mutex.Lock()
+		defer pbt.mutex.Unlock()
+		if failure, ok := pbt.failures[folderID]; ok && failure.lastAt.Before(at) {
+			delete(pbt.failures, folderID)
+		}
+	})
+}
+
+func (pbt *pullBackoffTracker) reset(folderID string) {
+	pbt.mutex.Lock()
+	defer pbt.mutex.Unlock()
+	delete(pbt.failures, folderID)
+}
+
+func (pbt *pullBackoffTracker) get(folderID string) (pullFailure, bool) {
+	pbt.mutex.Lock()
+	defer pbt.mutex.Unlock()
+	if failure, ok := pbt.failures[folderID]; ok {
+		return *failure, true
+	}
+	return pullFailure{}, false
+}
+
+// Returns whether pulling the folder failed recently, and when it will be retried
+func (fld *Folder) PullStatus() *PullStatus {
+	status := &PullStatus{LastError: fld.StateError()}
+	if status.LastError == "" {
+		if fileErrors := fld.client.folderErrors.get(fld.FolderID); len(fileErrors) > 0 {
+			status.LastError = fileErrors[0].Err
+		}
+	}
+
+	if failure, ok := fld.client.pullBackoff.get(fld.FolderID); ok {
+		status.ConsecutiveFailures = failure.consecutive
+		status.LastFailureAt = &Date{time: failure.lastAt}
+		if !failure.retryAt.IsZero() {
+			status.NextRetryAt = &Date{time: failure.retryAt}
+		}
+	}
+	return status
+}
+
+// Retries pulling the folder right away instead of waiting for the next retry. Syncthing does not allow scheduling a
+// pull from the outside, so the folder is restarted (by pausing and resuming it), which also resets the backoff. The
+// folder is scanned before it is pulled again; this is quick when nothing changed locally.
+func (fld *Folder) RetryNow() error {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+	if !fld.Exists() {
+		return ErrFolderNotFound
+	}
+	if fld.IsPaused() {
+		return errors.New("folder is paused")
+	}
+
+	slog.Info("retrying pull now", "folderID", fld.FolderID)
+	fld.client.pullBackoff.reset(fld.FolderID)
+	return fld.whilePaused(func() error { return nil })
+}
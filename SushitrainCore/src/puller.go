@@ -10,27 +10,29 @@ import (
 	"sync"
 	"time"
 
-	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/syncthing/syncthing/lib/model"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/syncthing"
 	"golang.org/x/exp/slog"
 )
 
-// Global cache of downloaded blocks. Block hash -> block data
-// Blocks are between 128 KiB and 16 MiB size, this will use 1 GiB at most
-var blockCache, _ = lru.New[string, []byte](64)
-
 type miniPuller struct {
 	measurements *Measurements
 	experiences  *experiences
 	internals    *syncthing.Internals
+	load         *peerLoad
 }
 
-func ClearBlockCache() {
-	slog.Info("Purging blocks cache", "entries", blockCache.Len())
-	blockCache.Purge()
-}
+const (
+	maxInFlightPerPeer = 4                // Maximum number of block requests sent to a single peer at the same time
+	maxParallelBlocks  = 16               // Maximum number of blocks of a single file fetched at the same time
+	maxBytesInFlight   = 64 * 1024 * 1024 // Maximum size of the blocks of a single file fetched at the same time
+	slowPeerFactor     = 4                // A peer is demoted when it is this many times slower than the fastest peer
+	slowPeerDemotion   = 30 * time.Second // How long a slow peer is demoted for
+)
+
+// Returned by the function passed to fetchBlocks to stop fetching without failing
+var errStopFetching = errors.New("stop fetching")
 
 func (mp *miniPuller) downloadRange(ctx context.Context, m *syncthing.Internals, folderID string, file protocol.FileInfo, dest []byte, offset int64, retry int) (n int64, e error) {
 	blockSize := int64(file.BlockSize())
@@ -44,19 +46,8 @@ func (mp *miniPuller) downloadRange(ctx context.Context, m *syncthing.Internals,
 	}
 
 	var written int64 = 0
-	for blockIndex := startBlock; blockIndex < startBlock+blockCount; blockIndex++ {
-		if int(blockIndex) > len(file.Blocks)-1 {
-			break
-		}
-
-		// Fetch block
+	err := mp.fetchBlocks(ctx, folderID, file, int(startBlock), int(blockCount), retry, func(blockIndex int, buf []byte) error {
 		block := file.Blocks[blockIndex]
-		buf, err := mp.downloadBlock(ctx, folderID, int(blockIndex), file, retry)
-		if err != nil {
-			slog.Warn("error downloading block", "index", blockIndex, "total", len(file.Blocks), "cause", err)
-			return 0, err
-		}
-
 		bufStart := int64(0)
 		bufEnd := int64(len(buf))
 
@@ -70,11 +61,16 @@ func (mp *miniPuller) downloadRange(ctx context.Context, m *syncthing.Internals,
 			bufEnd = rangeEnd - block.Offset
 		}
 		if bufEnd < 0 {
-			break
+			return errStopFetching
 		}
 
 		copy(dest[written:], buf[bufStart:bufEnd])
 		written += bufEnd - bufStart
+		return nil
+	})
+	if err != nil {
+		slog.Warn("error downloading range", "offset", offset, "length", len(dest), "cause", err)
+		return 0, err
 	}
 
 	return written, nil
@@ -107,11 +103,10 @@ func (exp *experiences) set(device protocol.DeviceID, wasGood bool) {
 
 func (mp *miniPuller) downloadBlock(ctx context.Context, folderID string, blockIndex int, file protocol.FileInfo, retry int) ([]byte, error) {
 	block := file.Blocks[blockIndex]
-	blockHashString := base64.StdEncoding.EncodeToString([]byte(block.Hash))
 
-	// Do we have this file in the local cache?
-	if cached, ok := blockCache.Get(blockHashString); ok {
-		slog.Info("cache hit for block", "hash", blockHashString)
+	// Do we have this block in the cache?
+	if cached, ok := blockCache.get(block.Hash); ok {
+		slog.Info("cache hit for block", "hash", base64.StdEncoding.EncodeToString(block.Hash))
 		return cached, nil
 	}
 
@@ -125,112 +120,59 @@ func (mp *miniPuller) downloadBlock(ctx context.Context, folderID string, blockI
 
 	slog.Info("download block", "index", blockIndex, "availablePeers", len(availables))
 
-	// Sort availables by latency
-	slices.SortFunc(availables, func(a model.Availability, b model.Availability) int {
-		latencyA := mp.measurements.LatencyFor(a.ID.String())
-		latencyB := mp.measurements.LatencyFor(b.ID.String())
-		if math.IsNaN(latencyA) && math.IsNaN(latencyB) {
-			return 0
-		} else if math.IsNaN(latencyA) {
-			return 1 // a > b
-		} else if math.IsNaN(latencyB) {
-			return -1 // b > a
-		} else if latencyA > latencyB {
-			return 1
-		} else if latencyB > latencyA {
-			return -1
-		} else {
-			return 0
-		}
-	})
-
-	// Attempt to download the block from an available and 'known good' peers first
-	for attempt := range retry {
+	for attempt := 0; attempt < retry; {
 		slog.Debug("downloadBlock", "attempt", attempt, "retry", retry)
 
-		for _, available := range availables {
+		// Try the peers in order of preference; this is re-evaluated for each attempt as other blocks are being fetched
+		// concurrently, which spreads the requests over the peers
+		freed := mp.load.slotFreed()
+		tried, saturated := 0, 0
+		for _, available := range mp.orderPeers(availables) {
 			// Check if we were cancelled
 			if err := ctx.Err(); err != nil {
 				return nil, ctx.Err()
 			}
 
-			if exp, ok := mp.experiences.get(available.ID); ok && exp {
-				// Skip devices we're not connected to
-				if !mp.internals.IsConnectedTo(available.ID) {
-					continue
-				}
-
-				downloadBlockCtx, cancelDownloadBlock := context.WithTimeout(ctx, mp.timeoutFor(&block))
-				defer cancelDownloadBlock()
-				slog.Debug("downloadBlock fetch good", "blockIndex", blockIndex, "from", available.ID)
-				buf, err := mp.internals.DownloadBlock(downloadBlockCtx, available.ID, folderID, file.Name, int(blockIndex), block, available.FromTemporary)
-				// Remember our experience with this peer for next time
-				mp.experiences.set(available.ID, err == nil || err == context.Canceled)
-				if err == nil {
-					blockCache.Add(blockHashString, buf)
-					return buf, nil
-				} else {
-					slog.Info("good peer", "id", available.ID, "error", err, "bufferSize", len(buf))
-				}
+			// Skip devices we're not connected to
+			if !mp.internals.IsConnectedTo(available.ID) {
+				continue
 			}
-		}
 
-		// Failed to download from a good peer, let's try the peers we don't have any experience with
-		for _, available := range availables {
-			// Check if we were cancelled
-			if err := ctx.Err(); err != nil {
-				return nil, ctx.Err()
+			// Leave peers alone that have enough requests in flight already
+			started, ok := mp.load.tryAcquire(available.ID)
+			if !ok {
+				saturated++
+				continue
 			}
-
-			if _, ok := mp.experiences.get(available.ID); !ok {
-				// Skip devices we're not connected to
-				if !mp.internals.IsConnectedTo(available.ID) {
-					continue
-				}
-
-				downloadBlockCtx, cancelDownloadBlock := context.WithTimeout(ctx, mp.timeoutFor(&block))
-				defer cancelDownloadBlock()
-				slog.Debug("downloadBlock fetch new", "blockIndex", blockIndex, "from", available.ID)
-				buf, err := mp.internals.DownloadBlock(downloadBlockCtx, available.ID, folderID, file.Name, int(blockIndex), block, available.FromTemporary)
-				// Remember our experience with this peer for next time
-				mp.experiences.set(available.ID, err == nil || err == context.Canceled)
-				if err == nil {
-					blockCache.Add(blockHashString, buf)
-					return buf, nil
-				} else {
-					slog.Info("unknown peer", "id", available.ID, "error", err, "bufferSize", len(buf))
-				}
+			tried++
+
+			downloadBlockCtx, cancelDownloadBlock := context.WithTimeout(ctx, mp.timeoutFor(&block))
+			slog.Debug("downloadBlock fetch", "blockIndex", blockIndex, "from", available.ID)
+			buf, err := mp.internals.DownloadBlock(downloadBlockCtx, available.ID, folderID, file.Name, int(blockIndex), block, available.FromTemporary)
+			cancelDownloadBlock()
+			mp.load.release(available.ID, started, len(buf), err == nil)
+
+			// Remember our experience with this peer for next time
+			mp.experiences.set(available.ID, err == nil || err == context.Canceled)
+			if err == nil {
+				blockCache.add(block.Hash, buf)
+				return buf, nil
+			} else {
+				slog.Info("peer failed to deliver block", "id", available.ID, "error", err, "bufferSize", len(buf))
 			}
 		}
 
-		// Failed to download from a good or unknown peer, let's try the 'bad' peers once again
-		for _, available := range availables {
-			// Check if we were cancelled
-			if err := ctx.Err(); err != nil {
+		// When all peers were busy, wait for one of them to finish a request; this does not count as an attempt
+		if tried == 0 && saturated > 0 {
+			select {
+			case <-freed:
+				continue
+			case <-ctx.Done():
 				return nil, ctx.Err()
 			}
-
-			if exp, ok := mp.experiences.get(available.ID); ok && !exp {
-				// Skip devices we're not connected to
-				if !mp.internals.IsConnectedTo(available.ID) {
-					continue
-				}
-
-				downloadBlockCtx, cancelDownloadBlock := context.WithTimeout(ctx, mp.timeoutFor(&block))
-				defer cancelDownloadBlock()
-				slog.Debug("downloadBlock fetch bad", "blockIndex", blockIndex, "from", available.ID)
-				buf, err := mp.internals.DownloadBlock(downloadBlockCtx, available.ID, folderID, file.Name, int(blockIndex), block, available.FromTemporary)
-				// Remember our experience with this peer for next time
-				mp.experiences.set(available.ID, err == nil || err == context.Canceled)
-				if err == nil {
-					blockCache.Add(blockHashString, buf)
-					return buf, nil
-				} else {
-					slog.Info("bad peer", "id", available.ID, "error", err, "bufferSize", len(buf))
-				}
-			}
 		}
 
+		attempt++
 		retryTime := time.Duration(700) * time.Millisecond
 		slog.Debug("waiting for retry", "retryTime", retryTime)
 		time.Sleep(retryTime)
@@ -240,33 +182,140 @@ func (mp *miniPuller) downloadBlock(ctx context.Context, folderID string, blockI
 	return nil, errors.New("no peer to download this block from")
 }
 
+// Orders the peers that have a block by preference. Peers that were good to us (or that we have no experience with)
+// come first, then peers demoted for being slow, then peers that failed us before. Within these, peers that have
+// fewer requests in flight are preferred (so requests are striped over the peers), and then those with lower latency.
+func (mp *miniPuller) orderPeers(availables []model.Availability) []model.Availability {
+	type candidate struct {
+		available model.Availability
+		group     int
+		saturated bool
+		inFlight  int
+		unknown   bool
+		latency   float64
+	}
+
+	candidates := Map(availables, func(available model.Availability) candidate {
+		wasGood, haveExperience := mp.experiences.get(available.ID)
+		inFlight, demoted := mp.load.state(available.ID)
+		group := 0
+		if haveExperience && !wasGood {
+			group = 2
+		} else if demoted {
+			group = 1
+		}
+		return candidate{
+			available: available,
+			group:     group,
+			saturated: inFlight >= maxInFlightPerPeer,
+			inFlight:  inFlight,
+			unknown:   !haveExperience,
+			latency:   mp.measurements.LatencyFor(available.ID.String()),
+		}
+	})
+
+	slices.SortStableFunc(candidates, func(a candidate, b candidate) int {
+		if a.group != b.group {
+			return a.group - b.group
+		}
+		if a.saturated != b.saturated {
+			if a.saturated {
+				return 1
+			}
+			return -1
+		}
+		if a.inFlight != b.inFlight {
+			return a.inFlight - b.inFlight
+		}
+		if a.unknown != b.unknown {
+			if a.unknown {
+				return 1
+			}
+			return -1
+		}
+		if math.IsNaN(a.latency) && math.IsNaN(b.latency) {
+			return 0
+		} else if math.IsNaN(a.latency) {
+			return 1 // a > b
+		} else if math.IsNaN(b.latency) {
+			return -1 // b > a
+		} else if a.latency > b.latency {
+			return 1
+		} else if b.latency > a.latency {
+			return -1
+		}
+		return 0
+	})
+
+	return Map(candidates, func(c candidate) model.Availability {
+		return c.available
+	})
+}
+
 func newMiniPuller(measurements *Measurements, internals *syncthing.Internals) *miniPuller {
 	return &miniPuller{
 		experiences:  newExperiences(),
 		measurements: measurements,
 		internals:    internals,
+		load:         peerLoads,
 	}
 }
 
 func (mp *miniPuller) downloadInto(ctx context.Context, w io.Writer, folderID string, info protocol.FileInfo) error {
-	var wg sync.WaitGroup
-	parallellism := 2
-	retry := 3
+	return mp.fetchBlocks(ctx, folderID, info, 0, len(info.Blocks), 3, func(blockIndex int, block []byte) error {
+		slog.Debug("download into write", "bytes", len(block))
+		_, err := w.Write(block)
+		if err != nil {
+			slog.Info("download into write error", "cause", err)
+		}
+		return err
+	})
+}
+
+// Returns how many blocks of the file to fetch at the same time. When several peers have the file, up to
+// maxInFlightPerPeer blocks are requested from each of them, within the memory budget.
+func (mp *miniPuller) parallelismFor(folderID string, info protocol.FileInfo, firstBlock int) int {
+	peers := 0
+	if availables, err := mp.internals.BlockAvailability(folderID, info, info.Blocks[firstBlock]); err == nil {
+		for _, available := range availables {
+			if mp.internals.IsConnectedTo(available.ID) {
+				peers++
+			}
+		}
+	}
+
+	parallelism := 2
+	if peers > 1 {
+		parallelism = min(peers*maxInFlightPerPeer, maxParallelBlocks)
+	}
+	return max(1, min(parallelism, maxBytesInFlight/info.BlockSize()))
+}
+
+// Fetches `count` blocks of the file starting at `firstBlock`, several at a time, and calls `deliver` for each of them in
+// order. Fetching stops when deliver returns an error; errStopFetching can be used to stop without failing.
+func (mp *miniPuller) fetchBlocks(ctx context.Context, folderID string, info protocol.FileInfo, firstBlock int, count int, retry int, deliver func(blockIndex int, block []byte) error) error {
+	lastBlock := min(firstBlock+count, len(info.Blocks))
+	if firstBlock >= lastBlock {
+		return nil
+	}
+	parallelism := min(mp.parallelismFor(folderID, info, firstBlock), lastBlock-firstBlock)
+	blockCache.noteRead(mp, folderID, info, firstBlock, lastBlock)
 
-	chans := make([]chan []byte, parallellism)
-	errChan := make(chan error, 1)
+	var wg sync.WaitGroup
+	chans := make([]chan []byte, parallelism)
+	errChan := make(chan error, parallelism)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// Spawn `parallellism` goroutines, each will fetch block i + n*parallellism
-	for threadIndex := range parallellism {
+	// Spawn `parallelism` goroutines, each will fetch block firstBlock + threadIndex + n*parallelism
+	for threadIndex := range parallelism {
 		chans[threadIndex] = make(chan []byte, 1)
+		wg.Add(1)
 		go func() {
-			wg.Add(1)
 			defer wg.Done()
 
-			var i = threadIndex
-			for i < len(info.Blocks) {
+			var i = firstBlock + threadIndex
+			for i < lastBlock {
 				// Check if we were cancelled
 				if err := ctx.Err(); err != nil {
 					slog.Debug("download worker cancelled", "index", i, "threadIndex", threadIndex)
@@ -281,29 +330,33 @@ func (mp *miniPuller) downloadInto(ctx context.Context, w io.Writer, folderID st
 					return
 				}
 				slog.Debug("done block", "index", i, "threadIndex", threadIndex)
-				// this will block until the  previous block we produced was read
-				chans[threadIndex] <- buf
-				i += parallellism
+				// this will block until the previous block we produced was read
+				select {
+				case chans[threadIndex] <- buf:
+				case <-ctx.Done():
+					return
+				}
+				i += parallelism
 			}
 		}()
 	}
 
 	defer wg.Wait()
 
-	// Read the blocks in order
-	for blockNo, _ := range info.Blocks {
+	// Deliver the blocks in order
+	for blockIndex := firstBlock; blockIndex < lastBlock; blockIndex++ {
 		select {
 		case err := <-errChan:
-			slog.Info("download into error", "cause", err)
+			slog.Info("fetch blocks error", "cause", err)
 			cancel()
 			return err
 
-		case block := <-chans[blockNo%parallellism]:
-			slog.Debug("download into write", "bytes", len(block))
-			_, err := w.Write(block)
-			if err != nil {
-				slog.Info("download into write error", "cause", err)
+		case block := <-chans[(blockIndex-firstBlock)%parallelism]:
+			if err := deliver(blockIndex, block); err != nil {
 				cancel()
+				if errors.Is(err, errStopFetching) {
+					return nil
+				}
 				return err
 			}
 		}
@@ -317,3 +370,84 @@ func newExperiences() *experiences {
 		data: map[protocol.DeviceID]bool{},
 	}
 }
+
+// Keeps track of the requests in flight to each peer, and of how fast each peer delivers blocks. Shared by all pullers
+// (see peerLoads), so that the limits apply to all requests to a peer together.
+type peerLoad struct {
+	mutex        sync.Mutex
+	freed        chan struct{} // Closed (and replaced) when a request ends
+	inFlight     map[protocol.DeviceID]int
+	rates        map[protocol.DeviceID]float64 // Bytes per second (moving average)
+	demotedUntil map[protocol.DeviceID]time.Time
+}
+
+// Global load of peers, shared by all pullers
+var peerLoads = newPeerLoad()
+
+func newPeerLoad() *peerLoad {
+	return &peerLoad{
+		freed:        make(chan struct{}),
+		inFlight:     map[protocol.DeviceID]int{},
+		rates:        map[protocol.DeviceID]float64{},
+		demotedUntil: map[protocol.DeviceID]time.Time{},
+	}
+}
+
+// Returns the number of requests in flight to the peer, and whether it is currently demoted for being slow
+func (pl *peerLoad) state(device protocol.DeviceID) (inFlight int, demoted bool) {
+	pl.mutex.Lock()
+	defer pl.mutex.Unlock()
+	return pl.inFlight[device], time.Now().Before(pl.demotedUntil[device])
+}
+
+// Records the start of a request to the peer, unless maxInFlightPerPeer requests are in flight to it already
+func (pl *peerLoad) tryAcquire(device protocol.DeviceID) (time.Time, bool) {
+	pl.mutex.Lock()
+	defer pl.mutex.Unlock()
+	if pl.inFlight[device] >= maxInFlightPerPeer {
+		return time.Time{}, false
+	}
+	pl.inFlight[device]++
+	return time.Now(), true
+}
+
+// Returns a channel that is closed when the next request to any peer ends
+func (pl *peerLoad) slotFreed() <-chan struct{} {
+	pl.mutex.Lock()
+	defer pl.mutex.Unlock()
+	return pl.freed
+}
+
+// Records the end of a request, and demotes the peer when it delivered much slower than the fastest peer
+func (pl *peerLoad) release(device protocol.DeviceID, started time.Time, size int, ok bool) {
+	pl.mutex.Lock()
+	defer pl.mutex.Unlock()
+	pl.inFlight[device]--
+	if pl.inFlight[device] <= 0 {
+		delete(pl.inFlight, device)
+	}
+	close(pl.freed)
+	pl.freed = make(chan struct{})
+	if !ok {
+		return
+	}
+
+	elapsed := time.Since(started).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rate := float64(size) / elapsed
+	if previous, ok := pl.rates[device]; ok {
+		rate = 0.7*previous + 0.3*rate
+	}
+	pl.rates[device] = rate
+
+	fastest := 0.0
+	for _, r := range pl.rates {
+		fastest = max(fastest, r)
+	}
+	if len(pl.rates) > 1 && rate*slowPeerFactor < fastest {
+		slog.Info("demoting slow peer", "id", device, "bytesPerSecond", int64(rate), "fastestBytesPerSecond", int64(fastest))
+		pl.demotedUntil[device] = time.Now().Add(slowPeerDemotion)
+	}
+}
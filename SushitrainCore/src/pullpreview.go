@@ -0,0 +1,106 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// What pulling a folder would do, based on the indexes received from peers so far (see Folder.PreviewPull)
+type PullPreview struct {
+	BytesToDownload int64
+	download        []string // Files and directories that do not exist locally yet
+	overwrite       []string // Local files that will be replaced by a newer version
+	delete          []string // Local files that will be deleted
+	conflict        []string // Local files that were changed concurrently, for which a conflict copy will be made
+}
+
+func (pp *PullPreview) DownloadPaths() *ListOfStrings {
+	return List(pp.download)
+}
+
+func (pp *PullPreview) OverwritePaths() *ListOfStrings {
+	return List(pp.overwrite)
+}
+
+func (pp *PullPreview) DeletePaths() *ListOfStrings {
+	return List(pp.delete)
+}
+
+func (pp *PullPreview) ConflictPaths() *ListOfStrings {
+	return List(pp.conflict)
+}
+
+// Returns the total number of changes a pull would make
+func (pp *PullPreview) ChangeCount() int {
+	return len(pp.download) + len(pp.overwrite) + len(pp.delete) + len(pp.conflict)
+}
+
+// Computes what a pull would do right now, without performing it. Only the indexes already received from peers are
+// taken into account, so the preview changes when connected peers send updates.
+func (fld *Folder) PreviewPull() (*PullPreview, error) {
+	client := fld.client
+	if client.app == nil || client.app.Internals == nil || client.sdb == nil {
+		return nil, ErrStillLoading
+	}
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return nil, ErrFolderNotFound
+	}
+
+	preview := &PullPreview{}
+	if fc.Type == config.FolderTypeSendOnly {
+		// Send-only folders never pull changes
+		return preview, nil
+	}
+
+	page := 1
+	perPage := 512
+	for {
+		progress, queued, rest, err := client.app.Internals.NeedFolderFiles(fld.FolderID, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+
+		batch := append(append(progress, queued...), rest...)
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, global := range batch {
+			local, ok, err := client.sdb.GetDeviceFile(fld.FolderID, protocol.LocalDeviceID, global.Name)
+			if err != nil {
+				return nil, err
+			}
+			preview.add(global, local, ok && !local.IsDeleted())
+		}
+		page += 1
+	}
+	return preview, nil
+}
+
+func (pp *PullPreview) add(global protocol.FileInfo, local protocol.FileInfo, existsLocally bool) {
+	name := global.FileName()
+	isFile := global.Type == protocol.FileInfoTypeFile
+	switch {
+	case global.IsDeleted():
+		if existsLocally {
+			pp.delete = append(pp.delete, name)
+		}
+		return
+	case !existsLocally:
+		pp.download = append(pp.download, name)
+	case isFile && local.Type == protocol.FileInfoTypeFile && local.Version.Concurrent(global.Version):
+		pp.conflict = append(pp.conflict, name)
+	default:
+		pp.overwrite = append(pp.overwrite, name)
+	}
+
+	if isFile {
+		pp.BytesToDownload += global.FileSize()
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/syncthing"
+)
+
+// Caches the results of database queries per folder, until the folder's index changes. The UI tends to ask for the same
+// statistics and listings many times in quick succession.
+type queryCache struct {
+	mutex       sync.Mutex
+	entries     map[string]map[string]any // folder ID -> query key -> result
+	generations map[string]uint64         // folder ID -> number of times the folder's results were invalidated
+	generation  uint64                    // Number of times all results were invalidated
+}
+
+func newQueryCache() *queryCache {
+	return &queryCache{
+		entries:     map[string]map[string]any{},
+		generations: map[string]uint64{},
+	}
+}
+
+func (qc *queryCache) invalidate(folderID string) {
+	qc.mutex.Lock()
+	defer qc.mutex.Unlock()
+	delete(qc.entries, folderID)
+	qc.generations[folderID]++
+}
+
+func (qc *queryCache) invalidateAll() {
+	qc.mutex.Lock()
+	defer qc.mutex.Unlock()
+	clear(qc.entries)
+	qc.generation++
+}
+
+// Invalidates cached results affected by the event
+func (qc *queryCache) handleEvent(evt events.Event) {
+	switch evt.Type {
+	case events.LocalIndexUpdated, events.RemoteIndexUpdated, events.StateChanged:
+		if data, ok := evt.Data.(map[string]interface{}); ok {
+			if folderID, ok := data["folder"].(string); ok {
+				qc.invalidate(folderID)
+				return
+			}
+		}
+		qc.invalidateAll()
+
+	case events.ConfigSaved:
+		qc.invalidateAll()
+	}
+}
+
+// Returns the cached result for the query, or performs it and caches the result. Errors are not cached, and neither
+// are results of queries during which the folder's results were invalidated, as these may be outdated already.
+func cachedQuery[T any](qc *queryCache, folderID string, key string, query func() (T, error)) (T, error) {
+	qc.mutex.Lock()
+	if result, ok := qc.entries[folderID][key]; ok {
+		qc.mutex.Unlock()
+		return result.(T), nil
+	}
+	folderGeneration, generation := qc.generations[folderID], qc.generation
+	qc.mutex.Unlock()
+
+	result, err := query()
+	if err != nil {
+		return result, err
+	}
+
+	qc.mutex.Lock()
+	defer qc.mutex.Unlock()
+	if qc.generations[folderID] != folderGeneration || qc.generation != generation {
+		return result, nil
+	}
+	if _, ok := qc.entries[folderID]; !ok {
+		qc.entries[folderID] = map[string]any{}
+	}
+	qc.entries[folderID][key] = result
+	return result, nil
+}
+
+func (clt *Client) globalSize(folderID string) (syncthing.Counts, error) {
+	return cachedQuery(clt.queryCache, folderID, "globalSize", func() (syncthing.Counts, error) {
+		return clt.app.Internals.GlobalSize(folderID)
+	})
+}
+
+func (clt *Client) localSize(folderID string) (syncthing.Counts, error) {
+	return cachedQuery(clt.queryCache, folderID, "localSize", func() (syncthing.Counts, error) {
+		return clt.app.Internals.LocalSize(folderID)
+	})
+}
+
+func (clt *Client) localNeedSize(folderID string) (syncthing.Counts, error) {
+	return cachedQuery(clt.queryCache, folderID, "localNeedSize", func() (syncthing.Counts, error) {
+		return clt.app.Internals.NeedSize(folderID, protocol.LocalDeviceID)
+	})
+}
+
+func (clt *Client) globalTree(folderID string, prefix string, levels int, directories bool) ([]*model.TreeEntry, error) {
+	key := fmt.Sprintf("globalTree:%s:%d:%t", prefix, levels, directories)
+	return cachedQuery(clt.queryCache, folderID, key, func() ([]*model.TreeEntry, error) {
+		return clt.app.Internals.GlobalTree(folderID, prefix, levels, directories)
+	})
+}
+
+var errSearchCancelled = errors.New("search cancelled")
+
+type searchMatch struct {
+	name     string
+	size     int64
+	fileType protocol.FileInfoType
+	modTime  time.Time
+}
+
+// Returns the files in the folder with a name containing the (lower case) text, below the prefix. The search is aborted
+// with errSearchCancelled when cancelled returns true.
+func (clt *Client) searchFolder(folderID string, text string, prefix string, cancelled func() bool) ([]searchMatch, error) {
+	key := fmt.Sprintf("search:%q:%q", text, prefix)
+	return cachedQuery(clt.queryCache, folderID, key, func() ([]searchMatch, error) {
+		matches := make([]searchMatch, 0)
+		for f, err := range zipError(clt.app.Internals.AllGlobalFiles(folderID)) {
+			if err != nil {
+				return nil, err
+			}
+			if cancelled() {
+				return nil, errSearchCancelled
+			}
+			if f.Deleted || !strings.HasPrefix(f.Name, prefix) {
+				continue
+			}
+			if strings.Contains(strings.ToLower(path.Base(f.Name)), text) {
+				matches = append(matches, searchMatch{name: f.Name, size: f.Size, fileType: f.Type, modTime: f.ModTime()})
+			}
+		}
+		return matches, nil
+	})
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCachedQuery(t *testing.T) {
+	qc := newQueryCache()
+	calls := 0
+	query := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	for range 2 {
+		if result, err := cachedQuery(qc, "folder", "key", query); err != nil || result != 1 {
+			t.Fatalf("expected cached result 1, got %d (%v)", result, err)
+		}
+	}
+
+	qc.invalidate("other")
+	if result, _ := cachedQuery(qc, "folder", "key", query); result != 1 {
+		t.Errorf("expected invalidating another folder to keep the result, got %d", result)
+	}
+
+	qc.invalidate("folder")
+	if result, _ := cachedQuery(qc, "folder", "key", query); result != 2 {
+		t.Errorf("expected query to run again after invalidation, got %d", result)
+	}
+}
+
+func TestCachedQueryErrorsAreNotCached(t *testing.T) {
+	qc := newQueryCache()
+	_, err := cachedQuery(qc, "folder", "key", func() (int, error) { return 0, errors.New("failed") })
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if result, err := cachedQuery(qc, "folder", "key", func() (int, error) { return 1, nil }); err != nil || result != 1 {
+		t.Errorf("expected query to run again after an error, got %d (%v)", result, err)
+	}
+}
+
+func TestCachedQueryDropsResultsComputedDuringInvalidation(t *testing.T) {
+	for _, invalidate := range []func(qc *queryCache){
+		func(qc *queryCache) { qc.invalidate("folder") },
+		func(qc *queryCache) { qc.invalidateAll() },
+	} {
+		qc := newQueryCache()
+		result, _ := cachedQuery(qc, "folder", "key", func() (int, error) {
+			invalidate(qc) // The index changes while the query runs
+			return 1, nil
+		})
+		if result != 1 {
+			t.Errorf("expected the stale result to be returned, got %d", result)
+		}
+
+		result, _ = cachedQuery(qc, "folder", "key", func() (int, error) { return 2, nil })
+		if result != 2 {
+			t.Errorf("expected the stale result not to be cached, got %d", result)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Largest number of bytes that can be read at once using EntryReader.ReadAt
+const maxEntryReadLength = 64 * 1024 * 1024
+
+// A handle for reading arbitrary ranges of a file (see Entry.OpenReader)
+type EntryReader struct {
+	reader *entryReader
+	cancel context.CancelFunc
+}
+
+// Opens the file for random access. Ranges are read from the local copy when it is the same version, and otherwise
+// only the blocks covering the range are fetched from peers. Fetched blocks are kept in the block cache, so reading
+// nearby ranges again is cheap. The handle always reads the version of the file this entry describes. Call Close when
+// done.
+func (entry *Entry) OpenReader() (*EntryReader, error) {
+	client := entry.Folder.client
+	if client.app == nil || client.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+	if entry.IsDirectory() || entry.IsSymlink() {
+		return nil, errors.New("entry is not a file")
+	}
+
+	ctx, cancel := context.WithCancel(client.ctx)
+	reader := newEntryReader(entry)
+	reader.ctx = ctx
+	return &EntryReader{reader: reader, cancel: cancel}, nil
+}
+
+func (er *EntryReader) Size() int64 {
+	return er.reader.entry.Size()
+}
+
+// Reads `length` bytes starting at `offset`. Fewer bytes are returned when the range extends beyond the end of the
+// file, and none when the offset is at or beyond the end.
+func (er *EntryReader) ReadAt(offset int64, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, errors.New("offset and length cannot be negative")
+	}
+	if length > maxEntryReadLength {
+		return nil, errors.New("cannot read this many bytes at once")
+	}
+
+	buffer := make([]byte, max(0, min(length, er.Size()-offset)))
+	if len(buffer) == 0 {
+		return buffer, nil
+	}
+	n, err := er.reader.ReadAt(buffer, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return buffer[:n], nil
+}
+
+// Cancels reads from peers in progress. The handle should not be used afterwards.
+func (er *EntryReader) Close() {
+	er.cancel()
+}
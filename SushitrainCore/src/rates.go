@@ -0,0 +1,141 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	defaultTransferRateWindow = 5 * time.Second
+
+	// Per-device counters maintained by Syncthing's protocol package, labeled by full device ID
+	metricNameDeviceRecvBytes = "syncthing_protocol_recv_bytes_total"
+	metricNameDeviceSentBytes = "syncthing_protocol_sent_bytes_total"
+)
+
+type TransferRates struct {
+	BytesInPerSecond  float64
+	BytesOutPerSecond float64
+}
+
+type rateSample struct {
+	at  time.Time
+	in  int64
+	out int64
+}
+
+// Calculates rates from a series of cumulative counter samples. Samples are taken whenever rates are requested; the
+// rate is averaged over (at least) the window.
+type rateMeter struct {
+	samples []rateSample
+}
+
+func (rm *rateMeter) sample(at time.Time, in int64, out int64, window time.Duration) *TransferRates {
+	rm.samples = append(rm.samples, rateSample{at: at, in: in, out: out})
+
+	// Keep the most recent sample taken before the window started, so we always have something to compare against
+	start := at.Add(-window)
+	for len(rm.samples) > 2 && !rm.samples[1].at.After(start) {
+		rm.samples = rm.samples[1:]
+	}
+
+	first := rm.samples[0]
+	elapsed := at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return &TransferRates{}
+	}
+
+	return &TransferRates{
+		BytesInPerSecond:  float64(max(0, in-first.in)) / elapsed,
+		BytesOutPerSecond: float64(max(0, out-first.out)) / elapsed,
+	}
+}
+
+type transferRates struct {
+	mutex     sync.Mutex
+	window    time.Duration
+	total     rateMeter
+	perDevice map[string]*rateMeter
+}
+
+func newTransferRates() *transferRates {
+	return &transferRates{
+		window:    defaultTransferRateWindow,
+		perDevice: map[string]*rateMeter{},
+	}
+}
+
+// Reads the cumulative number of bytes received from and sent to each device from Syncthing's metrics
+func deviceTransferTotals() (map[string]int64, map[string]int64) {
+	in := map[string]int64{}
+	out := map[string]int64{}
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		slog.Warn("could not gather transfer metrics", "cause", err)
+		return in, out
+	}
+
+	for _, family := range families {
+		var totals map[string]int64
+		switch family.GetName() {
+		case metricNameDeviceRecvBytes:
+			totals = in
+		case metricNameDeviceSentBytes:
+			totals = out
+		default:
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "device" {
+					totals[label.GetValue()] = int64(metric.GetCounter().GetValue())
+				}
+			}
+		}
+	}
+	return in, out
+}
+
+// Sets the period over which transfer rates are averaged
+func (clt *Client) SetTransferRateWindowSeconds(seconds float64) {
+	clt.transferRates.mutex.Lock()
+	defer clt.transferRates.mutex.Unlock()
+	clt.transferRates.window = time.Duration(seconds * float64(time.Second))
+}
+
+// Returns the current rate at which data is received from and sent to all devices combined
+func (clt *Client) TotalTransferRates() *TransferRates {
+	in, out := protocol.TotalInOut()
+
+	clt.transferRates.mutex.Lock()
+	defer clt.transferRates.mutex.Unlock()
+	return clt.transferRates.total.sample(time.Now(), in, out, clt.transferRates.window)
+}
+
+// Returns the current rate at which data is received from and sent to this device
+func (peer *Peer) TransferRates() *TransferRates {
+	deviceID := peer.deviceID.String()
+	in, out := deviceTransferTotals()
+
+	rates := peer.client.transferRates
+	rates.mutex.Lock()
+	defer rates.mutex.Unlock()
+
+	meter, ok := rates.perDevice[deviceID]
+	if !ok {
+		meter = &rateMeter{}
+		rates.perDevice[deviceID] = meter
+	}
+	return meter.sample(time.Now(), in[deviceID], out[deviceID], rates.window)
+}
@@ -0,0 +1,253 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"golang.org/x/exp/maps"
+)
+
+const (
+	RejectionKindFolder = "folder"
+	RejectionKindDevice = "device"
+
+	rejectionsFileName      = "rejections.json"
+	maxRejections           = 100
+	rejectionsFlushInterval = time.Minute
+)
+
+// A device that tried to connect to us while not being configured, or a folder that was offered to us but not accepted
+type Rejection struct {
+	ID          string
+	Kind        string
+	DeviceID    string
+	DeviceName  string
+	Address     string
+	FolderID    string
+	FolderLabel string
+	Count       int
+	FirstSeen   *Date
+	LastSeen    *Date
+}
+
+type Rejections struct {
+	items []*Rejection
+}
+
+func (rs *Rejections) Count() int {
+	return len(rs.items)
+}
+
+func (rs *Rejections) ItemAt(index int) *Rejection {
+	return rs.items[index]
+}
+
+type rejectionRecord struct {
+	Kind        string    `json:"kind"`
+	DeviceID    string    `json:"deviceID"`
+	DeviceName  string    `json:"deviceName,omitempty"`
+	Address     string    `json:"address,omitempty"`
+	FolderID    string    `json:"folderID,omitempty"`
+	FolderLabel string    `json:"folderLabel,omitempty"`
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	Dismissed   bool      `json:"dismissed"`
+}
+
+func rejectionID(kind string, deviceID string, folderID string) string {
+	if kind == RejectionKindFolder {
+		return kind + ":" + deviceID + ":" + folderID
+	}
+	return kind + ":" + deviceID
+}
+
+func (rec *rejectionRecord) id() string {
+	return rejectionID(rec.Kind, rec.DeviceID, rec.FolderID)
+}
+
+func (clt *Client) recordRejection(kind string, deviceID string, deviceName string, address string, folderID string, folderLabel string) {
+	id := rejectionID(kind, deviceID, folderID)
+	now := time.Now()
+
+	// Rejections repeat often (folder offers, for instance, with each cluster configuration the device sends). When only
+	// the count and time change, the store is written with the next change or periodic flush.
+	repeated := false
+	clt.rejections.read(func(records *map[string]*rejectionRecord) {
+		rec, ok := (*records)[id]
+		repeated = ok && !rec.Dismissed && (deviceName == "" || deviceName == rec.DeviceName) &&
+			(address == "" || address == rec.Address) && (folderLabel == "" || folderLabel == rec.FolderLabel)
+	})
+	if repeated {
+		clt.rejections.modifyLater(func(records *map[string]*rejectionRecord) {
+			if rec, ok := (*records)[id]; ok {
+				rec.Count += 1
+				rec.LastSeen = now
+			}
+		})
+		return
+	}
+
+	err := clt.rejections.modify(func(records *map[string]*rejectionRecord) {
+		if rec, ok := (*records)[id]; ok {
+			rec.Count += 1
+			rec.LastSeen = now
+			rec.Dismissed = false // A dismissed rejection re-appears when it happens again
+			if deviceName != "" {
+				rec.DeviceName = deviceName
+			}
+			if address != "" {
+				rec.Address = address
+			}
+			if folderLabel != "" {
+				rec.FolderLabel = folderLabel
+			}
+			return
+		}
+
+		(*records)[id] = &rejectionRecord{
+			Kind:        kind,
+			DeviceID:    deviceID,
+			DeviceName:  deviceName,
+			Address:     address,
+			FolderID:    folderID,
+			FolderLabel: folderLabel,
+			Count:       1,
+			FirstSeen:   now,
+			LastSeen:    now,
+		}
+
+		// Forget about the oldest rejections when there are too many
+		if len(*records) > maxRejections {
+			oldest := maps.Values(*records)
+			slices.SortFunc(oldest, func(a *rejectionRecord, b *rejectionRecord) int {
+				return a.LastSeen.Compare(b.LastSeen)
+			})
+			for _, rec := range oldest[0 : len(oldest)-maxRejections] {
+				delete(*records, rec.id())
+			}
+		}
+	})
+
+	if err != nil {
+		slog.Warn("could not save rejection", "id", id, "cause", err)
+	}
+}
+
+// Returns rejected devices and folder offers that were not dismissed, most recent first
+func (clt *Client) RecentRejections() *Rejections {
+	items := make([]*Rejection, 0)
+	clt.rejections.read(func(records *map[string]*rejectionRecord) {
+		for id, rec := range *records {
+			if rec.Dismissed {
+				continue
+			}
+			items = append(items, &Rejection{
+				ID:          id,
+				Kind:        rec.Kind,
+				DeviceID:    rec.DeviceID,
+				DeviceName:  rec.DeviceName,
+				Address:     rec.Address,
+				FolderID:    rec.FolderID,
+				FolderLabel: rec.FolderLabel,
+				Count:       rec.Count,
+				FirstSeen:   &Date{time: rec.FirstSeen},
+				LastSeen:    &Date{time: rec.LastSeen},
+			})
+		}
+	})
+
+	slices.SortFunc(items, func(a *Rejection, b *Rejection) int {
+		return b.LastSeen.time.Compare(a.LastSeen.time)
+	})
+	return &Rejections{items: items}
+}
+
+// Hides a rejection until it occurs again
+func (clt *Client) DismissRejection(id string) error {
+	found := false
+	err := clt.rejections.modify(func(records *map[string]*rejectionRecord) {
+		if rec, ok := (*records)[id]; ok {
+			rec.Dismissed = true
+			found = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("rejection not found")
+	}
+	return nil
+}
+
+// Adds the rejected device to the ignored devices list, or the rejected folder to the ignored folders list of the
+// offering device, so Syncthing will not report it anymore. The rejection is removed from the history.
+func (clt *Client) IgnoreRejectionForever(id string) error {
+	var rec *rejectionRecord
+	clt.rejections.read(func(records *map[string]*rejectionRecord) {
+		if r, ok := (*records)[id]; ok {
+			copied := *r
+			rec = &copied
+		}
+	})
+	if rec == nil {
+		return errors.New("rejection not found")
+	}
+
+	devID, err := protocol.DeviceIDFromString(rec.DeviceID)
+	if err != nil {
+		return err
+	}
+
+	switch rec.Kind {
+	case RejectionKindDevice:
+		err = clt.changeConfiguration(func(cfg *config.Configuration) {
+			for _, od := range cfg.IgnoredDevices {
+				if od.ID == devID {
+					return
+				}
+			}
+			cfg.IgnoredDevices = append(cfg.IgnoredDevices, config.ObservedDevice{
+				Time:    time.Now().Truncate(time.Second),
+				ID:      devID,
+				Name:    rec.DeviceName,
+				Address: rec.Address,
+			})
+		})
+
+	case RejectionKindFolder:
+		err = clt.changeConfiguration(func(cfg *config.Configuration) {
+			_, index, ok := cfg.Device(devID)
+			if !ok || cfg.Devices[index].IgnoredFolder(rec.FolderID) {
+				return
+			}
+			dc := &cfg.Devices[index]
+			dc.IgnoredFolders = append(dc.IgnoredFolders, config.ObservedFolder{
+				Time:  time.Now().Truncate(time.Second),
+				ID:    rec.FolderID,
+				Label: rec.FolderLabel,
+			})
+		})
+
+	default:
+		return errors.New("unknown rejection kind: " + rec.Kind)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return clt.rejections.modify(func(records *map[string]*rejectionRecord) {
+		delete(*records, id)
+	})
+}
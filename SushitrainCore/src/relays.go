@@ -0,0 +1,172 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/osutil"
+)
+
+const (
+	defaultRelayPoolURL       = "https://relays.syncthing.net/endpoint"
+	dynamicRelayPrefix        = "dynamic+"
+	relayPoolTimeout          = 10 * time.Second
+	relayLatencyTimeout       = 2 * time.Second
+	relayLatencyMeasurers     = 16
+	unreachableRelayLatencyMS = -1.0
+)
+
+type Relay struct {
+	URL string
+
+	// Round-trip time to connect to the relay in milliseconds, or -1 when the relay could not be reached
+	LatencyMS float64
+}
+
+type Relays struct {
+	data []*Relay
+}
+
+func (rs *Relays) Count() int {
+	return len(rs.data)
+}
+
+func (rs *Relays) ItemAt(index int) *Relay {
+	if index < 0 || index >= len(rs.data) {
+		return nil
+	}
+	return rs.data[index]
+}
+
+// The relay pool announcement, i.e. {"relays": [{"url": "relay://10.20.30.40:5060"}, ...]}
+type relayPoolAnnouncement struct {
+	Relays []struct {
+		URL string `json:"url"`
+	} `json:"relays"`
+}
+
+func (clt *Client) relayPoolURL() string {
+	for _, addr := range clt.config.Options().ListenAddresses() {
+		if strings.HasPrefix(addr, dynamicRelayPrefix) {
+			return strings.TrimPrefix(addr, dynamicRelayPrefix)
+		}
+	}
+	return defaultRelayPoolURL
+}
+
+// Fetches the list of relays from the relay pool and measures the latency to each of them. The result is sorted from
+// lowest to highest latency, with unreachable relays last. This takes a while; do not call from the main thread.
+func (clt *Client) AvailableRelays() (*Relays, error) {
+	ctx, cancel := context.WithTimeout(clt.ctx, relayPoolTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, clt.relayPoolURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var announcement relayPoolAnnouncement
+	if err := json.NewDecoder(res.Body).Decode(&announcement); err != nil {
+		return nil, err
+	}
+
+	relays := make([]*Relay, 0, len(announcement.Relays))
+	for _, ann := range announcement.Relays {
+		relays = append(relays, &Relay{URL: ann.URL, LatencyMS: unreachableRelayLatencyMS})
+	}
+
+	// Measure latencies, a few relays at a time
+	work := make(chan *Relay)
+	var wg sync.WaitGroup
+	for range relayLatencyMeasurers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for relay := range work {
+				measureCtx, measureCancel := context.WithTimeout(clt.ctx, relayLatencyTimeout)
+				if latency, err := osutil.GetLatencyForURL(measureCtx, relay.URL); err == nil {
+					relay.LatencyMS = float64(latency) / float64(time.Millisecond)
+				}
+				measureCancel()
+			}
+		}()
+	}
+	for _, relay := range relays {
+		work <- relay
+	}
+	close(work)
+	wg.Wait()
+
+	slices.SortFunc(relays, func(a, b *Relay) int {
+		if (a.LatencyMS < 0) != (b.LatencyMS < 0) {
+			if a.LatencyMS < 0 {
+				return 1
+			}
+			return -1
+		}
+		if a.LatencyMS < b.LatencyMS {
+			return -1
+		} else if a.LatencyMS > b.LatencyMS {
+			return 1
+		}
+		return 0
+	})
+	return &Relays{data: relays}, nil
+}
+
+// Returns the relays that were pinned using SetPreferredRelays
+func (clt *Client) PreferredRelays() *ListOfStrings {
+	return List(Filter(clt.config.Options().ListenAddresses(), func(addr string) bool {
+		return strings.HasPrefix(addr, "relay://")
+	}))
+}
+
+// Use only the given relays (relay:// URLs) instead of one picked from the relay pool. An empty list restores the
+// default of using the relay pool.
+func (clt *Client) SetPreferredRelays(urls *ListOfStrings) error {
+	relayURLs := make([]string, 0)
+	if urls != nil {
+		for _, relayURL := range urls.data {
+			u, err := url.Parse(relayURL)
+			if err != nil {
+				return err
+			}
+			if u.Scheme != "relay" || u.Host == "" {
+				return errors.New("not a relay URL: " + relayURL)
+			}
+			relayURLs = append(relayURLs, relayURL)
+		}
+	}
+
+	poolURL := clt.relayPoolURL()
+	return clt.changeConfiguration(func(cfg *config.Configuration) {
+		addrs := Filter(cfg.Options.ListenAddresses(), func(addr string) bool {
+			return !strings.HasPrefix(addr, dynamicRelayPrefix) && !strings.HasPrefix(addr, "relay://")
+		})
+
+		if len(relayURLs) == 0 {
+			addrs = append(addrs, dynamicRelayPrefix+poolURL)
+		} else {
+			addrs = append(addrs, relayURLs...)
+		}
+		cfg.Options.RawListenAddresses = addrs
+	})
+}
@@ -0,0 +1,279 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	remoteRequestTimeout = 10 * time.Second
+	remoteAPIKeyHeader   = "X-API-Key"
+)
+
+// A connection to the REST API of another Syncthing instance (e.g. a server the user also runs), used to show its
+// status. Only reads are performed.
+type RemoteSyncthing struct {
+	baseURL    *url.URL
+	apiKey     string
+	httpClient *http.Client
+}
+
+// Status of the remote instance itself
+type RemoteStatus struct {
+	DeviceID      string
+	Version       string
+	UptimeSeconds int
+}
+
+// Status of a folder on the remote instance
+type RemoteFolder struct {
+	FolderID    string
+	Label       string
+	Paused      bool
+	State       string // e.g. "idle", "scanning", "syncing" or "error"
+	GlobalBytes int64
+	NeedBytes   int64
+	NeedFiles   int
+	Errors      int
+}
+
+type RemoteFolders struct {
+	data []*RemoteFolder
+}
+
+func (rf *RemoteFolders) Count() int {
+	return len(rf.data)
+}
+
+func (rf *RemoteFolders) ItemAt(index int) *RemoteFolder {
+	if index < 0 || index >= len(rf.data) {
+		return nil
+	}
+	return rf.data[index]
+}
+
+// A device known to the remote instance, as seen from that instance
+type RemoteDevice struct {
+	DeviceID   string
+	Name       string
+	Paused     bool
+	Connected  bool
+	Address    string  // Address of the current connection, if any
+	Completion float64 // Percentage (0-100) of the shared data the device has
+}
+
+type RemoteDevices struct {
+	data []*RemoteDevice
+}
+
+func (rd *RemoteDevices) Count() int {
+	return len(rd.data)
+}
+
+func (rd *RemoteDevices) ItemAt(index int) *RemoteDevice {
+	if index < 0 || index >= len(rd.data) {
+		return nil
+	}
+	return rd.data[index]
+}
+
+// Creates a connection to the REST API of a Syncthing instance at `address` (e.g. "https://192.168.1.10:8384"; https is
+// assumed when no scheme is given), using the API key from its settings. Syncthing uses a self-signed certificate for
+// its GUI by default; pass the SHA-256 fingerprint of that certificate to accept it (and only it). When no fingerprint
+// is given, the certificate must be trusted by the system.
+func NewRemoteSyncthing(address string, apiKey string, certificateFingerprintSHA256 []byte) (*RemoteSyncthing, error) {
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	baseURL, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, errors.New("address must use http or https")
+	}
+	if baseURL.Host == "" {
+		return nil, errors.New("address must include a host")
+	}
+	if apiKey == "" {
+		return nil, errors.New("an API key is required")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(certificateFingerprintSHA256) > 0 {
+		fingerprint := bytes.Clone(certificateFingerprintSHA256)
+		transport.TLSClientConfig = &tls.Config{
+			// Verification is done by comparing the fingerprint instead
+			InsecureSkipVerify: true,
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 {
+					return errors.New("no certificate presented")
+				}
+				actual := sha256.Sum256(rawCerts[0])
+				if !bytes.Equal(actual[:], fingerprint) {
+					return errors.New("certificate fingerprint does not match")
+				}
+				return nil
+			},
+		}
+	}
+
+	return &RemoteSyncthing{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Transport: transport, Timeout: remoteRequestTimeout},
+	}, nil
+}
+
+// Performs a GET request for the REST endpoint and decodes the JSON response into `result`
+func (rs *RemoteSyncthing) get(endpoint string, query url.Values, result any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteRequestTimeout)
+	defer cancel()
+
+	endpointURL := rs.baseURL.JoinPath(endpoint)
+	endpointURL.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpointURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(remoteAPIKeyHeader, rs.apiKey)
+
+	res, err := rs.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return errors.New("the API key was not accepted")
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+func (rs *RemoteSyncthing) Status() (*RemoteStatus, error) {
+	var status struct {
+		MyID   string `json:"myID"`
+		Uptime int    `json:"uptime"`
+	}
+	if err := rs.get("rest/system/status", nil, &status); err != nil {
+		return nil, err
+	}
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := rs.get("rest/system/version", nil, &version); err != nil {
+		return nil, err
+	}
+
+	return &RemoteStatus{
+		DeviceID:      status.MyID,
+		Version:       version.Version,
+		UptimeSeconds: status.Uptime,
+	}, nil
+}
+
+// Returns the folders of the remote instance with their status. Paused folders have no status.
+func (rs *RemoteSyncthing) Folders() (*RemoteFolders, error) {
+	var configs []struct {
+		ID     string `json:"id"`
+		Label  string `json:"label"`
+		Paused bool   `json:"paused"`
+	}
+	if err := rs.get("rest/config/folders", nil, &configs); err != nil {
+		return nil, err
+	}
+
+	folders := make([]*RemoteFolder, 0, len(configs))
+	for _, fc := range configs {
+		folder := &RemoteFolder{FolderID: fc.ID, Label: fc.Label, Paused: fc.Paused}
+		if !fc.Paused {
+			var status struct {
+				State       string `json:"state"`
+				GlobalBytes int64  `json:"globalBytes"`
+				NeedBytes   int64  `json:"needBytes"`
+				NeedFiles   int    `json:"needFiles"`
+				Errors      int    `json:"errors"`
+			}
+			if err := rs.get("rest/db/status", url.Values{"folder": {fc.ID}}, &status); err != nil {
+				return nil, err
+			}
+			folder.State = status.State
+			folder.GlobalBytes = status.GlobalBytes
+			folder.NeedBytes = status.NeedBytes
+			folder.NeedFiles = status.NeedFiles
+			folder.Errors = status.Errors
+		}
+		folders = append(folders, folder)
+	}
+	return &RemoteFolders{data: folders}, nil
+}
+
+// Returns the devices the remote instance knows about (except itself), with their connection status
+func (rs *RemoteSyncthing) Devices() (*RemoteDevices, error) {
+	var status struct {
+		MyID string `json:"myID"`
+	}
+	if err := rs.get("rest/system/status", nil, &status); err != nil {
+		return nil, err
+	}
+
+	var configs []struct {
+		DeviceID string `json:"deviceID"`
+		Name     string `json:"name"`
+		Paused   bool   `json:"paused"`
+	}
+	if err := rs.get("rest/config/devices", nil, &configs); err != nil {
+		return nil, err
+	}
+
+	var connections struct {
+		Connections map[string]struct {
+			Connected bool   `json:"connected"`
+			Address   string `json:"address"`
+		} `json:"connections"`
+	}
+	if err := rs.get("rest/system/connections", nil, &connections); err != nil {
+		return nil, err
+	}
+
+	devices := make([]*RemoteDevice, 0, len(configs))
+	for _, dc := range configs {
+		if dc.DeviceID == status.MyID {
+			continue
+		}
+		device := &RemoteDevice{DeviceID: dc.DeviceID, Name: dc.Name, Paused: dc.Paused}
+		if connection, ok := connections.Connections[dc.DeviceID]; ok {
+			device.Connected = connection.Connected
+			device.Address = connection.Address
+		}
+
+		var completion struct {
+			Completion float64 `json:"completion"`
+		}
+		if err := rs.get("rest/db/completion", url.Values{"device": {dc.DeviceID}}, &completion); err != nil {
+			return nil, err
+		}
+		device.Completion = completion.Completion
+		devices = append(devices, device)
+	}
+	return &RemoteDevices{data: devices}, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/syncthing/syncthing/lib/svcutil"
+)
+
+// Returns whether some configuration changes only take effect after Syncthing is restarted (see RestartInPlace)
+func (clt *Client) ConfigRequiresRestart() bool {
+	if clt.config == nil {
+		return false
+	}
+	return clt.config.RequiresRestart()
+}
+
+// Stops Syncthing and starts it again with the saved configuration. The client itself (including the streaming server
+// and delegates) is kept, but connections are closed and transfers in progress are aborted. When the saved configuration
+// cannot be loaded, Syncthing is started again with the configuration in use. When the database cannot be opened again,
+// the client continues without Syncthing running, as if it could not be opened while loading (see RepairDatabase).
+func (clt *Client) RestartInPlace() error {
+	if clt.app == nil || clt.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	clt.restartMutex.Lock()
+	defer clt.restartMutex.Unlock()
+
+	slog.Info("restarting Syncthing in place")
+	clt.app.Stop(svcutil.ExitRestart)
+	clt.app.Wait()
+
+	// The configuration remembers whether a restart is required until it is reloaded
+	if err := clt.saveConfiguration(); err != nil {
+		slog.Warn("could not save configuration before restarting", "cause", err)
+	}
+
+	configCtx, configCancel := context.WithCancel(clt.ctx)
+	config, err := loadOrDefaultConfig(clt.deviceID(), configCtx, clt.evLogger, clt.filesPath, &clt.options,
+		!clt.hasCustomFolderDefaults(), clt.storedConnectionProfile())
+	if err != nil {
+		slog.Warn("could not reload configuration, restarting with the configuration in use", "cause", err)
+		configCancel()
+	} else {
+		clt.mutex.Lock()
+		previousCancel := clt.configCancel
+		clt.config = clt.newKeychainConfigWrapper(config, clt.evLogger)
+		clt.configCancel = configCancel
+		clt.mutex.Unlock()
+		previousCancel()
+
+		// The configuration file does not have the passwords in the keychain
+		if err := clt.restoreFolderEncryptionPasswords(); err != nil {
+			slog.Warn("could not restore folder encryption passwords from keychain", "cause", err)
+		}
+	}
+	clt.queryCache.invalidateAll()
+
+	app, err := clt.newApp(false)
+	clt.mutex.Lock()
+	if err != nil {
+		slog.Error("could not open database after restarting, continuing in degraded mode", "cause", err)
+		clt.app = nil
+		clt.databaseError = err
+		clt.mutex.Unlock()
+		return err
+	}
+	clt.app = app
+	clt.mutex.Unlock()
+	return app.Start()
+}
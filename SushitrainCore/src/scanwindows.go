@@ -0,0 +1,146 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+const scanWindowPolicyFileName = "scanwindows.json"
+
+type scanSettings struct {
+	RescanIntervalS  int  `json:"rescanIntervalS"`
+	FSWatcherEnabled bool `json:"fsWatcherEnabled"`
+}
+
+type scanWindowPolicy struct {
+	// Folders that are only scanned while the device is charging
+	ChargingOnly []string `json:"chargingOnly"`
+
+	// The scan settings of folders whose scanning we suspended, so they can be restored afterwards
+	Suspended map[string]scanSettings `json:"suspended"`
+}
+
+// Should be called by the app whenever the device starts or stops charging (and on launch). Until it is called (or
+// SetBatteryLevel is), the charging state is unknown and scanning is not restricted.
+func (clt *Client) SetCharging(charging bool) error {
+	clt.mutex.Lock()
+	changed := !clt.chargingKnown || clt.batteryCharging != charging
+	clt.batteryCharging = charging
+	clt.chargingKnown = true
+	clt.mutex.Unlock()
+
+	if !changed {
+		return nil
+	}
+	slog.Info("charging state changed", "charging", charging)
+	if err := clt.applyPowerPolicy(); err != nil {
+		return err
+	}
+	return clt.applyScanWindows()
+}
+
+// Returns whether the folder is only scanned while the device is charging (see SetScanOnlyWhenCharging)
+func (fld *Folder) ScansOnlyWhenCharging() bool {
+	chargingOnly := false
+	fld.client.scanWindows.read(func(policy *scanWindowPolicy) {
+		chargingOnly = slices.Contains(policy.ChargingOnly, fld.FolderID)
+	})
+	return chargingOnly
+}
+
+// Restricts scanning (and therefore hashing, which uses most of the battery) of the folder to periods in which the device
+// is charging. Changes from other devices are still pulled, and local changes are picked up once the device is charging
+// again. While not charging, the periodic rescan and the file system watcher of the folder are disabled; they are
+// restored when charging, unless they were changed in the meantime. Scans requested explicitly still happen.
+func (fld *Folder) SetScanOnlyWhenCharging(chargingOnly bool) error {
+	err := fld.client.scanWindows.modify(func(policy *scanWindowPolicy) {
+		policy.ChargingOnly = slices.DeleteFunc(policy.ChargingOnly, func(id string) bool { return id == fld.FolderID })
+		if chargingOnly {
+			policy.ChargingOnly = append(policy.ChargingOnly, fld.FolderID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return fld.client.applyScanWindows()
+}
+
+// Returns whether scanning of the folder is currently suspended because the device is not charging
+func (fld *Folder) IsScanSuspended() bool {
+	suspended := false
+	fld.client.scanWindows.read(func(policy *scanWindowPolicy) {
+		_, suspended = policy.Suspended[fld.FolderID]
+	})
+	return suspended
+}
+
+// Suspends or restores scanning of folders according to the current charging state
+func (clt *Client) applyScanWindows() error {
+	if clt.config == nil {
+		return ErrStillLoading
+	}
+
+	clt.mutex.Lock()
+	notCharging := clt.chargingKnown && !clt.batteryCharging
+	clt.mutex.Unlock()
+
+	suspend := map[string]bool{}
+	restore := map[string]scanSettings{}
+	err := clt.scanWindows.modify(func(policy *scanWindowPolicy) {
+		if policy.Suspended == nil {
+			policy.Suspended = map[string]scanSettings{}
+		}
+
+		folders := clt.config.Folders()
+		for folderID, fc := range folders {
+			_, suspended := policy.Suspended[folderID]
+			shouldSuspend := notCharging && slices.Contains(policy.ChargingOnly, folderID)
+			if shouldSuspend && !suspended {
+				suspend[folderID] = true
+				policy.Suspended[folderID] = scanSettings{
+					RescanIntervalS:  fc.RescanIntervalS,
+					FSWatcherEnabled: fc.FSWatcherEnabled,
+				}
+			} else if !shouldSuspend && suspended {
+				restore[folderID] = policy.Suspended[folderID]
+				delete(policy.Suspended, folderID)
+			}
+		}
+
+		// Forget about folders that were removed
+		for folderID := range policy.Suspended {
+			if _, ok := folders[folderID]; !ok {
+				delete(policy.Suspended, folderID)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(suspend) == 0 && len(restore) == 0 {
+		return nil
+	}
+
+	slog.Info("applying scan windows", "suspend", len(suspend), "restore", len(restore))
+	return clt.changeConfiguration(func(cfg *config.Configuration) {
+		for i := range cfg.Folders {
+			fc := &cfg.Folders[i]
+			if suspend[fc.ID] {
+				fc.RescanIntervalS = 0
+				fc.FSWatcherEnabled = false
+			} else if settings, ok := restore[fc.ID]; ok && fc.RescanIntervalS == 0 && !fc.FSWatcherEnabled {
+				// Only restore when the settings were not changed in the meantime
+				fc.RescanIntervalS = settings.RescanIntervalS
+				fc.FSWatcherEnabled = settings.FSWatcherEnabled
+			}
+		}
+	})
+}
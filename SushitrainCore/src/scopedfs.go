@@ -0,0 +1,527 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Swift-side interface for a file system that is accessed through security-scoped URLs (e.g. folders on external drives
+// or in the containers of other apps, picked by the user). The URI is the folder path as stored in the configuration
+// (typically a bookmark the delegate can resolve to a URL); paths are relative to it, slash-separated, and empty for the
+// root of the folder.
+type ScopedFilesystemDelegate interface {
+	// Called before the file system is used. May be called repeatedly for the same URI and should start accessing the
+	// security-scoped resource only once.
+	StartAccessing(uri string) error
+
+	// Returns information about the item at the path, or an item for which Exists returns false if there is none
+	Stat(uri string, path string) (ScopedFileInfo, error)
+
+	// Returns the names of the items in the directory at the path
+	List(uri string, path string) (*ListOfStrings, error)
+
+	// Opens the file at the path, creating it (empty) when `create` is set and it does not exist
+	Open(uri string, path string, write bool, create bool) (ScopedFileHandle, error)
+
+	Mkdir(uri string, path string) error
+
+	// Removes the file or directory (including its contents) at the path
+	Remove(uri string, path string) error
+
+	Rename(uri string, fromPath string, toPath string) error
+	SetModifiedTime(uri string, path string, modifiedTimeNano int64) error
+	FreeBytes(uri string) (int64, error)
+	TotalBytes(uri string) (int64, error)
+}
+
+type ScopedFileInfo interface {
+	Exists() bool
+	IsDir() bool
+	Size() int64
+	ModifiedTimeNano() int64 // Unix timestamp in nanoseconds
+}
+
+// An open file. ReadAt returns fewer bytes than requested (or none) when the end of the file is reached.
+type ScopedFileHandle interface {
+	ReadAt(offset int64, length int) ([]byte, error)
+	WriteAt(data []byte, offset int64) error
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+type scopedFilesystem struct {
+	fsType   fs.FilesystemType
+	uri      string
+	delegate ScopedFilesystemDelegate
+}
+
+type scopedFile struct {
+	fs       *scopedFilesystem
+	path     string
+	handle   ScopedFileHandle
+	position int64
+	mut      sync.Mutex
+}
+
+type scopedFileInfo struct {
+	name string
+	info ScopedFileInfo
+}
+
+var _ fs.Filesystem = &scopedFilesystem{}
+var _ fs.File = &scopedFile{}
+var _ fs.FileInfo = &scopedFileInfo{}
+
+var scopedFilesystemTypes = struct {
+	mutex sync.Mutex
+	types map[fs.FilesystemType]bool
+}{types: map[fs.FilesystemType]bool{}}
+
+// Registers a file system type for folders that are stored in locations the app can only access through security-scoped
+// URLs. Unlike file systems registered with RegisterCustomFilesystemType, these can be written to, so they can be used
+// for folders of any type. Add such folders using Client.AddSpecialFolder.
+func RegisterScopedFilesystemType(fsType string, delegate ScopedFilesystemDelegate) {
+	fsTypeStruct := fs.FilesystemType(fsType)
+	scopedFilesystemTypes.mutex.Lock()
+	scopedFilesystemTypes.types[fsTypeStruct] = true
+	scopedFilesystemTypes.mutex.Unlock()
+
+	fs.RegisterFilesystemType(fsTypeStruct, func(uri string, _opts ...fs.Option) (fs.Filesystem, error) {
+		if err := delegate.StartAccessing(uri); err != nil {
+			return nil, err
+		}
+		return &scopedFilesystem{
+			fsType:   fsTypeStruct,
+			uri:      uri,
+			delegate: delegate,
+		}, nil
+	})
+}
+
+func isScopedFilesystemType(fsType config.FilesystemType) bool {
+	scopedFilesystemTypes.mutex.Lock()
+	defer scopedFilesystemTypes.mutex.Unlock()
+	return scopedFilesystemTypes.types[fs.FilesystemType(fsType)]
+}
+
+// Turns a name as used by Syncthing into a path relative to the root of the folder ("" for the root itself)
+func scopedPath(name string) string {
+	cleaned := path.Clean(strings.TrimPrefix(name, "/"))
+	if cleaned == "." || cleaned == "/" {
+		return ""
+	}
+	return cleaned
+}
+
+func (sfs *scopedFilesystem) stat(name string) (*scopedFileInfo, error) {
+	p := scopedPath(name)
+	info, err := sfs.delegate.Stat(sfs.uri, p)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil || !info.Exists() {
+		return nil, fs.ErrNotExist
+	}
+	return &scopedFileInfo{name: path.Base(p), info: info}, nil
+}
+
+func (sfs *scopedFilesystem) Stat(name string) (fs.FileInfo, error) {
+	return sfs.stat(name)
+}
+
+// We don't have links, so Stat == Lstat
+func (sfs *scopedFilesystem) Lstat(name string) (fs.FileInfo, error) {
+	return sfs.stat(name)
+}
+
+func (sfs *scopedFilesystem) DirNames(name string) ([]string, error) {
+	names, err := sfs.delegate.List(sfs.uri, scopedPath(name))
+	if err != nil {
+		return nil, err
+	}
+	if names == nil {
+		return []string{}, nil
+	}
+	return names.data, nil
+}
+
+func (sfs *scopedFilesystem) Open(name string) (fs.File, error) {
+	return sfs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (sfs *scopedFilesystem) Create(name string) (fs.File, error) {
+	return sfs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+func (sfs *scopedFilesystem) OpenFile(name string, flags int, mode fs.FileMode) (fs.File, error) {
+	p := scopedPath(name)
+	write := flags&(os.O_WRONLY|os.O_RDWR) != 0
+	create := flags&os.O_CREATE != 0
+
+	if create && flags&os.O_EXCL != 0 {
+		if _, err := sfs.stat(p); err == nil {
+			return nil, fs.ErrExist
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	} else if !create {
+		if _, err := sfs.stat(p); err != nil {
+			return nil, err
+		}
+	}
+
+	handle, err := sfs.delegate.Open(sfs.uri, p, write, create)
+	if err != nil {
+		return nil, err
+	}
+
+	if write && flags&os.O_TRUNC != 0 {
+		if err := handle.Truncate(0); err != nil {
+			handle.Close()
+			return nil, err
+		}
+	}
+	return &scopedFile{fs: sfs, path: p, handle: handle}, nil
+}
+
+func (sfs *scopedFilesystem) Mkdir(name string, perm fs.FileMode) error {
+	p := scopedPath(name)
+	if _, err := sfs.stat(p); err == nil {
+		return fs.ErrExist
+	}
+	return sfs.delegate.Mkdir(sfs.uri, p)
+}
+
+func (sfs *scopedFilesystem) MkdirAll(name string, perm fs.FileMode) error {
+	p := scopedPath(name)
+	if p == "" {
+		return nil
+	}
+
+	current := ""
+	for _, part := range strings.Split(p, "/") {
+		current = path.Join(current, part)
+		info, err := sfs.stat(current)
+		if err == nil {
+			if !info.IsDir() {
+				return errors.New("not a directory: " + current)
+			}
+			continue
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := sfs.delegate.Mkdir(sfs.uri, current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Removes a file or an empty directory
+func (sfs *scopedFilesystem) Remove(name string) error {
+	p := scopedPath(name)
+	info, err := sfs.stat(p)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		children, err := sfs.DirNames(p)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return errors.New("directory not empty: " + p)
+		}
+	}
+	return sfs.delegate.Remove(sfs.uri, p)
+}
+
+func (sfs *scopedFilesystem) RemoveAll(name string) error {
+	p := scopedPath(name)
+	if _, err := sfs.stat(p); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return sfs.delegate.Remove(sfs.uri, p)
+}
+
+func (sfs *scopedFilesystem) Rename(oldname string, newname string) error {
+	return sfs.delegate.Rename(sfs.uri, scopedPath(oldname), scopedPath(newname))
+}
+
+func (sfs *scopedFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return sfs.delegate.SetModifiedTime(sfs.uri, scopedPath(name), mtime.UnixNano())
+}
+
+func (sfs *scopedFilesystem) Glob(pattern string) ([]string, error) {
+	dir, filePattern := path.Split(scopedPath(pattern))
+	names, err := sfs.DirNames(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	matches := make([]string, 0)
+	for _, name := range names {
+		if ok, err := path.Match(filePattern, name); err != nil {
+			return nil, err
+		} else if ok {
+			matches = append(matches, path.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func (sfs *scopedFilesystem) Usage(name string) (fs.Usage, error) {
+	free, err := sfs.delegate.FreeBytes(sfs.uri)
+	if err != nil {
+		return fs.Usage{}, err
+	}
+	total, err := sfs.delegate.TotalBytes(sfs.uri)
+	if err != nil {
+		return fs.Usage{}, err
+	}
+	return fs.Usage{Free: uint64(max(free, 0)), Total: uint64(max(total, 0))}, nil
+}
+
+func (sfs *scopedFilesystem) Roots() ([]string, error) {
+	return []string{"/"}, nil
+}
+
+func (sfs *scopedFilesystem) Walk(name string, walkFn fs.WalkFunc) error {
+	// Implemented by Syncthing itself through WalkFS
+	panic("unimplemented")
+}
+
+func (sfs *scopedFilesystem) SameFile(fi1 fs.FileInfo, fi2 fs.FileInfo) bool {
+	return fi1.Name() == fi2.Name() && fi1.Size() == fi2.Size() && fi1.ModTime().Equal(fi2.ModTime())
+}
+
+// We support no options
+func (sfs *scopedFilesystem) Options() []fs.Option {
+	return make([]fs.Option, 0)
+}
+
+func (sfs *scopedFilesystem) SymlinksSupported() bool {
+	return false
+}
+
+func (sfs *scopedFilesystem) Type() fs.FilesystemType {
+	return sfs.fsType
+}
+
+func (sfs *scopedFilesystem) URI() string {
+	return sfs.uri
+}
+
+func (sfs *scopedFilesystem) Underlying() (fs.Filesystem, bool) {
+	return nil, false
+}
+
+func (sfs *scopedFilesystem) PlatformData(name string, withOwnership bool, withXattrs bool, xattrFilter fs.XattrFilter) (protocol.PlatformData, error) {
+	return protocol.PlatformData{}, nil
+}
+
+// Permissions, ownership and hidden flags cannot be set through security-scoped URLs; pretend they were
+func (sfs *scopedFilesystem) Chmod(name string, mode fs.FileMode) error {
+	return nil
+}
+
+func (sfs *scopedFilesystem) Lchown(name string, uid string, gid string) error {
+	return nil
+}
+
+func (sfs *scopedFilesystem) Hide(name string) error {
+	return nil
+}
+
+func (sfs *scopedFilesystem) Unhide(name string) error {
+	return nil
+}
+
+// We don't have no xattrs
+func (sfs *scopedFilesystem) GetXattr(name string, xattrFilter fs.XattrFilter) ([]protocol.Xattr, error) {
+	return make([]protocol.Xattr, 0), nil
+}
+
+func (sfs *scopedFilesystem) SetXattr(path string, xattrs []protocol.Xattr, xattrFilter fs.XattrFilter) error {
+	return nil
+}
+
+// Unimplemented parts of the Filesystem interface return an error. Syncthing falls back to periodic scans when watching
+// is not supported.
+func (sfs *scopedFilesystem) CreateSymlink(target string, name string) error {
+	return errNotImplemented
+}
+
+func (sfs *scopedFilesystem) ReadSymlink(name string) (string, error) {
+	return "", errNotImplemented
+}
+
+func (sfs *scopedFilesystem) Watch(path string, ignore fs.Matcher, ctx context.Context, ignorePerms bool) (<-chan fs.Event, <-chan error, error) {
+	return nil, nil, errNotImplemented
+}
+
+// File implementation
+func (sf *scopedFile) Name() string {
+	return sf.path
+}
+
+func (sf *scopedFile) Close() error {
+	return sf.handle.Close()
+}
+
+func (sf *scopedFile) Read(p []byte) (int, error) {
+	sf.mut.Lock()
+	defer sf.mut.Unlock()
+	n, err := sf.readAt(p, sf.position)
+	sf.position += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (sf *scopedFile) ReadAt(p []byte, offset int64) (int, error) {
+	return sf.readAt(p, offset)
+}
+
+func (sf *scopedFile) readAt(p []byte, offset int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	data, err := sf.handle.ReadAt(offset, len(p))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, data)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (sf *scopedFile) Write(p []byte) (int, error) {
+	sf.mut.Lock()
+	defer sf.mut.Unlock()
+	if err := sf.handle.WriteAt(p, sf.position); err != nil {
+		return 0, err
+	}
+	sf.position += int64(len(p))
+	return len(p), nil
+}
+
+func (sf *scopedFile) WriteAt(p []byte, offset int64) (int, error) {
+	if err := sf.handle.WriteAt(p, offset); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (sf *scopedFile) Seek(offset int64, whence int) (int64, error) {
+	sf.mut.Lock()
+	defer sf.mut.Unlock()
+
+	var position int64
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = sf.position + offset
+	case io.SeekEnd:
+		info, err := sf.fs.stat(sf.path)
+		if err != nil {
+			return sf.position, err
+		}
+		position = info.Size() + offset
+	}
+
+	if position < 0 {
+		return sf.position, errSeekBeforeStart
+	}
+	sf.position = position
+	return sf.position, nil
+}
+
+func (sf *scopedFile) Stat() (fs.FileInfo, error) {
+	return sf.fs.stat(sf.path)
+}
+
+func (sf *scopedFile) Sync() error {
+	return sf.handle.Sync()
+}
+
+func (sf *scopedFile) Truncate(size int64) error {
+	return sf.handle.Truncate(size)
+}
+
+// FileInfo implementation
+func (sfi *scopedFileInfo) Name() string {
+	return sfi.name
+}
+
+func (sfi *scopedFileInfo) IsDir() bool {
+	return sfi.info.IsDir()
+}
+
+func (sfi *scopedFileInfo) IsRegular() bool {
+	return !sfi.info.IsDir()
+}
+
+// We don't do symlinks
+func (sfi *scopedFileInfo) IsSymlink() bool {
+	return false
+}
+
+func (sfi *scopedFileInfo) Mode() fs.FileMode {
+	if sfi.IsDir() {
+		return 0755
+	}
+	return 0644
+}
+
+func (sfi *scopedFileInfo) Size() int64 {
+	if sfi.IsDir() {
+		return 0
+	}
+	return sfi.info.Size()
+}
+
+func (sfi *scopedFileInfo) ModTime() time.Time {
+	return time.Unix(0, sfi.info.ModifiedTimeNano())
+}
+
+func (sfi *scopedFileInfo) Owner() int {
+	return 0
+}
+
+func (sfi *scopedFileInfo) Group() int {
+	return 0
+}
+
+func (sfi *scopedFileInfo) InodeChangeTime() time.Time {
+	return time.Time{}
+}
+
+func (sfi *scopedFileInfo) Sys() interface{} {
+	return nil
+}
@@ -25,13 +25,15 @@ func NewSelection(lines []string) *Selection {
 
 // Returns whether the provided set of ignore lines are valid for 'selective' mode
 func (sel *Selection) isSelectiveIgnore() bool {
-	if len(sel.lines) == 0 {
+	// The block of exclusions we maintain is allowed at the start
+	lines := withoutLineBlock(sel.lines, exclusionsStartLine, exclusionsEndLine)
+	if len(lines) == 0 {
 		return false
 	}
 
 	// All except the last pattern must start with '!', the last pattern must be  '*'
-	for idx, pattern := range sel.lines {
-		if idx == len(sel.lines)-1 {
+	for idx, pattern := range lines {
+		if idx == len(lines)-1 {
 			if pattern != "*" {
 				return false
 			}
@@ -71,8 +73,12 @@ func (sel *Selection) SetExplicitlySelected(paths map[string]bool) error {
 				return errors.New("failed to remove ignore line: " + line)
 			}
 		} else {
-			// To select, prepend it
-			sel.lines = append([]string{line}, sel.lines...)
+			// To select, prepend it (after the exclusions, which should take precedence)
+			insertAt := 0
+			if idx := slices.Index(sel.lines, exclusionsEndLine); idx >= 0 {
+				insertAt = idx + 1
+			}
+			sel.lines = slices.Insert(sel.lines, insertAt, line)
 		}
 	}
 	return nil
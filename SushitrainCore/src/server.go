@@ -6,13 +6,21 @@
 package sushitrain
 
 import (
+	"archive/zip"
 	"context"
 	"crypto/ed25519"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gotd/contrib/http_range"
@@ -33,6 +41,13 @@ type StreamingServer struct {
 	MaxMbitsPerSecondsStreaming int64
 	mux                         *http.ServeMux
 	Delegate                    StreamingServerDelegate
+	allowLAN                    bool
+	allowedOrigins              []string
+	allowedOriginsMutex         sync.Mutex
+	strictHTTPS                 bool
+	certificate                 *tls.Certificate
+	state                       *jsonStore[streamingServerState]
+	listenerMutex               sync.Mutex
 }
 
 func ceilDiv(a int64, b int64) int64 {
@@ -43,15 +58,29 @@ const (
 	signatureQueryParameter string = "signature"
 )
 
+// Returns the port the server is listening on, or zero when it is not listening
 func (srv *StreamingServer) port() int {
+	srv.listenerMutex.Lock()
+	defer srv.listenerMutex.Unlock()
+	if srv.listener == nil {
+		return 0
+	}
 	return srv.listener.Addr().(*net.TCPAddr).Port
 }
 
 func (srv *StreamingServer) urlFor(folder string, path string) string {
+	return srv.signedURLForEndpoint("/file", folder, path)
+}
+
+func (srv *StreamingServer) zipURLFor(folder string, prefix string) string {
+	return srv.signedURLForEndpoint("/zip", folder, prefix)
+}
+
+func (srv *StreamingServer) signedURLForEndpoint(endpoint string, folder string, path string) string {
 	url := url.URL{
-		Scheme: "http",
-		Host:   fmt.Sprintf("localhost:%d", srv.port()),
-		Path:   "/file",
+		Scheme: srv.scheme(),
+		Host:   fmt.Sprintf("127.0.0.1:%d", srv.port()), // Not 'localhost', which may resolve to ::1 where we are not listening
+		Path:   endpoint,
 	}
 
 	q := url.Query()
@@ -88,23 +117,195 @@ func (srv *StreamingServer) verifyURL(u *url.URL) bool {
 }
 
 func (srv *StreamingServer) Listen() error {
+	srv.listenerMutex.Lock()
+	defer srv.listenerMutex.Unlock()
+	return srv.listenLocked()
+}
+
+// Must be called with listenerMutex held
+func (srv *StreamingServer) listenLocked() error {
 	// Close existing listener
 	if srv.listener != nil {
 		srv.listener.Close()
+		srv.listener = nil
+	}
+
+	// Only accept connections from this device unless LAN access was allowed
+	host := "127.0.0.1"
+	if srv.allowLAN {
+		host = ""
 	}
 
-	listener, err := net.Listen("tcp", ":0")
+	listener, err := srv.listenOnPreferredPort(host)
 	if err != nil {
 		return err
 	}
+	if srv.strictHTTPS {
+		listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{*srv.certificate},
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
 
-	go http.Serve(listener, srv.mux)
+	go srv.serve(listener)
 	srv.listener = listener
-	slog.Info("HTTP service listening", "port", srv.port())
+	slog.Info("HTTP service listening", "address", listener.Addr().String())
 	return nil
 }
 
-func NewServer(app *syncthing.App, measurements *Measurements, ctx context.Context) (*StreamingServer, error) {
+// Serves requests until the listener is closed. When that happens while we are still supposed to be listening on it,
+// the server is restarted, so streaming does not stop working without anyone noticing. Restarts are retried with an
+// increasing delay, so that a failing socket does not make us spin.
+func (srv *StreamingServer) serve(listener net.Listener) {
+	err := http.Serve(listener, srv.withCORS(srv.mux))
+
+	current := listener
+	for delay := streamingServerRestartDelay; ; delay = min(2*delay, streamingServerMaxRestartDelay) {
+		srv.listenerMutex.Lock()
+		unexpected := srv.listener == current
+		srv.listenerMutex.Unlock()
+		if !unexpected {
+			// Restarted by someone else in the meantime
+			return
+		}
+
+		slog.Warn("HTTP service stopped unexpectedly, restarting", "cause", err, "delay", delay)
+		time.Sleep(delay)
+
+		srv.listenerMutex.Lock()
+		if srv.listener != current {
+			srv.listenerMutex.Unlock()
+			return
+		}
+		err = srv.listenLocked()
+		srv.listenerMutex.Unlock()
+		if err == nil {
+			return
+		}
+		slog.Error("could not restart HTTP service", "cause", err)
+
+		// A failed attempt leaves us without a listener
+		current = nil
+	}
+}
+
+// Returns the port the server is listening on, e.g. for advertising it on the local network using Bonjour
+func (srv *StreamingServer) Port() int {
+	return srv.port()
+}
+
+func (srv *StreamingServer) IsLANAllowed() bool {
+	return srv.allowLAN
+}
+
+// Sets whether other devices on the local network may connect to the server (e.g. to cast media to a TV). By default
+// only connections from this device are accepted. The server restarts listening, on the same port when possible.
+func (srv *StreamingServer) SetAllowLAN(allow bool) error {
+	if srv.allowLAN == allow {
+		return nil
+	}
+	srv.allowLAN = allow
+	return srv.Listen()
+}
+
+// Sets the origins (e.g. "http://192.168.1.20:8080") from which web pages may make requests to the server. Pass "*" to
+// allow any origin.
+func (srv *StreamingServer) SetAllowedOrigins(origins *ListOfStrings) {
+	srv.allowedOriginsMutex.Lock()
+	defer srv.allowedOriginsMutex.Unlock()
+	srv.allowedOrigins = slices.Clone(origins.data)
+}
+
+func (srv *StreamingServer) isOriginAllowed(origin string) bool {
+	srv.allowedOriginsMutex.Lock()
+	defer srv.allowedOriginsMutex.Unlock()
+	return slices.Contains(srv.allowedOrigins, origin) || slices.Contains(srv.allowedOrigins, "*")
+}
+
+func (srv *StreamingServer) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && srv.isOriginAllowed(origin)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Range")
+			w.Header().Set("Access-Control-Expose-Headers", "Content-Range, Content-Length, Accept-Ranges")
+		}
+		w.Header().Add("Vary", "Origin")
+
+		// Only answer preflight requests for allowed origins
+		if r.Method == http.MethodOptions && origin != "" {
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Returns the IPv4 address of this device on the local network. Only interfaces that are up and broadcast-capable
+// (i.e. WiFi and Ethernet, not cellular or VPN tunnels, which are point-to-point) are considered, and private addresses
+// are preferred over public ones.
+func lanAddress() (net.IP, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var public net.IP
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagPointToPoint != 0 ||
+			iface.Flags&net.FlagBroadcast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			if ipNet.IP.IsPrivate() {
+				return ipNet.IP, nil
+			}
+			if public == nil {
+				public = ipNet.IP
+			}
+		}
+	}
+	if public != nil {
+		return public, nil
+	}
+	return nil, errors.New("no local network address found")
+}
+
+// Returns a URL for the file that can be used by other devices on the local network, or an empty string when LAN
+// access is not allowed
+func (srv *StreamingServer) lanURLFor(folder string, path string) string {
+	if !srv.allowLAN {
+		return ""
+	}
+	ip, err := lanAddress()
+	if err != nil {
+		slog.Warn("could not determine LAN address", "cause", err)
+		return ""
+	}
+
+	u, err := url.Parse(srv.urlFor(folder, path))
+	if err != nil {
+		return ""
+	}
+	u.Host = net.JoinHostPort(ip.String(), fmt.Sprintf("%d", srv.port()))
+	return u.String()
+}
+
+func NewServer(client *Client, measurements *Measurements, ctx context.Context) (*StreamingServer, error) {
 	// Generate a private key to sign URLs with
 	publicKey, privateKey, err := ed25519.GenerateKey(nil)
 	if err != nil {
@@ -115,11 +316,15 @@ func NewServer(app *syncthing.App, measurements *Measurements, ctx context.Conte
 
 	server := StreamingServer{
 		mux:                         mux,
+		client:                      client,
 		publicKey:                   publicKey,
 		privateKey:                  privateKey,
 		MaxMbitsPerSecondsStreaming: 0, // no limit
+		state:                       newJSONStore(client.stores, streamingServerStateFileName, streamingServerState{}),
 	}
 
+	mux.Handle("/health", http.HandlerFunc(server.serveHealth))
+
 	mux.Handle("/file", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !server.verifyURL(r.URL) {
 			w.WriteHeader(403)
@@ -130,6 +335,12 @@ func NewServer(app *syncthing.App, measurements *Measurements, ctx context.Conte
 		path := r.URL.Query().Get("path")
 
 		slog.Info("request", "method", r.Method, "folder", folder, "path", path)
+		m := client.internals()
+		if m == nil {
+			w.WriteHeader(503)
+			w.Write([]byte(ErrStillLoading.Error()))
+			return
+		}
 		stFolder := server.client.FolderWithID(folder)
 		if stFolder == nil {
 			w.WriteHeader(404)
@@ -141,8 +352,11 @@ func NewServer(app *syncthing.App, measurements *Measurements, ctx context.Conte
 			w.Write([]byte(err.Error()))
 			return
 		}
+		if stEntry == nil {
+			w.WriteHeader(404)
+			return
+		}
 
-		m := app.Internals
 		info, ok, err := m.GlobalFileInfo(folder, path)
 		if err != nil {
 			w.WriteHeader(500)
@@ -184,6 +398,31 @@ func NewServer(app *syncthing.App, measurements *Measurements, ctx context.Conte
 		serveEntry(w, r, folder, stEntry, info, m, measurements, callback)
 	}))
 
+	mux.Handle("/zip", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !server.verifyURL(r.URL) {
+			w.WriteHeader(403)
+			return
+		}
+
+		folder := r.URL.Query().Get("folder")
+		prefix := r.URL.Query().Get("path")
+		slog.Info("zip request", "method", r.Method, "folder", folder, "prefix", prefix)
+		m := client.internals()
+		if m == nil {
+			w.WriteHeader(503)
+			w.Write([]byte(ErrStillLoading.Error()))
+			return
+		}
+
+		stFolder := server.client.FolderWithID(folder)
+		if stFolder == nil {
+			w.WriteHeader(404)
+			return
+		}
+
+		serveDirectoryZip(w, r, stFolder, prefix, m, measurements)
+	}))
+
 	if err := server.Listen(); err != nil {
 		return nil, err
 	}
@@ -191,6 +430,103 @@ func NewServer(app *syncthing.App, measurements *Measurements, ctx context.Conte
 	return &server, nil
 }
 
+// Streams a zip archive of all files in the global index of the folder under `prefix`. Files not available locally are
+// fetched block by block from peers while the archive is being written.
+func serveDirectoryZip(w http.ResponseWriter, r *http.Request, folder *Folder, prefix string, m *syncthing.Internals, measurements *Measurements) {
+	prefix = strings.Trim(prefix, "/")
+
+	// Collect the paths first, so we don't keep the database busy while downloading
+	paths := make([]string, 0)
+	for f, err := range zipError(m.AllGlobalFiles(folder.FolderID)) {
+		if err != nil {
+			w.WriteHeader(500)
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		if f.Deleted || f.Type != protocol.FileInfoTypeFile {
+			continue
+		}
+
+		if prefix != "" && !strings.HasPrefix(f.Name, prefix+"/") {
+			continue
+		}
+
+		if folder.client.isExtraneousIgnored(filepath.Base(f.Name)) {
+			continue
+		}
+		paths = append(paths, f.Name)
+	}
+
+	if len(paths) == 0 {
+		w.WriteHeader(404)
+		return
+	}
+
+	archiveName := folder.Label()
+	if prefix != "" {
+		archiveName = filepath.Base(prefix)
+	}
+	w.Header().Add("Content-type", "application/zip")
+	w.Header().Add("Content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archiveName + ".zip"}))
+	w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.WriteHeader(200)
+
+	// Headers have been sent, so the only thing we can do upon failure is abort the response. The archive must then not
+	// be closed, as that would write a central directory that makes the truncated archive look complete.
+	zipWriter := zip.NewWriter(w)
+	mp := newMiniPuller(measurements, m)
+
+	for _, filePath := range paths {
+		if err := r.Context().Err(); err != nil {
+			slog.Info("zip request cancelled", "cause", err)
+			panic(http.ErrAbortHandler)
+		}
+
+		entry, err := folder.GetFileInformation(filePath)
+		if err != nil || entry == nil {
+			slog.Warn("could not get file for zip, skipping", "path", filePath, "cause", err)
+			continue
+		}
+
+		nameInArchive := filePath
+		if prefix != "" {
+			nameInArchive = strings.TrimPrefix(filePath, prefix+"/")
+		}
+
+		// Most large files (media) are compressed already, so don't waste CPU on compressing again
+		fileWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     nameInArchive,
+			Modified: entry.info.ModTime(),
+			Method:   zip.Store,
+		})
+		if err != nil {
+			slog.Error("could not create zip entry", "path", filePath, "cause", err)
+			panic(http.ErrAbortHandler)
+		}
+
+		if file, err := entry.openLocalVersion(); err == nil {
+			_, err = io.Copy(fileWriter, file)
+			file.Close()
+			if err != nil {
+				slog.Error("could not write local file to zip", "path", filePath, "cause", err)
+				panic(http.ErrAbortHandler)
+			}
+		} else {
+			err = mp.downloadInto(r.Context(), fileWriter, folder.FolderID, entry.info)
+			if err != nil {
+				slog.Error("could not download file into zip", "path", filePath, "cause", err)
+				panic(http.ErrAbortHandler)
+			}
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		slog.Error("could not finish zip archive", "cause", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
 type serveCallback func(bytesSent int64, bytesRequested int64)
 
 func serveEntry(w http.ResponseWriter, r *http.Request, folderID string, entry *Entry, info protocol.FileInfo, m *syncthing.Internals, measurements *Measurements, callback serveCallback) {
@@ -252,22 +588,8 @@ func serveEntry(w http.ResponseWriter, r *http.Request, folderID string, entry *
 
 			bytesSent := int64(0)
 
-			for blockIndex := startBlock; blockIndex < startBlock+blockCount; blockIndex++ {
-				if int(blockIndex) > len(info.Blocks)-1 {
-					break
-				}
-
-				// Fetch block
+			err := mp.fetchBlocks(r.Context(), folderID, info, int(startBlock), int(blockCount), 1, func(blockIndex int, buf []byte) error {
 				block := info.Blocks[blockIndex]
-				buf, err := mp.downloadBlock(r.Context(), folderID, int(blockIndex), info, 1)
-				if err != nil {
-					slog.Warn("error downloading block", "blockIndex", blockIndex, "blockCount", len(info.Blocks), "cause", err)
-
-					// We are now sending less content than we promised in the header. The client should reject our response
-					// and try again later.
-					return
-				}
-
 				bufStart := int64(0)
 				bufEnd := int64(len(buf))
 
@@ -281,7 +603,7 @@ func serveEntry(w http.ResponseWriter, r *http.Request, folderID string, entry *
 					bufEnd = rangeEnd - block.Offset
 				}
 				if bufEnd < 0 {
-					break
+					return errStopFetching
 				}
 
 				// Write buffer
@@ -291,6 +613,14 @@ func serveEntry(w http.ResponseWriter, r *http.Request, folderID string, entry *
 				if callback != nil {
 					callback(bytesSent, rng.Length)
 				}
+				return nil
+			})
+			if err != nil {
+				slog.Warn("error downloading blocks", "startBlock", startBlock, "blockCount", len(info.Blocks), "cause", err)
+
+				// We are now sending less content than we promised in the header. The client should reject our response
+				// and try again later.
+				return
 			}
 
 			if rng.Length != bytesSent {
@@ -0,0 +1,109 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	streamingServerStateFileName = "server.json"
+
+	// How many ports following the remembered port are tried before letting the system pick one
+	streamingServerPortAttempts = 10
+
+	streamingServerHealthTimeout = time.Second
+
+	// Delay before restarting the server after it stopped unexpectedly, doubled after each failed attempt up to the
+	// maximum
+	streamingServerRestartDelay    = time.Second
+	streamingServerMaxRestartDelay = time.Minute
+)
+
+type streamingServerState struct {
+	// The port the server last listened on, which is tried first so that URLs and advertisements remain valid
+	Port int `json:"port"`
+}
+
+// Listens on the remembered port or, when it is taken by another app, one of the ports following it. When none of these
+// are available (or no port was remembered yet), the system picks a free port. The port is remembered for next time.
+func (srv *StreamingServer) listenOnPreferredPort(host string) (net.Listener, error) {
+	preferred := 0
+	srv.state.read(func(state *streamingServerState) {
+		preferred = state.Port
+	})
+
+	candidates := make([]int, 0, streamingServerPortAttempts+1)
+	if preferred > 0 {
+		for i := range streamingServerPortAttempts {
+			if port := preferred + i; port <= 65535 {
+				candidates = append(candidates, port)
+			}
+		}
+	}
+	candidates = append(candidates, 0)
+
+	var lastErr error
+	for _, port := range candidates {
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			slog.Warn("streaming server port not available", "port", port, "cause", err)
+			lastErr = err
+			continue
+		}
+
+		chosen := listener.Addr().(*net.TCPAddr).Port
+		if chosen != preferred {
+			if preferred > 0 {
+				slog.Warn("streaming server port changed because of a conflict", "preferred", preferred, "port", chosen)
+			}
+			if err := srv.state.modify(func(state *streamingServerState) { state.Port = chosen }); err != nil {
+				slog.Warn("could not remember streaming server port", "cause", err)
+			}
+		}
+		return listener, nil
+	}
+	return nil, lastErr
+}
+
+// Stops and starts listening again, on the same port when it is still available
+func (srv *StreamingServer) Restart() error {
+	slog.Info("restarting streaming server")
+	return srv.Listen()
+}
+
+// Checks whether the server still accepts connections (the system may close its socket, e.g. while the app was
+// suspended) and restarts it when it does not. Should be called when the app returns to the foreground.
+func (srv *StreamingServer) EnsureListening() error {
+	port := srv.port()
+	if port == 0 {
+		return srv.Listen()
+	}
+
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", port), streamingServerHealthTimeout)
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+	slog.Warn("streaming server does not accept connections", "port", port, "cause", err)
+	return srv.Restart()
+}
+
+// Serves the (unsigned) health endpoint, so that other apps and devices can tell the server is up
+func (srv *StreamingServer) serveHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": "ok",
+		"port":   srv.Port(),
+		"scheme": srv.scheme(),
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+)
+
+// The certificate only lives as long as the process, but should not expire while the app is running
+const serverCertificateLifetime = 30 * 24 * time.Hour
+
+func (srv *StreamingServer) scheme() string {
+	if srv.IsStrictHTTPS() {
+		return "https"
+	}
+	return "http"
+}
+
+func (srv *StreamingServer) IsStrictHTTPS() bool {
+	srv.listenerMutex.Lock()
+	defer srv.listenerMutex.Unlock()
+	return srv.strictHTTPS
+}
+
+// Sets whether the server only accepts HTTPS connections. The server then uses a self-signed certificate that is
+// generated on first use and never written to disk; clients should trust it by comparing its hash (see
+// CertificateHash). The server restarts listening, so URLs obtained earlier stop working as their scheme changes.
+func (srv *StreamingServer) SetStrictHTTPS(strict bool) error {
+	srv.listenerMutex.Lock()
+	defer srv.listenerMutex.Unlock()
+
+	if srv.strictHTTPS == strict {
+		return nil
+	}
+	if strict && srv.certificate == nil {
+		cert, err := generateServerCertificate()
+		if err != nil {
+			return err
+		}
+		srv.certificate = cert
+	}
+	srv.strictHTTPS = strict
+	return srv.listenLocked()
+}
+
+// Returns the SHA-256 hash (hex-encoded) of the certificate the server uses for HTTPS, or an empty string when strict
+// HTTPS is not enabled
+func (srv *StreamingServer) CertificateHash() string {
+	srv.listenerMutex.Lock()
+	defer srv.listenerMutex.Unlock()
+
+	if !srv.strictHTTPS || srv.certificate == nil {
+		return ""
+	}
+	hash := sha256.Sum256(srv.certificate.Certificate[0])
+	return hex.EncodeToString(hash[:])
+}
+
+// Returns the base URL of the server, with the hash of its certificate in the fragment (e.g.
+// "https://127.0.0.1:1234/#sha256=..."), so that the app can pin the certificate when the connection is challenged.
+// Without strict HTTPS, the plain HTTP base URL is returned.
+func (srv *StreamingServer) URLWithCertificateHash() string {
+	port := srv.port()
+	if port == 0 {
+		return ""
+	}
+	u := url.URL{
+		Scheme: srv.scheme(),
+		Host:   fmt.Sprintf("127.0.0.1:%d", port),
+		Path:   "/",
+	}
+	if hash := srv.CertificateHash(); hash != "" {
+		u.Fragment = "sha256=" + hash
+	}
+	return u.String()
+}
+
+// Generates a self-signed certificate for the loopback addresses (and the current LAN address, if any)
+func generateServerCertificate() (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 63))
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if ip, err := lanAddress(); err == nil {
+		addresses = append(addresses, ip)
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(serverCertificateLifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           addresses,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("generated streaming server certificate", "addresses", addresses)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/osutil"
+)
+
+// The directory the stores of a client are kept in (its configuration directory)
+type storeDirectory struct {
+	path string
+
+	// When set, changes are kept in memory but not written, e.g. while another instance uses the directory
+	readOnly atomic.Bool
+}
+
+// A small JSON document stored next to config.xml, for state we need to keep across launches but that does not belong
+// in the Syncthing configuration.
+type jsonStore[T any] struct {
+	directory *storeDirectory
+	path      string
+	mutex     sync.Mutex
+	data      T
+	dirty     bool
+}
+
+// Creates a store for the file with the given name in the directory
+func newJSONStore[T any](directory *storeDirectory, fileName string, initial T) *jsonStore[T] {
+	store := &jsonStore[T]{
+		directory: directory,
+		path:      path.Join(directory.path, fileName),
+		data:      initial,
+	}
+
+	contents, err := os.ReadFile(store.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("could not read store", "path", store.path, "cause", err)
+		}
+		return store
+	}
+
+	if err := json.Unmarshal(contents, &store.data); err != nil {
+		slog.Warn("could not parse store, starting afresh", "path", store.path, "cause", err)
+		store.data = initial
+	}
+	return store
+}
+
+func (store *jsonStore[T]) read(block func(data *T)) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	block(&store.data)
+}
+
+// Runs `block` to change the stored data, then writes it to disk
+func (store *jsonStore[T]) modify(block func(data *T)) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	block(&store.data)
+	return store.saveLocked()
+}
+
+// Runs `block` to change the stored data, but leaves writing it to disk to the next flush. Use this for data that
+// changes often.
+func (store *jsonStore[T]) modifyLater(block func(data *T)) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	block(&store.data)
+	store.dirty = true
+}
+
+func (store *jsonStore[T]) flush() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if !store.dirty {
+		return nil
+	}
+	return store.saveLocked()
+}
+
+// Periodically writes changes made with modifyLater to disk, until the context is cancelled
+func (store *jsonStore[T]) serveFlush(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := store.flush(); err != nil {
+				slog.Warn("could not save store", "path", store.path, "cause", err)
+			}
+			return
+		case <-ticker.C:
+			if err := store.flush(); err != nil {
+				slog.Warn("could not save store", "path", store.path, "cause", err)
+			}
+		}
+	}
+}
+
+func (store *jsonStore[T]) saveLocked() error {
+	if store.directory.readOnly.Load() {
+		return nil
+	}
+
+	contents, err := json.Marshal(store.data)
+	if err != nil {
+		return err
+	}
+
+	fd, err := osutil.CreateAtomic(store.path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fd.Write(contents); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	store.dirty = false
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestJSONStorePersists(t *testing.T) {
+	directory := &storeDirectory{path: t.TempDir()}
+	store := newJSONStore(directory, "test.json", map[string]int{})
+	if err := store.modify(func(data *map[string]int) { (*data)["a"] = 1 }); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := newJSONStore(directory, "test.json", map[string]int{})
+	reopened.read(func(data *map[string]int) {
+		if (*data)["a"] != 1 {
+			t.Errorf("expected stored value to be read back, got %v", *data)
+		}
+	})
+}
+
+func TestJSONStoreReadOnly(t *testing.T) {
+	directory := &storeDirectory{path: t.TempDir()}
+	directory.readOnly.Store(true)
+	store := newJSONStore(directory, "test.json", map[string]int{})
+	if err := store.modify(func(data *map[string]int) { (*data)["a"] = 1 }); err != nil {
+		t.Fatal(err)
+	}
+	store.modifyLater(func(data *map[string]int) { (*data)["b"] = 2 })
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	store.read(func(data *map[string]int) {
+		if (*data)["a"] != 1 || (*data)["b"] != 2 {
+			t.Errorf("expected changes to be kept in memory, got %v", *data)
+		}
+	})
+	if _, err := os.Stat(path.Join(directory.path, "test.json")); !os.IsNotExist(err) {
+		t.Errorf("expected read-only store not to be written, got %v", err)
+	}
+}
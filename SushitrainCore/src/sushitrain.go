@@ -9,6 +9,7 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -18,9 +19,11 @@ import (
 	"iter"
 	"log/slog"
 	"math"
+	"net"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"slices"
@@ -37,6 +40,7 @@ import (
 	"github.com/syncthing/syncthing/lib/model"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/rand"
 	"github.com/syncthing/syncthing/lib/svcutil"
 	"github.com/syncthing/syncthing/lib/syncthing"
 )
@@ -53,16 +57,78 @@ type Client struct {
 	IgnoreEvents               bool
 	IsUsingCustomConfiguration bool
 	Server                     *StreamingServer
+	CertificateDelegate        CertificateDelegate
+	KeychainDelegate           KeychainDelegate
+	FolderAccessDelegate       FolderAccessDelegate
+	CompletionDelegate         CompletionDelegate
+	DeleteGuardDelegate        DeleteGuardDelegate
+	WatchdogDelegate           WatchdogDelegate
 
 	connectedDeviceAddresses map[string]string
 	downloadProgress         map[string]map[string]*model.PullerProgress // folderID, path => progress
 	uploadProgress           map[string]map[string]map[string]int        // deviceID, folderID, path => block count
-	foldersDownloading       map[string]bool
+	folderStates             map[string]string                           // folderID => state (see model.FolderState)
 	ResolvedListenAddresses  map[string][]string
 	mutex                    sync.Mutex
 	extraneousIgnored        []string
 	Measurements             *Measurements
 	logHandler               *logHandler
+	rejections               *jsonStore[map[string]*rejectionRecord]
+	connections              *jsonStore[map[string]*connectionRecord]
+	transferRates            *transferRates
+	materialized             *materializationCache
+	activity                 *jsonStore[[]activityRecord]
+	itemsStarted             map[string]bool
+	natTracker               *natTracker
+	pins                     *jsonStore[map[string][]string]
+	webhooks                 *jsonStore[[]webhookRecord]
+	webhookQueues            map[string]chan webhookDelivery // Webhook ID => deliveries, each served by a goroutine
+	queryCache               *queryCache
+	networkChangeTimer       *time.Timer // Pending connection restart after a network change (see NetworkChanged)
+	configMutex              sync.Mutex  // Held while changing and saving the configuration
+	cellular                 *jsonStore[cellularPolicy]
+	onCellular               bool
+	autoAccept               *jsonStore[autoAcceptState]
+	autoAccepting            map[string]bool // Folder IDs of offers that are being accepted automatically
+	configCancel             context.CancelFunc
+	restartMutex             sync.Mutex
+	sdb                      localIndexWriter
+	symlinkPolicies          *jsonStore[map[string]string]
+	caseConflictFolders      *jsonStore[[]string]
+	indexExchange            *indexExchangeTracker
+	options                  clientOptions
+	stores                   *storeDirectory
+	configFile               string // Path of the configuration file, set while loading (locations are process-wide)
+	temporaryDatabasePath    string
+	instanceLock             *os.File
+	ipcListener              net.Listener
+	completionNotifier       *completionNotifier
+	deleteGuards             *jsonStore[map[string]*deleteGuardRecord]
+	deleteGuardChecks        map[string]bool // Folder ID => whether to check again once the running check is done
+	exclusions               *jsonStore[map[string]*exclusionRecord]
+	power                    *jsonStore[powerPolicy]
+	thermalState             string
+	batteryPercent           int // -1 when unknown
+	batteryCharging          bool
+	chargingKnown            bool                            // Whether the app told us about the charging state (see SetCharging)
+	replayBuffer             []func(delegate ClientDelegate) // Delegate calls made before a delegate was set
+	watchdog                 *watchdog
+	listeners                *listenerTracker
+	configDefaults           *jsonStore[configDefaultsState]
+	databaseError            error // Set when the database could not be opened (see RepairDatabase)
+	trafficTotals            *trafficTotals
+	dataUsage                *jsonStore[dataUsageState]
+	dataUsageTracker         *dataUsageTracker
+	folderErrors             *folderErrorTracker
+	changeHints              *changeHints
+	localDiscovery           *localDiscoveryTracker
+	scanWindows              *jsonStore[scanWindowPolicy]
+	configDiffs              *configDiffTracker
+	pullBackoff              *pullBackoffTracker
+	placeholders             *jsonStore[map[string]*placeholderRecord]
+	metadata                 *jsonStore[metadataState]
+	connectionProfile        *jsonStore[connectionProfileState]
+	tempFileFractions        *tempFileFractionCache
 }
 
 type Change struct {
@@ -93,10 +159,26 @@ type ClientDelegate interface {
 	OnListenAddressesChanged(addresses *ListOfStrings)
 	OnChange(change *Change)
 	OnMeasurementsUpdated()
+	OnFolderErrorsChanged(folderID string, count int)
+	OnConfigChanged(diffJSON string)
 }
 
 var (
-	ErrStillLoading = errors.New("still loading")
+	ErrStillLoading    = errors.New("still loading")
+	ErrReadOnly        = errors.New("client is read-only")
+	ErrFolderNotFound  = errors.New("folder does not exist")
+	ErrDeviceNotFound  = errors.New("device does not exist")
+	ErrInvalidDeviceID = errors.New("invalid device ID")
+)
+
+// Default retention interval taken from Syncthing's CLI default
+const dbDeleteRetentionInterval = time.Duration(4320) * time.Hour
+
+// Reported to other devices unless overridden by the client options
+const (
+	defaultBuildVersion = "v2.0.9"
+	defaultBuildHost    = "t-shaped.nl"
+	defaultBuildUser    = "sushitrain"
 )
 
 const (
@@ -107,10 +189,20 @@ const (
 )
 
 func NewClient(configPath string, filesPath string, saveLog bool) *Client {
-	// Set version info
-	build.Version = "v2.0.9"
-	build.Host = "t-shaped.nl"
-	build.User = "sushitrain"
+	options := clientOptions{
+		ConfigPath: configPath,
+		FilesPath:  filesPath,
+		SaveLog:    saveLog,
+	}
+	options.resolveProfile() // Cannot fail for the default profile
+	return newClient(options)
+}
+
+func newClient(options clientOptions) *Client {
+	filesPath := options.FilesPath
+	saveLog := options.SaveLog
+	stores := &storeDirectory{path: options.ConfigPath}
+	stores.readOnly.Store(options.ReadOnly)
 
 	// Set up logging
 	var logOutWriter io.Writer
@@ -142,47 +234,13 @@ func NewClient(configPath string, filesPath string, saveLog bool) *Client {
 		minLevel = slog.LevelInfo
 	}
 	logHandler := newLogHandler(logOutWriter, minLevel)
-	slog.SetDefault(slog.New(logHandler))
-
-	// Set up default locations
-	locations.SetBaseDir(locations.DataBaseDir, configPath)
-	locations.SetBaseDir(locations.ConfigBaseDir, configPath)
-	locations.SetBaseDir(locations.UserHomeBaseDir, filesPath)
-	slog.Info("paths", "databaseDir", configPath, "filesDir", filesPath)
-
-	// Check for custom user-provided config file
-	isUsingCustomConfiguration := false
-	customConfigFilePath := path.Join(filesPath, ConfigFileName)
-	if info, err := os.Stat(customConfigFilePath); err == nil {
-		if !info.IsDir() {
-			slog.Info("config XML exists in files dir, using it at", "path", customConfigFilePath)
-			locations.Set(locations.ConfigFile, customConfigFilePath)
-			isUsingCustomConfiguration = true
-		}
-	}
-
-	// Check for custom user-provided identity
-	customCertPath := path.Join(filesPath, CertFileName)
-	customKeyPath := path.Join(filesPath, KeyFileName)
-	if keyInfo, err := os.Stat(customKeyPath); err == nil {
-		if !keyInfo.IsDir() {
-			if certInfo, err := os.Stat(customCertPath); err == nil {
-				if !certInfo.IsDir() {
-					slog.Info("found user-provided identity files, using those")
-					locations.Set(locations.CertFile, customCertPath)
-					locations.Set(locations.KeyFile, customKeyPath)
-					isUsingCustomConfiguration = true
-				}
-			}
-		}
-	}
 
 	// Set up logging
 	ctx, cancel := context.WithCancel(context.Background())
 	evLogger := events.NewLogger()
 	go evLogger.Serve(ctx)
 
-	return &Client{
+	client := &Client{
 		Delegate:                   nil,
 		cert:                       nil,
 		config:                     nil,
@@ -191,9 +249,9 @@ func NewClient(configPath string, filesPath string, saveLog bool) *Client {
 		app:                        nil,
 		evLogger:                   evLogger,
 		Server:                     nil,
-		foldersDownloading:         make(map[string]bool, 0),
+		folderStates:               make(map[string]string, 0),
 		connectedDeviceAddresses:   make(map[string]string, 0),
-		IsUsingCustomConfiguration: isUsingCustomConfiguration,
+		IsUsingCustomConfiguration: usesCustomConfiguration(filesPath),
 		filesPath:                  filesPath,
 		IgnoreEvents:               false,
 		uploadProgress:             make(map[string]map[string]map[string]int),
@@ -201,7 +259,96 @@ func NewClient(configPath string, filesPath string, saveLog bool) *Client {
 		extraneousIgnored:          make([]string, 0),
 		Measurements:               nil,
 		logHandler:                 logHandler,
+		rejections:                 newJSONStore(stores, rejectionsFileName, map[string]*rejectionRecord{}),
+		connections:                newJSONStore(stores, connectionHistoryFileName, map[string]*connectionRecord{}),
+		transferRates:              newTransferRates(),
+		materialized:               newMaterializationCache(),
+		activity:                   newJSONStore(stores, activityFileName, []activityRecord{}),
+		itemsStarted:               make(map[string]bool),
+		natTracker:                 newNATTracker(),
+		pins:                       newJSONStore(stores, pinsFileName, map[string][]string{}),
+		webhooks:                   newJSONStore(stores, webhooksFileName, []webhookRecord{}),
+		webhookQueues:              make(map[string]chan webhookDelivery),
+		queryCache:                 newQueryCache(),
+		cellular:                   newJSONStore(stores, cellularPolicyFileName, cellularPolicy{}),
+		autoAccept:                 newJSONStore(stores, autoAcceptFileName, autoAcceptState{}),
+		autoAccepting:              make(map[string]bool),
+		symlinkPolicies:            newJSONStore(stores, symlinkPolicyFileName, map[string]string{}),
+		caseConflictFolders:        newJSONStore(stores, caseConflictsFileName, []string{}),
+		indexExchange:              newIndexExchangeTracker(),
+		completionNotifier:         newCompletionNotifier(),
+		deleteGuards:               newJSONStore(stores, deleteGuardFileName, map[string]*deleteGuardRecord{}),
+		deleteGuardChecks:          make(map[string]bool),
+		exclusions:                 newJSONStore(stores, exclusionsFileName, map[string]*exclusionRecord{}),
+		power:                      newJSONStore(stores, powerPolicyFileName, powerPolicy{}),
+		watchdog:                   newWatchdog(),
+		listeners:                  newListenerTracker(),
+		configDefaults:             newJSONStore(stores, configDefaultsFileName, configDefaultsState{}),
+		trafficTotals:              newTrafficTotals(),
+		dataUsage:                  newJSONStore(stores, dataUsageFileName, dataUsageState{}),
+		dataUsageTracker:           newDataUsageTracker(),
+		folderErrors:               newFolderErrorTracker(),
+		changeHints:                newChangeHints(),
+		localDiscovery:             newLocalDiscoveryTracker(),
+		scanWindows:                newJSONStore(stores, scanWindowPolicyFileName, scanWindowPolicy{}),
+		configDiffs:                newConfigDiffTracker(),
+		pullBackoff:                newPullBackoffTracker(),
+		placeholders:               newJSONStore(stores, placeholderFoldersFileName, map[string]*placeholderRecord{}),
+		metadata:                   newJSONStore(stores, metadataFileName, metadataState{}),
+		connectionProfile:          newJSONStore(stores, connectionProfileFileName, connectionProfileState{}),
+		tempFileFractions:          newTempFileFractionCache(),
+		thermalState:               ThermalStateNominal,
+		batteryPercent:             -1,
+		options:                    options,
+		stores:                     stores,
+	}
+	logHandler.observer = client.observeLogRecord
+	return client
+}
+
+// Returns the paths of the configuration file and identity (certificate and key) provided by the user in the files
+// directory, or empty strings when they were not provided
+func customConfigurationFiles(filesPath string) (configFile string, certFile string, keyFile string) {
+	customConfigFilePath := path.Join(filesPath, ConfigFileName)
+	if info, err := os.Stat(customConfigFilePath); err == nil && !info.IsDir() {
+		configFile = customConfigFilePath
+	}
+
+	customCertPath := path.Join(filesPath, CertFileName)
+	customKeyPath := path.Join(filesPath, KeyFileName)
+	if keyInfo, err := os.Stat(customKeyPath); err == nil && !keyInfo.IsDir() {
+		if certInfo, err := os.Stat(customCertPath); err == nil && !certInfo.IsDir() {
+			certFile, keyFile = customCertPath, customKeyPath
+		}
+	}
+	return
+}
+
+// Returns whether a custom configuration or identity provided by the user in the files directory will be used
+func usesCustomConfiguration(filesPath string) bool {
+	configFile, certFile, _ := customConfigurationFiles(filesPath)
+	return configFile != "" || certFile != ""
+}
+
+// Points Syncthing's (process-wide) locations at the configuration and files directories. Returns whether a custom
+// configuration or identity provided by the user in the files directory is used.
+func applyLocations(configPath string, filesPath string) bool {
+	locations.SetBaseDir(locations.DataBaseDir, configPath)
+	locations.SetBaseDir(locations.ConfigBaseDir, configPath)
+	locations.SetBaseDir(locations.UserHomeBaseDir, filesPath)
+	slog.Info("paths", "databaseDir", configPath, "filesDir", filesPath)
+
+	configFile, certFile, keyFile := customConfigurationFiles(filesPath)
+	if configFile != "" {
+		slog.Info("config XML exists in files dir, using it at", "path", configFile)
+		locations.Set(locations.ConfigFile, configFile)
+	}
+	if certFile != "" {
+		slog.Info("found user-provided identity files, using those")
+		locations.Set(locations.CertFile, certFile)
+		locations.Set(locations.KeyFile, keyFile)
 	}
+	return configFile != "" || certFile != ""
 }
 
 func (clt *Client) SetExtraneousIgnored(names []string) {
@@ -229,13 +376,12 @@ func (clt *Client) isExtraneousIgnored(name string) bool {
 }
 
 func (clt *Client) CurrentConfigDirectory() string {
-	return locations.GetBaseDir(locations.ConfigBaseDir)
+	return clt.options.ConfigPath
 }
 
 func (clt *Client) ExportConfigurationFile() error {
 	cfg := clt.config.RawCopy()
-	homeDir := locations.GetBaseDir(locations.UserHomeBaseDir)
-	customConfigFilePath := path.Join(homeDir, ExportConfigFileName)
+	customConfigFilePath := path.Join(clt.filesPath, ExportConfigFileName)
 	fd, err := osutil.CreateAtomic(customConfigFilePath)
 	if err != nil {
 		return err
@@ -253,87 +399,130 @@ func (clt *Client) ExportConfigurationFile() error {
 }
 
 func (clt *Client) Stop() {
-	clt.app.Stop(svcutil.ExitSuccess)
+	clt.StopIPCServer()
+	clt.mutex.Lock()
+	if clt.networkChangeTimer != nil {
+		clt.networkChangeTimer.Stop()
+		clt.networkChangeTimer = nil
+	}
+	clt.mutex.Unlock()
+	if clt.app != nil {
+		clt.app.Stop(svcutil.ExitSuccess)
+	}
 	clt.cancel()
-	clt.app.Wait()
+	if clt.app != nil {
+		clt.app.Wait()
+		clt.saveTrafficTotals()
+		clt.saveDataUsage()
+	}
+
+	if clt.temporaryDatabasePath != "" {
+		os.RemoveAll(clt.temporaryDatabasePath)
+	}
+	clt.releaseInstanceLock()
+	clt.releaseLocations()
 }
 
 func (clt *Client) handleEvent(evt events.Event) {
+	clt.queryCache.handleEvent(evt)
+	clt.dispatchWebhooks(evt)
+
 	switch evt.Type {
 	case events.DeviceDiscovered:
-		clt.mutex.Lock()
-		if !clt.IgnoreEvents && clt.Delegate != nil {
-			data := evt.Data.(map[string]interface{})
-			devID := data["device"].(string)
-			addresses := data["addrs"].([]string)
-			clt.mutex.Unlock()
-			clt.Delegate.OnDeviceDiscovered(devID, &ListOfStrings{data: addresses})
-		} else {
-			clt.mutex.Unlock()
+		data := evt.Data.(map[string]interface{})
+		devID := data["device"].(string)
+		addresses := data["addrs"].([]string)
+		if deviceID, err := protocol.DeviceIDFromString(devID); err == nil {
+			clt.localDiscovery.record(deviceID, addresses)
 		}
+		clt.notifyDelegate(func(delegate ClientDelegate) {
+			delegate.OnDeviceDiscovered(devID, &ListOfStrings{data: addresses})
+		})
 
 	case events.FolderRejected:
-		// FolderRejected is deprecated
-		break
+		// FolderRejected is deprecated, but still the simplest way to learn about each individual offer
+		data := evt.Data.(map[string]string)
+		if !clt.autoAcceptFolderOffer(data["device"], data["folder"], data["folderLabel"]) {
+			clt.recordRejection(RejectionKindFolder, data["device"], "", "", data["folder"], data["folderLabel"])
+		}
+		clt.deliverEvent(evt)
+
+	case events.DeviceRejected:
+		// DeviceRejected is deprecated, but still the simplest way to learn about each individual connection attempt
+		data := evt.Data.(map[string]string)
+		clt.recordRejection(RejectionKindDevice, data["device"], data["name"], data["address"], "", "")
+		clt.deliverEvent(evt)
 
 	case events.StateChanged:
-		// Keep track of which folders are in syncing state. We need to know whether we are idling or not
+		// Keep track of the state of each folder. We need to know whether we are idling or not
 		data := evt.Data.(map[string]interface{})
 		folder := data["folder"].(string)
 		state := data["to"].(string)
-		folderTransferring := (state == model.FolderSyncing.String() || state == model.FolderSyncWaiting.String() || state == model.FolderSyncPreparing.String())
 
-		clt.mutex.Lock()
-		clt.foldersDownloading[folder] = folderTransferring
-		if !clt.IgnoreEvents && clt.Delegate != nil {
-			clt.mutex.Unlock()
-			clt.Delegate.OnEvent(evt.Type.String())
-		} else {
-			clt.mutex.Unlock()
+		clt.handleFolderStateForErrors(folder, state)
+		if from, _ := data["from"].(string); state == model.FolderIdle.String() &&
+			(from == model.FolderSyncing.String() || from == model.FolderSyncPreparing.String()) {
+			clt.pullBackoff.pullFinished(folder, evt.Time)
+			clt.forgetItemsStarted(folder)
+		}
+		if state == model.FolderError.String() {
+			go clt.checkFolderAccess(folder)
+		} else if state == model.FolderIdle.String() {
+			go clt.checkAutoAcceptedFolderSize(folder)
+			go clt.checkSkippedSymlinks(folder)
+			go clt.renameCaseConflicts(folder)
+			go clt.resetDeleteGuardApproval(folder)
+			go clt.checkExclusions(folder)
+		} else if state == model.FolderSyncPreparing.String() {
+			// Pausing changes the configuration, which should not hold up the event loop
+			clt.scheduleDeleteGuardCheck(folder)
+			go clt.checkExclusions(folder)
 		}
 
-	case events.ListenAddressesChanged:
 		clt.mutex.Lock()
-		if !clt.IgnoreEvents && clt.Delegate != nil {
-			addrs := make([]string, 0)
-			data := evt.Data.(map[string]interface{})
-			addressSpec := data["address"].(*url.URL)
-			wanAddresses := data["wan"].([]*url.URL)
-			lanAddresses := data["lan"].([]*url.URL)
-
-			for _, wa := range wanAddresses {
-				addrs = append(addrs, wa.String())
-			}
-			for _, la := range lanAddresses {
-				addrs = append(addrs, la.String())
-			}
-			clt.ResolvedListenAddresses[addressSpec.String()] = addrs
+		clt.folderStates[folder] = state
+		clt.mutex.Unlock()
+		clt.deliverEvent(evt)
 
-			// Get all current addresses and send to client
-			currentResolved := make([]string, 0)
-			for _, addrs := range clt.ResolvedListenAddresses {
-				currentResolved = append(currentResolved, addrs...)
-			}
-			clt.mutex.Unlock()
-			clt.Delegate.OnListenAddressesChanged(List(currentResolved))
-		} else {
-			clt.mutex.Unlock()
+	case events.ListenAddressesChanged:
+		addrs := make([]string, 0)
+		data := evt.Data.(map[string]interface{})
+		addressSpec := data["address"].(*url.URL)
+		wanAddresses := data["wan"].([]*url.URL)
+		lanAddresses := data["lan"].([]*url.URL)
+
+		for _, wa := range wanAddresses {
+			addrs = append(addrs, wa.String())
 		}
+		for _, la := range lanAddresses {
+			addrs = append(addrs, la.String())
+		}
+		clt.listeners.handleAddressesChanged(addressSpec, len(addrs) > 0)
+
+		// Get all current addresses and send to client
+		clt.mutex.Lock()
+		clt.ResolvedListenAddresses[addressSpec.String()] = addrs
+		currentResolved := make([]string, 0)
+		for _, addrs := range clt.ResolvedListenAddresses {
+			currentResolved = append(currentResolved, addrs...)
+		}
+		clt.mutex.Unlock()
+		clt.notifyDelegate(func(delegate ClientDelegate) {
+			delegate.OnListenAddressesChanged(List(currentResolved))
+		})
 
 	case events.DeviceConnected:
 		data := evt.Data.(map[string]string)
 		devID := data["id"]
 		address := data["addr"]
 
+		go clt.recordConnected(data)
+		clt.indexExchange.reset(devID)
+
 		clt.mutex.Lock()
 		clt.connectedDeviceAddresses[devID] = address
-
-		if !clt.IgnoreEvents && clt.Delegate != nil {
-			clt.mutex.Unlock()
-			clt.Delegate.OnEvent(evt.Type.String())
-		} else {
-			clt.mutex.Unlock()
-		}
+		clt.mutex.Unlock()
+		clt.deliverEvent(evt)
 
 	case events.LocalChangeDetected, events.RemoteChangeDetected:
 		data := evt.Data.(map[string]string)
@@ -342,41 +531,50 @@ func (clt *Client) handleEvent(evt events.Event) {
 			modifiedBy = clt.DeviceID()
 		}
 
-		clt.mutex.Lock()
-		if !clt.IgnoreEvents && clt.Delegate != nil {
-			go clt.Delegate.OnChange(&Change{
-				FolderID: data["folder"],
-				ShortID:  modifiedBy,
-				Action:   data["action"],
-				Path:     data["path"],
-				Time:     &Date{time: evt.Time},
-			})
-			clt.mutex.Unlock()
-			clt.Delegate.OnEvent(evt.Type.String())
-		} else {
-			clt.mutex.Unlock()
+		change := &Change{
+			FolderID: data["folder"],
+			ShortID:  modifiedBy,
+			Action:   data["action"],
+			Path:     data["path"],
+			Time:     &Date{time: evt.Time},
 		}
+		clt.notifyDelegate(func(delegate ClientDelegate) {
+			go delegate.OnChange(change)
+			delegate.OnEvent(evt.Type.String())
+		})
 
-	case events.LocalIndexUpdated, events.DeviceDisconnected, events.ConfigSaved,
+	case events.DeviceDisconnected:
+		data := evt.Data.(map[string]string)
+		go clt.recordDisconnected(data)
+		clt.deliverEvent(evt)
+
+	case events.RemoteIndexUpdated:
+		data := evt.Data.(map[string]interface{})
+		clt.indexExchange.handleRemoteIndexUpdated(data)
+		if folderID, ok := data["folder"].(string); ok {
+			clt.scheduleDeleteGuardCheck(folderID)
+		}
+
+	case events.FolderCompletion:
+		clt.handleFolderCompletion(evt.Data.(map[string]interface{}))
+
+	case events.FolderErrors:
+		clt.handleFolderErrors(evt.Data.(map[string]interface{}))
+
+	case events.ConfigSaved:
+		clt.handleConfigSaved(evt.Data.(config.Configuration))
+		clt.deliverEvent(evt)
+
+	case events.LocalIndexUpdated,
 		events.ClusterConfigReceived, events.FolderResumed, events.FolderPaused:
 		// Just deliver the event
-		clt.mutex.Lock()
-		if !clt.IgnoreEvents && clt.Delegate != nil {
-			clt.mutex.Unlock()
-			clt.Delegate.OnEvent(evt.Type.String())
-		} else {
-			clt.mutex.Unlock()
-		}
+		clt.deliverEvent(evt)
 
 	case events.DownloadProgress:
 		clt.mutex.Lock()
 		clt.downloadProgress = evt.Data.(map[string]map[string]*model.PullerProgress)
-		if !clt.IgnoreEvents && clt.Delegate != nil {
-			clt.mutex.Unlock()
-			clt.Delegate.OnEvent(evt.Type.String())
-		} else {
-			clt.mutex.Unlock()
-		}
+		clt.mutex.Unlock()
+		clt.deliverEvent(evt)
 
 	case events.RemoteDownloadProgress:
 		peerData := evt.Data.(map[string]interface{})
@@ -394,23 +592,42 @@ func (clt *Client) handleEvent(evt events.Event) {
 		}
 
 		clt.uploadProgress[peerID][folderID] = state
+		clt.mutex.Unlock()
+		clt.deliverEvent(evt)
 
-		if !clt.IgnoreEvents && clt.Delegate != nil {
-			clt.mutex.Unlock()
-			clt.Delegate.OnEvent(evt.Type.String())
-		} else {
-			clt.mutex.Unlock()
-		}
+	case events.ItemStarted:
+		clt.handleItemStarted(evt.Data.(map[string]string))
 
-	case events.ItemFinished, events.ItemStarted:
-		// Ignore these events
-		break
+	case events.ItemFinished:
+		clt.handleItemFinished(evt)
 
 	default:
 		slog.Debug("event", "type", evt.Type.String(), "event", evt)
 	}
 }
 
+// Called for messages logged by Syncthing, for conditions and state that are not reported through events
+func (clt *Client) observeLogRecord(r slog.Record) {
+	switch r.Message {
+	case "Bad certificate from remote":
+		clt.handleBadCertificateLogRecord(r)
+	case "Detected NAT type", "Resolved external address", "Detected NAT services", "New external port opened",
+		"Removing external open port", "Failed to acquire open port", "Failed to renew open port":
+		clt.natTracker.handleLogRecord(r)
+	case "Failed to listen (TCP)", "Failed to listen (QUIC)", "Failed to listen (relay)", "Failed to get listener",
+		"Skipping malformed listener URL", "Skipping malformed listener URL (not canonical)":
+		clt.listeners.handleLogRecord(r)
+	case "Folder failed to sync, will be retried":
+		clt.pullBackoff.handleLogRecord(r)
+	}
+}
+
+func (clt *Client) deliverEvent(evt events.Event) {
+	clt.notifyDelegate(func(delegate ClientDelegate) {
+		delegate.OnEvent(evt.Type.String())
+	})
+}
+
 func (clt *Client) startEventListener() {
 	sub := clt.evLogger.Subscribe(events.AllEvents)
 	defer sub.Unsubscribe()
@@ -532,12 +749,14 @@ func (clt *Client) DownloadingPathsForFolder(folderID string) *ListOfStrings {
 	return &ListOfStrings{}
 }
 
+// Returns whether any folder is pulling changes. See TransferringFolderIDs and ScanningFolderIDs to find out which
+// folders are busy.
 func (clt *Client) IsDownloading() bool {
 	clt.mutex.Lock()
 	defer clt.mutex.Unlock()
 
-	for _, isTransferring := range clt.foldersDownloading {
-		if isTransferring {
+	for _, state := range clt.folderStates {
+		if isPullingState(state) {
 			return true
 		}
 	}
@@ -562,7 +781,7 @@ func (clt *Client) HasMigratedLegacyDatabase() bool {
 
 // This method loads and migrates the Syncthing database. It also starts the streaming web
 // server. This method can take a while to complete and should only ever be called once.
-func (clt *Client) Load(resetDeltaIdxs bool) error {
+func (clt *Client) Load(resetDeltaIdxs bool) (err error) {
 	clt.mutex.Lock()
 	defer clt.mutex.Unlock()
 
@@ -570,6 +789,19 @@ func (clt *Client) Load(resetDeltaIdxs bool) error {
 		return errors.New("client already started")
 	}
 
+	if err := clt.claimLocations(); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			clt.releaseLocations()
+		}
+	}()
+
+	if err := clt.acquireInstanceLockOrAttach(); err != nil {
+		return err
+	}
+
 	// Some early chores
 	osutil.MaximizeOpenFileLimit()
 
@@ -591,21 +823,56 @@ func (clt *Client) Load(resetDeltaIdxs bool) error {
 	// Load or create the config
 	devID := protocol.NewDeviceID(cert.Certificate[0])
 	slog.Info("loading config file", "path", locations.Get(locations.ConfigFile))
-	config, err := loadOrDefaultConfig(devID, clt.ctx, clt.evLogger, clt.filesPath)
+	configCtx, configCancel := context.WithCancel(clt.ctx)
+	config, err := loadOrDefaultConfig(devID, configCtx, clt.evLogger, clt.filesPath, &clt.options, !clt.hasCustomFolderDefaults(),
+		clt.storedConnectionProfile())
 	if err != nil {
+		configCancel()
 		clt.cancel()
 		return err
 	}
-	clt.config = config
+	clt.config = clt.newKeychainConfigWrapper(config, clt.evLogger)
+	clt.configCancel = configCancel
+
+	if err := clt.restoreFolderEncryptionPasswords(); err != nil {
+		slog.Warn("could not restore folder encryption passwords from keychain", "cause", err)
+	}
+	if err := clt.moveWebhookSecretsToKeychain(); err != nil {
+		slog.Warn("could not move webhook secrets to keychain", "cause", err)
+	}
+
+	if clt.options.InMemoryDatabase {
+		// The database has no in-memory mode, so use a temporary one that is removed when the client stops
+		tempPath, err := os.MkdirTemp("", "sushitrain-db-")
+		if err != nil {
+			return err
+		}
+		clt.temporaryDatabasePath = tempPath
+	} else {
+		// It really wants to set up a temporary API while migrating...
+		if err := syncthing.TryMigrateDatabase(clt.ctx, dbDeleteRetentionInterval); err != nil {
+			slog.Warn("failed to migrate legacy database", "cause", err)
+			return err
+		}
+	}
 
-	// Default retention interval taken from Syncthing's CLI default
-	dbDeleteRetentionInterval := time.Duration(4320) * time.Hour
-	// It really wants to set up a temporary API while migrating...
-	if err := syncthing.TryMigrateDatabase(clt.ctx, dbDeleteRetentionInterval); err != nil {
-		slog.Warn("failed to migrate legacy database", "cause", err)
+	app, err := clt.newApp(resetDeltaIdxs)
+	if errors.Is(err, ErrDatabaseUnavailable) {
+		// Continue without the database, so the configuration remains accessible and the database can be repaired
+		slog.Error("could not open database, continuing in degraded mode", "cause", err)
+		clt.databaseError = err
+		return nil
+	}
+	if err != nil {
 		return err
 	}
+	clt.app = app
 
+	return nil
+}
+
+// Opens the database and creates the Syncthing app (not yet started) using the loaded configuration
+func (clt *Client) newApp(resetDeltaIdxs bool) (*syncthing.App, error) {
 	appOpts := syncthing.Options{
 		NoUpgrade:      false,
 		ProfilerAddr:   "",
@@ -615,23 +882,21 @@ func (clt *Client) Load(resetDeltaIdxs bool) error {
 	}
 
 	// Load database
-	dbPath := locations.Get(locations.Database)
+	dbPath := cmp.Or(clt.temporaryDatabasePath, locations.Get(locations.Database))
 
 	sdb, err := syncthing.OpenDatabase(dbPath, dbDeleteRetentionInterval)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("%w: %w", ErrDatabaseUnavailable, err)
 	}
+	clt.sdb = sdb
 
-	app, err := syncthing.New(clt.config, sdb, clt.evLogger, *clt.cert, appOpts)
-	if err != nil {
-		return err
-	}
-	clt.app = app
-
-	return nil
+	return syncthing.New(clt.config, sdb, clt.evLogger, *clt.cert, appOpts)
 }
 
 func (clt *Client) Start() error {
+	if clt.databaseError != nil {
+		return clt.databaseError
+	}
 	if clt.app == nil {
 		return errors.New("call Client.Load first")
 	}
@@ -639,15 +904,35 @@ func (clt *Client) Start() error {
 	clt.Measurements = NewMeasurements(clt)
 
 	// Set up streaming server
-	server, err := NewServer(clt.app, clt.Measurements, clt.ctx)
+	server, err := NewServer(clt, clt.Measurements, clt.ctx)
 	if err != nil {
 		return err
 	}
-	server.client = clt
 	clt.Server = server
 
 	// Subscribe to events
 	go clt.startEventListener()
+	if err := clt.setMaterializationDirectory(); err != nil {
+		return err
+	}
+	go clt.materialized.serve(clt.ctx)
+	go clt.activity.serveFlush(clt.ctx, activityFlushInterval)
+	go clt.rejections.serveFlush(clt.ctx, rejectionsFlushInterval)
+	go clt.serveWatchdog(clt.ctx)
+	go clt.serveTrafficTotals(clt.ctx)
+	go clt.serveDataUsage(clt.ctx)
+	go clt.serveChangeHints(clt.ctx)
+	clt.resetConfigDiffs()
+	go clt.serveConfigFileWatch(clt.ctx)
+
+	// Folders may still be throttled or paused for the power state or network at the end of the previous run. Apply the
+	// policies for the current state (normal, unless the app reported otherwise already) before the folders start.
+	if err := clt.applyPowerPolicy(); err != nil {
+		slog.Warn("could not apply power policy", "cause", err)
+	}
+	if err := clt.applyCellularPolicy(); err != nil {
+		slog.Warn("could not apply cellular policy", "cause", err)
+	}
 
 	if err := clt.app.Start(); err != nil {
 		return err
@@ -658,14 +943,13 @@ func (clt *Client) Start() error {
 
 func (clt *Client) SetFSWatchingEnabledForAllFolders(enabled bool) {
 	clt.changeConfiguration(func(cfg *config.Configuration) {
-		for _, fc := range cfg.Folders {
-			fc.FSWatcherEnabled = enabled
-			cfg.SetFolder(fc)
+		for i := range cfg.Folders {
+			cfg.Folders[i].FSWatcherEnabled = enabled
 		}
 	})
 }
 
-func loadOrDefaultConfig(devID protocol.DeviceID, ctx context.Context, logger events.Logger, filesPath string) (config.Wrapper, error) {
+func loadOrDefaultConfig(devID protocol.DeviceID, ctx context.Context, logger events.Logger, filesPath string, options *clientOptions, forceFolderDefaults bool, profile connectionProfile) (config.Wrapper, error) {
 	cfgFile := locations.Get(locations.ConfigFile)
 	cfg, _, err := config.Load(cfgFile, devID, logger)
 	if err != nil {
@@ -674,21 +958,34 @@ func loadOrDefaultConfig(devID protocol.DeviceID, ctx context.Context, logger ev
 		cfg = config.Wrap(cfgFile, newCfg, devID, logger)
 	}
 
+	// A wrapper without a path never saves
+	if options.ReadOnly {
+		cfg = config.Wrap("", cfg.RawCopy(), devID, logger)
+	}
+
 	go cfg.Serve(ctx)
 
 	// Always override the following options in config
 	waiter, err := cfg.Modify(func(conf *config.Configuration) {
-		conf.GUI.Enabled = false                             // Don't need the web UI, we have our own :-)
-		conf.Options.CREnabled = false                       // No crash reporting for now
-		conf.Options.URAccepted = -1                         // No usage reporting for now
-		conf.Options.ProgressUpdateIntervalS = 1             // We want to update the user often, it improves the experience and is worth the compute cost
-		conf.Options.CRURL = ""                              // No crash reporting for now
-		conf.Options.URURL = ""                              // No usage reporting for now
-		conf.Options.ReleasesURL = ""                        // Disable auto update, we can't do so on iOS anyway
-		conf.Defaults.Folder.IgnorePerms = true              // iOS doesn't expose permissions to users
-		conf.Defaults.Folder.RescanIntervalS = 3600          // Force default rescan interval
-		conf.Options.RelayReconnectIntervalM = 1             // Set this to one minute (from the default 10) because on mobile networks this is more often necessary
-		conf.Defaults.Folder.FSWatcherEnabled = !build.IsIOS // Enable watching by default but not on iOS
+		conf.GUI.Enabled = false       // Don't need the web UI, we have our own :-)
+		conf.Options.CREnabled = false // No crash reporting for now
+		conf.Options.CRURL = ""        // No crash reporting for now
+		conf.Options.ReleasesURL = ""  // Disable auto update, we can't do so on iOS anyway
+
+		// By default, progress is reported every second (which improves the experience and is worth the compute cost) and
+		// relays are reconnected to after a minute (instead of ten) because on mobile networks this is more often
+		// necessary. The radio-friendly profile does neither.
+		profile.applyLoadOptions(&conf.Options)
+
+		// Until the user changes them (see SetDefaultFolderSettings)
+		if forceFolderDefaults {
+			applyAppFolderDefaults(&conf.Defaults.Folder)
+		}
+
+		// No usage reporting unless accepted through SetUsageReportingAccepted
+		if conf.Options.URAccepted <= 0 {
+			conf.Options.URURL = ""
+		}
 
 		// On iOS and probably macOS, the absolute path to the apps container that has the synchronized folders changes on each
 		// run. Therefore we re-set the absolute folder path here to [app documents directory]/[folder ID] if we don't have
@@ -720,6 +1017,8 @@ func loadOrDefaultConfig(devID protocol.DeviceID, ctx context.Context, logger ev
 				conf.SetFolder(folderConfig)
 			}
 		}
+
+		options.apply(conf)
 	})
 
 	if err != nil {
@@ -727,6 +1026,9 @@ func loadOrDefaultConfig(devID protocol.DeviceID, ctx context.Context, logger ev
 	}
 	waiter.Wait()
 
+	if options.ReadOnly {
+		return cfg, nil
+	}
 	err = cfg.Save()
 	if err != nil {
 		return nil, err
@@ -775,6 +1077,17 @@ func (clt *Client) FolderWithID(id string) *Folder {
 	}
 }
 
+// Like FolderWithID, but returns ErrStillLoading or ErrFolderNotFound instead of nil, so the app can tell why
+func (clt *Client) FolderWithIDOrError(id string) (*Folder, error) {
+	if clt.config == nil {
+		return nil, ErrStillLoading
+	}
+	if fld := clt.FolderWithID(id); fld != nil {
+		return fld, nil
+	}
+	return nil, ErrFolderNotFound
+}
+
 func (clt *Client) ConnectedPeerCount() int {
 	if clt.app == nil || clt.app.Internals == nil {
 		return 0
@@ -820,7 +1133,23 @@ func (clt *Client) PeerWithID(deviceID string) *Peer {
 	}
 }
 
+// Like PeerWithID, but returns ErrInvalidDeviceID (wrapping the reason) instead of nil. As with PeerWithID, the device
+// does not need to be configured (e.g. for devices asking to connect); use Peer.Exists to check.
+func (clt *Client) PeerWithIDOrError(deviceID string) (*Peer, error) {
+	devID, err := protocol.DeviceIDFromString(deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDeviceID, err)
+	}
+	return &Peer{
+		client:   clt,
+		deviceID: devID,
+	}, nil
+}
+
 func (clt *Client) PeerWithShortID(shortID string) *Peer {
+	if clt.config == nil {
+		return nil
+	}
 	for _, dc := range clt.config.DeviceList() {
 		if dc.DeviceID.Short().String() == shortID {
 			return &Peer{
@@ -832,12 +1161,24 @@ func (clt *Client) PeerWithShortID(shortID string) *Peer {
 	return nil
 }
 
+// Like PeerWithShortID, but returns ErrStillLoading or ErrDeviceNotFound instead of nil
+func (clt *Client) PeerWithShortIDOrError(shortID string) (*Peer, error) {
+	if clt.config == nil {
+		return nil, ErrStillLoading
+	}
+	if peer := clt.PeerWithShortID(shortID); peer != nil {
+		return peer, nil
+	}
+	return nil, ErrDeviceNotFound
+}
+
 // This function sets all the listed device to the desired pause state, and all other devices to the opposite state.
 func (clt *Client) SetDevicesPaused(peers *ListOfStrings, pause bool) error {
 	ids := peers.data
 
 	clt.changeConfiguration(func(cfg *config.Configuration) {
-		for _, dc := range clt.config.DeviceList() {
+		for i := range cfg.Devices {
+			dc := &cfg.Devices[i]
 			listed := slices.ContainsFunc(ids, func(v string) bool {
 				did, err := protocol.DeviceIDFromString(v)
 				return err == nil && dc.DeviceID.Equals(did)
@@ -847,25 +1188,32 @@ func (clt *Client) SetDevicesPaused(peers *ListOfStrings, pause bool) error {
 			if !pause {
 				shouldPause = !shouldPause
 			}
-
-			if dc.Paused != shouldPause {
-				dc.Paused = shouldPause
-				cfg.SetDevice(dc)
-			}
+			dc.Paused = shouldPause
 		}
 	})
 	return nil
 }
 
 func (clt *Client) changeConfiguration(block config.ModifyFunction) error {
+	if clt.options.ReadOnly {
+		return ErrReadOnly
+	}
+	clt.configMutex.Lock()
+	defer clt.configMutex.Unlock()
 	waiter, err := clt.config.Modify(block)
 	if err != nil {
 		return err
 	}
 	waiter.Wait()
 
-	err = clt.config.Save()
-	return err
+	return clt.saveConfiguration()
+}
+
+func (clt *Client) saveConfiguration() error {
+	if clt.options.ReadOnly {
+		return nil
+	}
+	return clt.config.Save()
 }
 
 func (clt *Client) AddPeer(deviceID string) error {
@@ -911,13 +1259,49 @@ func (clt *Client) AddSpecialFolder(folderID string, fsType string, folderPath s
 
 // Leave path empty to add folder at default location
 func (clt *Client) AddFolder(folderID string, folderPath string, createAsOnDemand bool) error {
+	return clt.CreateFolder(folderID, folderID, folderPath, createAsOnDemand)
+}
+
+// Returns a random folder ID in the format the Syncthing web UI uses (e.g. 'abcde-fgh12')
+func (clt *Client) GenerateFolderID() string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	for {
+		id := make([]byte, 11)
+		for i := range id {
+			if i == 5 {
+				id[i] = '-'
+			} else {
+				id[i] = chars[rand.Intn(len(chars))]
+			}
+		}
+
+		if clt.config == nil {
+			return string(id)
+		}
+		if _, exists := clt.config.Folder(string(id)); !exists {
+			return string(id)
+		}
+	}
+}
+
+// Creates a new folder. Leave the path empty to create the folder at the default location. When `selective` is set,
+// the folder is created with an ignore pattern that ignores everything, so that files are only synchronized when
+// selected explicitly.
+func (clt *Client) CreateFolder(folderID string, label string, folderPath string, selective bool) error {
 	if clt.app == nil || clt.app.Internals == nil {
 		return ErrStillLoading
 	}
 
+	if folderID == "" {
+		return errors.New("folder ID cannot be empty")
+	}
+	if _, exists := clt.config.Folder(folderID); exists {
+		return errors.New("a folder with this ID already exists")
+	}
+
 	folderConfig := clt.config.DefaultFolder()
 	folderConfig.ID = folderID
-	folderConfig.Label = folderID
+	folderConfig.Label = label
 	if len(folderPath) == 0 {
 		folderConfig.Path = path.Join(clt.filesPath, folderID)
 	} else {
@@ -925,6 +1309,10 @@ func (clt *Client) AddFolder(folderID string, folderPath string, createAsOnDeman
 	}
 	folderConfig.Paused = false
 
+	if err := clt.checkFolderPathAvailable(folderConfig.Path, ""); err != nil {
+		return err
+	}
+
 	// Add to configuration
 	err := clt.changeConfiguration(func(cfg *config.Configuration) {
 		cfg.SetFolder(folderConfig)
@@ -934,7 +1322,7 @@ func (clt *Client) AddFolder(folderID string, folderPath string, createAsOnDeman
 	}
 
 	// Set default ignores for on-demand sync
-	if createAsOnDemand {
+	if selective {
 		return clt.app.Internals.SetIgnores(folderID, []string{"*"})
 	} else {
 		// Create empty .stignore anyway because there may be an old one lingering around
@@ -942,6 +1330,115 @@ func (clt *Client) AddFolder(folderID string, folderPath string, createAsOnDeman
 	}
 }
 
+// Returns the name of the directory for a folder with the given ID in the files directory. Folder IDs may come from
+// other devices or configurations, so IDs that are not a single path component (e.g. '..') are refused.
+func folderDirectoryName(folderID string) (string, error) {
+	if folderID == "" || folderID == "." || folderID == ".." || strings.ContainsAny(folderID, `/\`) {
+		return "", errors.New("folder ID cannot be used as a directory name: " + folderID)
+	}
+	return folderID, nil
+}
+
+// Returned when creating a folder or changing its path fails because of where the path is
+var (
+	ErrFolderPathInUse          = errors.New("another folder already uses this path")
+	ErrFolderPathNested         = errors.New("folders cannot be nested inside each other")
+	ErrFolderPathInConfigFolder = errors.New("folders cannot be inside the app's configuration directory")
+)
+
+// Returns an error when the path is already used by another (local) folder, or is inside or contains one. The folder
+// with ID `exceptFolderID` is skipped, so that a folder can be checked against the others when its path changes.
+func (clt *Client) checkFolderPathAvailable(folderPath string, exceptFolderID string) error {
+	folderPath = filepath.Clean(folderPath)
+	configPath := filepath.Clean(clt.options.ConfigPath)
+	if clt.options.ConfigPath != "" && (folderPath == configPath || strings.HasPrefix(folderPath, configPath+string(filepath.Separator))) {
+		return ErrFolderPathInConfigFolder
+	}
+
+	for _, fc := range clt.config.FolderList() {
+		if fc.FilesystemType != config.FilesystemTypeBasic || fc.ID == exceptFolderID {
+			continue
+		}
+
+		existingPath := filepath.Clean(fc.Path)
+		if existingPath == folderPath {
+			return ErrFolderPathInUse
+		}
+		if isSameOrNestedPath(folderPath, existingPath) {
+			return ErrFolderPathNested
+		}
+	}
+	return nil
+}
+
+// Returns whether the paths are equal or one is inside the other (both should be clean)
+func isSameOrNestedPath(a string, b string) bool {
+	separator := string(filepath.Separator)
+	return a == b || strings.HasPrefix(a, b+separator) || strings.HasPrefix(b, a+separator)
+}
+
+type AdoptFolderDelegate interface {
+	OnProgress(fraction float64)
+	OnFinished()
+	OnError(error string)
+}
+
+// Creates a folder for a directory that already contains files (e.g. copied onto the device beforehand). The initial
+// scan adds the existing files to the local index, so that files identical to those on other devices are not
+// downloaded again. The delegate is informed about the progress of this initial scan.
+func (clt *Client) AdoptFolder(folderID string, existingPath string, delegate AdoptFolderDelegate) error {
+	info, err := os.Stat(existingPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("path is not a directory")
+	}
+
+	// Subscribe before creating the folder so we do not miss the start of the initial scan
+	sub := clt.evLogger.Subscribe(events.FolderScanProgress | events.StateChanged)
+	if err := clt.CreateFolder(folderID, filepath.Base(existingPath), existingPath, false); err != nil {
+		sub.Unsubscribe()
+		return err
+	}
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-clt.ctx.Done():
+				return
+			case evt := <-sub.C():
+				data, ok := evt.Data.(map[string]interface{})
+				if !ok || data["folder"] != folderID {
+					continue
+				}
+
+				switch evt.Type {
+				case events.FolderScanProgress:
+					current, _ := data["current"].(int64)
+					total, _ := data["total"].(int64)
+					if total > 0 {
+						delegate.OnProgress(float64(current) / float64(total))
+					}
+
+				case events.StateChanged:
+					if data["to"] == "error" {
+						message, _ := data["error"].(string)
+						delegate.OnError(message)
+						return
+					}
+					if data["from"] == "scanning" {
+						delegate.OnFinished()
+						return
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
 func (clt *Client) SetNATEnabled(enabled bool) error {
 	return clt.changeConfiguration(func(cfg *config.Configuration) {
 		cfg.Options.NATEnabled = enabled
@@ -1114,6 +1611,7 @@ func (clt *Client) GetTotalUploadProgress() *Progress {
 	}
 }
 
+// Returns the progress of the downloads currently in progress. See OverallSyncProgress for the progress of all folders.
 func (clt *Client) GetTotalDownloadProgress() *Progress {
 	clt.mutex.Lock()
 	defer clt.mutex.Unlock()
@@ -1179,16 +1677,12 @@ func (clt *Client) GetName() (string, error) {
 }
 
 func (clt *Client) SetName(name string) error {
-	devID := clt.deviceID()
-
-	selfConfig, ok := clt.config.Devices()[devID]
-	if !ok {
+	self := &Peer{client: clt, deviceID: clt.deviceID()}
+	if self.deviceConfiguration() == nil {
 		return errors.New("cannot find myself")
 	}
-	selfConfig.Name = name
-
-	return clt.changeConfiguration(func(cfg *config.Configuration) {
-		cfg.SetDevice(selfConfig)
+	return self.changeDeviceConfiguration(func(dc *config.DeviceConfiguration) {
+		dc.Name = name
 	})
 }
 
@@ -1201,11 +1695,11 @@ func (clt *Client) Statistics() (*FolderStats, error) {
 	localTotal := FolderCounts{}
 
 	for _, folder := range clt.config.FolderList() {
-		globalFolderSize, err := clt.app.Internals.GlobalSize(folder.ID)
+		globalFolderSize, err := clt.globalSize(folder.ID)
 		if err != nil {
 			return nil, err
 		}
-		localFolderSize, err := clt.app.Internals.LocalSize(folder.ID)
+		localFolderSize, err := clt.localSize(folder.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -1225,6 +1719,19 @@ type SearchResultDelegate interface {
 	IsCancelled() bool
 }
 
+// Estimates how much would be downloaded when the given paths in a folder are selected. Directories are counted
+// including everything below them.
+func (clt *Client) EstimateSelectionSize(folderID string, paths *ListOfStrings) (*FolderCounts, error) {
+	fld := clt.FolderWithID(folderID)
+	if fld == nil {
+		return nil, ErrFolderNotFound
+	}
+	if paths == nil || len(paths.data) == 0 {
+		return &FolderCounts{}, nil
+	}
+	return fld.sizeOfPaths(paths.data)
+}
+
 // Zip interleaves the iterator value with the error. The iteration ends
 // after a non-nil error.
 func zipError[T any](it iter.Seq[T], errFn func() error) iter.Seq2[T, error] {
@@ -1263,32 +1770,22 @@ func (clt *Client) Search(text string, delegate SearchResultDelegate, maxResults
 			FolderID: folder.ID,
 		}
 
-		for f, err := range zipError(clt.app.Internals.AllGlobalFiles(folder.ID)) {
-			if err != nil {
-				return err
-			}
-
-			if delegate.IsCancelled() {
-				// This shouild cancel the scan
-				break
-			}
-
-			gimmeMore := maxResults <= 0 || resultCount < maxResults
+		matches, err := clt.searchFolder(folder.ID, text, prefix, delegate.IsCancelled)
+		if errors.Is(err, errSearchCancelled) {
+			return nil
+		} else if err != nil {
+			return err
+		}
 
-			// Check prefix
-			if !strings.HasPrefix(f.Name, prefix) {
-				continue
+		for _, match := range matches {
+			if delegate.IsCancelled() || (maxResults > 0 && resultCount >= maxResults) {
+				return nil
 			}
 
-			pathParts := strings.Split(f.Name, "/")
-			lowerFileName := strings.ToLower(pathParts[len(pathParts)-1])
-
-			if gimmeMore && !f.Deleted && strings.Contains(lowerFileName, text) {
-				entry, err := folderObject.GetFileInformation(f.Name)
-				if err == nil {
-					resultCount += 1
-					delegate.Result(entry)
-				}
+			entry, err := folderObject.GetFileInformation(match.name)
+			if err == nil {
+				resultCount += 1
+				delegate.Result(entry)
 			}
 		}
 	}
@@ -1383,11 +1880,16 @@ func (clt *Client) SetReconnectIntervalS(secs int) error {
 	})
 }
 
+// Returns the configured listen addresses, which may include 'default'
 func (clt *Client) ListenAddresses() *ListOfStrings {
 	return List(clt.config.Options().RawListenAddresses)
 }
 
+// Sets the addresses to listen on for incoming connections (see ListenerStatus for whether that succeeded)
 func (clt *Client) SetListenAddresses(addrs *ListOfStrings) error {
+	if err := validateListenAddresses(addrs.data); err != nil {
+		return err
+	}
 	return clt.changeConfiguration(func(cfg *config.Configuration) {
 		cfg.Options.RawListenAddresses = addrs.data
 	})
@@ -1449,7 +1951,13 @@ func IsValidDeviceID(devID string) bool {
 	return err == nil
 }
 
+// Returns the version of the app, as reported to other devices once a client is loaded
 func Version() string {
+	loadedClientMutex.Lock()
+	defer loadedClientMutex.Unlock()
+	if loadedClient == nil {
+		return defaultBuildVersion
+	}
 	return build.Version
 }
 
@@ -1670,7 +2178,7 @@ func (m *Measurements) actuallyMeasure() {
 	for _, device := range devices {
 		if m.client.app.Internals.IsConnectedTo(device.DeviceID) {
 			start := time.Now()
-			pingContext, _ := context.WithTimeout(ctx, time.Second*1)
+			pingContext, cancel := context.WithTimeout(ctx, time.Second*1)
 
 			// Make a faux request. This is expected to return a 'generic error' but we are not actually interested in the
 			// requested block anyway, just in the time it takes to respond.
@@ -1684,6 +2192,7 @@ func (m *Measurements) actuallyMeasure() {
 			} else {
 				slog.Info("ping error", "cause", pingContext.Err())
 			}
+			cancel()
 		}
 	}
 
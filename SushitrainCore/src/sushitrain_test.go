@@ -0,0 +1,53 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestConnectedClientsShareFolder(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts two clients")
+	}
+
+	first, second := startConnectedTestClients(t)
+	shareTestFolder(t, first, second, "shared")
+
+	for _, pair := range [][2]*Client{{first, second}, {second, first}} {
+		client, peer := pair[0], pair[1]
+		fld := client.FolderWithID("shared")
+		if fld == nil {
+			t.Fatalf("folder missing on %s", client.deviceID().Short())
+		}
+		if !slices.Contains(fld.SharedWithDeviceIDs().data, peer.DeviceID()) {
+			t.Errorf("folder on %s not shared with %s", client.deviceID().Short(), peer.deviceID().Short())
+		}
+	}
+
+	deadline := time.Now().Add(testClientConnectTimeout)
+	for first.FolderWithID("shared").ConnectedPeerCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("folder peer not connected in time")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestFolderDirectoryName(t *testing.T) {
+	for _, folderID := range []string{"abcd-1234", "Photos", "..hidden"} {
+		if name, err := folderDirectoryName(folderID); err != nil || name != folderID {
+			t.Errorf("expected %q to be accepted, got %q (%v)", folderID, name, err)
+		}
+	}
+	for _, folderID := range []string{"", ".", "..", "a/b", `a\b`} {
+		if _, err := folderDirectoryName(folderID); err == nil {
+			t.Errorf("expected %q to be rejected", folderID)
+		}
+	}
+}
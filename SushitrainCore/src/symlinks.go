@@ -0,0 +1,159 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const symlinkPolicyFileName = "symlinks.json"
+
+const (
+	// Symlinks are created like any other file (Syncthing's behaviour)
+	SymlinkPolicyCreate = "create"
+
+	// Symlinks are ignored, so they are not created and do not cause errors
+	SymlinkPolicySkip = "skip"
+)
+
+// Lines delimiting the ignore patterns we maintain for skipped symlinks
+const (
+	skippedSymlinksStartLine = "// Symlinks skipped by Synctrain (maintained automatically)"
+	skippedSymlinksEndLine   = "// End of skipped symlinks"
+)
+
+// Returns how symlinks in this folder are handled (SymlinkPolicyCreate or SymlinkPolicySkip)
+func (fld *Folder) SymlinkPolicy() string {
+	policy := SymlinkPolicyCreate
+	fld.client.symlinkPolicies.read(func(policies *map[string]string) {
+		if p, ok := (*policies)[fld.FolderID]; ok {
+			policy = p
+		}
+	})
+	return policy
+}
+
+// Sets how symlinks in this folder are handled. Skipped symlinks are added to the ignore patterns of the folder, which
+// is kept up to date as symlinks appear and disappear. Selective folders only ever contain the selected paths, so
+// symlinks cannot be selected when they are skipped; symlinks inside a selected directory are still created.
+func (fld *Folder) SetSymlinkPolicy(policy string) error {
+	if policy != SymlinkPolicyCreate && policy != SymlinkPolicySkip {
+		return errors.New("invalid symlink policy")
+	}
+
+	err := fld.client.symlinkPolicies.modify(func(policies *map[string]string) {
+		if policy == SymlinkPolicyCreate {
+			delete(*policies, fld.FolderID)
+		} else {
+			(*policies)[fld.FolderID] = policy
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return fld.updateSkippedSymlinks()
+}
+
+// Returns the paths of the symlinks in the global index. When onlyMissing is set, only symlinks that do not exist
+// locally are returned (i.e. symlinks that were skipped or could not be created).
+func (fld *Folder) Symlinks(onlyMissing bool) (*ListOfStrings, error) {
+	symlinks, err := fld.symlinks()
+	if err != nil {
+		return nil, err
+	}
+	if !onlyMissing {
+		return List(symlinks), nil
+	}
+
+	ffs := fld.folderConfiguration().Filesystem()
+	missing := make([]string, 0)
+	for _, symlink := range symlinks {
+		if stat, err := ffs.Lstat(osutil.NativeFilename(symlink)); err != nil || !stat.IsSymlink() {
+			missing = append(missing, symlink)
+		}
+	}
+	return List(missing), nil
+}
+
+func (fld *Folder) symlinks() ([]string, error) {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+	if fld.folderConfiguration() == nil {
+		return nil, ErrFolderNotFound
+	}
+
+	symlinks := make([]string, 0)
+	for f, err := range zipError(fld.client.app.Internals.AllGlobalFiles(fld.FolderID)) {
+		if err != nil {
+			return nil, err
+		}
+		if !f.Deleted && f.Type == protocol.FileInfoTypeSymlink {
+			symlinks = append(symlinks, f.Name)
+		}
+	}
+	slices.Sort(symlinks)
+	return symlinks, nil
+}
+
+// Brings the ignore patterns for skipped symlinks in line with the policy and the symlinks currently in the index. Not
+// applicable to selective folders, whose ignore file must consist of selection lines only.
+func (fld *Folder) updateSkippedSymlinks() error {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	lines, _, err := fld.client.app.Internals.Ignores(fld.FolderID)
+	if err != nil {
+		return err
+	}
+	if NewSelection(lines).isSelectiveIgnore() {
+		return nil
+	}
+
+	// Remove our previous block
+	newLines := withoutLineBlock(lines, skippedSymlinksStartLine, skippedSymlinksEndLine)
+
+	if fld.SymlinkPolicy() == SymlinkPolicySkip {
+		symlinks, err := fld.symlinks()
+		if err != nil {
+			return err
+		}
+		if len(symlinks) > 0 {
+			// Patterns are matched in order, so put ours first
+			block := []string{skippedSymlinksStartLine}
+			for _, symlink := range symlinks {
+				block = append(block, strings.TrimPrefix(ignoreLineForSelectingPath(symlink), "!"))
+			}
+			block = append(block, skippedSymlinksEndLine)
+			newLines = append(block, newLines...)
+		}
+	}
+
+	if slices.Equal(lines, newLines) {
+		return nil
+	}
+	slog.Info("updating ignore patterns for skipped symlinks", "folderID", fld.FolderID)
+	fld.cachedIgnore.matcher = nil
+	return fld.client.app.Internals.SetIgnores(fld.FolderID, newLines)
+}
+
+// Called when a folder becomes idle, to pick up symlinks that appeared in the meantime
+func (clt *Client) checkSkippedSymlinks(folderID string) {
+	fld := clt.FolderWithID(folderID)
+	if fld == nil || fld.SymlinkPolicy() != SymlinkPolicySkip {
+		return
+	}
+	if err := fld.updateSkippedSymlinks(); err != nil {
+		slog.Warn("could not update ignore patterns for skipped symlinks", "folderID", folderID, "cause", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+// Progress of bringing all folders in sync with the global state (see Client.OverallSyncProgress)
+type SyncProgress struct {
+	BytesTotal     int64 // Global size of the folders taken into account
+	BytesNeeded    int64 // Bytes that still need to be pulled
+	FilesNeeded    int64
+	Percentage     float32
+	BytesPerSecond float64 // Current download rate from all devices combined
+	ETASeconds     float64 // Estimated number of seconds until in sync, or -1 when unknown
+}
+
+// Returns how far all folders that pull changes (i.e. are not paused, send-only or placeholders) are from being in sync,
+// based on how much each of them still needs compared to its global size. Folders are weighted by their size. Unlike
+// GetTotalDownloadProgress, this also covers files that are not being downloaded yet.
+func (clt *Client) OverallSyncProgress() (*SyncProgress, error) {
+	if clt.app == nil || clt.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+
+	progress := &SyncProgress{ETASeconds: -1}
+	for folderID, fc := range clt.config.Folders() {
+		if fc.Paused || fc.Type == config.FolderTypeSendOnly || clt.FolderWithID(folderID).IsPlaceholder() {
+			continue
+		}
+
+		global, err := clt.globalSize(folderID)
+		if err != nil {
+			return nil, err
+		}
+		need, err := clt.localNeedSize(folderID)
+		if err != nil {
+			return nil, err
+		}
+
+		progress.BytesTotal += global.Bytes
+		progress.BytesNeeded += min(need.Bytes, global.Bytes)
+		progress.FilesNeeded += int64(need.Files + need.Directories + need.Symlinks + need.Deleted)
+	}
+
+	if progress.BytesTotal == 0 {
+		progress.Percentage = 1.0
+	} else {
+		progress.Percentage = float32(float64(progress.BytesTotal-progress.BytesNeeded) / float64(progress.BytesTotal))
+	}
+
+	progress.BytesPerSecond = clt.TotalTransferRates().BytesInPerSecond
+	if progress.BytesNeeded == 0 {
+		progress.ETASeconds = 0
+	} else if progress.BytesPerSecond > 0 {
+		progress.ETASeconds = float64(progress.BytesNeeded) / progress.BytesPerSecond
+	}
+	return progress, nil
+}
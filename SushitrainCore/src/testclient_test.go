@@ -0,0 +1,148 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const testClientConnectTimeout = 30 * time.Second
+
+// Creates a client for tests. The client uses temporary configuration and files directories and a temporary database,
+// has discovery, relaying and NAT traversal disabled, and listens on a free port on the loopback interface only. Folders
+// are created in the temporary files directory. The client is stopped when the test finishes.
+//
+// Loading a client changes process-wide state (Syncthing's locations and the default logger). This is restored when the
+// test finishes. While two test clients are loaded, Syncthing logs through the handler of the one loaded last.
+func newTestClient(t testing.TB) *Client {
+	t.Helper()
+
+	address, err := freeLoopbackListenAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := clientOptions{
+		ConfigPath:       t.TempDir(),
+		FilesPath:        t.TempDir(),
+		ListenAddresses:  []string{address},
+		InMemoryDatabase: true,
+		DisableDiscovery: true,
+		testing:          true,
+	}
+	if err := options.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := options.resolveProfile(); err != nil {
+		t.Fatal(err)
+	}
+
+	restoreProcessState := saveProcessState()
+	client := newClient(options)
+	t.Cleanup(func() {
+		client.Stop()
+		restoreProcessState()
+	})
+	return client
+}
+
+// Returns a function that restores the process-wide state a client changes when it is loaded
+func saveProcessState() func() {
+	logger := slog.Default()
+	baseDirs := map[locations.BaseDirEnum]string{}
+	for _, baseDir := range []locations.BaseDirEnum{locations.ConfigBaseDir, locations.DataBaseDir, locations.UserHomeBaseDir} {
+		baseDirs[baseDir] = locations.GetBaseDir(baseDir)
+	}
+
+	return func() {
+		slog.SetDefault(logger)
+		for baseDir, dir := range baseDirs {
+			locations.SetBaseDir(baseDir, dir)
+		}
+	}
+}
+
+// Creates, loads and starts a test client (see newTestClient)
+func startTestClient(t testing.TB) *Client {
+	t.Helper()
+	client := newTestClient(t)
+	if err := client.Load(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// Creates, loads and starts two test clients that are peers of each other, and waits until they are connected
+func startConnectedTestClients(t testing.TB) (*Client, *Client) {
+	t.Helper()
+	first := startTestClient(t)
+	second := startTestClient(t)
+
+	if err := first.connectTestPeer(second); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.connectTestPeer(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.waitUntilConnectedTo(second.deviceID(), testClientConnectTimeout); err != nil {
+		t.Fatal(err)
+	}
+	return first, second
+}
+
+// Shares a new folder with the given ID between both clients
+func shareTestFolder(t testing.TB, first *Client, second *Client, folderID string) {
+	t.Helper()
+	for _, pair := range [][2]*Client{{first, second}, {second, first}} {
+		client, peer := pair[0], pair[1]
+		if err := client.AddFolder(folderID, "", false); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.FolderWithID(folderID).ShareWithDevice(peer.DeviceID(), true, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Adds the other test client as peer, reachable at its listen addresses
+func (clt *Client) connectTestPeer(other *Client) error {
+	if err := clt.AddPeer(other.DeviceID()); err != nil {
+		return err
+	}
+	peer := clt.PeerWithID(other.DeviceID())
+	return peer.SetAddresses(List(other.options.ListenAddresses))
+}
+
+// Waits until the client is connected to the device, or fails after the timeout has passed
+func (clt *Client) waitUntilConnectedTo(deviceID protocol.DeviceID, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for !clt.app.Internals.IsConnectedTo(deviceID) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not connected to %s in time", deviceID.Short())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// Returns a listen address on the loopback interface with a port that is currently free
+func freeLoopbackListenAddress() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return fmt.Sprintf("tcp://%s", listener.Addr().String()), nil
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Where a link in the cluster topology was learned from
+const (
+	ClusterLinkSourceDirect     = "direct"     // We share the folder with the device ourselves
+	ClusterLinkSourceIntroducer = "introducer" // An introducer told us it shares the folder with the device
+)
+
+// Indicates that two devices synchronize a folder with each other
+type ClusterLink struct {
+	FolderID     string
+	FromDeviceID string
+	ToDeviceID   string
+	Source       string
+}
+
+// Which devices synchronize which folders with whom, as far as we know (see Client.ClusterTopology)
+type ClusterTopology struct {
+	links []*ClusterLink
+}
+
+func (ct *ClusterTopology) Count() int {
+	return len(ct.links)
+}
+
+func (ct *ClusterTopology) ItemAt(index int) *ClusterLink {
+	if index < 0 || index >= len(ct.links) {
+		return nil
+	}
+	return ct.links[index]
+}
+
+// Returns the IDs of all devices that appear in the topology (including our own)
+func (ct *ClusterTopology) DeviceIDs() *ListOfStrings {
+	devices := map[string]bool{}
+	for _, link := range ct.links {
+		devices[link.FromDeviceID] = true
+		devices[link.ToDeviceID] = true
+	}
+	return List(slices.Sorted(maps.Keys(devices)))
+}
+
+// Returns the links for a single folder
+func (ct *ClusterTopology) LinksForFolder(folderID string) *ClusterTopology {
+	return &ClusterTopology{links: slices.DeleteFunc(slices.Clone(ct.links), func(link *ClusterLink) bool {
+		return link.FolderID != folderID
+	})}
+}
+
+// Returns, per folder, which devices share it with which others. Besides the devices we share folders with directly,
+// this includes the shares introducers told us about in their cluster configuration. Devices that share a folder with
+// our peers but were not introduced to us remain invisible, as Syncthing does not keep the cluster configuration of
+// other devices.
+func (clt *Client) ClusterTopology() (*ClusterTopology, error) {
+	if clt.config == nil {
+		return nil, ErrStillLoading
+	}
+
+	self := clt.deviceID()
+	links := make([]*ClusterLink, 0)
+	for _, fc := range clt.config.FolderList() {
+		for _, fdc := range fc.Devices {
+			if fdc.DeviceID == self {
+				continue
+			}
+			links = append(links, &ClusterLink{
+				FolderID:     fc.ID,
+				FromDeviceID: self.String(),
+				ToDeviceID:   fdc.DeviceID.String(),
+				Source:       ClusterLinkSourceDirect,
+			})
+			if fdc.IntroducedBy != protocol.EmptyDeviceID && fdc.IntroducedBy != self {
+				links = append(links, &ClusterLink{
+					FolderID:     fc.ID,
+					FromDeviceID: fdc.IntroducedBy.String(),
+					ToDeviceID:   fdc.DeviceID.String(),
+					Source:       ClusterLinkSourceIntroducer,
+				})
+			}
+		}
+	}
+	return &ClusterTopology{links: links}, nil
+}
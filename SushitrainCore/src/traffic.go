@@ -0,0 +1,130 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// How often the traffic counted during this session is added to the totals saved in the connection history
+const trafficSaveInterval = time.Minute
+
+// Keeps track of which part of Syncthing's (per-process) traffic counters was already added to the saved totals
+type trafficTotals struct {
+	mutex    sync.Mutex
+	savedIn  map[string]int64
+	savedOut map[string]int64
+}
+
+func newTrafficTotals() *trafficTotals {
+	return &trafficTotals{
+		savedIn:  map[string]int64{},
+		savedOut: map[string]int64{},
+	}
+}
+
+// Returns the traffic counted by Syncthing for a device that was not yet added to the saved totals
+func (tt *trafficTotals) unsaved(deviceID string, in map[string]int64, out map[string]int64) (int64, int64) {
+	return max(0, in[deviceID]-tt.savedIn[deviceID]), max(0, out[deviceID]-tt.savedOut[deviceID])
+}
+
+func (clt *Client) serveTrafficTotals(ctx context.Context) {
+	ticker := time.NewTicker(trafficSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			clt.saveTrafficTotals()
+		}
+	}
+}
+
+// Adds the traffic counted since the last save to the totals in the connection history
+func (clt *Client) saveTrafficTotals() {
+	in, out := deviceTransferTotals()
+
+	tt := clt.trafficTotals
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	changed := false
+	for deviceID := range in {
+		if deltaIn, deltaOut := tt.unsaved(deviceID, in, out); deltaIn > 0 || deltaOut > 0 {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+
+	err := clt.connections.modify(func(records *map[string]*connectionRecord) {
+		now := time.Now()
+		for deviceID := range in {
+			deltaIn, deltaOut := tt.unsaved(deviceID, in, out)
+			if deltaIn == 0 && deltaOut == 0 {
+				continue
+			}
+			rec, ok := (*records)[deviceID]
+			if !ok {
+				rec = &connectionRecord{}
+				(*records)[deviceID] = rec
+			}
+			if rec.TrafficSince.IsZero() {
+				rec.TrafficSince = now
+			}
+			rec.BytesIn += deltaIn
+			rec.BytesOut += deltaOut
+		}
+	})
+	if err != nil {
+		slog.Warn("could not save traffic totals", "cause", err)
+		return
+	}
+	for deviceID := range in {
+		tt.savedIn[deviceID] = max(tt.savedIn[deviceID], in[deviceID])
+		tt.savedOut[deviceID] = max(tt.savedOut[deviceID], out[deviceID])
+	}
+}
+
+func (peer *Peer) trafficTotals() (int64, int64) {
+	in, out := deviceTransferTotals()
+	rec := peer.connectionRecord()
+
+	tt := peer.client.trafficTotals
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+	unsavedIn, unsavedOut := tt.unsaved(peer.deviceID.String(), in, out)
+	return rec.BytesIn + unsavedIn, rec.BytesOut + unsavedOut
+}
+
+// Returns the total number of bytes received from this device (including protocol overhead), counted across launches
+// since the date returned by Since
+func (peer *Peer) TotalBytesIn() int64 {
+	in, _ := peer.trafficTotals()
+	return in
+}
+
+// Returns the total number of bytes sent to this device (including protocol overhead), counted across launches since
+// the date returned by Since
+func (peer *Peer) TotalBytesOut() int64 {
+	_, out := peer.trafficTotals()
+	return out
+}
+
+// Returns since when traffic with this device is counted, or nil if no traffic was counted yet
+func (peer *Peer) Since() *Date {
+	rec := peer.connectionRecord()
+	if rec.TrafficSince.IsZero() {
+		return nil
+	}
+	return &Date{time: rec.TrafficSince}
+}
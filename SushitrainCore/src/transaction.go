@@ -0,0 +1,121 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+// A set of configuration changes that is validated and applied at once. Only changes made through the transaction
+// (see FolderWithID, PeerWithID and SetOptions) are part of it; other changes are applied right away as usual.
+type ConfigTransaction struct {
+	client *Client
+	batch  *ConfigurationBatch
+}
+
+var errTransactionDone = errors.New("transaction is no longer active")
+
+// Starts collecting configuration changes until Commit or Rollback is called
+func (clt *Client) BeginConfigTransaction() (*ConfigTransaction, error) {
+	batch, err := clt.BeginConfigurationBatch()
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigTransaction{client: clt, batch: batch}, nil
+}
+
+func (tx *ConfigTransaction) isActive() bool {
+	return tx.batch != nil
+}
+
+// Returns the folder with the given ID, of which changes made through the setters become part of the transaction, or
+// nil when it does not exist (in the transaction)
+func (tx *ConfigTransaction) FolderWithID(id string) *Folder {
+	if !tx.isActive() {
+		return nil
+	}
+	return tx.batch.FolderWithID(id)
+}
+
+// Returns the device with the given ID, of which changes made through the setters become part of the transaction, or
+// nil when the ID is invalid
+func (tx *ConfigTransaction) PeerWithID(deviceID string) *Peer {
+	if !tx.isActive() {
+		return nil
+	}
+	return tx.batch.PeerWithID(deviceID)
+}
+
+// Changes the options in the transaction. Only the keys present in the JSON object are changed; the format is that of
+// Syncthing's options configuration (e.g. `natEnabled`, `relaysEnabled`, `maxSendKbps`).
+func (tx *ConfigTransaction) SetOptions(optionsJSON []byte) error {
+	if !tx.isActive() {
+		return errTransactionDone
+	}
+	tx.batch.mutex.Lock()
+	_, err := mergeSettings(tx.batch.working.Options, optionsJSON)
+	tx.batch.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return tx.batch.change(func(cfg *config.Configuration) {
+		// The changes were checked above and only differ in the options they are merged with
+		if options, err := mergeSettings(cfg.Options, optionsJSON); err == nil {
+			cfg.Options = options
+		}
+	})
+}
+
+// Checks whether the current configuration with all changes in the transaction applied is valid, without applying it
+func (tx *ConfigTransaction) Validate() error {
+	if !tx.isActive() {
+		return errTransactionDone
+	}
+
+	// The validation Syncthing performs on a configuration change is not accessible directly, so we apply the
+	// changes to a scratch wrapper that is not saved (it has no path) and does not notify anyone
+	scratch := config.Wrap("", tx.client.config.RawCopy(), tx.client.deviceID(), events.NoopLogger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scratch.Serve(ctx)
+
+	tx.batch.mutex.Lock()
+	blocks := tx.batch.blocks
+	tx.batch.mutex.Unlock()
+	_, err := scratch.Modify(func(cfg *config.Configuration) {
+		for _, block := range blocks {
+			block(cfg)
+		}
+	})
+	return err
+}
+
+// Validates and applies all changes in the transaction, saving the configuration once. Returns whether Syncthing needs
+// to be restarted for (some of) the changes to take effect. When validation fails, the transaction stays active so
+// the changes can be corrected or rolled back.
+func (tx *ConfigTransaction) Commit() (bool, error) {
+	if err := tx.Validate(); err != nil {
+		return false, err
+	}
+	batch := tx.batch
+	tx.batch = nil
+	if err := batch.Commit(); err != nil {
+		return false, err
+	}
+	return tx.client.config.RequiresRestart(), nil
+}
+
+// Discards all changes in the transaction
+func (tx *ConfigTransaction) Rollback() {
+	if tx.isActive() {
+		tx.batch.Cancel()
+	}
+	tx.batch = nil
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"slices"
+
+	"github.com/syncthing/syncthing/lib/model"
+)
+
+func isPullingState(state string) bool {
+	return state == model.FolderSyncing.String() || state == model.FolderSyncWaiting.String() ||
+		state == model.FolderSyncPreparing.String()
+}
+
+func isScanningState(state string) bool {
+	return state == model.FolderScanning.String() || state == model.FolderScanWaiting.String()
+}
+
+// Returns whether files of the folder are being uploaded to a connected peer. Must be called with the client mutex held.
+func (clt *Client) isUploadingFolder(folderID string) bool {
+	for devID, uploadsPerFolder := range clt.uploadProgress {
+		if len(uploadsPerFolder[folderID]) == 0 {
+			continue
+		}
+		if peer := clt.PeerWithID(devID); peer != nil && peer.IsConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns whether the folder is pulling changes or uploading files to a connected peer
+func (fld *Folder) IsTransferring() bool {
+	clt := fld.client
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+	return isPullingState(clt.folderStates[fld.FolderID]) || clt.isUploadingFolder(fld.FolderID)
+}
+
+// Returns whether the folder is scanning (or waiting to scan) for local changes
+func (fld *Folder) IsScanning() bool {
+	clt := fld.client
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+	return isScanningState(clt.folderStates[fld.FolderID])
+}
+
+// Returns the IDs of the folders that are pulling changes or uploading files to a connected peer
+func (clt *Client) TransferringFolderIDs() *ListOfStrings {
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+
+	folderIDs := make([]string, 0)
+	for folderID, state := range clt.folderStates {
+		if isPullingState(state) {
+			folderIDs = append(folderIDs, folderID)
+		}
+	}
+	for _, uploadsPerFolder := range clt.uploadProgress {
+		for folderID := range uploadsPerFolder {
+			if !slices.Contains(folderIDs, folderID) && clt.isUploadingFolder(folderID) {
+				folderIDs = append(folderIDs, folderID)
+			}
+		}
+	}
+	slices.Sort(folderIDs)
+	return List(folderIDs)
+}
+
+// Returns the IDs of the folders that are scanning (or waiting to scan) for local changes
+func (clt *Client) ScanningFolderIDs() *ListOfStrings {
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+
+	folderIDs := make([]string, 0)
+	for folderID, state := range clt.folderStates {
+		if isScanningState(state) {
+			folderIDs = append(folderIDs, folderID)
+		}
+	}
+	slices.Sort(folderIDs)
+	return List(folderIDs)
+}
@@ -0,0 +1,205 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/versioner"
+)
+
+// A deleted or replaced version of a file, kept by simple or trash can versioning
+type TrashEntry struct {
+	Path        string
+	Size        int64
+	VersionTime *Date // When the file was moved to the trash
+	ModifiedAt  *Date
+}
+
+type TrashEntries struct {
+	items     []*TrashEntry
+	TotalSize int64
+}
+
+func (te *TrashEntries) Count() int {
+	return len(te.items)
+}
+
+func (te *TrashEntries) ItemAt(index int) *TrashEntry {
+	return te.items[index]
+}
+
+// Returns the versioner of the folder, when it uses simple or trash can versioning
+func (fld *Folder) trashVersioner() (versioner.Versioner, *config.FolderConfiguration, error) {
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return nil, nil, ErrFolderNotFound
+	}
+	if fc.Versioning.Type != "simple" && fc.Versioning.Type != "trashcan" {
+		return nil, nil, errors.New("folder does not use simple or trash can versioning")
+	}
+	v, err := versioner.New(*fc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, fc, nil
+}
+
+// Returns the versions of files kept in the trash of this folder, newest first
+func (fld *Folder) TrashEntries() (*TrashEntries, error) {
+	v, _, err := fld.trashVersioner()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := v.GetVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TrashEntries{items: make([]*TrashEntry, 0)}
+	for path, fileVersions := range versions {
+		for _, version := range fileVersions {
+			result.items = append(result.items, &TrashEntry{
+				Path:        path,
+				Size:        version.Size,
+				VersionTime: &Date{time: version.VersionTime},
+				ModifiedAt:  &Date{time: version.ModTime},
+			})
+			result.TotalSize += version.Size
+		}
+	}
+
+	slices.SortFunc(result.items, func(a, b *TrashEntry) int {
+		if c := b.VersionTime.time.Compare(a.VersionTime.time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return result, nil
+}
+
+// Restores the most recent version of this file from the trash of the folder. A file that currently exists is moved to
+// the trash in turn.
+func (entry *Entry) RestoreFromTrash() error {
+	fld := entry.Folder
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	v, _, err := fld.trashVersioner()
+	if err != nil {
+		return err
+	}
+	versions, err := v.GetVersions()
+	if err != nil {
+		return err
+	}
+
+	name := osutil.NormalizedFilename(entry.info.Name)
+	fileVersions, ok := versions[name]
+	if !ok || len(fileVersions) == 0 {
+		return errors.New("file is not in the trash")
+	}
+	latest := slices.MaxFunc(fileVersions, func(a, b versioner.FileVersion) int {
+		return a.VersionTime.Compare(b.VersionTime)
+	})
+
+	// In a selective folder, the file would be ignored (and eventually removed) when it is not selected
+	if fld.IsSelective() {
+		if err := fld.setExplicitlySelected(map[string]bool{entry.info.Name: true}); err != nil {
+			return err
+		}
+	}
+
+	if err := v.Restore(name, latest.VersionTime); err != nil {
+		return err
+	}
+	return fld.RescanSubdirectory(entry.info.Name)
+}
+
+// Permanently removes versions that were moved to the trash more than `olderThanDays` days ago (or all versions when
+// zero), and returns the number of bytes freed.
+func (fld *Folder) EmptyTrash(olderThanDays int) (int64, error) {
+	if olderThanDays < 0 {
+		return 0, errors.New("number of days cannot be negative")
+	}
+	_, fc, err := fld.trashVersioner()
+	if err != nil {
+		return 0, err
+	}
+
+	trashFS := trashFilesystem(fc)
+	cutoff := time.Now().Add(-time.Duration(olderThanDays) * 24 * time.Hour)
+
+	toRemove := make([]string, 0)
+	freed := int64(0)
+	err = trashFS.Walk(".", func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if path == "." || info.IsDir() || info.IsSymlink() {
+			return nil
+		}
+
+		// Simple versioning tags file names with the time they were moved to the trash, the trash can does not
+		versionTime := info.ModTime()
+		if _, tag := versioner.UntagFilename(path); tag != "" {
+			if t, err := time.ParseInLocation(versioner.TimeFormat, tag, time.Local); err == nil {
+				versionTime = t
+			}
+		}
+
+		if olderThanDays == 0 || versionTime.Before(cutoff) {
+			toRemove = append(toRemove, path)
+			freed += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, path := range toRemove {
+		if err := trashFS.Remove(path); err != nil {
+			slog.Warn("could not remove version from trash", "folderID", fld.FolderID, "path", path, "cause", err)
+			continue
+		}
+		deleteEmptyParentDirectories(trashFS, path)
+	}
+	slog.Info("emptied trash", "folderID", fld.FolderID, "files", len(toRemove), "bytes", freed)
+	return freed, nil
+}
+
+// Returns the file system versions are stored in, following the same rules as Syncthing's versioners
+func trashFilesystem(fc *config.FolderConfiguration) fs.Filesystem {
+	folderFS := fc.Filesystem()
+	if fc.Versioning.FSPath == "" {
+		return fs.NewFilesystem(folderFS.Type(), filepath.Join(folderFS.URI(), versioner.DefaultPath))
+	}
+
+	if fc.Versioning.FSType == config.FilesystemTypeBasic {
+		path, err := fs.ExpandTilde(fc.Versioning.FSPath)
+		if err != nil {
+			path = fc.Versioning.FSPath
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(folderFS.URI(), path)
+		}
+		return fs.NewFilesystem(fc.Versioning.FSType.ToFS(), path)
+	}
+	return fs.NewFilesystem(fc.Versioning.FSType.ToFS(), fc.Versioning.FSPath)
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/connections"
+	"github.com/syncthing/syncthing/lib/syncthing"
+	"github.com/syncthing/syncthing/lib/ur"
+	"github.com/syncthing/syncthing/lib/ur/contract"
+)
+
+const defaultUsageReportURL = "https://data.syncthing.net/newdata"
+
+// The usage reporting service Syncthing runs is not accessible to us, so for the preview we create our own, which
+// needs access to some parts of the model and connection service.
+type usageReportModel struct {
+	client *Client
+}
+
+func (m *usageReportModel) GlobalSize(folder string) (syncthing.Counts, error) {
+	return m.client.globalSize(folder)
+}
+
+func (m *usageReportModel) UsageReportingStats(report *contract.Report, version int, preview bool) {
+	// Block, transport and ignore statistics are kept inside the model, which we cannot access
+}
+
+type usageReportConnections struct {
+	connections.Service // Only NATType is used
+	client              *Client
+}
+
+func (c *usageReportConnections) NATType() string {
+	return c.client.NATStatus().NATType
+}
+
+// Returns the version of the usage report this version of Syncthing sends
+func (clt *Client) UsageReportVersion() int {
+	return ur.Version
+}
+
+// Returns the version of the usage report the user accepted, zero when not decided yet, or -1 when declined
+func (clt *Client) UsageReportingAccepted() int {
+	return clt.config.Options().URAccepted
+}
+
+// Returns the usage report that would be sent when reporting is accepted, as JSON. Block, transport and ignore pattern
+// statistics are not included in the preview, but are sent.
+func (clt *Client) UsageReportPreview() ([]byte, error) {
+	if clt.app == nil || clt.app.Internals == nil {
+		return nil, ErrStillLoading
+	}
+
+	svc := ur.New(clt.config, &usageReportModel{client: clt}, &usageReportConnections{client: clt}, true)
+	report, err := svc.ReportDataPreview(context.Background(), ur.Version)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// Accepts sending anonymous usage reports of the given version (use UsageReportVersion), or declines when version is
+// -1. Reports are sent to the Syncthing project once a day.
+func (clt *Client) SetUsageReportingAccepted(version int) error {
+	if version != -1 && (version < 1 || version > ur.Version) {
+		return errors.New("invalid usage report version")
+	}
+
+	return clt.changeConfiguration(func(cfg *config.Configuration) {
+		cfg.Options.URAccepted = version
+		cfg.Options.URSeen = max(cfg.Options.URSeen, ur.Version)
+		if version > 0 {
+			cfg.Options.URURL = defaultUsageReportURL
+		} else {
+			cfg.Options.URURL = ""
+		}
+	})
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+type VerifyDelegate interface {
+	OnProgress(fraction float64)
+	OnMismatch(path string, reason string)
+	OnFinished(filesChecked int, mismatches int)
+	OnError(error string)
+	IsCancelled() bool
+}
+
+// Rehashes the local copies of files and compares them against the index, reporting files whose contents differ (e.g.
+// because of bit rot or an incomplete write). Files that were modified locally since the last scan are skipped. When
+// maxMBPerSecond is larger than zero, reading is throttled to that rate. The check runs in the background.
+func (fld *Folder) VerifyLocalFiles(maxMBPerSecond int, delegate VerifyDelegate) error {
+	if fld.client.app == nil || fld.client.app.Internals == nil {
+		return ErrStillLoading
+	}
+
+	fc := fld.folderConfiguration()
+	if fc == nil {
+		return errors.New("invalid folder")
+	}
+	if fc.Type == config.FolderTypeReceiveEncrypted {
+		return errors.New("files in receive-encrypted folders cannot be verified")
+	}
+
+	go func() {
+		internals := fld.client.app.Internals
+		ffs := fc.Filesystem()
+
+		// Collect the paths first, so we don't keep the database busy while hashing
+		paths := make([]string, 0)
+		var totalBytes int64
+		for f, err := range zipError(internals.AllGlobalFiles(fld.FolderID)) {
+			if err != nil {
+				delegate.OnError(err.Error())
+				return
+			}
+			if f.Deleted || f.Type != protocol.FileInfoTypeFile {
+				continue
+			}
+			paths = append(paths, f.Name)
+			totalBytes += f.Size
+		}
+
+		started := time.Now()
+		var bytesRead int64
+		var bytesHashed int64 // Excluding skipped files, for throttling
+		checked := 0
+		mismatches := 0
+		delegate.OnProgress(0.0)
+
+		for _, path := range paths {
+			if delegate.IsCancelled() {
+				return
+			}
+
+			info, ok, err := internals.GlobalFileInfo(fld.FolderID, path)
+			if err != nil {
+				delegate.OnError(err.Error())
+				return
+			}
+			if !ok {
+				continue
+			}
+
+			nativePath := osutil.NativeFilename(path)
+			stat, err := ffs.Lstat(nativePath)
+			if err != nil || !stat.IsRegular() {
+				// Not available locally (e.g. not selected)
+				bytesRead += info.Size
+				continue
+			}
+
+			modTimeDifference := stat.ModTime().Sub(info.ModTime()).Abs()
+			if stat.Size() != info.Size || modTimeDifference > fc.ModTimeWindow() {
+				// Changed locally; the next scan will pick this up
+				bytesRead += info.Size
+				continue
+			}
+
+			file, err := ffs.Open(nativePath)
+			if err != nil {
+				delegate.OnError(err.Error())
+				return
+			}
+
+			fileStart := bytesRead
+			reason := ""
+			buffer := make([]byte, info.BlockSize())
+			for blockIndex, block := range info.Blocks {
+				if delegate.IsCancelled() {
+					file.Close()
+					return
+				}
+
+				n, err := file.ReadAt(buffer[:block.Size], block.Offset)
+				if err != nil && !(errors.Is(err, io.EOF) && n == block.Size) {
+					reason = fmt.Sprintf("could not read block %d: %s", blockIndex, err.Error())
+					break
+				}
+
+				hash := sha256.Sum256(buffer[:block.Size])
+				if !bytes.Equal(hash[:], block.Hash) {
+					reason = fmt.Sprintf("block %d does not match", blockIndex)
+					break
+				}
+
+				bytesRead += int64(block.Size)
+				bytesHashed += int64(block.Size)
+				delegate.OnProgress(float64(bytesRead) / float64(max(totalBytes, 1)))
+
+				// Throttle to the requested average rate
+				if maxMBPerSecond > 0 {
+					shouldHaveTaken := time.Duration(float64(bytesHashed) / float64(maxMBPerSecond*1024*1024) * float64(time.Second))
+					if elapsed := time.Since(started); elapsed < shouldHaveTaken {
+						time.Sleep(shouldHaveTaken - elapsed)
+					}
+				}
+			}
+			file.Close()
+			bytesRead = fileStart + info.Size
+
+			checked += 1
+			if reason != "" {
+				slog.Warn("local file does not match index", "folderID", fld.FolderID, "path", path, "reason", reason)
+				mismatches += 1
+				delegate.OnMismatch(path, reason)
+			}
+		}
+
+		delegate.OnFinished(checked, mismatches)
+	}()
+
+	return nil
+}
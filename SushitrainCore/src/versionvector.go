@@ -0,0 +1,112 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// How the versions of two entries relate (see VersionVector.Compare)
+const (
+	VersionOrderingEqual      = "equal"
+	VersionOrderingNewer      = "newer"      // This version includes all changes of the other, and more
+	VersionOrderingOlder      = "older"      // The other version includes all changes of this one, and more
+	VersionOrderingConcurrent = "concurrent" // Both versions contain changes the other does not have (a conflict)
+)
+
+// The number of changes a device made to a file, as recorded in its version vector. Syncthing usually uses the time of
+// the change (in seconds since the Unix epoch) as counter value, but only when it is higher than the previous value.
+type VersionCounter struct {
+	ShortDeviceID string
+	DeviceID      string // Empty when the device is not in our configuration
+	DeviceName    string
+	IsSelf        bool
+	Value         int64
+}
+
+type VersionVector struct {
+	vector protocol.Vector
+	data   []*VersionCounter
+}
+
+func (vv *VersionVector) Count() int {
+	return len(vv.data)
+}
+
+func (vv *VersionVector) ItemAt(index int) *VersionCounter {
+	if index < 0 || index >= len(vv.data) {
+		return nil
+	}
+	return vv.data[index]
+}
+
+// Returns how this version relates to another one (one of the VersionOrdering... constants)
+func (vv *VersionVector) Compare(other *VersionVector) string {
+	if other == nil {
+		other = &VersionVector{}
+	}
+	switch vv.vector.Compare(other.vector) {
+	case protocol.Equal:
+		return VersionOrderingEqual
+	case protocol.Greater:
+		return VersionOrderingNewer
+	case protocol.Lesser:
+		return VersionOrderingOlder
+	default:
+		return VersionOrderingConcurrent
+	}
+}
+
+// Returns the counters that are higher in this version than in the other one, i.e. those of the devices that made
+// changes the other version does not have. For concurrent versions, these are the devices that made the competing change.
+func (vv *VersionVector) CountersNewerThan(other *VersionVector) *VersionVector {
+	if other == nil {
+		other = &VersionVector{}
+	}
+	newer := &VersionVector{vector: vv.vector, data: make([]*VersionCounter, 0)}
+	for _, counter := range vv.data {
+		if counter.Value > other.counterValue(counter.ShortDeviceID) {
+			newer.data = append(newer.data, counter)
+		}
+	}
+	return newer
+}
+
+func (vv *VersionVector) counterValue(shortDeviceID string) int64 {
+	for _, counter := range vv.data {
+		if counter.ShortDeviceID == shortDeviceID {
+			return counter.Value
+		}
+	}
+	return 0
+}
+
+// Returns the version vector of the entry, with a counter for each device that ever changed the file
+func (entry *Entry) VersionVector() *VersionVector {
+	clt := entry.Folder.client
+	self := clt.deviceID().Short()
+	version := entry.info.Version
+	counters := make([]*VersionCounter, 0, len(version.Counters))
+	for _, counter := range version.Counters {
+		vc := &VersionCounter{
+			ShortDeviceID: counter.ID.String(),
+			IsSelf:        counter.ID == self,
+			Value:         int64(counter.Value),
+		}
+		if peer := clt.PeerWithShortID(vc.ShortDeviceID); peer != nil {
+			vc.DeviceID = peer.DeviceID()
+			vc.DeviceName = peer.Name()
+		}
+		counters = append(counters, vc)
+	}
+	return &VersionVector{vector: version, data: counters}
+}
+
+// Returns whether the entry carries information about which devices changed it (see Entry.VersionVector and
+// Entry.ModifiedByShortDeviceID). This is not the case for entries that were never scanned or announced.
+func (entry *Entry) ModificationHistoryAvailable() bool {
+	return !entry.info.Version.IsEmpty()
+}
@@ -0,0 +1,265 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	watchdogInterval            = time.Minute
+	defaultWatchdogStallMinutes = 10
+
+	// Database queries taking longer than this suggest the database is locked
+	watchdogSlowQueryDuration = 10 * time.Second
+)
+
+// Suspected causes of a stalled folder (see WatchdogDelegate)
+const (
+	WatchdogCausePullerStuck       = "pullerStuck"       // Data comes in, but the folder does not progress
+	WatchdogCauseDatabaseLocked    = "databaseLocked"    // Database queries for the folder are very slow
+	WatchdogCausePeerNotResponding = "peerNotResponding" // No data is received from the connected peers
+	WatchdogCauseNoSource          = "noSource"          // None of the connected peers has the needed versions
+)
+
+// Number of needed files looked at to tell whether any connected peer has them
+const watchdogSourceSampleSize = 25
+
+type WatchdogDelegate interface {
+	// Called once when a folder did not make progress for the configured time while pulling, even though it needs
+	// files and peers sharing it are connected
+	OnFolderStalled(folderID string, cause string, stalledMinutes int)
+}
+
+// What we look at to tell whether a folder makes progress
+type watchdogProgress struct {
+	needItems int
+	needBytes int64
+	sequence  int64
+	bytesDone int64
+}
+
+type watchdogFolder struct {
+	progress      watchdogProgress
+	since         time.Time // When the progress last changed
+	bytesReceived int64     // Received from the connected peers sharing the folder at that time
+	reported      bool
+}
+
+type watchdog struct {
+	mutex        sync.Mutex
+	stallMinutes int // Zero when the watchdog is disabled
+	autoRestart  bool
+	folders      map[string]*watchdogFolder
+}
+
+func newWatchdog() *watchdog {
+	return &watchdog{
+		stallMinutes: defaultWatchdogStallMinutes,
+		folders:      map[string]*watchdogFolder{},
+	}
+}
+
+// Configures after how many minutes without progress a folder is considered stalled (zero disables the watchdog), and
+// whether stalled folders are restarted automatically (by pausing and resuming them)
+func (clt *Client) SetWatchdog(stallMinutes int, autoRestart bool) error {
+	if stallMinutes < 0 {
+		return errors.New("number of minutes cannot be negative")
+	}
+	clt.watchdog.mutex.Lock()
+	defer clt.watchdog.mutex.Unlock()
+	clt.watchdog.stallMinutes = stallMinutes
+	clt.watchdog.autoRestart = autoRestart
+	clt.watchdog.folders = map[string]*watchdogFolder{}
+	return nil
+}
+
+func (clt *Client) serveWatchdog(ctx context.Context) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			clt.checkWatchdog()
+		}
+	}
+}
+
+func (clt *Client) checkWatchdog() {
+	if clt.app == nil || clt.app.Internals == nil || clt.sdb == nil || clt.config == nil {
+		return
+	}
+
+	wd := clt.watchdog
+	wd.mutex.Lock()
+	stallDuration := time.Duration(wd.stallMinutes) * time.Minute
+	autoRestart := wd.autoRestart
+	wd.mutex.Unlock()
+	if stallDuration == 0 {
+		return
+	}
+
+	received, _ := deviceTransferTotals()
+	now := time.Now()
+	seen := map[string]bool{}
+
+	for folderID, fc := range clt.config.Folders() {
+		progress, bytesReceived, isSlow, ok := clt.watchdogProgress(folderID, &fc, received)
+		if !ok {
+			continue
+		}
+		seen[folderID] = true
+
+		wd.mutex.Lock()
+		state, tracked := wd.folders[folderID]
+		if !tracked || state.progress != progress {
+			wd.folders[folderID] = &watchdogFolder{progress: progress, since: now, bytesReceived: bytesReceived}
+			wd.mutex.Unlock()
+			continue
+		}
+		stalledFor := now.Sub(state.since)
+		if state.reported || stalledFor < stallDuration {
+			wd.mutex.Unlock()
+			continue
+		}
+		state.reported = true
+		wd.mutex.Unlock()
+
+		cause := WatchdogCausePullerStuck
+		if isSlow {
+			cause = WatchdogCauseDatabaseLocked
+		} else if bytesReceived == state.bytesReceived {
+			cause = WatchdogCausePeerNotResponding
+			if !clt.neededVersionsAvailable(folderID, &fc) {
+				cause = WatchdogCauseNoSource
+			}
+		}
+		clt.reportStalledFolder(folderID, cause, stalledFor, autoRestart)
+	}
+
+	// Forget folders that are not pulling or no longer need anything (or were removed or paused)
+	wd.mutex.Lock()
+	for folderID := range wd.folders {
+		if !seen[folderID] {
+			delete(wd.folders, folderID)
+		}
+	}
+	wd.mutex.Unlock()
+}
+
+// Returns the progress of the folder, the number of bytes received from connected peers sharing it, whether querying
+// the database was suspiciously slow, and whether the folder should be watched at all (i.e. it is pulling, needs files
+// and peers sharing it are connected). Folders that are not pulling (e.g. because they are waiting for their turn, or
+// Syncthing gave up on the needed files until something changes) are not stalled.
+func (clt *Client) watchdogProgress(folderID string, fc *config.FolderConfiguration, received map[string]int64) (watchdogProgress, int64, bool, bool) {
+	var progress watchdogProgress
+	if fc.Paused || fc.Type == config.FolderTypeSendOnly {
+		return progress, 0, false, false
+	}
+
+	clt.mutex.Lock()
+	state := clt.folderStates[folderID]
+	clt.mutex.Unlock()
+	if state != model.FolderSyncing.String() && state != model.FolderSyncPreparing.String() {
+		return progress, 0, false, false
+	}
+
+	// Not using the query cache here, as the time the query takes is what we are interested in
+	started := time.Now()
+	need, err := clt.app.Internals.NeedSize(folderID, protocol.LocalDeviceID)
+	if err != nil {
+		return progress, 0, false, false
+	}
+	sequence, err := clt.sdb.GetDeviceSequence(folderID, protocol.LocalDeviceID)
+	if err != nil {
+		return progress, 0, false, false
+	}
+	isSlow := time.Since(started) > watchdogSlowQueryDuration
+
+	progress.needItems = need.Files + need.Directories + need.Symlinks + need.Deleted
+	progress.needBytes = need.Bytes
+	progress.sequence = sequence
+	if progress.needItems == 0 {
+		return progress, 0, false, false
+	}
+
+	connected := 0
+	bytesReceived := int64(0)
+	for _, deviceID := range fc.DeviceIDs() {
+		if deviceID == clt.deviceID() || !clt.app.Internals.IsConnectedTo(deviceID) {
+			continue
+		}
+		connected += 1
+		bytesReceived += received[deviceID.String()]
+	}
+	if connected == 0 {
+		return progress, 0, false, false
+	}
+
+	clt.mutex.Lock()
+	for _, file := range clt.downloadProgress[folderID] {
+		progress.bytesDone += file.BytesDone
+	}
+	clt.mutex.Unlock()
+	return progress, bytesReceived, isSlow, true
+}
+
+// Returns whether a connected peer has the version we need of at least one of the first needed files. When none does,
+// the folder cannot progress until a device that has them connects.
+func (clt *Client) neededVersionsAvailable(folderID string, fc *config.FolderConfiguration) bool {
+	progress, queued, rest, err := clt.app.Internals.NeedFolderFiles(folderID, 1, watchdogSourceSampleSize)
+	if err != nil {
+		return true
+	}
+
+	checked := 0
+	for _, file := range slices.Concat(progress, queued, rest) {
+		if file.IsDeleted() || file.IsDirectory() {
+			// Nothing to download
+			return true
+		}
+		checked += 1
+		for _, deviceID := range fc.DeviceIDs() {
+			if deviceID == clt.deviceID() || !clt.app.Internals.IsConnectedTo(deviceID) {
+				continue
+			}
+			remote, ok, err := clt.sdb.GetDeviceFile(folderID, deviceID, file.Name)
+			if err == nil && ok && !remote.IsInvalid() && remote.Version.Equal(file.Version) {
+				return true
+			}
+		}
+	}
+	return checked == 0
+}
+
+func (clt *Client) reportStalledFolder(folderID string, cause string, stalledFor time.Duration, autoRestart bool) {
+	slog.Warn("folder is not making progress", "folderID", folderID, "cause", cause, "stalledFor", stalledFor,
+		"autoRestart", autoRestart)
+	if clt.WatchdogDelegate != nil {
+		clt.WatchdogDelegate.OnFolderStalled(folderID, cause, int(stalledFor.Minutes()))
+	}
+
+	if autoRestart {
+		fld := clt.FolderWithID(folderID)
+		if fld == nil {
+			return
+		}
+		if err := fld.whilePaused(func() error { return nil }); err != nil {
+			slog.Warn("could not restart stalled folder", "folderID", folderID, "cause", err)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright (C) 2026 Tommy van der Vorst
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+package sushitrain
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+const (
+	webhooksFileName       = "webhooks.json"
+	webhookQueueSize       = 64 // Per webhook
+	webhookAttempts        = 4
+	webhookInitialBackoff  = 2 * time.Second
+	webhookRequestTimeout  = 10 * time.Second
+	webhookSignatureHeader = "X-Sushitrain-Signature"
+)
+
+type webhookRecord struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	HasSecret  bool     `json:"hasSecret"` // The secret itself is kept in the keychain (see KeychainDelegate)
+
+	// Secrets used to be stored here. These are moved to the keychain when the client is loaded.
+	Secret string `json:"secret,omitempty"`
+}
+
+type Webhook struct {
+	ID         string
+	URL        string
+	EventTypes *ListOfStrings
+}
+
+type Webhooks struct {
+	data []*Webhook
+}
+
+func (ws *Webhooks) Count() int {
+	return len(ws.data)
+}
+
+func (ws *Webhooks) ItemAt(index int) *Webhook {
+	if index < 0 || index >= len(ws.data) {
+		return nil
+	}
+	return ws.data[index]
+}
+
+type webhookDelivery struct {
+	hook    webhookRecord
+	payload []byte
+}
+
+type webhookPayload struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Registers a URL that will receive a POST request with a JSON payload for each event of the given types (e.g.
+// ItemFinished, FolderCompletion, DeviceConnected). When a secret is provided, the request carries an HMAC-SHA256
+// signature of the body in the X-Sushitrain-Signature header. The secret is kept in the keychain, so a
+// KeychainDelegate must be set to use one. Returns the ID of the new webhook.
+func (clt *Client) AddWebhook(hookURL string, eventTypes *ListOfStrings, secret string) (string, error) {
+	u, err := url.Parse(hookURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.New("webhook URL must be an HTTP(S) URL")
+	}
+
+	if eventTypes == nil || len(eventTypes.data) == 0 {
+		return "", errors.New("no event types selected")
+	}
+	for _, eventType := range eventTypes.data {
+		if events.UnmarshalEventType(eventType) == 0 {
+			return "", errors.New("unknown event type: " + eventType)
+		}
+	}
+
+	hook := webhookRecord{
+		ID:         rand.String(8),
+		URL:        hookURL,
+		EventTypes: slices.Clone(eventTypes.data),
+		HasSecret:  secret != "",
+	}
+	if secret != "" {
+		if clt.KeychainDelegate == nil {
+			return "", errors.New("webhook secrets cannot be stored without a keychain")
+		}
+		if err := clt.KeychainDelegate.StoreWebhookSecret(hook.ID, secret); err != nil {
+			return "", err
+		}
+	}
+	err = clt.webhooks.modify(func(hooks *[]webhookRecord) {
+		*hooks = append(*hooks, hook)
+	})
+	if err != nil {
+		return "", err
+	}
+	return hook.ID, nil
+}
+
+func (clt *Client) RemoveWebhook(id string) error {
+	hasSecret := false
+	err := clt.webhooks.modify(func(hooks *[]webhookRecord) {
+		*hooks = slices.DeleteFunc(*hooks, func(hook webhookRecord) bool {
+			if hook.ID == id {
+				hasSecret = hook.HasSecret
+				return true
+			}
+			return false
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	clt.mutex.Lock()
+	if queue, ok := clt.webhookQueues[id]; ok {
+		close(queue)
+		delete(clt.webhookQueues, id)
+	}
+	clt.mutex.Unlock()
+
+	if hasSecret && clt.KeychainDelegate != nil {
+		if err := clt.KeychainDelegate.StoreWebhookSecret(id, ""); err != nil {
+			slog.Warn("could not remove webhook secret from keychain", "id", id, "cause", err)
+		}
+	}
+	return nil
+}
+
+// Moves secrets of webhooks that were stored in the webhooks file to the keychain
+func (clt *Client) moveWebhookSecretsToKeychain() error {
+	if clt.KeychainDelegate == nil {
+		return nil
+	}
+
+	var moveErr error
+	err := clt.webhooks.modify(func(hooks *[]webhookRecord) {
+		for i := range *hooks {
+			hook := &(*hooks)[i]
+			if hook.Secret == "" {
+				continue
+			}
+			if err := clt.KeychainDelegate.StoreWebhookSecret(hook.ID, hook.Secret); err != nil {
+				moveErr = err
+				continue
+			}
+			hook.Secret = ""
+			hook.HasSecret = true
+		}
+	})
+	return errors.Join(err, moveErr)
+}
+
+// Returns the secret to sign requests to the webhook with, or an empty string when requests are not signed
+func (clt *Client) webhookSecret(hook webhookRecord) string {
+	if hook.Secret != "" {
+		// Not moved to the keychain (yet)
+		return hook.Secret
+	}
+	if !hook.HasSecret || clt.KeychainDelegate == nil {
+		return ""
+	}
+	return clt.KeychainDelegate.WebhookSecret(hook.ID)
+}
+
+func (clt *Client) Webhooks() *Webhooks {
+	list := make([]*Webhook, 0)
+	clt.webhooks.read(func(hooks *[]webhookRecord) {
+		for _, hook := range *hooks {
+			list = append(list, &Webhook{ID: hook.ID, URL: hook.URL, EventTypes: List(slices.Clone(hook.EventTypes))})
+		}
+	})
+	return &Webhooks{data: list}
+}
+
+// Queues the event for delivery to webhooks that are interested in it
+func (clt *Client) dispatchWebhooks(evt events.Event) {
+	eventType := evt.Type.String()
+	var interested []webhookRecord
+	clt.webhooks.read(func(hooks *[]webhookRecord) {
+		for _, hook := range *hooks {
+			if slices.Contains(hook.EventTypes, eventType) {
+				interested = append(interested, hook)
+			}
+		}
+	})
+	if len(interested) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(webhookPayload{Type: eventType, Time: evt.Time, Data: evt.Data})
+	if err != nil {
+		slog.Warn("could not encode webhook payload", "type", eventType, "cause", err)
+		return
+	}
+
+	// Each webhook has its own queue, so that a slow or unreachable endpoint does not hold up the others
+	clt.mutex.Lock()
+	defer clt.mutex.Unlock()
+	for _, hook := range interested {
+		queue, ok := clt.webhookQueues[hook.ID]
+		if !ok {
+			queue = make(chan webhookDelivery, webhookQueueSize)
+			clt.webhookQueues[hook.ID] = queue
+			go clt.serveWebhook(clt.ctx, queue)
+		}
+
+		select {
+		case queue <- webhookDelivery{hook: hook, payload: payload}:
+		default:
+			slog.Warn("webhook queue is full, dropping event", "url", hook.URL, "type", eventType)
+		}
+	}
+}
+
+// Delivers the events queued for a webhook in order, until the webhook is removed (which closes the queue)
+func (clt *Client) serveWebhook(ctx context.Context, queue chan webhookDelivery) {
+	defer func() {
+		// Forget the queue when stopping, so that a new one is started when the client is started again
+		clt.mutex.Lock()
+		defer clt.mutex.Unlock()
+		for id, q := range clt.webhookQueues {
+			if q == queue {
+				delete(clt.webhookQueues, id)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery, ok := <-queue:
+			if !ok {
+				return
+			}
+			secret := clt.webhookSecret(delivery.hook)
+			if delivery.hook.HasSecret && secret == "" {
+				slog.Warn("webhook secret not found in keychain, not delivering", "url", delivery.hook.URL)
+				continue
+			}
+			backoff := webhookInitialBackoff
+			for attempt := 1; ; attempt++ {
+				err := deliverWebhook(ctx, delivery, secret)
+				if err == nil {
+					break
+				}
+				if attempt == webhookAttempts {
+					slog.Warn("webhook delivery failed, giving up", "url", delivery.hook.URL, "attempts", attempt, "cause", err)
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+			}
+		}
+	}
+}
+
+func deliverWebhook(ctx context.Context, delivery webhookDelivery, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.hook.URL, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(delivery.payload)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}